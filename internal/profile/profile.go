@@ -32,6 +32,9 @@ type Profile struct {
 	Version string
 	// InstanceURL is the url of your memos instance.
 	InstanceURL string
+	// MetricsAddr is the binding address of a separate listener serving Prometheus metrics.
+	// Metrics are not served when it is empty.
+	MetricsAddr string
 }
 
 func checkDataDir(dataDir string) (string, error) {
@@ -0,0 +1,185 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// maxActionItems caps how many action items are returned to the client.
+	maxActionItems = 20
+
+	actionItemsFormatJSON     = "json"
+	actionItemsFormatMarkdown = "markdown"
+)
+
+// actionItemsFormats are the formats /ai/action_items can reply in.
+var actionItemsFormats = []string{actionItemsFormatJSON, actionItemsFormatMarkdown}
+
+// actionItemMarkerPattern matches list and checklist markers a model may leave at the start
+// of an item, such as "- [ ] ", "* " or "1. ".
+var actionItemMarkerPattern = regexp.MustCompile(`^(?:[-*+]\s+|\d+[.)]\s+)?(?:\[[ xX]?\]\s*)?`)
+
+type ActionItemsRequest struct {
+	Content string `json:"content"`
+	// Format is "json" (the default) for a list of items or "markdown" for a checklist
+	// ready to insert into a memo.
+	Format string `json:"format"`
+	// Stream sends the items as server-sent events while the model generates them, each
+	// event carrying the response in the requested format with all the items so far.
+	Stream bool `json:"stream"`
+}
+
+type ActionItem struct {
+	Text string `json:"text"`
+	Done bool   `json:"done"`
+}
+
+type ActionItemsResponse struct {
+	Items []ActionItem `json:"items"`
+}
+
+type ActionItemsMarkdownResponse struct {
+	// Markdown is a memo checklist with one "- [ ] item" line per action item.
+	Markdown string `json:"markdown"`
+}
+
+// ActionItems extracts the tasks and to-dos from a memo, such as meeting notes.
+func (s *AIService) ActionItems(c echo.Context) error {
+	ctx := c.Request().Context()
+	apiKey, err := s.requireAPIKey(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	request := new(ActionItemsRequest)
+	if err := c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	}
+	if err := s.validateContent(request.Content); err != nil {
+		return err
+	}
+	format := strings.ToLower(strings.TrimSpace(request.Format))
+	if format == "" {
+		format = actionItemsFormatJSON
+	}
+	if !slices.Contains(actionItemsFormats, format) {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("format must be one of %s", strings.Join(actionItemsFormats, ", ")))
+	}
+
+	model, err := s.resolveModel("")
+	if err != nil {
+		return err
+	}
+	prompt, err := s.prompt(promptActionItems, actionItemsPromptData{MaxItems: maxActionItems})
+	if err != nil {
+		return err
+	}
+	req := &ChatCompletionRequest{
+		Model: model,
+		Messages: []ChatCompletionMessage{
+			{Role: "system", Content: withContextGuard(prompt)},
+			{Role: "user", Content: delimitContext("note", request.Content)},
+		},
+		ResponseFormat: jsonObjectFormat,
+	}
+	if request.Stream {
+		return s.streamJSONArray(c, apiKey, req, func(elements []json.RawMessage) any {
+			return actionItemsResponse(format, decodeActionItems(elements))
+		})
+	}
+	// The items array is found inside the {"items": [...]} object, and bare arrays from
+	// models that ignore the format still parse.
+	var items []ActionItem
+	if err := s.completeJSON(ctx, apiKey, req, func(content string) error {
+		var err error
+		items, err = parseActionItems(content)
+		return err
+	}); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, actionItemsResponse(format, items))
+}
+
+// actionItemsResponse normalizes items and wraps them in the response for format.
+func actionItemsResponse(format string, items []ActionItem) any {
+	items = normalizeActionItems(items)
+	if format == actionItemsFormatMarkdown {
+		return &ActionItemsMarkdownResponse{
+			Markdown: actionItemsMarkdown(items),
+		}
+	}
+	return &ActionItemsResponse{
+		Items: items,
+	}
+}
+
+// parseActionItems decodes the JSON array of action items in content. Items may be objects
+// with text and done fields or plain strings; anything else is skipped rather than failing
+// the whole reply.
+func parseActionItems(content string) ([]ActionItem, error) {
+	var raw []json.RawMessage
+	if err := parseJSONArray(content, &raw); err != nil {
+		return nil, err
+	}
+	return decodeActionItems(raw), nil
+}
+
+// decodeActionItems decodes the elements of an action items array, skipping any that are
+// neither objects nor strings.
+func decodeActionItems(raw []json.RawMessage) []ActionItem {
+	items := make([]ActionItem, 0, len(raw))
+	for _, value := range raw {
+		var text string
+		if json.Unmarshal(value, &text) == nil {
+			items = append(items, ActionItem{Text: text})
+			continue
+		}
+		var item ActionItem
+		if json.Unmarshal(value, &item) == nil {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// normalizeActionItems puts each item on a single line without list or checkbox markers,
+// drops blank and duplicate items (ignoring case) and caps the result at maxActionItems.
+// It always returns a non-nil slice.
+func normalizeActionItems(items []ActionItem) []ActionItem {
+	result := []ActionItem{}
+	for _, item := range items {
+		item.Text = strings.Join(strings.Fields(item.Text), " ")
+		item.Text = strings.TrimSpace(actionItemMarkerPattern.ReplaceAllString(item.Text, ""))
+		duplicate := slices.ContainsFunc(result, func(kept ActionItem) bool { return strings.EqualFold(kept.Text, item.Text) })
+		if item.Text == "" || duplicate {
+			continue
+		}
+		result = append(result, item)
+		if len(result) == maxActionItems {
+			break
+		}
+	}
+	return result
+}
+
+// actionItemsMarkdown formats items as a memo checklist.
+func actionItemsMarkdown(items []ActionItem) string {
+	var markdown strings.Builder
+	for _, item := range items {
+		if item.Done {
+			markdown.WriteString("- [x] ")
+		} else {
+			markdown.WriteString("- [ ] ")
+		}
+		markdown.WriteString(item.Text)
+		markdown.WriteString("\n")
+	}
+	return markdown.String()
+}
@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseActionItems(t *testing.T) {
+	items, err := parseActionItems("Here you go:\n```json\n{\"items\": [{\"text\": \"Book the room\", \"done\": true}, \"Email Sam\", 42, {\"done\": false}]}\n```")
+	require.NoError(t, err)
+	require.Equal(t, []ActionItem{{Text: "Book the room", Done: true}, {Text: "Email Sam"}, {}}, items)
+
+	_, err = parseActionItems("Book the room and email Sam.")
+	require.Error(t, err)
+}
+
+func TestNormalizeActionItems(t *testing.T) {
+	require.Equal(t, []ActionItem{{Text: "Book the room"}, {Text: "Email Sam", Done: true}, {Text: "Send notes"}}, normalizeActionItems([]ActionItem{
+		{Text: "- [ ] Book the\nroom"},
+		{Text: "  "},
+		{Text: "book the room"},
+		{Text: "* Email Sam", Done: true},
+		{Text: "1. Send notes"},
+	}))
+	require.Equal(t, []ActionItem{}, normalizeActionItems(nil))
+}
+
+func TestActionItems(t *testing.T) {
+	const reply = `{"items": [{"text": "Book the room", "done": false}, {"text": "Email Sam", "done": true}]}`
+	var received *ChatCompletionRequest
+	newChatUpstream(t, reply, func(req *ChatCompletionRequest) {
+		received = req
+	})
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"content":"Meeting notes: book the room, Sam was emailed."}`)
+	require.NoError(t, s.ActionItems(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	response := new(ActionItemsResponse)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), response))
+	require.Equal(t, []ActionItem{{Text: "Book the room"}, {Text: "Email Sam", Done: true}}, response.Items)
+	require.Equal(t, jsonObjectFormat, received.ResponseFormat)
+	require.Contains(t, received.Messages[1].Content, "Meeting notes: book the room")
+
+	c, rec = newTestContext(`{"content":"Meeting notes: book the room, Sam was emailed.","format":"markdown"}`)
+	require.NoError(t, s.ActionItems(c))
+	markdown := new(ActionItemsMarkdownResponse)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), markdown))
+	require.Equal(t, "- [ ] Book the room\n- [x] Email Sam\n", markdown.Markdown)
+}
+
+func TestActionItemsEmpty(t *testing.T) {
+	newChatUpstream(t, `{"items": []}`, nil)
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"content":"Nothing to do here."}`)
+	require.NoError(t, s.ActionItems(c))
+	require.JSONEq(t, `{"items":[]}`, rec.Body.String())
+}
+
+func TestActionItemsRejectsUnknownFormat(t *testing.T) {
+	s := NewAIService(nil, "", "test-key")
+	c, _ := newTestContext(`{"content":"Call Sam","format":"csv"}`)
+	httpErr, ok := s.ActionItems(c).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusBadRequest, httpErr.Code)
+}
@@ -1,109 +1,881 @@
 package ai
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
-	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/usememos/memos/server/auth"
+	"github.com/usememos/memos/store"
+)
+
+const (
+	// maxLoggedBodyBytes bounds how much of an upstream error body is written to the log.
+	maxLoggedBodyBytes = 512
+	// defaultTimeout is the upstream request timeout used when MEMOS_AI_TIMEOUT is unset.
+	defaultTimeout = 60 * time.Second
+	// defaultMaxResponseBytes caps upstream responses when MEMOS_AI_MAX_RESPONSE_BYTES is unset.
+	defaultMaxResponseBytes = 10 << 20
 )
 
 type AIService struct {
-	apiKey string
+	store         *store.Store
+	authenticator *auth.Authenticator
+	// userHashKey keys the hashed user identifier sent upstream; omitUser disables it.
+	userHashKey []byte
+	omitUser    bool
+
+	// apiKey is the server-wide key, replaced at runtime through /ai/config/key.
+	apiKeyMutex sync.RWMutex
+	apiKey      string
+	// keyInvalid is set while the provider rejects apiKey.
+	keyInvalid atomic.Bool
+	logger     *slog.Logger
+	// auditLogger records AI interactions for compliance; nil when auditing is disabled.
+	auditLogger *auditLogger
+	// debug includes raw upstream error bodies in error responses.
+	debug    bool
+	provider Provider
+	// fallback serves chat completions when provider fails; nil disables failover.
+	fallback    Provider
+	fallbackKey string
+	// providerConfig rebuilds the provider for per-request base_url overrides.
+	providerConfig ProviderConfig
+	// targetErr is set when the configured endpoint failed validateTarget; upstream calls are refused.
+	targetErr error
+	// client is shared by all outbound requests so connections are pooled.
+	client  httpDoer
+	timeout time.Duration
+	// allowPrivate permits provider endpoints on private addresses.
+	allowPrivate bool
+	// allowedHosts lists the only hosts upstream requests may go to, including base URL
+	// overrides. Empty allows the configured endpoints and disables overrides.
+	allowedHosts []string
+	// defaultModel is used when a request names no model: MEMOS_AI_DEFAULT_MODEL or the provider's default.
+	defaultModel string
+	// allowedModels restricts which models may be requested. Empty allows any model.
+	allowedModels []string
+	// modelAliases maps friendly model names to the provider model IDs they stand for.
+	modelAliases  map[string]string
+	maxMessages   int
+	maxInputBytes int
+	// autoTruncate retries context_too_long requests once with the oldest messages dropped.
+	autoTruncate bool
+	// slowThreshold is how long a request may take before it is logged as slow.
+	slowThreshold time.Duration
+	// overflowModel, when set, retries context_too_long requests once with this
+	// larger-context model instead of truncating them.
+	overflowModel string
+	// maxContextTokens caps the tokens of memo context in a prompt; zero disables the cap.
+	maxContextTokens int
+	// maxImageBytes caps the decoded size of each inline image.
+	maxImageBytes int
+	// maxResponseBytes caps how much of an upstream response is buffered or streamed.
+	maxResponseBytes int64
+	// embeddingModel overrides the provider's default embedding model.
+	embeddingModel string
+	// developerRoleModels lists model prefixes whose system messages are sent as developer.
+	developerRoleModels []string
+	// reasoningModels lists the prefixes of reasoning models; rejectReasoningParams fails
+	// requests that set sampling parameters for them instead of dropping the parameters.
+	reasoningModels       []string
+	rejectReasoningParams bool
+	// askTopK is the number of memos retrieved as context for /ai/ask.
+	askTopK int
+	// embeddingStore provides memo embeddings for /ai/ask; nil uses memoIndex.
+	embeddingStore MemoEmbeddingStore
+	memoIndex      memoIndex
+	// titleMaxChars is the maximum length of generated titles.
+	titleMaxChars int
+	// systemPrompt is prepended to chat requests that carry no system message of their own.
+	systemPrompt string
+	// localeAware asks for replies in the user's language when there is no system prompt.
+	localeAware bool
+	// extraHeaders are added to every request to the configured provider.
+	extraHeaders http.Header
+	// responseFilters rewrite model output, such as redacting MEMOS_AI_REDACT_PATTERNS.
+	responseFilters responseFilters
+	// prompts holds the endpoint prompt templates.
+	prompts *promptTemplates
+	// maxRetries is the number of times a transient upstream failure is retried.
+	maxRetries     int
+	retryBaseDelay time.Duration
+	// jsonRetries is the number of times a malformed JSON reply is re-requested.
+	jsonRetries int
+	// retryBudget is how many upstream attempts a client request may make in total.
+	retryBudget int
+	// maxHistoryMessages caps the saved session messages sent upstream; historyStrategy
+	// decides what happens to older ones.
+	maxHistoryMessages int
+	historyStrategy    string
+	// sseKeepAlive is how long a stream may be silent before a keep-alive comment is sent.
+	sseKeepAlive time.Duration
+	// streamReplayTTL is how long streamed events are kept for clients that reconnect with
+	// Last-Event-ID; zero disables replay.
+	streamReplayTTL time.Duration
+	replays         streamReplays
+	// streamFlushBytes and streamFlushInterval enable buffering of streamed chunks.
+	streamFlushBytes    int
+	streamFlushInterval time.Duration
+	// streamWriteTimeout aborts a stream whose client has stopped reading.
+	streamWriteTimeout time.Duration
+
+	modelsCache    modelsCache
+	modelsCacheTTL time.Duration
+	// healthCache caches /ai/health results for healthCacheTTL.
+	healthCache healthCache
+	// requests tracks in-flight requests so Shutdown can drain them.
+	requests requestTracker
+	// streams tracks in-progress streams so /ai/cancel can stop them.
+	streams        streamRegistry
+	healthTimeout  time.Duration
+	healthCacheTTL time.Duration
+	// jobs holds the summarization jobs queued through /ai/jobs.
+	jobs jobQueue
+
+	// breaker fails fast during sustained upstream outages; nil disables it.
+	breaker *circuitBreaker
+	// concurrency caps in-flight upstream requests; nil means unlimited.
+	concurrency *concurrencyLimiter
+	// moderationCache holds recent moderation verdicts; nil when moderation is disabled.
+	moderationCache *responseCache
+	// responseCache stores non-streaming responses; nil when caching is disabled.
+	responseCache *responseCache
+	// idempotency keeps responses to requests sent with an Idempotency-Key for replay.
+	idempotency *responseCache
+	// inflight coalesces identical concurrent non-streaming requests.
+	inflight singleflight.Group
+	// quota records per-user usage and enforces daily token limits; nil without a store.
+	quota *quotaTracker
+	// pricing prices the tokens recorded by quota.
+	pricing pricingTable
+	// rateLimiter is nil when rate limiting is disabled.
+	rateLimiter *rateLimiter
 }
 
-func NewAIService(apiKey string) *AIService {
-	return &AIService{
-		apiKey: apiKey,
+// NewAIService creates a new AI service.
+// The store is used to authenticate requests and look up per-user API keys; it may be nil.
+func NewAIService(store *store.Store, secret string, apiKey string) *AIService {
+	return NewAIServiceWithLogger(store, secret, apiKey, slog.Default())
+}
+
+// NewAIServiceWithLogger creates an AIService that writes its logs to the given logger.
+// Its configuration is read from the environment; a non-empty apiKey overrides the
+// environment's key. Invalid prompt templates or CA certificates are logged and replaced by
+// the defaults; an endpoint missing from MEMOS_AI_ALLOWED_HOSTS is logged and upstream calls
+// are refused.
+func NewAIServiceWithLogger(store *store.Store, secret string, apiKey string, logger *slog.Logger) *AIService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	cfg := LoadConfig(logger)
+	if apiKey != "" {
+		cfg.APIKey = apiKey
+	}
+	s, err := NewAIServiceFromConfig(store, secret, cfg, logger)
+	if err != nil {
+		logger.Error("invalid AI configuration, using the defaults", "error", err)
+		cfg.PromptsDir, cfg.CACert = "", ""
+		s, err = NewAIServiceFromConfig(store, secret, cfg, logger)
+	}
+	if errors.Is(err, errHostNotAllowed) {
+		// There is no default for the allowlist, so refuse upstream calls instead.
+		cfg.AllowedHosts = nil
+		s, _ = NewAIServiceFromConfig(store, secret, cfg, logger)
+		s.targetErr = err
+	}
+	return s
+}
+
+// NewAIServiceFromConfig creates an AIService from an explicit configuration. It fails
+// when a prompt template in cfg.PromptsDir or the cfg.CACert file cannot be read or parsed,
+// or when cfg.AllowedHosts is non-empty and does not list the provider endpoint's host.
+func NewAIServiceFromConfig(store *store.Store, secret string, cfg Config, logger *slog.Logger) (*AIService, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	cfg = cfg.withDefaults()
+	prompts, err := loadPromptTemplates(cfg.PromptsDir)
+	if err != nil {
+		return nil, err
 	}
+	provider, err := newProvider(cfg.Provider)
+	if err != nil {
+		logger.Error("invalid AI provider, falling back to openai", "error", err)
+		cfg.Provider = ProviderConfig{Name: providerOpenAI, BaseURL: cfg.Provider.BaseURL}
+		provider, _ = newProvider(cfg.Provider)
+	}
+	_, mock := provider.(*mockProvider)
+	if mock {
+		// The mock answers in-process: there is no endpoint to restrict and nothing to
+		// fail over to, and no request may reach the network.
+		logger.Warn("using the mock AI provider, replies are canned")
+		cfg.AllowedHosts = nil
+		cfg.FallbackBaseURL = ""
+		cfg.HTTPClient = mockTransport{}
+	}
+	if err := checkAllowedHost(cfg.AllowedHosts, providerEndpoint(provider)); err != nil {
+		return nil, err
+	}
+	var targetErr error
+	if err := validateTarget(providerEndpoint(provider), cfg.AllowPrivate); err != nil && !mock {
+		if errors.Is(err, errBlockedTarget) {
+			logger.Error("AI provider endpoint is not allowed, set MEMOS_AI_ALLOW_PRIVATE=true for self-hosted providers", "error", err)
+			targetErr = err
+		} else {
+			// The endpoint may be unreachable at startup; the upstream call will report it.
+			logger.Warn("failed to validate AI provider endpoint", "error", err)
+		}
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		if client, err = newHTTPClient(logger, cfg); err != nil {
+			return nil, err
+		}
+	}
+	var limiter *rateLimiter
+	if cfg.RateLimit > 0 {
+		limiter = newRateLimiter(cfg.RateLimit)
+	}
+	var breaker *circuitBreaker
+	if cfg.BreakerThreshold > 0 {
+		breaker = newCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown)
+	}
+	var concurrency *concurrencyLimiter
+	if cfg.MaxConcurrency > 0 {
+		concurrency = newConcurrencyLimiter(cfg.MaxConcurrency, cfg.ConcurrencyWait)
+	}
+	var cache *responseCache
+	if cfg.CacheTTL > 0 {
+		cache = newResponseCache(cfg.CacheSize, cfg.CacheTTL)
+	}
+	var moderationCache *responseCache
+	if cfg.Moderation {
+		moderationCache = newResponseCache(moderationCacheSize, moderationCacheTTL)
+	}
+	var quota *quotaTracker
+	if store != nil {
+		quota = newQuotaTracker(store, int64(cfg.DailyTokenLimit))
+	}
+	var audit *auditLogger
+	if cfg.Audit {
+		audit = newAuditLogger(logger, cfg.AuditContent)
+	}
+	defaultModel := cfg.DefaultModel
+	if defaultModel == "" {
+		defaultModel = provider.DefaultModel()
+	}
+	modelAliases := newModelAliases(logger, cfg.ModelAliases, provider, cfg.AllowedModels)
+	return &AIService{
+		store:         store,
+		authenticator: auth.NewAuthenticator(store, secret),
+		userHashKey:   []byte(secret),
+		omitUser:      cfg.OmitUser,
+
+		apiKey:         cfg.APIKey,
+		logger:         logger,
+		auditLogger:    audit,
+		debug:          cfg.Debug,
+		provider:       provider,
+		providerConfig: cfg.Provider,
+		fallback:       newFallbackProvider(logger, cfg),
+		fallbackKey:    cfg.FallbackAPIKey,
+		targetErr:      targetErr,
+		client:         client,
+		timeout:        cfg.Timeout,
+
+		allowPrivate:        cfg.AllowPrivate,
+		defaultModel:        defaultModel,
+		allowedModels:       cfg.AllowedModels,
+		allowedHosts:        cfg.AllowedHosts,
+		maxMessages:         cfg.MaxMessages,
+		maxInputBytes:       cfg.MaxInputBytes,
+		autoTruncate:        cfg.AutoTruncate,
+		maxImageBytes:       cfg.MaxImageBytes,
+		maxResponseBytes:    cfg.MaxResponseBytes,
+		systemPrompt:        cfg.SystemPrompt,
+		prompts:             prompts,
+		embeddingModel:      cfg.EmbeddingModel,
+		developerRoleModels: cfg.DeveloperRoleModels,
+		reasoningModels:     cfg.ReasoningModels,
+		askTopK:             cfg.AskTopK,
+		titleMaxChars:       cfg.TitleMaxChars,
+		maxRetries:          cfg.MaxRetries,
+		retryBaseDelay:      defaultRetryBaseDelay,
+		jsonRetries:         cfg.JSONRetries,
+		retryBudget:         cfg.RetryBudget,
+		maxHistoryMessages:  cfg.MaxHistoryMessages,
+		historyStrategy:     cfg.HistoryStrategy,
+		sseKeepAlive:        cfg.SSEKeepAlive,
+		streamReplayTTL:     cfg.StreamReplayTTL,
+		streamFlushBytes:    cfg.StreamFlushBytes,
+		streamFlushInterval: cfg.StreamFlushInterval,
+		streamWriteTimeout:  cfg.StreamWriteTimeout,
+		modelsCacheTTL:      cfg.ModelsCacheTTL,
+		healthTimeout:       cfg.HealthTimeout,
+		healthCacheTTL:      cfg.HealthCacheTTL,
+		rateLimiter:         limiter,
+		breaker:             breaker,
+		concurrency:         concurrency,
+		responseCache:       cache,
+		moderationCache:     moderationCache,
+		idempotency:         newResponseCache(defaultCacheSize, cfg.IdempotencyTTL),
+		quota:               quota,
+		pricing:             cfg.Pricing,
+
+		rejectReasoningParams: cfg.RejectReasoningParams,
+		modelAliases:          modelAliases,
+		responseFilters:       newResponseFilters(logger, cfg.RedactPatterns),
+		localeAware:           cfg.LocaleAware,
+		extraHeaders:          newExtraHeaders(logger, cfg.ExtraHeaders, cfg.OverrideAuthHeaders),
+		slowThreshold:         cfg.SlowThreshold,
+		overflowModel:         newOverflowModel(logger, cfg.OverflowFallbackModel, modelAliases, cfg.AllowedModels),
+		maxContextTokens:      cfg.MaxContextTokens,
+	}, nil
 }
 
 type ChatCompletionMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// Parts holds multi-part content such as images. When set it is sent instead of
+	// Content, which then contains only the joined text parts.
+	Parts []ContentPart `json:"-"`
+	// ToolCalls and ToolCallID carry tool calling turns through unchanged.
+	ToolCalls  json.RawMessage `json:"tool_calls,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
 }
 
 type ChatCompletionRequest struct {
 	Model    string                  `json:"model"`
 	Messages []ChatCompletionMessage `json:"messages"`
+	Stream   bool                    `json:"stream,omitempty"`
+	// StreamOptions is set on streaming requests so OpenAI-compatible providers report usage.
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+
+	// Optional generation parameters, forwarded only when set.
+	Temperature      *float64 `json:"temperature,omitempty"`
+	MaxTokens        *int     `json:"max_tokens,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	// N is the number of choices to generate, at most maxChoices. Only OpenAI-compatible
+	// providers honor it; the others always return a single choice.
+	N *int `json:"n,omitempty"`
+	// ReasoningEffort is forwarded to reasoning models, which also reject the sampling
+	// parameters above; see applyReasoningModel.
+	ReasoningEffort *string `json:"reasoning_effort,omitempty"`
+	// MaxCompletionTokens replaces MaxTokens for reasoning models on OpenAI-compatible
+	// providers, which reject max_tokens.
+	MaxCompletionTokens *int `json:"max_completion_tokens,omitempty"`
+	// Seed asks for deterministic sampling and LogitBias adjusts the likelihood of token IDs
+	// by -100 to 100. Both are stripped for providers that don't support them; see
+	// applyDeterminism.
+	Seed      *int           `json:"seed,omitempty"`
+	LogitBias map[string]int `json:"logit_bias,omitempty"`
+
+	// User is a hashed identifier of the memos user, set by the server before the request
+	// is sent; any value from the client is replaced.
+	User string `json:"user,omitempty"`
+
+	// SessionID continues a saved conversation: its history is sent before Messages, and the
+	// new messages and the reply are appended to it. It is never forwarded upstream.
+	SessionID int32 `json:"session_id,omitempty"`
+
+	// MemoIDs attaches the current user's memos with these IDs as context, in a system
+	// message built from their content. It is never forwarded upstream.
+	MemoIDs []int32 `json:"memo_ids,omitempty"`
+
+	// BaseURL overrides the provider endpoint for this request. It must be on an allowed
+	// host (MEMOS_AI_ALLOWED_HOSTS) and is never forwarded upstream.
+	BaseURL string `json:"base_url,omitempty"`
+
+	// ResponseFormat requests JSON output, e.g. {"type":"json_object"}. Providers without
+	// support receive the request as a prompt instruction instead.
+	ResponseFormat json.RawMessage `json:"response_format,omitempty"`
+
+	// Tool definitions are passed through untouched to OpenAI-compatible providers.
+	Tools      []json.RawMessage `json:"tools,omitempty"`
+	ToolChoice json.RawMessage   `json:"tool_choice,omitempty"`
+}
+
+// StreamOptions configures what a streaming response includes.
+type StreamOptions struct {
+	// IncludeUsage asks for a final chunk carrying the token usage of the whole stream.
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// StatusResponse reports whether AI features are available to the frontend.
+type StatusResponse struct {
+	Enabled bool `json:"enabled"`
+	// Reason explains why AI features are disabled: "not_configured" or "invalid_api_key".
+	Reason string `json:"reason,omitempty"`
 }
 
 func (s *AIService) RegisterRoutes(g *echo.Group) {
-	g.POST("/ai/chat_completion", s.ChatCompletion)
+	aiGroup := g.Group("/ai", requestIDMiddleware, s.errorMiddleware, s.metricsMiddleware, s.drainMiddleware)
+	// The status probe is polled by the frontend on load, so it is not rate limited.
+	aiGroup.GET("/status", s.Status)
+	// Health results are cached, so monitoring may poll it freely.
+	aiGroup.GET("/health", s.Health)
+	// Sessions only touch the database, so they are not rate limited either.
+	aiGroup.POST("/sessions", s.CreateSession)
+	aiGroup.GET("/sessions", s.ListSessions)
+	// Cancelling only stops a stream the caller already started.
+	aiGroup.POST("/cancel/:request_id", s.CancelStream)
+	// Polling and cancelling a job never reach the provider.
+	aiGroup.GET("/jobs/:id", s.GetJob)
+	aiGroup.DELETE("/jobs/:id", s.CancelJob)
+	// Estimates are computed locally and never reach the provider.
+	aiGroup.POST("/estimate", s.Estimate)
+	aiGroup.GET("/usage", s.GetUsage)
+	aiGroup.GET("/usage/users", s.ListUsage)
+	aiGroup.POST("/config/key", s.RotateAPIKey)
+
+	limited := aiGroup.Group("", s.rateLimitMiddleware, s.quotaMiddleware, s.upstreamUserMiddleware, rateLimitHeadersMiddleware, s.retryBudgetMiddleware)
+	limited.GET("/models", s.ListModels)
+	limited.POST("/chat_completion", s.ChatCompletion)
+	limited.POST("/batch", s.Batch)
+	limited.POST("/summarize", s.Summarize)
+	limited.POST("/jobs", s.CreateJob)
+	limited.POST("/suggest_tags", s.SuggestTags)
+	limited.POST("/followups", s.Followups)
+	limited.POST("/action_items", s.ActionItems)
+	limited.POST("/embeddings", s.Embeddings)
+	limited.POST("/generate_title", s.GenerateTitle)
+	limited.POST("/proofread", s.Proofread)
+	limited.POST("/diff_summary", s.DiffSummary)
+	limited.POST("/clean_transcript", s.CleanTranscript)
+	limited.POST("/expand", s.Expand)
+	limited.POST("/translate", s.Translate)
+	limited.POST("/detect_language", s.DetectLanguage)
+	limited.POST("/ask", s.Ask)
+	limited.POST("/continue", s.Continue)
+	limited.GET("/ws", s.ChatWebSocket)
 }
 
-func (s *AIService) ChatCompletion(c echo.Context) error {
-	// 1. Check if API Key is configured
-	if s.apiKey == "" {
-		// Fallback to Env if not passed in constructor (though constructor should handle it)
-		s.apiKey = os.Getenv("MEMOS_OPENAI_API_KEY")
-		if s.apiKey == "" {
-			s.apiKey = os.Getenv("OPENAI_API_KEY")
+// resolveAPIKey returns the API key to use for a request.
+// Priority: the user's own key > the configured key.
+func (s *AIService) resolveAPIKey(ctx context.Context, user *store.User) string {
+	userAPIKey, err := s.getUserAPIKey(ctx, user)
+	if err != nil {
+		s.log(ctx).Warn("failed to get user AI setting", "error", err)
+	}
+	if userAPIKey != "" {
+		return userAPIKey
+	}
+	return s.serverAPIKey()
+}
+
+// Status reports whether a working API key is configured so the frontend can hide AI features.
+// It never reveals the key or the provider URL.
+func (s *AIService) Status(c echo.Context) error {
+	ctx := c.Request().Context()
+	user, err := s.getCurrentUser(ctx, c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get current user").SetInternal(err)
+	}
+	apiKey := s.resolveAPIKey(ctx, user)
+	switch {
+	case apiKey == "" && s.provider.RequiresAPIKey():
+		return c.JSON(http.StatusOK, &StatusResponse{Reason: healthErrorNotConfigured})
+	case apiKey != "" && apiKey == s.serverAPIKey() && s.keyInvalid.Load():
+		return c.JSON(http.StatusOK, &StatusResponse{Reason: errorCodeInvalidAPIKey})
+	default:
+		return c.JSON(http.StatusOK, &StatusResponse{Enabled: true})
+	}
+}
+
+func (s *AIService) ChatCompletion(c echo.Context) (err error) {
+	ctx := c.Request().Context()
+	if lastEventID := c.Request().Header.Get(headerLastEventID); lastEventID != "" {
+		// A client reconnecting to a buffered stream resumes it instead of starting over.
+		if resumed, err := s.resumeStream(c, lastEventID); resumed {
+			return err
 		}
 	}
-	if s.apiKey == "" {
-		return echo.NewHTTPError(http.StatusServiceUnavailable, "AI Service not configured (missing API Key)")
+
+	// 1. Check if API Key is configured
+	apiKey, err := s.requireAPIKey(ctx, c)
+	if err != nil {
+		return err
 	}
 
 	// 2. Bind Request
 	reqBody := new(ChatCompletionRequest)
-	if err := c.Bind(reqBody); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	if err := bindChatCompletionRequest(c, reqBody); err != nil {
+		return err
+	}
+	idempotent, replay, err := s.idempotencyLookup(c, reqBody)
+	if err != nil {
+		return err
+	}
+	if replay != nil {
+		c.Response().Header().Set(headerIdempotentReplayed, "true")
+		return c.JSONBlob(http.StatusOK, replay)
 	}
 
 	// 3. Prepare OpenAI/GitHub Models Request
-	targetURL := os.Getenv("MEMOS_AI_BASE_URL")
-	if targetURL == "" {
-		targetURL = "https://models.github.ai/inference/chat/completions"
+	caller, err := s.resolveCaller(ctx, c)
+	if err != nil {
+		return err
+	}
+	ctx, session, newMessages, err := s.prepareChatCompletion(ctx, caller, apiKey, reqBody)
+	if err != nil {
+		return err
 	}
+	if contextUsage, ok := contextUsageFrom(ctx); ok {
+		c.Response().Header().Set(headerXAIMemosRequested, strconv.Itoa(contextUsage.RequestedMemos))
+		c.Response().Header().Set(headerXAIMemosIncluded, strconv.Itoa(contextUsage.IncludedMemos))
+	}
+
+	start := time.Now()
+	var usage *Usage
+	defer func() {
+		s.audit(c, reqBody, usage, start, err)
+	}()
 
-	// Force model to openai/gpt-4o if not specified
-	if reqBody.Model == "" || reqBody.Model == "gpt-4o" {
-		reqBody.Model = "openai/gpt-4o"
+	// 4. Non-streaming requests go through the response cache.
+	if !reqBody.Stream {
+		body, hit, servedBy, err := s.completionWithFallback(ctx, apiKey, reqBody)
+		if err != nil {
+			return err
+		}
+		if s.fallback != nil {
+			c.Response().Header().Set(headerXAIProvider, servedBy)
+		}
+		c.Response().Header().Set(headerXAIModel, reqBody.Model)
+		response, parseErr := unmarshalResponse(body)
+		if parseErr == nil && response.Choices[0].FinishReason != "" {
+			c.Response().Header().Set(headerXAIFinishReason, response.Choices[0].FinishReason)
+		}
+		if parseErr == nil && response.SystemFingerprint != "" {
+			c.Response().Header().Set(headerXAISystemFingerprint, response.SystemFingerprint)
+		}
+		if s.responseCache != nil {
+			c.Response().Header().Set(headerXCache, cacheStatus(hit))
+		}
+		if !hit {
+			usage = parseUsage(body)
+		}
+		if session != nil {
+			if parseErr != nil {
+				return parseErr
+			}
+			s.saveSession(ctx, session, newMessages, response.Choices[0].Content())
+		}
+		s.idempotencyStore(idempotent, body)
+		return c.JSONBlob(http.StatusOK, body)
 	}
 
-	jsonBody, err := json.Marshal(reqBody)
+	// 5. Stream the upstream response back. OpenAI only reports usage on streams that ask for
+	// it; the usage chunk is forwarded to the client like any other. Providers with their own
+	// wire format ignore the option.
+	reqBody.StreamOptions = &StreamOptions{IncludeUsage: true}
+	if s.replayable(ctx) {
+		// The completion may outlive the client's connection for it to reconnect;
+		// streamResponse aborts it once no client has been connected for streamReplayTTL.
+		ctx = context.WithoutCancel(ctx)
+	}
+	ctx, done, err := s.trackStream(ctx, c)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to marshal request").SetInternal(err)
+		return err
 	}
-
-	// 4. Send Request to Upstream
-	// Debug: Print API key length and prefix
-	if len(s.apiKey) > 10 {
-		println("AI Service: Using API Key starting with:", s.apiKey[:10], "Length:", len(s.apiKey))
-	} else {
-		println("AI Service: API Key is likely invalid, length:", len(s.apiKey))
+	defer done()
+	resp, provider, servedBy, err := s.startStream(ctx, apiKey, reqBody)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if s.fallback != nil {
+		c.Response().Header().Set(headerXAIProvider, servedBy)
 	}
+	c.Response().Header().Set(headerXAIModel, reqBody.Model)
+	var reply string
+	usage, reply = s.streamResponse(c, resp.Body, provider)
+	recordUsage(ctx, reqBody.Model, usage)
+	if session != nil {
+		s.saveSession(ctx, session, newMessages, reply)
+	}
+	return nil
+}
 
-	proxyReq, err := http.NewRequest("POST", targetURL, bytes.NewBuffer(jsonBody))
+// prepareChatCompletion validates a chat completion request and readies it for the
+// provider: it prepends the session history, applies a base_url override, resolves the
+// model, runs moderation and adds the system prompt or the locale instruction. It returns
+// the context to send the request with, the session the reply belongs to (nil without
+// one) and the new messages to save to it.
+func (s *AIService) prepareChatCompletion(ctx context.Context, caller caller, apiKey string, req *ChatCompletionRequest) (context.Context, *store.AISession, []ChatCompletionMessage, error) {
+	if err := s.validateChatCompletionRequest(req); err != nil {
+		return nil, nil, nil, err
+	}
+	var session *store.AISession
+	newMessages := req.Messages
+	var historySummary string
+	if req.SessionID != 0 {
+		var history []ChatCompletionMessage
+		var err error
+		if session, history, err = s.loadSession(ctx, caller.user, req.SessionID); err != nil {
+			return nil, nil, nil, err
+		}
+		req.SessionID = 0
+		history, historySummary = s.windowHistory(ctx, apiKey, history)
+		req.Messages = append(history, req.Messages...)
+	}
+	if req.BaseURL != "" {
+		provider, err := s.overrideProvider(req.BaseURL)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		req.BaseURL = ""
+		ctx = withProvider(ctx, provider)
+	}
+	model, err := s.resolveModel(req.Model)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create proxy request").SetInternal(err)
+		return nil, nil, nil, err
+	}
+	req.Model = model
+	var memoContext string
+	if len(req.MemoIDs) > 0 {
+		var contextUsage ContextUsage
+		if memoContext, contextUsage, err = s.loadMemoContext(ctx, caller.user, req.MemoIDs, model); err != nil {
+			return nil, nil, nil, err
+		}
+		req.MemoIDs = nil
+		ctx = withContextUsage(ctx, contextUsage)
+	}
+	if s.moderationCache != nil {
+		if err := s.moderate(ctx, apiKey, req.Messages); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	req.Messages = s.withLocalePrompt(ctx, caller, s.withSystemPrompt(req.Messages))
+	if historySummary != "" {
+		req.Messages = withSystemNote(req.Messages, historySummary)
+	}
+	if memoContext != "" {
+		req.Messages = withSystemNote(req.Messages, memoContext)
 	}
+	return ctx, session, newMessages, nil
+}
 
-	proxyReq.Header.Set("Content-Type", "application/json")
-	proxyReq.Header.Set("Authorization", "Bearer "+s.apiKey)
+// startStream opens a streaming completion. Like non-streaming requests it retries with a
+// larger-context model or older messages dropped when the conversation is too long, as
+// configured, and fails over to the fallback
+// provider; once the stream has started the response is committed, so failover only
+// covers establishing it. It returns the provider serving the stream and its role.
+func (s *AIService) startStream(ctx context.Context, apiKey string, req *ChatCompletionRequest) (*http.Response, Provider, string, error) {
+	resp, err := s.streamUpstream(ctx, apiKey, req)
+	if err != nil && s.retryContextOverflow(ctx, err, req) {
+		resp, err = s.streamUpstream(ctx, apiKey, req)
+	}
+	if err != nil && s.shouldFailOver(ctx, apiKey, err) {
+		s.log(ctx).Warn("AI provider failed, retrying with the fallback provider", "error", err)
+		ctx = withProvider(ctx, s.fallback)
+		if resp, err = s.streamUpstream(ctx, s.fallbackAPIKey(), req); err != nil {
+			return nil, nil, "", err
+		}
+		return resp, s.fallback, providerRoleFallback, nil
+	}
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return resp, s.providerFor(ctx), providerRolePrimary, nil
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(proxyReq)
+// streamUpstream starts a streaming completion, converting an upstream error status into
+// a normalized error. The caller must close the body of a successful response.
+func (s *AIService) streamUpstream(ctx context.Context, apiKey string, req *ChatCompletionRequest) (*http.Response, error) {
+	resp, err := s.callUpstream(ctx, apiKey, req)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadGateway, "Failed to contact AI provider").SetInternal(err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		body, err := s.readBody(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.upstreamError(ctx, resp.StatusCode, body)
+	}
+	return resp, nil
+}
 
-	// 5. Proxy Response Back
-	// We read the body and return it directly.
-	body, err := io.ReadAll(resp.Body)
+// requireAPIKey resolves the API key for the current request, returning 503 when none is configured.
+func (s *AIService) requireAPIKey(ctx context.Context, c echo.Context) (string, error) {
+	user, err := s.getCurrentUser(ctx, c)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read upstream response").SetInternal(err)
+		return "", echo.NewHTTPError(http.StatusInternalServerError, "Failed to get current user").SetInternal(err)
+	}
+	apiKey := s.resolveAPIKey(ctx, user)
+	if apiKey == "" && s.provider.RequiresAPIKey() {
+		return "", s.errorResponse(http.StatusServiceUnavailable, errorCodeNotConfigured, nil)
 	}
+	return apiKey, nil
+}
 
-	if resp.StatusCode >= 400 {
-		println("AI Service: Upstream Error:", resp.StatusCode, string(body))
-		// Forward upstream error for debugging
-		return c.JSONBlob(resp.StatusCode, body)
+// withSystemPrompt prepends the configured system prompt unless the caller already supplied
+// a system or developer message. It returns a new slice and is safe to call more than once.
+func (s *AIService) withSystemPrompt(messages []ChatCompletionMessage) []ChatCompletionMessage {
+	if s.systemPrompt == "" {
+		return messages
+	}
+	for _, message := range messages {
+		if message.Role == roleSystem || message.Role == roleDeveloper {
+			return messages
+		}
+	}
+	return append([]ChatCompletionMessage{{Role: roleSystem, Content: s.systemPrompt}}, messages...)
+}
+
+// resolveModel applies the default model, resolves model aliases and checks the result
+// against the allowlist. Aliases resolve first, so the allowlist lists resolved model IDs;
+// an alias is resolved once and never to another alias.
+func (s *AIService) resolveModel(model string) (string, error) {
+	if model == "" {
+		model = s.defaultModel
+	}
+	if resolved, ok := s.modelAliases[model]; ok {
+		model = resolved
+	}
+	if err := s.checkModelAllowed(model); err != nil {
+		return "", err
+	}
+	return model, nil
+}
+
+// checkModelAllowed rejects models outside a non-empty allowlist.
+func (s *AIService) checkModelAllowed(model string) error {
+	if len(s.allowedModels) > 0 && !slices.Contains(s.allowedModels, model) {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("model %q is not allowed, allowed models: %s", model, strings.Join(s.allowedModels, ", ")))
 	}
+	return nil
+}
+
+// startSSE writes the headers of a server-sent events response and returns the writer for
+// its events. A client that stops reading for streamWriteTimeout aborts the response and
+// closes body, which cancels the upstream request.
+func (s *AIService) startSSE(c echo.Context, body io.Closer) *streamWriter {
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set(echo.HeaderCacheControl, "no-cache")
+	w.Header().Set(echo.HeaderConnection, "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	writer := newStreamWriter(w, s.sseKeepAlive, s.streamFlushBytes, s.streamFlushInterval)
+	ctx := c.Request().Context()
+	writer.watch(s.streamWriteTimeout, func() {
+		s.log(ctx).Warn("AI stream client stopped reading, aborting", "timeout", s.streamWriteTimeout)
+		body.Close()
+	})
+	return writer
+}
+
+// streamResponse decodes an upstream stream with the provider's streamDecoder and writes
+// each delta to the client as an OpenAI-style SSE chunk, flushing after each one so tokens
+// reach the browser as soon as they arrive, unless stream buffering is configured. While the
+// upstream is silent for longer than sseKeepAlive a keep-alive comment is sent instead.
+// The stream is cut off once it exceeds maxResponseBytes, the client disconnects or a write
+// to the client blocks for streamWriteTimeout; closing body then cancels the upstream request.
+// When the stream is replayable its events carry IDs and the stream outlives the client's
+// connection, for streamReplayTTL, so that a client reconnecting with Last-Event-ID can
+// resume it.
+// It returns the usage reported in the stream, if any, and the streamed reply text.
+func (s *AIService) streamResponse(c echo.Context, body io.ReadCloser, provider Provider) (*Usage, string) {
+	replay := s.newStreamReplay(c, func() { body.Close() })
+	var abort io.Closer = body
+	if replay != nil {
+		defer replay.finish()
+		// A client that stopped reading is treated like one that went away.
+		abort = io.NopCloser(nil)
+	}
+	writer := s.startSSE(c, abort)
+	defer writer.stop()
 
-	return c.JSONBlob(http.StatusOK, body)
+	ctx := c.Request().Context()
+	send, stopWatching := replaySender(ctx, writer, replay)
+	defer stopWatching()
+	var usage *Usage
+	var reply strings.Builder
+	lines := newStreamLineReader(body, s.maxResponseBytes)
+	decoder := newStreamDecoder(provider, lines)
+	filter := newStreamFilter(s.responseFilters)
+	for {
+		delta, err := decoder.Next()
+		if err != nil {
+			var chunkErr *streamChunkError
+			switch {
+			case errors.As(err, &chunkErr):
+				s.log(ctx).Warn("failed to decode AI stream chunk", "error", err)
+				continue
+			case errors.Is(err, io.EOF):
+				if rest := filter.rest(); rest != "" {
+					reply.WriteString(rest)
+					if chunk, encodeErr := encodeStreamDelta(&streamDelta{Content: rest}); encodeErr == nil {
+						_ = send(chunk)
+					}
+				}
+				if writeErr := send(streamDone); writeErr != nil {
+					s.logStreamAborted(ctx, writeErr, lines.read, usage)
+				}
+			case errors.Is(err, errStreamTooLarge):
+				s.log(ctx).Warn("AI stream exceeded the response size limit, closing", "limit", s.maxResponseBytes)
+			case ctx.Err() != nil:
+				// Headers are already sent, so upstream read errors can only end the stream.
+				s.logStreamAborted(ctx, ctx.Err(), lines.read, usage)
+			case !errors.Is(err, io.ErrUnexpectedEOF):
+				s.log(ctx).Warn("AI stream ended with an error", "error", err)
+			}
+			return usage, reply.String()
+		}
+		if delta.Usage != nil {
+			usage = delta.Usage
+		}
+		filter.apply(delta)
+		if delta.empty() {
+			continue
+		}
+		reply.WriteString(delta.Content)
+		chunk, err := encodeStreamDelta(delta)
+		if err != nil {
+			s.log(ctx).Warn("failed to encode AI stream chunk", "error", err)
+			continue
+		}
+		// A failed write means the client went away. Returning closes the upstream body,
+		// which cancels the provider request so it stops generating tokens.
+		if writeErr := send(chunk); writeErr != nil {
+			s.logStreamAborted(ctx, writeErr, lines.read, usage)
+			return usage, reply.String()
+		}
+	}
+}
+
+// logStreamAborted records a stream cut short by the client, with the tokens used so far
+// when the provider has reported them.
+func (s *AIService) logStreamAborted(ctx context.Context, err error, bytesRead int64, usage *Usage) {
+	attrs := []any{"error", err, "bytes_read", bytesRead}
+	if usage != nil {
+		attrs = append(attrs, "total_tokens", usage.TotalTokens)
+	}
+	s.log(ctx).Debug("AI stream aborted by client disconnect", attrs...)
+}
+
+// truncate shortens s to at most n bytes so large payloads don't flood the log.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
 }
@@ -0,0 +1,174 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestContext builds an echo context for a JSON POST with the given body.
+func newTestContext(body string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ai/chat_completion", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	return e.NewContext(req, rec), rec
+}
+
+// newChatUpstream starts a fake provider that answers every chat completion with content.
+// Each decoded request is passed to inspect when it is non-nil.
+func newChatUpstream(t *testing.T, content string, inspect func(*ChatCompletionRequest)) *httptest.Server {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := new(ChatCompletionRequest)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		if inspect != nil {
+			inspect(req)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{
+				{"index": 0, "message": map[string]any{"role": "assistant", "content": content}, "finish_reason": "stop"},
+			},
+		}))
+	}))
+	t.Cleanup(upstream.Close)
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	return upstream
+}
+
+func TestChatCompletionTimeout(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(500 * time.Millisecond):
+			w.WriteHeader(http.StatusOK)
+		case <-r.Context().Done():
+		}
+	}))
+	defer upstream.Close()
+
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	t.Setenv("MEMOS_AI_TIMEOUT", "50ms")
+	s := NewAIService(nil, "", "test-key")
+	require.Equal(t, 50*time.Millisecond, s.timeout)
+
+	c, _ := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	err := s.ChatCompletion(c)
+	require.Error(t, err)
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusGatewayTimeout, httpErr.Code)
+}
+
+func TestLoadDuration(t *testing.T) {
+	s := NewAIService(nil, "", "")
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: defaultTimeout},
+		{value: "5s", want: 5 * time.Second},
+		{value: "15", want: 15 * time.Second},
+		{value: "bogus", want: defaultTimeout},
+		{value: "-1s", want: defaultTimeout},
+	}
+	for _, test := range tests {
+		t.Setenv("MEMOS_AI_TIMEOUT", test.value)
+		require.Equal(t, test.want, loadDuration(s.logger, "MEMOS_AI_TIMEOUT", defaultTimeout), test.value)
+	}
+}
+
+func TestChatCompletionInjectsSystemPromptOnce(t *testing.T) {
+	var attempts int
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		req := new(ChatCompletionRequest)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		systemMessages := 0
+		for _, message := range req.Messages {
+			if message.Role == "system" {
+				systemMessages++
+				require.Equal(t, "You are a helpful note assistant.", message.Content)
+			}
+		}
+		require.Equal(t, 1, systemMessages)
+		require.Equal(t, "system", req.Messages[0].Role)
+		// Fail the first attempt to make sure retries don't duplicate the prompt.
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[]}`))
+	}))
+	defer upstream.Close()
+
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	t.Setenv("MEMOS_AI_SYSTEM_PROMPT", "You are a helpful note assistant.")
+	s := NewAIService(nil, "", "test-key")
+	s.retryBaseDelay = time.Millisecond
+
+	c, rec := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, 2, attempts)
+}
+
+func TestWithSystemPrompt(t *testing.T) {
+	t.Setenv("MEMOS_AI_SYSTEM_PROMPT", "Be nice.")
+	s := NewAIService(nil, "", "")
+
+	messages := []ChatCompletionMessage{{Role: "user", Content: "hi"}}
+	once := s.withSystemPrompt(messages)
+	require.Len(t, once, 2)
+	require.Len(t, messages, 1)
+	require.Equal(t, once, s.withSystemPrompt(once))
+
+	own := []ChatCompletionMessage{{Role: "system", Content: "Be terse."}, {Role: "user", Content: "hi"}}
+	require.Equal(t, own, s.withSystemPrompt(own))
+}
+
+func TestChatCompletionToolCallPassthrough(t *testing.T) {
+	const upstreamBody = `{"id":"chatcmpl-1","choices":[{"index":0,"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]},"finish_reason":"tool_calls"}]}`
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]json.RawMessage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.JSONEq(t, `[{"type":"function","function":{"name":"get_weather","parameters":{"type":"object"}}}]`, string(req["tools"]))
+		require.JSONEq(t, `"auto"`, string(req["tool_choice"]))
+		require.JSONEq(t, `[{"role":"user","content":"Weather?"},{"role":"assistant","content":"","tool_calls":[{"id":"call_0"}]},{"role":"tool","content":"sunny","tool_call_id":"call_0"}]`, string(req["messages"]))
+		_, _ = w.Write([]byte(upstreamBody))
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"messages":[{"role":"user","content":"Weather?"},{"role":"assistant","content":"","tool_calls":[{"id":"call_0"}]},{"role":"tool","content":"sunny","tool_call_id":"call_0"}],"tools":[{"type":"function","function":{"name":"get_weather","parameters":{"type":"object"}}}],"tool_choice":"auto"}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, upstreamBody, rec.Body.String())
+}
+
+func TestChatCompletionForwardsPenaltiesAndN(t *testing.T) {
+	var forwarded *ChatCompletionRequest
+	newChatUpstream(t, "hello", func(req *ChatCompletionRequest) {
+		forwarded = req
+	})
+	s := NewAIService(nil, "", "test-key")
+
+	c, _ := newTestContext(`{"messages":[{"role":"user","content":"hi"}],"presence_penalty":0.5,"frequency_penalty":-1,"n":2}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, 0.5, *forwarded.PresencePenalty)
+	require.Equal(t, -1.0, *forwarded.FrequencyPenalty)
+	require.Equal(t, 2, *forwarded.N)
+
+	c, _ = newTestContext(`{"messages":[{"role":"user","content":"hello"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Nil(t, forwarded.PresencePenalty)
+	require.Nil(t, forwarded.FrequencyPenalty)
+	require.Nil(t, forwarded.N)
+}
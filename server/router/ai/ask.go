@@ -0,0 +1,290 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/usememos/memos/store"
+)
+
+const (
+	// defaultAskTopK is the number of memos added to the prompt when MEMOS_AI_ASK_TOP_K is unset.
+	defaultAskTopK = 5
+	// maxIndexedMemos bounds how many of a user's most recent memos the built-in index embeds.
+	maxIndexedMemos = 500
+)
+
+// MemoEmbedding is the embedding of a memo's content under a given embedding model.
+type MemoEmbedding struct {
+	MemoID    int32
+	Content   string
+	Embedding []float64
+}
+
+// MemoEmbeddingStore provides precomputed memo embeddings for retrieval. Without one,
+// the service embeds memos on demand and keeps the vectors in memory.
+type MemoEmbeddingStore interface {
+	// ListMemoEmbeddings returns the embeddings of the user's memos computed with model.
+	ListMemoEmbeddings(ctx context.Context, userID int32, model string) ([]*MemoEmbedding, error)
+}
+
+// SetMemoEmbeddingStore makes /ai/ask retrieve memos from embeddingStore.
+func (s *AIService) SetMemoEmbeddingStore(embeddingStore MemoEmbeddingStore) {
+	s.embeddingStore = embeddingStore
+}
+
+type AskRequest struct {
+	Question string `json:"question"`
+}
+
+type AskResponse struct {
+	Answer string `json:"answer"`
+	// SourceIDs are the IDs of the memos given to the model as context.
+	SourceIDs []int32 `json:"source_ids"`
+	// Context reports how many of the retrieved memos fit in MEMOS_AI_MAX_CONTEXT_TOKENS.
+	Context ContextUsage `json:"context"`
+}
+
+// Ask answers a question about the current user's memos. The memos most similar to the
+// question are retrieved by embedding similarity and passed to the model as context.
+func (s *AIService) Ask(c echo.Context) error {
+	ctx := c.Request().Context()
+	apiKey, err := s.requireAPIKey(ctx, c)
+	if err != nil {
+		return err
+	}
+	user, err := s.getCurrentUser(ctx, c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get current user").SetInternal(err)
+	}
+	if user == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Authentication required to ask about memos")
+	}
+
+	request := new(AskRequest)
+	if err := c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	}
+	if err := s.validateContent(request.Question); err != nil {
+		return err
+	}
+
+	vectors, err := s.embed(ctx, apiKey, []string{request.Question})
+	if err != nil {
+		return err
+	}
+	memos, err := s.listMemoEmbeddings(ctx, apiKey, user.ID)
+	if err != nil {
+		return err
+	}
+
+	model, err := s.resolveModel("")
+	if err != nil {
+		return err
+	}
+	sources, contextUsage := s.fitAskSources(model, topKMemos(vectors[0], memos, s.askTopK))
+	answer, err := s.complete(ctx, apiKey, &ChatCompletionRequest{
+		Model: model,
+		Messages: []ChatCompletionMessage{
+			{Role: "system", Content: buildAskPrompt(sources)},
+			{Role: "user", Content: request.Question},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	sourceIDs := make([]int32, 0, len(sources))
+	for _, memo := range sources {
+		sourceIDs = append(sourceIDs, memo.MemoID)
+	}
+	return c.JSON(http.StatusOK, &AskResponse{
+		Answer:    strings.TrimSpace(answer),
+		SourceIDs: sourceIDs,
+		Context:   contextUsage,
+	})
+}
+
+// fitAskSources drops the least similar of the retrieved memos until their content fits
+// in maxContextTokens, truncating the most similar one if it does not fit by itself.
+func (s *AIService) fitAskSources(model string, memos []*MemoEmbedding) ([]*MemoEmbedding, ContextUsage) {
+	blocks := make([]contextBlock, len(memos))
+	for i, memo := range memos {
+		blocks[i] = contextBlock{source: fmt.Sprintf("memo %d", memo.MemoID), content: memo.Content}
+	}
+	blocks, usage := fitContext(blocks, s.maxContextTokens, newTokenCounter(model), delimitContext)
+	sources := make([]*MemoEmbedding, len(blocks))
+	for i, block := range blocks {
+		sources[i] = &MemoEmbedding{MemoID: memos[i].MemoID, Content: block.content, Embedding: memos[i].Embedding}
+	}
+	return sources, usage
+}
+
+// buildAskPrompt lists the retrieved memos, tagged with their IDs, in the system prompt.
+func buildAskPrompt(memos []*MemoEmbedding) string {
+	var prompt strings.Builder
+	prompt.WriteString("You answer questions about the user's notes. Use only the notes below. ")
+	prompt.WriteString("If they do not contain the answer, say that you could not find it in the notes. ")
+	prompt.WriteString(contextGuardPrompt)
+	prompt.WriteString("\n")
+	for _, memo := range memos {
+		prompt.WriteString("\n")
+		prompt.WriteString(delimitContext(fmt.Sprintf("memo %d", memo.MemoID), memo.Content))
+	}
+	return prompt.String()
+}
+
+// topKMemos returns the k memos most similar to query, most similar first.
+func topKMemos(query []float64, memos []*MemoEmbedding, k int) []*MemoEmbedding {
+	type scored struct {
+		memo  *MemoEmbedding
+		score float64
+	}
+	candidates := make([]scored, 0, len(memos))
+	for _, memo := range memos {
+		candidates = append(candidates, scored{memo: memo, score: cosineSimilarity(query, memo.Embedding)})
+	}
+	slices.SortStableFunc(candidates, func(a, b scored) int {
+		switch {
+		case a.score > b.score:
+			return -1
+		case a.score < b.score:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	result := make([]*MemoEmbedding, 0, k)
+	for i := 0; i < len(candidates) && i < k; i++ {
+		result = append(result, candidates[i].memo)
+	}
+	return result
+}
+
+// cosineSimilarity returns the cosine of the angle between a and b, or 0 when they
+// differ in length or either is zero.
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// listMemoEmbeddings returns the user's memo embeddings from the configured store, or from
+// the built-in index which embeds memos that are new or changed since they were last seen.
+func (s *AIService) listMemoEmbeddings(ctx context.Context, apiKey string, userID int32) ([]*MemoEmbedding, error) {
+	model := s.resolveEmbeddingModel()
+	if s.embeddingStore != nil {
+		memos, err := s.embeddingStore.ListMemoEmbeddings(ctx, userID, model)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to list memo embeddings").SetInternal(err)
+		}
+		return memos, nil
+	}
+	if s.store == nil {
+		return nil, echo.NewHTTPError(http.StatusNotImplemented, "No memo store is configured")
+	}
+
+	normal := store.Normal
+	limit := maxIndexedMemos
+	memos, err := s.store.ListMemos(ctx, &store.FindMemo{
+		CreatorID:        &userID,
+		RowStatus:        &normal,
+		ExcludeComments:  true,
+		OrderByUpdatedTs: true,
+		Limit:            &limit,
+	})
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to list memos").SetInternal(err)
+	}
+	return s.memoIndex.embeddings(ctx, userID, model, memos, func(ctx context.Context, inputs []string) ([][]float64, error) {
+		return s.embed(ctx, apiKey, inputs)
+	})
+}
+
+// maxEmbeddingTokens caps the part of a memo that is embedded, below the 8191-token input
+// limit of OpenAI's embedding models; a longer memo is represented by its beginning.
+const maxEmbeddingTokens = 8000
+
+// indexedMemo is the embedding of one revision of a memo.
+type indexedMemo struct {
+	model     string
+	updatedTs int64
+	vector    []float64
+}
+
+// memoIndex caches memo embeddings in memory. Only the embedding of each memo's latest
+// revision under the current model is kept, and only for the memos last listed for its
+// user, so the index holds at most maxIndexedMemos embeddings per user.
+type memoIndex struct {
+	mutex sync.Mutex
+	// users maps a user ID to their indexed memos by memo ID.
+	users map[int32]map[int32]indexedMemo
+}
+
+// embeddings returns the embeddings of userID's memos, computing missing ones in batches
+// with embed. Embeddings of memos that are no longer listed are dropped.
+func (i *memoIndex) embeddings(ctx context.Context, userID int32, model string, memos []*store.Memo, embed func(context.Context, []string) ([][]float64, error)) ([]*MemoEmbedding, error) {
+	i.mutex.Lock()
+	indexed := i.users[userID]
+	i.mutex.Unlock()
+
+	current := make(map[int32]indexedMemo, len(memos))
+	var missing []*store.Memo
+	for _, memo := range memos {
+		entry, ok := indexed[memo.ID]
+		if ok && entry.model == model && entry.updatedTs == memo.UpdatedTs {
+			current[memo.ID] = entry
+		} else if strings.TrimSpace(memo.Content) != "" {
+			missing = append(missing, memo)
+		}
+	}
+
+	counter := newTokenCounter(model)
+	for start := 0; start < len(missing); start += maxEmbeddingInputs {
+		batch := missing[start:min(start+maxEmbeddingInputs, len(missing))]
+		inputs := make([]string, len(batch))
+		for j, memo := range batch {
+			inputs[j] = counter.truncate(memo.Content, maxEmbeddingTokens)
+		}
+		vectors, err := embed(ctx, inputs)
+		if err != nil {
+			return nil, err
+		}
+		for j, memo := range batch {
+			current[memo.ID] = indexedMemo{model: model, updatedTs: memo.UpdatedTs, vector: vectors[j]}
+		}
+	}
+
+	i.mutex.Lock()
+	if i.users == nil {
+		i.users = make(map[int32]map[int32]indexedMemo)
+	}
+	i.users[userID] = current
+	i.mutex.Unlock()
+
+	result := make([]*MemoEmbedding, 0, len(memos))
+	for _, memo := range memos {
+		if entry, ok := current[memo.ID]; ok {
+			result = append(result, &MemoEmbedding{MemoID: memo.ID, Content: memo.Content, Embedding: entry.vector})
+		}
+	}
+	return result, nil
+}
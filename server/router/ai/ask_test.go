@@ -0,0 +1,125 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/usememos/memos/store"
+	teststore "github.com/usememos/memos/store/test"
+)
+
+// fakeVector embeds text as keyword counts so similarity is predictable.
+func fakeVector(text string) []float64 {
+	text = strings.ToLower(text)
+	return []float64{
+		float64(strings.Count(text, "cat")),
+		float64(strings.Count(text, "tax")),
+		float64(strings.Count(text, "trip")),
+	}
+}
+
+func TestAsk(t *testing.T) {
+	ctx := context.Background()
+	ts := teststore.NewTestingStore(ctx, t)
+	defer ts.Close()
+	user, err := ts.CreateUser(ctx, &store.User{Username: "asker", Role: store.RoleUser, Email: "asker@test.com"})
+	require.NoError(t, err)
+	memoIDs := map[string]int32{}
+	for i, content := range []string{"My cat likes tuna. The cat sleeps a lot.", "Tax return is due in April.", "Trip to Lisbon in May."} {
+		memo, err := ts.CreateMemo(ctx, &store.Memo{UID: fmt.Sprintf("memo-%d", i), CreatorID: user.ID, Content: content, Visibility: store.Private})
+		require.NoError(t, err)
+		memoIDs[content] = memo.ID
+	}
+
+	embeddingCalls := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/embeddings":
+			embeddingCalls++
+			req := new(EmbeddingsRequest)
+			require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+			data := []map[string]any{}
+			for i, input := range req.Input {
+				data = append(data, map[string]any{"index": i, "embedding": fakeVector(input)})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+		default:
+			req := new(ChatCompletionRequest)
+			require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+			require.Contains(t, req.Messages[0].Content, "My cat likes tuna")
+			_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"Your cat likes tuna."},"finish_reason":"stop"}]}`))
+		}
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL+"/chat/completions")
+	t.Setenv("MEMOS_AI_ASK_TOP_K", "1")
+	s := NewAIService(ts, "secret", "test-key")
+
+	for i := 0; i < 2; i++ {
+		c, rec := newTestContext(`{"question":"What does my cat eat?"}`)
+		c.Set(currentUserContextKey, user)
+		require.NoError(t, s.Ask(c))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		response := new(AskResponse)
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), response))
+		require.Equal(t, "Your cat likes tuna.", response.Answer)
+		require.Equal(t, []int32{memoIDs["My cat likes tuna. The cat sleeps a lot."]}, response.SourceIDs)
+		require.Equal(t, ContextUsage{RequestedMemos: 1, IncludedMemos: 1}, response.Context)
+	}
+	// One call embeds the memos; afterwards only the questions are embedded.
+	require.Equal(t, 3, embeddingCalls)
+}
+
+func TestAskRequiresUser(t *testing.T) {
+	s := NewAIService(nil, "", "test-key")
+	c, _ := newTestContext(`{"question":"anything"}`)
+	require.Error(t, s.Ask(c))
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	require.InDelta(t, 1, cosineSimilarity([]float64{1, 2}, []float64{2, 4}), 1e-9)
+	require.InDelta(t, 0, cosineSimilarity([]float64{1, 0}, []float64{0, 1}), 1e-9)
+	require.Zero(t, cosineSimilarity([]float64{1}, []float64{1, 2}))
+	require.Zero(t, cosineSimilarity([]float64{0, 0}, []float64{1, 2}))
+}
+
+func TestMemoIndexKeepsLatestRevisions(t *testing.T) {
+	var embedded []string
+	embed := func(_ context.Context, inputs []string) ([][]float64, error) {
+		embedded = append(embedded, inputs...)
+		vectors := make([][]float64, len(inputs))
+		for i, input := range inputs {
+			vectors[i] = fakeVector(input)
+		}
+		return vectors, nil
+	}
+	var index memoIndex
+	memos := []*store.Memo{{ID: 1, Content: "cat", UpdatedTs: 1}, {ID: 2, Content: "tax", UpdatedTs: 1}}
+	_, err := index.embeddings(context.Background(), 7, "text-embedding-3-small", memos, embed)
+	require.NoError(t, err)
+
+	// An edited memo replaces its old embedding and a memo no longer listed is dropped.
+	memos = []*store.Memo{{ID: 1, Content: "cat trip", UpdatedTs: 2}}
+	result, err := index.embeddings(context.Background(), 7, "text-embedding-3-small", memos, embed)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	require.Equal(t, []string{"cat", "tax", "cat trip"}, embedded)
+	require.Equal(t, map[int32]indexedMemo{1: {model: "text-embedding-3-small", updatedTs: 2, vector: fakeVector("cat trip")}}, index.users[7])
+
+	// Memos too long for the embedding model are embedded by their beginning.
+	embedded = nil
+	long := strings.Repeat("cat ", 3*maxEmbeddingTokens)
+	result, err = index.embeddings(context.Background(), 7, "text-embedding-3-small", []*store.Memo{{ID: 3, Content: long, UpdatedTs: 1}}, embed)
+	require.NoError(t, err)
+	require.Equal(t, long, result[0].Content)
+	require.LessOrEqual(t, newTokenCounter("text-embedding-3-small").count(embedded[0]), maxEmbeddingTokens)
+	require.True(t, strings.HasPrefix(long, embedded[0]))
+}
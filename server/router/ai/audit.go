@@ -0,0 +1,84 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/usememos/memos/store"
+)
+
+// auditLogger is a dedicated logger for AI audit records.
+type auditLogger struct {
+	logger *slog.Logger
+	// content logs truncated message content instead of only its length and hash.
+	content bool
+}
+
+// newAuditLogger returns the audit logger enabled by MEMOS_AI_AUDIT=true. content enables
+// MEMOS_AI_AUDIT_CONTENT.
+func newAuditLogger(logger *slog.Logger, content bool) *auditLogger {
+	return &auditLogger{
+		logger:  logger.With("logger", "ai.audit"),
+		content: content,
+	}
+}
+
+// audit records a chat completion call. Message content is represented by its length and
+// SHA-256 hash unless content logging is enabled.
+func (s *AIService) audit(c echo.Context, req *ChatCompletionRequest, usage *Usage, start time.Time, err error) {
+	if s.auditLogger == nil {
+		return
+	}
+
+	var userID int32
+	if user, ok := c.Get(currentUserContextKey).(*store.User); ok && user != nil {
+		userID = user.ID
+	}
+	status := http.StatusOK
+	if err != nil {
+		status = http.StatusInternalServerError
+		if httpErr, ok := err.(*echo.HTTPError); ok {
+			status = httpErr.Code
+		}
+	}
+
+	var content strings.Builder
+	for _, message := range req.Messages {
+		content.WriteString(message.Role)
+		content.WriteString(": ")
+		content.WriteString(message.Content)
+		content.WriteString("\n")
+	}
+	sum := sha256.Sum256([]byte(content.String()))
+
+	attrs := []any{
+		"request_id", requestIDFromContext(c.Request().Context()),
+		"user_id", userID,
+		"provider", s.provider.Name(),
+		"model", req.Model,
+		"stream", req.Stream,
+		"status", status,
+		"latency_ms", time.Since(start).Milliseconds(),
+		"messages", len(req.Messages),
+		"content_length", content.Len(),
+		"content_sha256", hex.EncodeToString(sum[:]),
+	}
+	if usage != nil {
+		attrs = append(attrs,
+			"prompt_tokens", usage.PromptTokens,
+			"completion_tokens", usage.CompletionTokens,
+			"total_tokens", usage.TotalTokens,
+			"cost", s.pricing.cost(req.Model, usage),
+		)
+	}
+	if s.auditLogger.content {
+		attrs = append(attrs, "content", truncate(content.String(), maxLoggedBodyBytes))
+	}
+	s.auditLogger.logger.Info("AI chat completion", attrs...)
+}
@@ -0,0 +1,40 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatCompletionAuditRedactsContent(t *testing.T) {
+	newChatUpstream(t, "hello", nil)
+	t.Setenv("MEMOS_AI_AUDIT", "true")
+	var logs bytes.Buffer
+	s := NewAIServiceWithLogger(nil, "", "test-key", slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	c, _ := newTestContext(`{"model":"test-model","messages":[{"role":"user","content":"secret memo"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+
+	record := map[string]any{}
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &record))
+	require.Equal(t, "ai.audit", record["logger"])
+	require.Equal(t, "test-model", record["model"])
+	require.Equal(t, float64(200), record["status"])
+	require.NotEmpty(t, record["content_sha256"])
+	require.NotContains(t, logs.String(), "secret memo")
+}
+
+func TestChatCompletionAuditContent(t *testing.T) {
+	newChatUpstream(t, "hello", nil)
+	t.Setenv("MEMOS_AI_AUDIT", "true")
+	t.Setenv("MEMOS_AI_AUDIT_CONTENT", "true")
+	var logs bytes.Buffer
+	s := NewAIServiceWithLogger(nil, "", "test-key", slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	c, _ := newTestContext(`{"messages":[{"role":"user","content":"secret memo"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Contains(t, logs.String(), "user: secret memo")
+}
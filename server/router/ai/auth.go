@@ -0,0 +1,97 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	storepb "github.com/usememos/memos/proto/gen/store"
+	"github.com/usememos/memos/server/auth"
+	"github.com/usememos/memos/store"
+)
+
+// currentUserContextKey caches the authenticated user on the Echo context so middleware
+// and handlers don't authenticate the same request twice.
+const currentUserContextKey = "ai.currentUser"
+
+// getCurrentUser retrieves the current authenticated user from the Echo context.
+// Authentication priority: Bearer token (Access Token V2 or PAT) > Refresh token cookie.
+// Returns nil without an error when the request is unauthenticated.
+func (s *AIService) getCurrentUser(ctx context.Context, c echo.Context) (*store.User, error) {
+	if user, ok := c.Get(currentUserContextKey).(*store.User); ok {
+		return user, nil
+	}
+	user, err := s.authenticate(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		c.Set(currentUserContextKey, user)
+	}
+	return user, nil
+}
+
+// caller is what preparing a completion needs to know about the request it serves. It is
+// resolved from the Echo context once, so that completions can run on other goroutines:
+// the Echo context is not safe for concurrent use.
+type caller struct {
+	// user is the authenticated user, nil for anonymous requests.
+	user           *store.User
+	acceptLanguage string
+}
+
+func (s *AIService) resolveCaller(ctx context.Context, c echo.Context) (caller, error) {
+	user, err := s.getCurrentUser(ctx, c)
+	if err != nil {
+		return caller{}, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get current user").SetInternal(err)
+	}
+	return caller{user: user, acceptLanguage: c.Request().Header.Get("Accept-Language")}, nil
+}
+
+func (s *AIService) authenticate(ctx context.Context, c echo.Context) (*store.User, error) {
+	if s.store == nil {
+		return nil, nil
+	}
+
+	// Try Bearer token authentication first
+	if token := auth.ExtractBearerToken(c.Request().Header.Get("Authorization")); token != "" {
+		if strings.HasPrefix(token, auth.PersonalAccessTokenPrefix) {
+			user, _, err := s.authenticator.AuthenticateByPAT(ctx, token)
+			if err == nil && user != nil {
+				return user, nil
+			}
+		} else {
+			claims, err := s.authenticator.AuthenticateByAccessTokenV2(token)
+			if err == nil && claims != nil {
+				return s.store.GetUser(ctx, &store.FindUser{ID: &claims.UserID})
+			}
+		}
+	}
+
+	// Fallback: Try refresh token cookie authentication
+	if refreshToken := auth.ExtractRefreshTokenFromCookie(c.Request().Header.Get("Cookie")); refreshToken != "" {
+		user, _, err := s.authenticator.AuthenticateByRefreshToken(ctx, refreshToken)
+		if err == nil && user != nil {
+			return user, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// getUserAPIKey returns the AI API key stored in the user's settings, if any.
+func (s *AIService) getUserAPIKey(ctx context.Context, user *store.User) (string, error) {
+	if s.store == nil || user == nil {
+		return "", nil
+	}
+	userSetting, err := s.store.GetUserSetting(ctx, &store.FindUserSetting{
+		UserID: &user.ID,
+		Key:    storepb.UserSetting_AI,
+	})
+	if err != nil {
+		return "", err
+	}
+	return userSetting.GetAi().GetApiKey(), nil
+}
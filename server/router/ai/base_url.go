@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+// providerKey is the context key of a per-request provider override.
+type providerKey struct{}
+
+// withProvider returns a context whose upstream calls go to provider instead of the configured one.
+func withProvider(ctx context.Context, provider Provider) context.Context {
+	return context.WithValue(ctx, providerKey{}, provider)
+}
+
+func hasProviderOverride(ctx context.Context) bool {
+	_, ok := ctx.Value(providerKey{}).(Provider)
+	return ok
+}
+
+// providerFor returns the provider that serves requests made with ctx.
+func (s *AIService) providerFor(ctx context.Context) Provider {
+	if provider, ok := ctx.Value(providerKey{}).(Provider); ok {
+		return provider
+	}
+	return s.provider
+}
+
+// overrideProvider builds a provider of the configured kind talking to baseURL, which must
+// be an http(s) URL on one of the allowed hosts.
+func (s *AIService) overrideProvider(baseURL string) (Provider, error) {
+	if len(s.allowedHosts) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "base_url overrides are not enabled")
+	}
+	target, err := url.Parse(baseURL)
+	if err != nil || (target.Scheme != "https" && target.Scheme != "http") || target.Hostname() == "" || target.User != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "base_url must be an http(s) URL")
+	}
+	host := strings.ToLower(target.Hostname())
+	if !hostAllowed(s.allowedHosts, target) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("base_url host %q is not allowed", host))
+	}
+	if err := validateTarget(baseURL, s.allowPrivate); err != nil {
+		if errors.Is(err, errBlockedTarget) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("base_url host %q is an internal address", host)).SetInternal(err)
+		}
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid base_url").SetInternal(err)
+	}
+
+	cfg := s.providerConfig
+	cfg.Name, cfg.BaseURL = s.provider.Name(), baseURL
+	provider, err := newProvider(cfg)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid base_url").SetInternal(err)
+	}
+	return provider, nil
+}
@@ -0,0 +1,154 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// maxBatchSize caps how many completions one /ai/batch request may carry.
+	maxBatchSize = 20
+	// batchConcurrency is how many completions of a batch run at once. The concurrency
+	// limiter still bounds the upstream requests of all clients together.
+	batchConcurrency = 4
+)
+
+type BatchRequest struct {
+	// Requests are non-streaming chat completion requests, in the format of
+	// /ai/chat_completion. Sessions are not supported.
+	Requests []json.RawMessage `json:"requests"`
+}
+
+// BatchResult is the outcome of one request of a batch: the chat completion response, or
+// the error it failed with and that error's HTTP status.
+type BatchResult struct {
+	Response json.RawMessage `json:"response,omitempty"`
+	Status   int             `json:"status"`
+	Error    *ErrorDetail    `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	// Results has one entry per request, in the order of the requests.
+	Results []*BatchResult `json:"results"`
+	// Usage is the token usage of the whole batch.
+	Usage *Usage `json:"usage"`
+}
+
+// Batch runs several chat completions in one request, for bulk operations such as
+// summarizing many memos. A failed completion does not fail the batch; its error is
+// reported in its result. The tokens of all completions count towards the quota, and
+// every completion counts as one request towards the rate limit.
+func (s *AIService) Batch(c echo.Context) error {
+	ctx := c.Request().Context()
+	apiKey, err := s.requireAPIKey(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	request := new(BatchRequest)
+	if err := c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	}
+	if len(request.Requests) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "requests must be a non-empty array")
+	}
+	if limit := s.batchLimit(); len(request.Requests) > limit {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("a batch may hold at most %d requests", limit))
+	}
+	if s.rateLimiter != nil && len(request.Requests) > 1 {
+		// The rate limit middleware took the token of the first completion.
+		key, err := s.rateLimitKey(c)
+		if err != nil {
+			return err
+		}
+		if delay := s.rateLimiter.reserveN(key, len(request.Requests)-1); delay > 0 {
+			return rateLimitExceeded(c, delay)
+		}
+	}
+	// The completions run on goroutines of their own, which must not touch the Echo context.
+	caller, err := s.resolveCaller(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	response := &BatchResponse{Results: make([]*BatchResult, len(request.Requests)), Usage: &Usage{}}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, batchConcurrency)
+	for i, raw := range request.Requests {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			result := &BatchResult{Status: http.StatusOK}
+			body, hit, err := s.batchCompletion(ctx, caller, apiKey, raw)
+			if err != nil {
+				result.Status, result.Error = s.errorDetail(ctx, err)
+			} else {
+				result.Response = body
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			response.Results[i] = result
+			// Cached responses cost no tokens.
+			if usage := parseUsage(body); usage != nil && !hit {
+				response.Usage.PromptTokens += usage.PromptTokens
+				response.Usage.CompletionTokens += usage.CompletionTokens
+				response.Usage.TotalTokens += usage.TotalTokens
+			}
+		}()
+	}
+	wg.Wait()
+	return c.JSON(http.StatusOK, response)
+}
+
+// batchLimit returns how many completions a batch may hold: maxBatchSize, or fewer when
+// the rate limit allows fewer requests at once.
+func (s *AIService) batchLimit() int {
+	if s.rateLimiter != nil {
+		return min(maxBatchSize, s.rateLimiter.perMinute)
+	}
+	return maxBatchSize
+}
+
+// batchCompletion validates and runs one completion of a batch, returning the response body
+// in the OpenAI format and whether it came from the response cache.
+func (s *AIService) batchCompletion(ctx context.Context, caller caller, apiKey string, raw json.RawMessage) ([]byte, bool, error) {
+	// Each completion of a batch gets a retry budget of its own.
+	ctx = withRetryBudget(ctx, s.retryBudget)
+	req := new(ChatCompletionRequest)
+	if err := decodeChatCompletionRequest(raw, req); err != nil {
+		return nil, false, err
+	}
+	if req.Stream {
+		return nil, false, echo.NewHTTPError(http.StatusBadRequest, "streaming is not supported in batches")
+	}
+	if req.SessionID != 0 {
+		return nil, false, echo.NewHTTPError(http.StatusBadRequest, "sessions are not supported in batches")
+	}
+	exceeded, err := s.quotaExceeded(ctx)
+	if err != nil {
+		return nil, false, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get AI usage").SetInternal(err)
+	}
+	if exceeded {
+		return nil, false, s.errorResponse(http.StatusForbidden, errorCodeQuotaExceeded, nil)
+	}
+	ctx, _, _, err = s.prepareChatCompletion(ctx, caller, apiKey, req)
+	if err != nil {
+		return nil, false, err
+	}
+	body, hit, _, err := s.completionWithFallback(ctx, apiKey, req)
+	if err != nil {
+		return nil, false, err
+	}
+	if _, err := unmarshalResponse(body); err != nil {
+		return nil, false, err
+	}
+	return body, hit, nil
+}
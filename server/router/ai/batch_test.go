@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchKeepsOrderAndReportsErrors(t *testing.T) {
+	s := newMockTestService(t)
+
+	c, rec := newTestContext(`{"requests":[
+		{"messages":[{"role":"user","content":"first memo"}]},
+		{"messages":[]},
+		{"stream":true,"messages":[{"role":"user","content":"hi"}]},
+		{"messages":[{"role":"user","content":"third memo here"}]}
+	]}`)
+	require.NoError(t, s.Batch(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	response := new(BatchResponse)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), response))
+	require.Len(t, response.Results, 4)
+
+	first, err := unmarshalResponse(response.Results[0].Response)
+	require.NoError(t, err)
+	require.Equal(t, "first memo", first.Choices[0].Content())
+
+	require.Equal(t, http.StatusBadRequest, response.Results[1].Status)
+	require.Equal(t, errorCodeInvalidRequest, response.Results[1].Error.Code)
+	require.Contains(t, response.Results[1].Error.Message, "messages")
+	require.Nil(t, response.Results[1].Response)
+
+	require.Equal(t, http.StatusBadRequest, response.Results[2].Status)
+	require.Contains(t, response.Results[2].Error.Message, "streaming")
+
+	last, err := unmarshalResponse(response.Results[3].Response)
+	require.NoError(t, err)
+	require.Equal(t, "third memo here", last.Choices[0].Content())
+	require.Equal(t, http.StatusOK, response.Results[3].Status)
+
+	require.Equal(t, first.Usage.TotalTokens+last.Usage.TotalTokens, response.Usage.TotalTokens)
+}
+
+func TestBatchRejectsInvalidBatches(t *testing.T) {
+	s := newMockTestService(t)
+
+	requests := make([]string, maxBatchSize+1)
+	for i := range requests {
+		requests[i] = fmt.Sprintf(`{"messages":[{"role":"user","content":"memo %d"}]}`, i)
+	}
+	for _, body := range []string{`{"requests":[]}`, `{"requests":[` + strings.Join(requests, ",") + `]}`} {
+		c, _ := newTestContext(body)
+		httpErr, ok := s.Batch(c).(*echo.HTTPError)
+		require.True(t, ok)
+		require.Equal(t, http.StatusBadRequest, httpErr.Code)
+	}
+}
+
+func TestBatchCountsEachRequestTowardsRateLimit(t *testing.T) {
+	t.Setenv("MEMOS_AI_RATE_LIMIT", "5")
+	s := newMockTestService(t)
+
+	batch := func(n int) error {
+		requests := make([]string, n)
+		for i := range requests {
+			requests[i] = fmt.Sprintf(`{"messages":[{"role":"user","content":"memo %d"}]}`, i)
+		}
+		c, _ := newTestContext(`{"requests":[` + strings.Join(requests, ",") + `]}`)
+		return s.rateLimitMiddleware(s.Batch)(c)
+	}
+
+	// A batch may not hold more requests than the rate limit allows at once.
+	httpErr, ok := batch(6).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusBadRequest, httpErr.Code)
+
+	// The rejected batch took one token and three completions take three of the other four,
+	// which leaves too few for three more.
+	require.NoError(t, batch(3))
+	httpErr, ok = batch(3).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusTooManyRequests, httpErr.Code)
+}
@@ -0,0 +1,182 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ValidationErrorResponse is returned with 400 when a request body has missing or
+// mistyped fields. Errors maps each field, such as "messages[0].role", to its problem.
+type ValidationErrorResponse struct {
+	Errors map[string]string `json:"errors"`
+	// Category is always "input"; see classifyError.
+	Category string `json:"category"`
+}
+
+// jsonType is the type of a JSON value as far as request validation cares.
+type jsonType int
+
+const (
+	jsonString jsonType = iota
+	jsonBoolean
+	jsonNumber
+	jsonInteger
+	jsonObject
+	jsonStringArray
+	jsonIntegerArray
+	jsonArray
+)
+
+var jsonTypeErrors = map[jsonType]string{
+	jsonString:       "must be a string",
+	jsonBoolean:      "must be a boolean",
+	jsonNumber:       "must be a number",
+	jsonInteger:      "must be an integer",
+	jsonObject:       "must be an object",
+	jsonStringArray:  "must be an array of strings",
+	jsonIntegerArray: "must be an array of integers",
+	jsonArray:        "must be an array",
+}
+
+// chatCompletionFieldTypes are the types of the optional ChatCompletionRequest fields.
+// Unknown fields are ignored, as they are by c.Bind.
+var chatCompletionFieldTypes = map[string]jsonType{
+	"model":                 jsonString,
+	"stream":                jsonBoolean,
+	"temperature":           jsonNumber,
+	"max_tokens":            jsonInteger,
+	"max_completion_tokens": jsonInteger,
+	"top_p":                 jsonNumber,
+	"stop":                  jsonStringArray,
+	"presence_penalty":      jsonNumber,
+	"frequency_penalty":     jsonNumber,
+	"n":                     jsonInteger,
+	"reasoning_effort":      jsonString,
+	"user":                  jsonString,
+	"session_id":            jsonInteger,
+	"memo_ids":              jsonIntegerArray,
+	"base_url":              jsonString,
+	"response_format":       jsonObject,
+	"tools":                 jsonArray,
+}
+
+// chatCompletionMessageFieldTypes are the types of the optional message fields; content is
+// checked separately since it may be a string or an array of parts.
+var chatCompletionMessageFieldTypes = map[string]jsonType{
+	"tool_calls":   jsonArray,
+	"tool_call_id": jsonString,
+}
+
+// bindChatCompletionRequest decodes a chat completion request body into req, reporting
+// missing and mistyped fields with a ValidationErrorResponse rather than a decoding error.
+func bindChatCompletionRequest(c echo.Context, req *ChatCompletionRequest) error {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	}
+	return decodeChatCompletionRequest(body, req)
+}
+
+func decodeChatCompletionRequest(body []byte, req *ChatCompletionRequest) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Request body must be a JSON object").SetInternal(err)
+	}
+	if errs := validateChatCompletionFields(fields); len(errs) > 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, &ValidationErrorResponse{Errors: errs, Category: errorCategoryInput})
+	}
+	if err := json.Unmarshal(body, req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	}
+	return nil
+}
+
+// validateChatCompletionFields checks the JSON types of a request's fields and that the
+// required ones are present.
+func validateChatCompletionFields(fields map[string]json.RawMessage) map[string]string {
+	errs := map[string]string{}
+	// An empty model selects the default one, so only its type is checked.
+	checkFieldTypes(errs, "", fields, chatCompletionFieldTypes)
+
+	var messages []json.RawMessage
+	if raw := fields["messages"]; !isJSONArray(raw) || json.Unmarshal(raw, &messages) != nil || len(messages) == 0 {
+		errs["messages"] = "must be a non-empty array"
+	}
+	for i, raw := range messages {
+		name := fmt.Sprintf("messages[%d]", i)
+		var message map[string]json.RawMessage
+		if json.Unmarshal(raw, &message) != nil || message == nil {
+			errs[name] = "must be an object"
+			continue
+		}
+		if role, ok := message["role"]; !ok || isJSONNull(role) {
+			errs[name+".role"] = "is required"
+		} else if !hasJSONType(role, jsonString) {
+			errs[name+".role"] = jsonTypeErrors[jsonString]
+		}
+		if content, ok := message["content"]; ok && !isJSONNull(content) && !hasJSONType(content, jsonString) && !isJSONArray(content) {
+			errs[name+".content"] = "must be a string or an array of content parts"
+		}
+		checkFieldTypes(errs, name+".", message, chatCompletionMessageFieldTypes)
+	}
+	return errs
+}
+
+// checkFieldTypes records an error for each field in types whose value has another type.
+// Null is accepted for every optional field.
+func checkFieldTypes(errs map[string]string, prefix string, fields map[string]json.RawMessage, types map[string]jsonType) {
+	for name, want := range types {
+		value, ok := fields[name]
+		if !ok || isJSONNull(value) {
+			continue
+		}
+		if !hasJSONType(value, want) {
+			errs[prefix+name] = jsonTypeErrors[want]
+		}
+	}
+}
+
+// hasJSONType reports whether value is a JSON value of type want.
+func hasJSONType(value json.RawMessage, want jsonType) bool {
+	var ok bool
+	switch want {
+	case jsonString:
+		var v string
+		ok = json.Unmarshal(value, &v) == nil
+	case jsonBoolean:
+		var v bool
+		ok = json.Unmarshal(value, &v) == nil
+	case jsonNumber:
+		var v float64
+		ok = json.Unmarshal(value, &v) == nil
+	case jsonInteger:
+		var v int64
+		ok = json.Unmarshal(value, &v) == nil
+	case jsonObject:
+		var v map[string]json.RawMessage
+		ok = json.Unmarshal(value, &v) == nil && v != nil
+	case jsonStringArray:
+		var v []string
+		ok = isJSONArray(value) && json.Unmarshal(value, &v) == nil
+	case jsonIntegerArray:
+		var v []int64
+		ok = isJSONArray(value) && json.Unmarshal(value, &v) == nil
+	case jsonArray:
+		ok = isJSONArray(value)
+	}
+	return ok
+}
+
+func isJSONArray(value json.RawMessage) bool {
+	value = bytes.TrimSpace(value)
+	return len(value) > 0 && value[0] == '['
+}
+
+func isJSONNull(value json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(value), []byte("null"))
+}
@@ -0,0 +1,82 @@
+package ai
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatCompletionReportsFieldErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		errors map[string]string
+	}{
+		{
+			name:   "missing messages",
+			body:   `{"model":"gpt-4o"}`,
+			errors: map[string]string{"messages": "must be a non-empty array"},
+		},
+		{
+			name:   "null messages",
+			body:   `{"messages":null}`,
+			errors: map[string]string{"messages": "must be a non-empty array"},
+		},
+		{
+			name:   "messages object",
+			body:   `{"messages":{"role":"user","content":"hi"}}`,
+			errors: map[string]string{"messages": "must be a non-empty array"},
+		},
+		{
+			name:   "empty messages",
+			body:   `{"messages":[]}`,
+			errors: map[string]string{"messages": "must be a non-empty array"},
+		},
+		{
+			name: "wrong-typed fields",
+			body: `{"model":4,"stream":"yes","temperature":"hot","max_tokens":1.5,"stop":"END","memo_ids":["1"],"messages":[{"role":"user","content":"hi"}]}`,
+			errors: map[string]string{
+				"model":       "must be a string",
+				"stream":      "must be a boolean",
+				"temperature": "must be a number",
+				"max_tokens":  "must be an integer",
+				"stop":        "must be an array of strings",
+				"memo_ids":    "must be an array of integers",
+			},
+		},
+		{
+			name: "invalid messages",
+			body: `{"messages":["hi",{"content":"hi"},{"role":1,"content":{"text":"hi"},"tool_call_id":7}]}`,
+			errors: map[string]string{
+				"messages[0]":              "must be an object",
+				"messages[1].role":         "is required",
+				"messages[2].role":         "must be a string",
+				"messages[2].content":      "must be a string or an array of content parts",
+				"messages[2].tool_call_id": "must be a string",
+			},
+		},
+	}
+	s := NewAIService(nil, "", "test-key")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, _ := newTestContext(test.body)
+			httpErr, ok := s.ChatCompletion(c).(*echo.HTTPError)
+			require.True(t, ok)
+			require.Equal(t, http.StatusBadRequest, httpErr.Code)
+			require.Equal(t, &ValidationErrorResponse{Errors: test.errors, Category: errorCategoryInput}, httpErr.Message)
+		})
+	}
+}
+
+func TestDecodeChatCompletionRequest(t *testing.T) {
+	req := new(ChatCompletionRequest)
+	require.NoError(t, decodeChatCompletionRequest([]byte(`{"model":"","temperature":null,"messages":[{"role":"user","content":[{"type":"text","text":"hi"}]},{"role":"assistant","content":null,"tool_calls":[]}]}`), req))
+	require.Empty(t, req.Model, "an empty model selects the default")
+	require.Equal(t, "hi", req.Messages[0].Content)
+
+	httpErr, ok := decodeChatCompletionRequest([]byte(`[1]`), req).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusBadRequest, httpErr.Code)
+}
@@ -0,0 +1,106 @@
+package ai
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultCacheSize is the number of responses kept when MEMOS_AI_CACHE_SIZE is unset.
+	defaultCacheSize = 1000
+	// headerXCache reports whether a response was served from the cache.
+	headerXCache = "X-Cache"
+)
+
+type cacheEntry struct {
+	key       string
+	body      []byte
+	expiresAt time.Time
+}
+
+// responseCache is a size-bounded LRU cache of upstream responses with a fixed TTL.
+type responseCache struct {
+	size int
+	ttl  time.Duration
+
+	mutex   sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func newResponseCache(size int, ttl time.Duration) *responseCache {
+	if size <= 0 {
+		size = defaultCacheSize
+	}
+	return &responseCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *responseCache) get(key string) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.body, true
+}
+
+func (c *responseCache) put(key string, body []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*cacheEntry)
+		entry.body = body
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, body: body, expiresAt: expiresAt})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// cacheKey hashes everything that determines a response: the provider, the caller's key
+// (so users never share answers paid for with another key) and the full request.
+func cacheKey(provider, apiKey string, req *ChatCompletionRequest) (string, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	hash.Write([]byte(provider))
+	hash.Write([]byte{0})
+	hash.Write([]byte(apiKey))
+	hash.Write([]byte{0})
+	hash.Write(data)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func cacheStatus(hit bool) string {
+	if hit {
+		return "HIT"
+	}
+	return "MISS"
+}
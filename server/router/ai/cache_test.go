@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newResponseCache(2, time.Minute)
+	cache.put("a", []byte("1"))
+	cache.put("b", []byte("2"))
+	_, ok := cache.get("a")
+	require.True(t, ok)
+	cache.put("c", []byte("3"))
+
+	_, ok = cache.get("b")
+	require.False(t, ok, "b was least recently used")
+	body, ok := cache.get("a")
+	require.True(t, ok)
+	require.Equal(t, "1", string(body))
+}
+
+func TestResponseCacheExpires(t *testing.T) {
+	cache := newResponseCache(10, time.Millisecond)
+	cache.put("a", []byte("1"))
+	time.Sleep(5 * time.Millisecond)
+	_, ok := cache.get("a")
+	require.False(t, ok)
+}
+
+func TestChatCompletionCache(t *testing.T) {
+	calls := 0
+	newChatUpstream(t, "cached answer", func(*ChatCompletionRequest) { calls++ })
+	t.Setenv("MEMOS_AI_CACHE_TTL", "1m")
+	s := NewAIService(nil, "", "test-key")
+
+	send := func(body string) string {
+		c, rec := newTestContext(body)
+		require.NoError(t, s.ChatCompletion(c))
+		return rec.Header().Get(headerXCache)
+	}
+	request := `{"messages":[{"role":"user","content":"hi"}]}`
+	require.Equal(t, "MISS", send(request))
+	require.Equal(t, "HIT", send(request))
+	require.Equal(t, "MISS", send(`{"temperature":0.5,"messages":[{"role":"user","content":"hi"}]}`))
+	require.Equal(t, 2, calls)
+
+	c, rec := newTestContext(`{"stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Empty(t, rec.Header().Get(headerXCache), "streaming requests bypass the cache")
+	require.Equal(t, 3, calls)
+}
+
+func TestCacheKeyDependsOnAPIKey(t *testing.T) {
+	req := &ChatCompletionRequest{Model: "m", Messages: []ChatCompletionMessage{{Role: "user", Content: "hi"}}}
+	a, err := cacheKey(providerOpenAI, "key-a", req)
+	require.NoError(t, err)
+	b, err := cacheKey(providerOpenAI, "key-b", req)
+	require.NoError(t, err)
+	require.NotEqual(t, a, b)
+}
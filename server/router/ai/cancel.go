@@ -0,0 +1,97 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// activeStream is a streaming completion that can be cancelled through /ai/cancel.
+type activeStream struct {
+	// userID owns the stream.
+	userID int32
+	cancel context.CancelFunc
+}
+
+// streamRegistry tracks in-progress streams by request ID.
+type streamRegistry struct {
+	mutex   sync.Mutex
+	streams map[string]*activeStream
+}
+
+// register records a stream under id and returns a function that removes it again.
+func (r *streamRegistry) register(id string, stream *activeStream) func() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.streams == nil {
+		r.streams = map[string]*activeStream{}
+	}
+	r.streams[id] = stream
+	return func() {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		// A later stream may have reused the client-supplied ID.
+		if r.streams[id] == stream {
+			delete(r.streams, id)
+		}
+	}
+}
+
+// cancel stops the stream registered under id if userID owns it. It reports whether a
+// stream was cancelled.
+func (r *streamRegistry) cancel(id string, userID int32) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stream, ok := r.streams[id]
+	if !ok || stream.userID != userID {
+		return false
+	}
+	stream.cancel()
+	delete(r.streams, id)
+	return true
+}
+
+// trackStream makes the stream served under ctx's request ID cancellable by its owner.
+// The returned context is cancelled by /ai/cancel; the returned function must be called
+// once the stream completes. Anonymous streams cannot be cancelled: every anonymous client
+// could cancel them by sending the same request ID.
+func (s *AIService) trackStream(ctx context.Context, c echo.Context) (context.Context, func(), error) {
+	id := requestIDFromContext(ctx)
+	if id == "" {
+		return ctx, func() {}, nil
+	}
+	user, err := s.getCurrentUser(ctx, c)
+	if err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get current user").SetInternal(err)
+	}
+	if user == nil {
+		return ctx, func() {}, nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	stream := &activeStream{userID: user.ID, cancel: cancel}
+	unregister := s.streams.register(id, stream)
+	return ctx, func() {
+		unregister()
+		cancel()
+	}, nil
+}
+
+// CancelStream stops an in-progress streaming completion by its request ID, for clients
+// that cannot close the connection themselves. Streams owned by other users are reported
+// as unknown so their IDs are not disclosed. Cancelling requires authentication.
+func (s *AIService) CancelStream(c echo.Context) error {
+	ctx := c.Request().Context()
+	user, err := s.getCurrentUser(ctx, c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get current user").SetInternal(err)
+	}
+	if user == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Authentication required to cancel a stream")
+	}
+	if !s.streams.cancel(c.Param("request_id"), user.ID) {
+		return echo.NewHTTPError(http.StatusNotFound, "Stream not found")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
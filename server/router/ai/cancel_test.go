@@ -0,0 +1,130 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usememos/memos/store"
+)
+
+func newCancelContext(requestID string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ai/cancel/"+requestID, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("request_id")
+	c.SetParamValues(requestID)
+	return c, rec
+}
+
+// firstWriteWriter closes written once the first bytes are sent to the client.
+type firstWriteWriter struct {
+	*httptest.ResponseRecorder
+	once    sync.Once
+	written chan struct{}
+}
+
+func (w *firstWriteWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseRecorder.Write(b)
+	w.once.Do(func() { close(w.written) })
+	return n, err
+}
+
+func TestCancelStream(t *testing.T) {
+	upstreamDone := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(upstreamDone)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for {
+			if _, err := w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"chunk\"}}]}\n\n")); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	c.SetRequest(c.Request().WithContext(context.WithValue(c.Request().Context(), requestIDKey{}, "stream-1")))
+	user := &store.User{ID: 1}
+	c.Set(currentUserContextKey, user)
+	w := &firstWriteWriter{ResponseRecorder: rec, written: make(chan struct{})}
+	c.Response().Writer = w
+	finished := make(chan error, 1)
+	go func() {
+		finished <- s.ChatCompletion(c)
+	}()
+
+	// Cancelling before the stream has started fails the completion instead.
+	select {
+	case <-w.written:
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream did not start")
+	}
+	cancelCtx, rec := newCancelContext("stream-1")
+	cancelCtx.Set(currentUserContextKey, user)
+	require.NoError(t, s.CancelStream(cancelCtx))
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	select {
+	case err := <-finished:
+		require.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream did not stop after it was cancelled")
+	}
+	<-upstreamDone
+	require.Empty(t, s.streams.streams)
+}
+
+func TestCancelStreamUnknown(t *testing.T) {
+	s := NewAIService(nil, "", "test-key")
+	c, _ := newCancelContext("missing")
+	c.Set(currentUserContextKey, &store.User{ID: 1})
+	httpErr, ok := s.CancelStream(c).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusNotFound, httpErr.Code)
+}
+
+func TestCancelStreamRequiresAuthentication(t *testing.T) {
+	s := NewAIService(nil, "", "test-key")
+
+	// Anonymous streams are not registered, so no other anonymous client can cancel them.
+	c, _ := newTestContext("")
+	c.SetRequest(c.Request().WithContext(context.WithValue(c.Request().Context(), requestIDKey{}, "stream-1")))
+	_, done, err := s.trackStream(c.Request().Context(), c)
+	require.NoError(t, err)
+	defer done()
+	require.Empty(t, s.streams.streams)
+
+	cancelCtx, _ := newCancelContext("stream-1")
+	httpErr, ok := s.CancelStream(cancelCtx).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusUnauthorized, httpErr.Code)
+}
+
+func TestStreamRegistryChecksOwner(t *testing.T) {
+	var registry streamRegistry
+	cancelled := false
+	unregister := registry.register("id", &activeStream{userID: 1, cancel: func() { cancelled = true }})
+	defer unregister()
+
+	require.False(t, registry.cancel("id", 2))
+	require.False(t, cancelled)
+	require.True(t, registry.cancel("id", 1))
+	require.True(t, cancelled)
+	require.False(t, registry.cancel("id", 1))
+}
@@ -0,0 +1,79 @@
+package ai
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// defaultBreakerThreshold is the number of consecutive upstream failures that opens the circuit.
+	defaultBreakerThreshold = 5
+	// defaultBreakerCooldown is how long the circuit stays open before a trial request is allowed.
+	defaultBreakerCooldown = 30 * time.Second
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker fails fast while the provider is having a sustained outage. After
+// threshold consecutive failures it opens for cooldown, then lets a single trial
+// request through; the trial's outcome closes or re-opens the circuit.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mutex    sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// allow reports whether a request may be sent upstream. Every allowed request must be
+// followed by a call to record.
+func (b *circuitBreaker) allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		// Only the trial request is in flight until it reports back.
+		return false
+	default:
+		return true
+	}
+}
+
+// record reports the outcome of an allowed request.
+func (b *circuitBreaker) record(success bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if success {
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,58 @@
+package ai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	b := newCircuitBreaker(2, 20*time.Millisecond)
+
+	require.True(t, b.allow())
+	b.record(false)
+	require.True(t, b.allow())
+	b.record(false)
+	require.False(t, b.allow(), "circuit opens after threshold failures")
+
+	time.Sleep(25 * time.Millisecond)
+	require.True(t, b.allow(), "trial request allowed after cooldown")
+	require.False(t, b.allow(), "only one trial request at a time")
+	b.record(false)
+	require.False(t, b.allow(), "failed trial re-opens the circuit")
+
+	time.Sleep(25 * time.Millisecond)
+	require.True(t, b.allow())
+	b.record(true)
+	require.True(t, b.allow(), "successful trial closes the circuit")
+	require.True(t, b.allow())
+}
+
+func TestChatCompletionFailsFastWhenCircuitOpen(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	t.Setenv("MEMOS_AI_MAX_RETRIES", "0")
+	t.Setenv("MEMOS_AI_BREAKER_THRESHOLD", "2")
+	t.Setenv("MEMOS_AI_BREAKER_COOLDOWN", "1m")
+	s := NewAIService(nil, "", "test-key")
+
+	codes := []int{}
+	for i := 0; i < 3; i++ {
+		c, _ := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+		httpErr, ok := s.ChatCompletion(c).(*echo.HTTPError)
+		require.True(t, ok)
+		codes = append(codes, httpErr.Code)
+	}
+	require.Equal(t, []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusServiceUnavailable}, codes)
+	require.Equal(t, int32(2), calls.Load())
+}
@@ -0,0 +1,171 @@
+package ai
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// cleanTranscriptChunkBytes caps the part of a transcript cleaned by one completion. The
+// cleaned text is about as long as its input, so both fit well within the context window
+// of any current model.
+const cleanTranscriptChunkBytes = 6000
+
+// sentenceEndPattern matches the end of a sentence: terminal punctuation followed by
+// whitespace, or a line break.
+var sentenceEndPattern = regexp.MustCompile(`[.!?。！？]\s+|\n+`)
+
+type CleanTranscriptRequest struct {
+	Content string `json:"content"`
+}
+
+type CleanTranscriptResponse struct {
+	Cleaned string `json:"cleaned"`
+}
+
+// transcriptChunk is a part of a transcript cleaned by one completion.
+type transcriptChunk struct {
+	text string
+	// separator joins the cleaned chunk to the previous one; see chunkSeparator.
+	separator string
+}
+
+// CleanTranscript turns a dictated transcript into a readable memo: filler words are
+// removed and punctuation and paragraphs restored, without changing the wording. Long
+// transcripts are cleaned in chunks split on sentence boundaries.
+func (s *AIService) CleanTranscript(c echo.Context) error {
+	ctx := c.Request().Context()
+	apiKey, err := s.requireAPIKey(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	request := new(CleanTranscriptRequest)
+	if err := c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	}
+	if err := s.validateContent(request.Content); err != nil {
+		return err
+	}
+
+	model, err := s.resolveModel("")
+	if err != nil {
+		return err
+	}
+	prompt, err := s.prompt(promptCleanTranscript, cleanTranscriptPromptData{})
+	if err != nil {
+		return err
+	}
+	var cleaned strings.Builder
+	for i, chunk := range splitTranscript(strings.TrimSpace(request.Content), cleanTranscriptChunkBytes) {
+		reply, err := s.complete(ctx, apiKey, &ChatCompletionRequest{
+			Model: model,
+			Messages: []ChatCompletionMessage{
+				{Role: roleSystem, Content: prompt},
+				{Role: roleUser, Content: chunk.text},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			cleaned.WriteString(chunk.separator)
+		}
+		cleaned.WriteString(acceptCleanedChunk(chunk.text, reply))
+	}
+
+	return c.JSON(http.StatusOK, &CleanTranscriptResponse{
+		Cleaned: cleaned.String(),
+	})
+}
+
+// acceptCleanedChunk returns the model's cleanup of chunk, or chunk itself when the output
+// is empty or more than twice as long, which indicates added content.
+func acceptCleanedChunk(chunk, cleaned string) string {
+	cleaned = strings.TrimSpace(cleaned)
+	if cleaned == "" || len(cleaned) > 2*len(chunk) {
+		return chunk
+	}
+	return cleaned
+}
+
+// splitTranscript splits text into chunks of at most limit bytes, cutting between
+// sentences. Sentences longer than limit, common in unpunctuated transcripts, are cut
+// between words, and words longer than limit anywhere outside a UTF-8 sequence.
+func splitTranscript(text string, limit int) []transcriptChunk {
+	var chunks []transcriptChunk
+	var current strings.Builder
+	// boundary is the whitespace since the end of the last chunk.
+	boundary := ""
+	flush := func() {
+		piece := current.String()
+		current.Reset()
+		chunk := strings.TrimSpace(piece)
+		if chunk == "" {
+			boundary += piece
+			return
+		}
+		start := strings.Index(piece, chunk)
+		chunks = append(chunks, transcriptChunk{text: chunk, separator: chunkSeparator(boundary + piece[:start])})
+		boundary = piece[start+len(chunk):]
+	}
+	add := func(piece string) {
+		if current.Len()+len(piece) > limit {
+			flush()
+		}
+		current.WriteString(piece)
+	}
+	for _, sentence := range splitSentences(text) {
+		if len(sentence) <= limit {
+			add(sentence)
+			continue
+		}
+		for _, word := range strings.SplitAfter(sentence, " ") {
+			for len(word) > limit {
+				head := truncateBytes(word, limit)
+				if head == "" {
+					break
+				}
+				add(head)
+				word = word[len(head):]
+			}
+			add(word)
+		}
+	}
+	flush()
+	return chunks
+}
+
+// chunkSeparator returns the text joining two cleaned chunks that the whitespace boundary
+// separated in the transcript: a paragraph break or a line break when it had one, a space
+// otherwise, and nothing when a word longer than a chunk was cut.
+func chunkSeparator(boundary string) string {
+	if boundary == "" {
+		return ""
+	}
+	switch strings.Count(boundary, "\n") {
+	case 0:
+		return " "
+	case 1:
+		return "\n"
+	default:
+		return "\n\n"
+	}
+}
+
+// splitSentences splits text after each sentence end, keeping the punctuation and
+// whitespace with the sentence they follow.
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for _, end := range sentenceEndPattern.FindAllStringIndex(text, -1) {
+		sentences = append(sentences, text[start:end[1]])
+		start = end[1]
+	}
+	if start < len(text) {
+		sentences = append(sentences, text[start:])
+	}
+	return sentences
+}
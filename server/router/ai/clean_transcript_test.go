@@ -0,0 +1,98 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanTranscript(t *testing.T) {
+	newChatUpstream(t, "So I think we should ship on Friday.\n", func(req *ChatCompletionRequest) {
+		require.Equal(t, cleanTranscriptPrompt, req.Messages[0].Content)
+		require.Equal(t, "so um I think uh we should ship on friday", req.Messages[1].Content)
+	})
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"content":"so um I think uh we should ship on friday"}`)
+	require.NoError(t, s.CleanTranscript(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	response := new(CleanTranscriptResponse)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), response))
+	require.Equal(t, "So I think we should ship on Friday.", response.Cleaned)
+}
+
+func TestCleanTranscriptStitchesChunks(t *testing.T) {
+	var chunks []string
+	newChatUpstream(t, "Cleaned.", func(req *ChatCompletionRequest) {
+		chunks = append(chunks, req.Messages[1].Content)
+	})
+	s := NewAIService(nil, "", "test-key")
+
+	sentence := strings.Repeat("word ", 199) + "end. "
+	body, err := json.Marshal(&CleanTranscriptRequest{Content: strings.Repeat(sentence, 13)})
+	require.NoError(t, err)
+	c, rec := newTestContext(string(body))
+	require.NoError(t, s.CleanTranscript(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	response := new(CleanTranscriptResponse)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), response))
+	require.Equal(t, 3, len(chunks))
+	// The chunks were cut mid-line, so they are joined with spaces.
+	require.Equal(t, "Cleaned. Cleaned. Cleaned.", response.Cleaned)
+	for _, chunk := range chunks {
+		require.LessOrEqual(t, len(chunk), cleanTranscriptChunkBytes)
+		require.True(t, strings.HasSuffix(chunk, "end."), "chunks end on a sentence boundary")
+	}
+}
+
+func TestCleanTranscriptKeepsLineBreaksBetweenChunks(t *testing.T) {
+	newChatUpstream(t, "Cleaned.", nil)
+	s := NewAIService(nil, "", "test-key")
+
+	paragraph := strings.Repeat("word ", 700) + "end."
+	body, err := json.Marshal(&CleanTranscriptRequest{Content: paragraph + "\n\n" + paragraph + "\n" + paragraph})
+	require.NoError(t, err)
+	c, rec := newTestContext(string(body))
+	require.NoError(t, s.CleanTranscript(c))
+
+	response := new(CleanTranscriptResponse)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), response))
+	require.Equal(t, "Cleaned.\n\nCleaned.\nCleaned.", response.Cleaned)
+}
+
+func TestCleanTranscriptPromptOverride(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, promptCleanTranscript+".tmpl"), []byte("Tidy this dictation."), 0o600))
+	t.Setenv("MEMOS_AI_PROMPTS_DIR", dir)
+	newChatUpstream(t, "Cleaned.", func(req *ChatCompletionRequest) {
+		require.Equal(t, "Tidy this dictation.", req.Messages[0].Content)
+	})
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"content":"so um we left"}`)
+	require.NoError(t, s.CleanTranscript(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestSplitTranscript(t *testing.T) {
+	require.Equal(t, []transcriptChunk{{"One. Two.", ""}, {"Three?", " "}}, splitTranscript("One. Two. Three?", 10))
+	require.Equal(t, []transcriptChunk{{"One.", ""}, {"Two.", "\n\n"}}, splitTranscript("One.\n\nTwo.", 5))
+	// Unpunctuated text is cut between words.
+	require.Equal(t, []transcriptChunk{{"so um and", ""}, {"then we", " "}, {"left", " "}}, splitTranscript("so um and then we left", 10))
+	// Words longer than the limit are cut without splitting a character.
+	require.Equal(t, []transcriptChunk{{"ééé", ""}, {"éé", ""}}, splitTranscript("ééééé", 7))
+	require.Nil(t, splitTranscript("   ", 10))
+}
+
+func TestAcceptCleanedChunk(t *testing.T) {
+	require.Equal(t, "Hello there.", acceptCleanedChunk("um hello there", " Hello there.\n"))
+	require.Equal(t, "um hello", acceptCleanedChunk("um hello", ""))
+	require.Equal(t, "um hello", acceptCleanedChunk("um hello", strings.Repeat("Here is your cleaned text. ", 2)))
+}
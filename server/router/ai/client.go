@@ -0,0 +1,106 @@
+package ai
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxIdleConns bounds the idle connections kept open across all providers.
+	maxIdleConns = 100
+	// maxIdleConnsPerHost matches maxIdleConns since there is usually a single upstream host.
+	maxIdleConnsPerHost = 100
+	// idleConnTimeout is how long an unused keep-alive connection stays in the pool.
+	idleConnTimeout = 90 * time.Second
+	// tlsHandshakeTimeout bounds the TLS handshake with the provider.
+	tlsHandshakeTimeout = 10 * time.Second
+	// dialTimeout and dialKeepAlive match http.DefaultTransport's dialer.
+	dialTimeout   = 30 * time.Second
+	dialKeepAlive = 30 * time.Second
+)
+
+// newHTTPClient returns the client shared by all outbound AI requests. It is created once
+// so keep-alive connections and TLS sessions are reused across requests; per-request
+// deadlines come from the request context rather than http.Client.Timeout.
+// MEMOS_AI_PROXY routes traffic through an explicit HTTP(S) or SOCKS5 proxy; otherwise the
+// standard HTTPS_PROXY, HTTP_PROXY and NO_PROXY variables apply.
+//
+// The TLS settings belong to the same transport, so they also govern connections tunnelled
+// through a proxy: the provider's certificate is verified end to end against MEMOS_AI_CA_CERT
+// when it is set. An https:// proxy's own certificate is verified with the same roots.
+//
+// Unless MEMOS_AI_ALLOW_PRIVATE is set, every connection is checked again when it is dialed,
+// since a provider host may resolve to a private address after validateTarget accepted it.
+func newHTTPClient(logger *slog.Logger, cfg Config) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.MaxIdleConns = maxIdleConns
+	transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	transport.IdleConnTimeout = idleConnTimeout
+	transport.TLSHandshakeTimeout = tlsHandshakeTimeout
+	if !cfg.AllowPrivate {
+		dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: dialKeepAlive}
+		transport.DialContext = guardedDialContext(dialer, proxyAddrs(cfg.Proxy))
+	}
+	if cfg.Proxy != "" {
+		proxyURL, err := parseProxyURL(cfg.Proxy)
+		if err != nil {
+			logger.Error("invalid AI proxy, using environment proxy settings", "error", err)
+		} else {
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CACert != "" {
+		pool, err := loadCertPool(cfg.CACert)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.InsecureSkipVerify {
+		logger.Warn("TLS certificate verification of the AI provider is DISABLED; only use MEMOS_AI_INSECURE_SKIP_VERIFY for local testing")
+		tlsConfig.InsecureSkipVerify = true
+	}
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
+}
+
+// loadCertPool reads the PEM certificates at path into a pool that replaces the system roots.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read MEMOS_AI_CA_CERT")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.Errorf("MEMOS_AI_CA_CERT %s contains no PEM certificates", path)
+	}
+	return pool, nil
+}
+
+// parseProxyURL validates a proxy URL. Supported schemes are http, https, socks5 and socks5h.
+func parseProxyURL(value string) (*url.URL, error) {
+	proxyURL, err := url.Parse(value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse proxy URL %q", value)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, errors.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+	if proxyURL.Host == "" {
+		return nil, errors.Errorf("proxy URL %q has no host", value)
+	}
+	return proxyURL, nil
+}
@@ -0,0 +1,50 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// quotaUserKey is the context key under which quotaMiddleware stores the metered user's ID.
+type quotaUserKey struct{}
+
+type noCoalesceKey struct{}
+
+// withoutCoalescing returns a context whose completions are never shared with other
+// callers, so cancelling it aborts the upstream call. Background jobs use it, since a
+// cancelled job should stop generating tokens.
+func withoutCoalescing(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCoalesceKey{}, true)
+}
+
+// coalesce runs fetch once for all concurrent callers with the same key and hands each of
+// them the result. Requests metered against a user's quota are only coalesced with that
+// user's own requests, so every user is charged for the calls made on their behalf.
+//
+// The shared call is detached from any single caller's cancellation so one client going
+// away doesn't fail the others; it is still bounded by the upstream timeout. Each caller
+// stops waiting when its own request is cancelled. Contexts from withoutCoalescing run
+// fetch directly under their own cancellation.
+func (s *AIService) coalesce(ctx context.Context, key string, fetch func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	if noCoalesce, _ := ctx.Value(noCoalesceKey{}).(bool); noCoalesce {
+		return fetch(ctx)
+	}
+	if userID, ok := ctx.Value(quotaUserKey{}).(int32); ok {
+		key += ":" + strconv.Itoa(int(userID))
+	}
+	result := s.inflight.DoChan(key, func() (any, error) {
+		return fetch(context.WithoutCancel(ctx))
+	})
+	select {
+	case <-ctx.Done():
+		return nil, echo.NewHTTPError(http.StatusBadGateway, "Failed to contact AI provider").SetInternal(ctx.Err())
+	case r := <-result:
+		if r.Err != nil {
+			return nil, r.Err
+		}
+		return r.Val.([]byte), nil
+	}
+}
@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatCompletionCoalescesIdenticalRequests(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"shared"},"finish_reason":"stop"}]}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	s := NewAIService(nil, "", "test-key")
+
+	const callers = 3
+	var wg sync.WaitGroup
+	bodies := make([]string, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, rec := newTestContext(`{"messages":[{"role":"user","content":"same prompt"}]}`)
+			require.NoError(t, s.ChatCompletion(c))
+			bodies[i] = rec.Body.String()
+		}()
+	}
+	// Give every caller time to join the in-flight request before it completes.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, int32(1), calls.Load())
+	for _, body := range bodies {
+		require.Contains(t, body, "shared")
+	}
+}
+
+func TestCoalesceSeparatesQuotaUsers(t *testing.T) {
+	s := NewAIService(nil, "", "test-key")
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fetch := func(context.Context) ([]byte, error) {
+		calls.Add(1)
+		<-release
+		return []byte("{}"), nil
+	}
+
+	var wg sync.WaitGroup
+	for _, userID := range []int32{1, 1, 2} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := context.WithValue(context.Background(), quotaUserKey{}, userID)
+			_, err := s.coalesce(ctx, "key", fetch)
+			require.NoError(t, err)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	require.Equal(t, int32(2), calls.Load())
+}
+
+func TestCoalesceReturnsWhenCallerCancels(t *testing.T) {
+	s := NewAIService(nil, "", "test-key")
+	release := make(chan struct{})
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := s.coalesce(ctx, "key", func(context.Context) ([]byte, error) {
+		<-release
+		return nil, nil
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+// defaultConcurrencyWait is how long a request waits for a free upstream slot before it is rejected.
+const defaultConcurrencyWait = 10 * time.Second
+
+// concurrencyLimiter caps the number of upstream requests in flight at once, so a
+// rate-limited provider or a small local model is not overwhelmed. Requests beyond the
+// limit queue for up to wait.
+type concurrencyLimiter struct {
+	slots chan struct{}
+	wait  time.Duration
+}
+
+func newConcurrencyLimiter(limit int, wait time.Duration) *concurrencyLimiter {
+	return &concurrencyLimiter{
+		slots: make(chan struct{}, limit),
+		wait:  wait,
+	}
+}
+
+// acquire takes a slot, waiting for one to free up for at most l.wait. Every successful
+// acquire must be followed by exactly one call to release.
+func (l *concurrencyLimiter) acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	timer := time.NewTimer(l.wait)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "Too many AI requests in progress, please try again later")
+	case <-ctx.Done():
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "AI request cancelled while waiting for capacity").SetInternal(errors.WithStack(ctx.Err()))
+	}
+}
+
+func (l *concurrencyLimiter) release() {
+	<-l.slots
+}
@@ -0,0 +1,86 @@
+package ai
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func newConcurrencyTestService(t *testing.T, do doerFunc) *AIService {
+	t.Helper()
+	s, err := NewAIServiceFromConfig(nil, "", Config{
+		Provider:        ProviderConfig{BaseURL: "https://ai.example.com/v1/chat/completions"},
+		APIKey:          "test-key",
+		HTTPClient:      do,
+		AllowPrivate:    true,
+		MaxConcurrency:  1,
+		ConcurrencyWait: 20 * time.Millisecond,
+	}, slog.Default())
+	require.NoError(t, err)
+	return s
+}
+
+func TestConcurrencyLimitRejectsAfterWait(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s := newConcurrencyTestService(t, func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("X-Test-Block") != "" {
+			close(started)
+			<-release
+		}
+		return cannedResponse(http.StatusOK, `{"choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`), nil
+	})
+
+	done := make(chan error)
+	go func() {
+		resp, err := s.send(t.Context(), func(ctx context.Context) (*http.Request, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://ai.example.com", nil)
+			if err == nil {
+				req.Header.Set("X-Test-Block", "1")
+			}
+			return req, err
+		})
+		if err == nil {
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+	<-started
+
+	c, _ := newTestContext(`{"messages":[{"role":"user","content":"second"}]}`)
+	httpErr, ok := s.ChatCompletion(c).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusServiceUnavailable, httpErr.Code)
+
+	close(release)
+	require.NoError(t, <-done)
+	c, rec := newTestContext(`{"messages":[{"role":"user","content":"third"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestConcurrencyLimitReleasedOnPanic(t *testing.T) {
+	panicking := true
+	s := newConcurrencyTestService(t, func(*http.Request) (*http.Response, error) {
+		if panicking {
+			panic("upstream client bug")
+		}
+		return cannedResponse(http.StatusOK, `{"choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`), nil
+	})
+
+	require.Panics(t, func() {
+		_, _ = s.send(t.Context(), func(ctx context.Context) (*http.Request, error) {
+			return http.NewRequestWithContext(ctx, http.MethodPost, "https://ai.example.com", nil)
+		})
+	})
+
+	panicking = false
+	c, rec := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,360 @@
+package ai
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// httpDoer sends outbound AI requests. *http.Client satisfies it; tests substitute fakes.
+type httpDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// ProviderConfig selects the upstream provider and its endpoint.
+type ProviderConfig struct {
+	// Name is openai, anthropic, ollama, azure, gemini or mock (MEMOS_AI_PROVIDER). Empty selects openai.
+	Name string
+	// BaseURL is the provider endpoint (MEMOS_AI_BASE_URL, or MEMOS_AZURE_ENDPOINT for Azure).
+	// Empty selects the provider's default endpoint.
+	BaseURL string
+	// AzureDeployment and AzureAPIVersion configure Azure OpenAI
+	// (MEMOS_AZURE_DEPLOYMENT and MEMOS_AZURE_API_VERSION).
+	AzureDeployment string
+	AzureAPIVersion string
+}
+
+// Config holds the AI service settings. LoadConfig reads them from the environment.
+// Zero limits, timeouts and sizes fall back to their defaults; a zero RateLimit,
+// BreakerThreshold, MaxRetries, JSONRetries, MaxConcurrency, CacheTTL or DailyTokenLimit
+// disables that feature.
+type Config struct {
+	Provider ProviderConfig
+	// APIKey is the server-wide API key, used when the user has none of their own.
+	APIKey string
+	// HTTPClient sends upstream requests. Nil uses a pooled client honoring Proxy and the
+	// TLS settings.
+	HTTPClient httpDoer
+	// FallbackBaseURL is a second endpoint of the same provider kind that serves chat
+	// completions when the primary fails (MEMOS_AI_FALLBACK_BASE_URL). FallbackAPIKey
+	// is its key (MEMOS_AI_FALLBACK_API_KEY); empty reuses the primary key.
+	FallbackBaseURL string
+	FallbackAPIKey  string
+	// Proxy is an explicit HTTP(S) or SOCKS5 proxy URL (MEMOS_AI_PROXY).
+	Proxy string
+	// CACert is a PEM file whose certificates replace the system roots when verifying the
+	// provider, pinning it to that CA (MEMOS_AI_CA_CERT).
+	CACert string
+	// InsecureSkipVerify disables TLS certificate verification, for local testing against
+	// self-signed endpoints only (MEMOS_AI_INSECURE_SKIP_VERIFY).
+	InsecureSkipVerify bool
+
+	// AllowedHosts, when non-empty, lists the only hosts upstream requests may go to: the
+	// provider and fallback endpoints and any per-request base_url (MEMOS_AI_ALLOWED_HOSTS).
+	// Startup fails when the configured endpoint's host is not listed.
+	AllowedHosts []string
+
+	Debug            bool
+	Timeout          time.Duration
+	AllowPrivate     bool
+	AllowedModels    []string
+	DefaultModel     string
+	MaxMessages      int
+	MaxInputBytes    int
+	MaxImageBytes    int
+	MaxResponseBytes int64
+	AutoTruncate     bool
+	SystemPrompt     string
+	// PromptsDir holds <name>.tmpl files overriding the built-in endpoint prompts
+	// (MEMOS_AI_PROMPTS_DIR).
+	PromptsDir string
+
+	EmbeddingModel      string
+	DeveloperRoleModels []string
+	ReasoningModels     []string
+	AskTopK             int
+	TitleMaxChars       int
+
+	MaxRetries  int
+	JSONRetries int
+	// RetryBudget caps the upstream attempts of one client request across every retry
+	// layer: transient errors, JSON re-requests, context overflow retries and failover
+	// (MEMOS_AI_RETRY_BUDGET).
+	RetryBudget      int
+	RateLimit        int
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	CacheTTL         time.Duration
+	CacheSize        int
+	Moderation       bool
+	DailyTokenLimit  int
+	ModelsCacheTTL   time.Duration
+	HealthTimeout    time.Duration
+	HealthCacheTTL   time.Duration
+
+	// MaxConcurrency caps in-flight upstream requests (MEMOS_AI_MAX_CONCURRENCY). Requests
+	// over the cap wait up to ConcurrencyWait (MEMOS_AI_CONCURRENCY_WAIT) for a slot.
+	MaxConcurrency  int
+	ConcurrencyWait time.Duration
+	// IdempotencyTTL is how long responses to requests sent with an Idempotency-Key are
+	// kept for replay (MEMOS_AI_IDEMPOTENCY_TTL).
+	IdempotencyTTL time.Duration
+	// OmitUser stops sending a hashed user identifier upstream (MEMOS_AI_SEND_USER=false).
+	OmitUser bool
+	// MaxHistoryMessages caps how many saved session messages are sent upstream
+	// (MEMOS_AI_MAX_HISTORY_MESSAGES); zero sends the whole history. HistoryStrategy
+	// (MEMOS_AI_HISTORY_STRATEGY) is "drop" to discard older messages or "summarize" to
+	// replace them with a generated summary.
+	MaxHistoryMessages int
+	HistoryStrategy    string
+	// SSEKeepAlive is how long a stream may go without upstream data before a keep-alive
+	// comment is sent to the client (MEMOS_AI_SSE_KEEPALIVE).
+	SSEKeepAlive time.Duration
+	// StreamReplayTTL enables resuming streams: events get IDs and are kept for that long,
+	// and a client that reconnects with Last-Event-ID within it receives the events it
+	// missed and then the rest of the stream instead of a new completion
+	// (MEMOS_AI_STREAM_REPLAY_TTL). A completion whose client went away is aborted once no
+	// client has reconnected for that long.
+	StreamReplayTTL time.Duration
+	// StreamFlushBytes and StreamFlushInterval buffer streamed chunks, flushing them once
+	// that many bytes have accumulated or the interval has passed, whichever comes first
+	// (MEMOS_AI_STREAM_FLUSH_BYTES, MEMOS_AI_STREAM_FLUSH_INTERVAL in milliseconds). Zero
+	// flushes every chunk immediately.
+	StreamFlushBytes    int
+	StreamFlushInterval time.Duration
+	// StreamWriteTimeout aborts a stream, cancelling the upstream request, once a write to
+	// the client has been blocked for that long by a client that stopped reading
+	// (MEMOS_AI_STREAM_WRITE_TIMEOUT).
+	StreamWriteTimeout time.Duration
+	// RejectReasoningParams fails requests that set sampling parameters such as temperature
+	// for a reasoning model instead of dropping them (MEMOS_AI_STRIP_REASONING_PARAMS=false).
+	RejectReasoningParams bool
+	// Pricing maps model names to their price per 1,000 tokens (MEMOS_AI_PRICING, a JSON
+	// object). Models missing from it are treated as free.
+	Pricing map[string]ModelPrice
+	// ModelAliases maps friendly model names to provider model IDs (MEMOS_AI_MODEL_ALIASES,
+	// a JSON object such as {"fast": "openai/gpt-4o-mini"}). Aliases are resolved before
+	// AllowedModels is checked, so the allowlist names the resolved IDs.
+	ModelAliases map[string]string
+	// RedactPatterns are regular expressions whose matches are replaced in model output
+	// (MEMOS_AI_REDACT_PATTERNS, a JSON array). Streams are filtered best-effort.
+	RedactPatterns []string
+	// LocaleAware tells the model to reply in the user's language, from their settings or
+	// the Accept-Language header, when a chat has no system message (MEMOS_AI_LOCALE_AWARE).
+	LocaleAware bool
+	// ExtraHeaders are added to every request to the provider (MEMOS_AI_EXTRA_HEADERS, a JSON
+	// object such as {"OpenAI-Organization": "org-123"}). Credential headers such as
+	// Authorization are dropped unless OverrideAuthHeaders is set
+	// (MEMOS_AI_EXTRA_HEADERS_OVERRIDE_AUTH).
+	ExtraHeaders        map[string]string
+	OverrideAuthHeaders bool
+	// OverflowFallbackModel is a larger-context model that chat requests exceeding the
+	// context length are retried with once, instead of AutoTruncate dropping messages
+	// (MEMOS_AI_OVERFLOW_FALLBACK_MODEL).
+	OverflowFallbackModel string
+	// SlowThreshold is how long a request may take before a warning with its models, tokens
+	// and, for streams, time to first byte is logged (MEMOS_AI_SLOW_THRESHOLD).
+	SlowThreshold time.Duration
+	// MaxContextTokens caps the tokens of the memos added to a prompt by /ai/ask and memo_ids
+	// (MEMOS_AI_MAX_CONTEXT_TOKENS); zero leaves only the other limits. The least relevant
+	// memos are dropped first.
+	MaxContextTokens int
+
+	Audit        bool
+	AuditContent bool
+}
+
+// LoadConfig reads the AI configuration from MEMOS_AI_* environment variables. Invalid
+// values are logged and replaced by their defaults.
+func LoadConfig(logger *slog.Logger) Config {
+	providerConfig := ProviderConfig{
+		Name:            os.Getenv("MEMOS_AI_PROVIDER"),
+		BaseURL:         os.Getenv("MEMOS_AI_BASE_URL"),
+		AzureDeployment: os.Getenv("MEMOS_AZURE_DEPLOYMENT"),
+		AzureAPIVersion: os.Getenv("MEMOS_AZURE_API_VERSION"),
+	}
+	if strings.EqualFold(providerConfig.Name, providerAzure) {
+		providerConfig.BaseURL = os.Getenv("MEMOS_AZURE_ENDPOINT")
+	}
+	apiKey := os.Getenv("MEMOS_OPENAI_API_KEY")
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+
+	return Config{
+		Provider: providerConfig,
+		APIKey:   apiKey,
+		Proxy:    os.Getenv("MEMOS_AI_PROXY"),
+
+		CACert:             os.Getenv("MEMOS_AI_CA_CERT"),
+		InsecureSkipVerify: os.Getenv("MEMOS_AI_INSECURE_SKIP_VERIFY") == "true",
+
+		FallbackBaseURL: os.Getenv("MEMOS_AI_FALLBACK_BASE_URL"),
+		FallbackAPIKey:  os.Getenv("MEMOS_AI_FALLBACK_API_KEY"),
+
+		Debug:            os.Getenv("MEMOS_AI_DEBUG") == "true",
+		Timeout:          loadDuration(logger, "MEMOS_AI_TIMEOUT", defaultTimeout),
+		AllowPrivate:     os.Getenv("MEMOS_AI_ALLOW_PRIVATE") == "true",
+		AllowedHosts:     loadList("MEMOS_AI_ALLOWED_HOSTS"),
+		AllowedModels:    loadList("MEMOS_AI_ALLOWED_MODELS"),
+		DefaultModel:     strings.TrimSpace(os.Getenv("MEMOS_AI_DEFAULT_MODEL")),
+		MaxMessages:      loadInt(logger, "MEMOS_AI_MAX_MESSAGES", defaultMaxMessages),
+		MaxInputBytes:    loadInt(logger, "MEMOS_AI_MAX_INPUT_BYTES", defaultMaxInputBytes),
+		MaxImageBytes:    loadInt(logger, "MEMOS_AI_MAX_IMAGE_BYTES", defaultMaxImageBytes),
+		MaxResponseBytes: int64(loadInt(logger, "MEMOS_AI_MAX_RESPONSE_BYTES", defaultMaxResponseBytes)),
+		AutoTruncate:     os.Getenv("MEMOS_AI_AUTO_TRUNCATE") == "true",
+		SystemPrompt:     strings.TrimSpace(os.Getenv("MEMOS_AI_SYSTEM_PROMPT")),
+		PromptsDir:       os.Getenv("MEMOS_AI_PROMPTS_DIR"),
+
+		EmbeddingModel:      os.Getenv("MEMOS_AI_EMBEDDING_MODEL"),
+		DeveloperRoleModels: loadList("MEMOS_AI_DEVELOPER_ROLE_MODELS"),
+		ReasoningModels:     loadList("MEMOS_AI_REASONING_MODELS"),
+		AskTopK:             loadInt(logger, "MEMOS_AI_ASK_TOP_K", defaultAskTopK),
+		TitleMaxChars:       loadInt(logger, "MEMOS_AI_TITLE_MAX_CHARS", defaultTitleMaxChars),
+
+		MaxRetries:       loadInt(logger, "MEMOS_AI_MAX_RETRIES", defaultMaxRetries),
+		JSONRetries:      loadInt(logger, "MEMOS_AI_JSON_RETRIES", defaultJSONRetries),
+		RetryBudget:      loadInt(logger, "MEMOS_AI_RETRY_BUDGET", defaultRetryBudget),
+		RateLimit:        loadInt(logger, "MEMOS_AI_RATE_LIMIT", defaultRateLimit),
+		BreakerThreshold: loadInt(logger, "MEMOS_AI_BREAKER_THRESHOLD", defaultBreakerThreshold),
+		BreakerCooldown:  loadDuration(logger, "MEMOS_AI_BREAKER_COOLDOWN", defaultBreakerCooldown),
+		CacheTTL:         loadDuration(logger, "MEMOS_AI_CACHE_TTL", 0),
+		CacheSize:        loadInt(logger, "MEMOS_AI_CACHE_SIZE", defaultCacheSize),
+		Moderation:       os.Getenv("MEMOS_AI_MODERATION") == "true",
+		DailyTokenLimit:  loadInt(logger, "MEMOS_AI_DAILY_TOKEN_LIMIT", 0),
+		ModelsCacheTTL:   loadDuration(logger, "MEMOS_AI_MODELS_CACHE_TTL", defaultModelsCacheTTL),
+		HealthTimeout:    loadDuration(logger, "MEMOS_AI_HEALTH_TIMEOUT", defaultHealthTimeout),
+		HealthCacheTTL:   loadDuration(logger, "MEMOS_AI_HEALTH_CACHE_TTL", defaultHealthCacheTTL),
+
+		MaxConcurrency:  loadInt(logger, "MEMOS_AI_MAX_CONCURRENCY", 0),
+		ConcurrencyWait: loadDuration(logger, "MEMOS_AI_CONCURRENCY_WAIT", defaultConcurrencyWait),
+		IdempotencyTTL:  loadDuration(logger, "MEMOS_AI_IDEMPOTENCY_TTL", defaultIdempotencyTTL),
+		OmitUser:        os.Getenv("MEMOS_AI_SEND_USER") == "false",
+
+		MaxHistoryMessages: loadInt(logger, "MEMOS_AI_MAX_HISTORY_MESSAGES", 0),
+		HistoryStrategy:    loadChoice(logger, "MEMOS_AI_HISTORY_STRATEGY", historyStrategies, historyStrategyDrop),
+		SSEKeepAlive:       loadDuration(logger, "MEMOS_AI_SSE_KEEPALIVE", defaultSSEKeepAlive),
+		StreamReplayTTL:    loadDuration(logger, "MEMOS_AI_STREAM_REPLAY_TTL", 0),
+		Pricing:            loadPricing(logger),
+
+		StreamFlushBytes:    loadInt(logger, "MEMOS_AI_STREAM_FLUSH_BYTES", 0),
+		StreamFlushInterval: time.Duration(loadInt(logger, "MEMOS_AI_STREAM_FLUSH_INTERVAL", 0)) * time.Millisecond,
+		StreamWriteTimeout:  loadDuration(logger, "MEMOS_AI_STREAM_WRITE_TIMEOUT", defaultStreamWriteTimeout),
+
+		RejectReasoningParams: os.Getenv("MEMOS_AI_STRIP_REASONING_PARAMS") == "false",
+		ModelAliases:          loadModelAliases(logger),
+		RedactPatterns:        loadRedactPatterns(logger),
+		LocaleAware:           os.Getenv("MEMOS_AI_LOCALE_AWARE") == "true",
+		ExtraHeaders:          loadExtraHeaders(logger),
+		OverrideAuthHeaders:   os.Getenv("MEMOS_AI_EXTRA_HEADERS_OVERRIDE_AUTH") == "true",
+		OverflowFallbackModel: strings.TrimSpace(os.Getenv("MEMOS_AI_OVERFLOW_FALLBACK_MODEL")),
+		SlowThreshold:         loadDuration(logger, "MEMOS_AI_SLOW_THRESHOLD", defaultSlowThreshold),
+		MaxContextTokens:      loadInt(logger, "MEMOS_AI_MAX_CONTEXT_TOKENS", 0),
+
+		Audit:        os.Getenv("MEMOS_AI_AUDIT") == "true",
+		AuditContent: os.Getenv("MEMOS_AI_AUDIT_CONTENT") == "true",
+	}
+}
+
+// withDefaults returns cfg with zero limits and timeouts replaced by their defaults.
+func (cfg Config) withDefaults() Config {
+	cfg.Timeout = orDefault(cfg.Timeout, defaultTimeout)
+	cfg.MaxMessages = orDefault(cfg.MaxMessages, defaultMaxMessages)
+	cfg.MaxInputBytes = orDefault(cfg.MaxInputBytes, defaultMaxInputBytes)
+	cfg.MaxImageBytes = orDefault(cfg.MaxImageBytes, defaultMaxImageBytes)
+	cfg.MaxResponseBytes = orDefault(cfg.MaxResponseBytes, defaultMaxResponseBytes)
+	cfg.AskTopK = orDefault(cfg.AskTopK, defaultAskTopK)
+	cfg.RetryBudget = orDefault(cfg.RetryBudget, defaultRetryBudget)
+	cfg.TitleMaxChars = orDefault(cfg.TitleMaxChars, defaultTitleMaxChars)
+	cfg.BreakerCooldown = orDefault(cfg.BreakerCooldown, defaultBreakerCooldown)
+	cfg.CacheSize = orDefault(cfg.CacheSize, defaultCacheSize)
+	cfg.ModelsCacheTTL = orDefault(cfg.ModelsCacheTTL, defaultModelsCacheTTL)
+	cfg.HealthTimeout = orDefault(cfg.HealthTimeout, defaultHealthTimeout)
+	cfg.HealthCacheTTL = orDefault(cfg.HealthCacheTTL, defaultHealthCacheTTL)
+	cfg.ConcurrencyWait = orDefault(cfg.ConcurrencyWait, defaultConcurrencyWait)
+	cfg.IdempotencyTTL = orDefault(cfg.IdempotencyTTL, defaultIdempotencyTTL)
+	cfg.SSEKeepAlive = orDefault(cfg.SSEKeepAlive, defaultSSEKeepAlive)
+	cfg.StreamWriteTimeout = orDefault(cfg.StreamWriteTimeout, defaultStreamWriteTimeout)
+	cfg.SlowThreshold = orDefault(cfg.SlowThreshold, defaultSlowThreshold)
+	if cfg.HistoryStrategy == "" {
+		cfg.HistoryStrategy = historyStrategyDrop
+	}
+	if len(cfg.DeveloperRoleModels) == 0 {
+		cfg.DeveloperRoleModels = defaultDeveloperRoleModels
+	}
+	if len(cfg.ReasoningModels) == 0 {
+		cfg.ReasoningModels = defaultReasoningModels
+	}
+	return cfg
+}
+
+func orDefault[T int | int64 | time.Duration](value, def T) T {
+	if value <= 0 {
+		return def
+	}
+	return value
+}
+
+// loadInt reads a non-negative integer from the environment variable key, falling back to def.
+func loadInt(logger *slog.Logger, key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		logger.Warn("invalid integer in environment, using default", "key", key, "value", value, "default", def)
+		return def
+	}
+	return n
+}
+
+// loadChoice reads one of choices from the environment variable key, falling back to def.
+func loadChoice(logger *slog.Logger, key string, choices []string, def string) string {
+	value := strings.ToLower(strings.TrimSpace(os.Getenv(key)))
+	if value == "" {
+		return def
+	}
+	if !slices.Contains(choices, value) {
+		logger.Warn("invalid value in environment, using default", "key", key, "value", value, "default", def)
+		return def
+	}
+	return value
+}
+
+// loadList reads a comma-separated list from the environment variable key, dropping empty items.
+func loadList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// loadDuration reads a duration such as "30s" from the environment variable key.
+// Plain integers are interpreted as seconds. Invalid or non-positive values fall back to def.
+func loadDuration(logger *slog.Logger, key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			logger.Warn("invalid duration in environment, using default", "key", key, "value", value, "default", def)
+			return def
+		}
+		d = time.Duration(seconds) * time.Second
+	}
+	if d <= 0 {
+		return def
+	}
+	return d
+}
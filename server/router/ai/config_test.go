@@ -0,0 +1,122 @@
+package ai
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// doerFunc adapts a function to httpDoer so tests can answer upstream requests in-process.
+type doerFunc func(*http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func cannedResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestNewAIServiceFromConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		do     doerFunc
+		status int
+		code   string
+	}{
+		{
+			name: "success",
+			do: func(*http.Request) (*http.Response, error) {
+				return cannedResponse(http.StatusOK, `{"choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`), nil
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "invalid key",
+			do: func(*http.Request) (*http.Response, error) {
+				return cannedResponse(http.StatusUnauthorized, `{"error":{"message":"bad key","code":"invalid_api_key"}}`), nil
+			},
+			status: http.StatusServiceUnavailable,
+			code:   errorCodeAIUnavailable,
+		},
+		{
+			name: "transport error",
+			do: func(*http.Request) (*http.Response, error) {
+				return nil, errors.New("connection refused")
+			},
+			status: http.StatusBadGateway,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests int
+			s, err := NewAIServiceFromConfig(nil, "", Config{
+				Provider: ProviderConfig{BaseURL: "https://ai.example.com/v1/chat/completions"},
+				APIKey:   "test-key",
+				HTTPClient: doerFunc(func(req *http.Request) (*http.Response, error) {
+					requests++
+					require.Equal(t, "ai.example.com", req.URL.Host)
+					require.Equal(t, "Bearer test-key", req.Header.Get("Authorization"))
+					return test.do(req)
+				}),
+				AllowPrivate: true,
+			}, slog.Default())
+			require.NoError(t, err)
+
+			c, rec := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+			err = s.ChatCompletion(c)
+			require.Equal(t, 1, requests)
+			if test.status == http.StatusOK {
+				require.NoError(t, err)
+				require.Equal(t, http.StatusOK, rec.Code)
+				return
+			}
+			httpErr, ok := err.(*echo.HTTPError)
+			require.True(t, ok)
+			require.Equal(t, test.status, httpErr.Code)
+			if test.code != "" {
+				response, ok := httpErr.Message.(*ErrorResponse)
+				require.True(t, ok)
+				require.Equal(t, test.code, response.Error.Code)
+			}
+		})
+	}
+}
+
+func TestConfigWithDefaults(t *testing.T) {
+	cfg := Config{MaxMessages: 5}.withDefaults()
+	require.Equal(t, 5, cfg.MaxMessages)
+	require.Equal(t, defaultTimeout, cfg.Timeout)
+	require.Equal(t, defaultMaxInputBytes, cfg.MaxInputBytes)
+	require.Equal(t, defaultDeveloperRoleModels, cfg.DeveloperRoleModels)
+	require.Zero(t, cfg.RateLimit)
+	require.Zero(t, cfg.MaxRetries)
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("MEMOS_AI_PROVIDER", "azure")
+	t.Setenv("MEMOS_AZURE_ENDPOINT", "https://example.openai.azure.com")
+	t.Setenv("MEMOS_AZURE_DEPLOYMENT", "my-gpt")
+	t.Setenv("MEMOS_AI_TIMEOUT", "45")
+	t.Setenv("MEMOS_AI_ALLOWED_MODELS", "gpt-4o, gpt-4o-mini,")
+	t.Setenv("MEMOS_OPENAI_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "env-key")
+
+	cfg := LoadConfig(slog.Default())
+	require.Equal(t, ProviderConfig{Name: "azure", BaseURL: "https://example.openai.azure.com", AzureDeployment: "my-gpt"}, cfg.Provider)
+	require.Equal(t, 45*time.Second, cfg.Timeout)
+	require.Equal(t, []string{"gpt-4o", "gpt-4o-mini"}, cfg.AllowedModels)
+	require.Equal(t, "env-key", cfg.APIKey)
+	require.Equal(t, defaultMaxRetries, cfg.MaxRetries)
+}
@@ -0,0 +1,162 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+// defaultMaxImageBytes caps the decoded size of a single inline image.
+const defaultMaxImageBytes = 5 << 20
+
+const (
+	contentPartText     = "text"
+	contentPartImageURL = "image_url"
+)
+
+// ContentPart is one element of the OpenAI multi-part message content array.
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+type ImageURL struct {
+	// URL is an http(s) URL or a base64 data URI.
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// chatCompletionMessageJSON mirrors ChatCompletionMessage with content left raw.
+type chatCompletionMessageJSON struct {
+	Role       string          `json:"role"`
+	Content    json.RawMessage `json:"content"`
+	ToolCalls  json.RawMessage `json:"tool_calls,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+}
+
+// UnmarshalJSON accepts content either as a string or as an array of content parts.
+// For the array form Content holds the joined text parts so length checks and
+// text-only providers keep working.
+func (m *ChatCompletionMessage) UnmarshalJSON(data []byte) error {
+	var raw chatCompletionMessageJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = ChatCompletionMessage{Role: raw.Role, ToolCalls: raw.ToolCalls, ToolCallID: raw.ToolCallID}
+
+	content := bytes.TrimSpace(raw.Content)
+	switch {
+	case len(content) == 0 || bytes.Equal(content, []byte("null")):
+		return nil
+	case content[0] == '[':
+		if err := json.Unmarshal(content, &m.Parts); err != nil {
+			return err
+		}
+		var text []string
+		for _, part := range m.Parts {
+			if part.Type == contentPartText {
+				text = append(text, part.Text)
+			}
+		}
+		m.Content = strings.Join(text, "\n")
+		return nil
+	default:
+		return json.Unmarshal(content, &m.Content)
+	}
+}
+
+// MarshalJSON writes content parts as an array and plain content as a string, since
+// some providers reject the array form.
+func (m ChatCompletionMessage) MarshalJSON() ([]byte, error) {
+	var content any = m.Content
+	if m.Parts != nil {
+		content = m.Parts
+	}
+	return json.Marshal(&struct {
+		Role       string          `json:"role"`
+		Content    any             `json:"content"`
+		ToolCalls  json.RawMessage `json:"tool_calls,omitempty"`
+		ToolCallID string          `json:"tool_call_id,omitempty"`
+	}{
+		Role:       m.Role,
+		Content:    content,
+		ToolCalls:  m.ToolCalls,
+		ToolCallID: m.ToolCallID,
+	})
+}
+
+// hasImages reports whether any message carries image parts.
+func hasImages(messages []ChatCompletionMessage) bool {
+	for _, message := range messages {
+		for _, part := range message.Parts {
+			if part.Type == contentPartImageURL {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// supportsImageInput reports whether the provider accepts OpenAI-style image parts as is.
+func supportsImageInput(provider Provider) bool {
+	switch provider.Name() {
+	case providerOpenAI, providerAzure:
+		return true
+	default:
+		return false
+	}
+}
+
+// validateContentParts checks part types and image sources, enforcing maxImageBytes on inline images.
+func (s *AIService) validateContentParts(messages []ChatCompletionMessage) error {
+	for i, message := range messages {
+		for j, part := range message.Parts {
+			switch part.Type {
+			case contentPartText:
+			case contentPartImageURL:
+				if part.ImageURL == nil {
+					return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("messages[%d].content[%d]: image_url is required", i, j))
+				}
+				if err := s.validateImageURL(part.ImageURL.URL); err != nil {
+					return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("messages[%d].content[%d]: %s", i, j, err.Error()))
+				}
+			default:
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("messages[%d].content[%d]: unsupported content type %q", i, j, part.Type))
+			}
+		}
+	}
+	if hasImages(messages) && !supportsImageInput(s.provider) {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("AI provider %q does not support image input", s.provider.Name()))
+	}
+	return nil
+}
+
+// validateImageURL accepts http(s) URLs and base64 image data URIs within the size limit.
+func (s *AIService) validateImageURL(value string) error {
+	if rest, ok := strings.CutPrefix(value, "data:"); ok {
+		mediaType, data, ok := strings.Cut(rest, ";base64,")
+		if !ok || !strings.HasPrefix(mediaType, "image/") {
+			return errors.New("image data URI must be a base64 encoded image")
+		}
+		if size := base64.StdEncoding.DecodedLen(len(data)); size > s.maxImageBytes {
+			return errors.Errorf("image too large: %d bytes exceeds the limit of %d", size, s.maxImageBytes)
+		}
+		if _, err := base64.StdEncoding.DecodeString(data); err != nil {
+			return errors.New("image data URI is not valid base64")
+		}
+		return nil
+	}
+	imageURL, err := url.Parse(value)
+	if err != nil || (imageURL.Scheme != "https" && imageURL.Scheme != "http") || imageURL.Host == "" {
+		return errors.New("image url must be an http(s) URL or a data URI")
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package ai
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatCompletionMessageContentForms(t *testing.T) {
+	var messages []ChatCompletionMessage
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"role":"user","content":"plain"},
+		{"role":"user","content":[{"type":"text","text":"What is this?"},{"type":"image_url","image_url":{"url":"https://example.com/cat.png"}}]},
+		{"role":"assistant","content":null}
+	]`), &messages))
+	require.Equal(t, "plain", messages[0].Content)
+	require.Nil(t, messages[0].Parts)
+	require.Equal(t, "What is this?", messages[1].Content)
+	require.Len(t, messages[1].Parts, 2)
+	require.Equal(t, "https://example.com/cat.png", messages[1].Parts[1].ImageURL.URL)
+
+	data, err := json.Marshal(messages[:2])
+	require.NoError(t, err)
+	require.JSONEq(t, `[
+		{"role":"user","content":"plain"},
+		{"role":"user","content":[{"type":"text","text":"What is this?"},{"type":"image_url","image_url":{"url":"https://example.com/cat.png"}}]}
+	]`, string(data))
+}
+
+func TestValidateContentParts(t *testing.T) {
+	t.Setenv("MEMOS_AI_MAX_IMAGE_BYTES", "16")
+	s := NewAIService(nil, "", "test-key")
+	image := func(url string) []ChatCompletionMessage {
+		return []ChatCompletionMessage{{Role: "user", Parts: []ContentPart{{Type: contentPartImageURL, ImageURL: &ImageURL{URL: url}}}}}
+	}
+
+	require.NoError(t, s.validateContentParts(image("https://example.com/cat.png")))
+	require.NoError(t, s.validateContentParts(image("data:image/png;base64,"+base64.StdEncoding.EncodeToString([]byte("tiny")))))
+	for _, url := range []string{
+		"file:///etc/passwd",
+		"data:text/plain;base64,aGk=",
+		"data:image/png;base64,not base64!",
+		"data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", 64))),
+	} {
+		err := s.validateContentParts(image(url))
+		httpErr, ok := err.(*echo.HTTPError)
+		require.True(t, ok, url)
+		require.Equal(t, http.StatusBadRequest, httpErr.Code, url)
+	}
+	require.Error(t, s.validateContentParts([]ChatCompletionMessage{{Role: "user", Parts: []ContentPart{{Type: "audio"}}}}))
+}
+
+func TestImageInputRejectedForTextOnlyProvider(t *testing.T) {
+	t.Setenv("MEMOS_AI_PROVIDER", "anthropic")
+	s := NewAIService(nil, "", "test-key")
+	c, _ := newTestContext(`{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"https://example.com/cat.png"}}]}]}`)
+	err := s.ChatCompletion(c)
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusBadRequest, httpErr.Code)
+}
@@ -0,0 +1,108 @@
+package ai
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+const (
+	// headerXAIMemosRequested and headerXAIMemosIncluded report how many of the memos
+	// attached to a chat request with memo_ids were given to the model.
+	headerXAIMemosRequested = "X-AI-Memos-Requested"
+	headerXAIMemosIncluded  = "X-AI-Memos-Included"
+)
+
+// ContextUsage reports how many of the memos selected as context were given to the model.
+type ContextUsage struct {
+	RequestedMemos int `json:"requested_memos"`
+	IncludedMemos  int `json:"included_memos"`
+	// Truncated is set when the content of the last included memo was cut to fit.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+type contextUsageKey struct{}
+
+func withContextUsage(ctx context.Context, usage ContextUsage) context.Context {
+	return context.WithValue(ctx, contextUsageKey{}, usage)
+}
+
+// contextUsageFrom returns the usage of the memo context attached to the request, if any.
+func contextUsageFrom(ctx context.Context) (ContextUsage, bool) {
+	usage, ok := ctx.Value(contextUsageKey{}).(ContextUsage)
+	return usage, ok
+}
+
+// tokenCounter counts tokens with a model's tokenizer, or from the number of characters
+// when the model has none.
+type tokenCounter struct {
+	tokenizer *tiktoken.Tiktoken
+}
+
+func newTokenCounter(model string) tokenCounter {
+	tokenizer, _ := tokenizerForModel(model)
+	return tokenCounter{tokenizer: tokenizer}
+}
+
+func (t tokenCounter) count(text string) int {
+	if t.tokenizer == nil {
+		return (len(text) + charsPerToken - 1) / charsPerToken
+	}
+	return len(t.tokenizer.EncodeOrdinary(text))
+}
+
+// truncate cuts text to at most n tokens.
+func (t tokenCounter) truncate(text string, n int) string {
+	if t.tokenizer == nil {
+		return truncateBytes(text, n*charsPerToken)
+	}
+	tokens := t.tokenizer.EncodeOrdinary(text)
+	if len(tokens) <= n {
+		return text
+	}
+	// A token may end inside a multi-byte character.
+	return strings.ToValidUTF8(t.tokenizer.Decode(tokens[:n]), "")
+}
+
+// contextBlock is a memo added to a prompt as context.
+type contextBlock struct {
+	source  string
+	content string
+}
+
+// fitContext keeps the leading blocks, which come most relevant first, while their
+// rendering with render fits in maxTokens, and drops the rest. When not even the first
+// block fits, its content is truncated instead, so the prompt keeps some context. Zero
+// maxTokens keeps every block.
+func fitContext(blocks []contextBlock, maxTokens int, counter tokenCounter, render func(source, content string) string) ([]contextBlock, ContextUsage) {
+	usage := ContextUsage{RequestedMemos: len(blocks), IncludedMemos: len(blocks)}
+	if maxTokens <= 0 {
+		return blocks, usage
+	}
+	remaining := maxTokens
+	for i, block := range blocks {
+		tokens := counter.count(render(block.source, block.content))
+		if tokens <= remaining {
+			remaining -= tokens
+			continue
+		}
+		usage.IncludedMemos = i
+		if i > 0 {
+			return blocks[:i], usage
+		}
+		// Truncate the content rather than the block, so the block is still closed. Tokens
+		// can merge across the cut, so the rendered block is counted again.
+		for keep := remaining - counter.count(render(block.source, "")); keep > 0; {
+			content := counter.truncate(block.content, keep)
+			over := counter.count(render(block.source, content)) - remaining
+			if over <= 0 {
+				usage.IncludedMemos, usage.Truncated = 1, true
+				return []contextBlock{{source: block.source, content: content}}, usage
+			}
+			keep -= over
+		}
+		return nil, usage
+	}
+	return blocks, usage
+}
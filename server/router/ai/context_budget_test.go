@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFitContext(t *testing.T) {
+	counter := newTokenCounter("gpt-4o")
+	require.NotNil(t, counter.tokenizer)
+	blocks := []contextBlock{
+		{source: "memo 1", content: strings.Repeat("cat ", 40)},
+		{source: "memo 2", content: strings.Repeat("tax ", 40)},
+		{source: "memo 3", content: strings.Repeat("trip ", 40)},
+	}
+	first := counter.count(dataBlock(blocks[0].source, blocks[0].content))
+
+	kept, usage := fitContext(blocks, 0, counter, dataBlock)
+	require.Equal(t, blocks, kept)
+	require.Equal(t, ContextUsage{RequestedMemos: 3, IncludedMemos: 3}, usage)
+
+	// The least relevant blocks are dropped first.
+	kept, usage = fitContext(blocks, first+10, counter, dataBlock)
+	require.Equal(t, blocks[:1], kept)
+	require.Equal(t, ContextUsage{RequestedMemos: 3, IncludedMemos: 1}, usage)
+
+	// A first block too large by itself is truncated rather than dropped.
+	kept, usage = fitContext(blocks, first/2, counter, dataBlock)
+	require.Len(t, kept, 1)
+	require.Equal(t, ContextUsage{RequestedMemos: 3, IncludedMemos: 1, Truncated: true}, usage)
+	require.True(t, strings.HasPrefix(blocks[0].content, kept[0].content))
+	require.LessOrEqual(t, counter.count(dataBlock(kept[0].source, kept[0].content)), first/2)
+
+	kept, usage = fitContext(blocks, 1, counter, dataBlock)
+	require.Empty(t, kept)
+	require.Equal(t, 0, usage.IncludedMemos)
+}
+
+func TestTokenCounterTruncate(t *testing.T) {
+	counter := newTokenCounter("gpt-4o")
+	require.Equal(t, "short", counter.truncate("short", 10))
+	truncated := counter.truncate(strings.Repeat("日本語の文章。", 20), 5)
+	require.LessOrEqual(t, counter.count(truncated), 5)
+	require.True(t, strings.HasPrefix(strings.Repeat("日本語の文章。", 20), truncated))
+
+	// Models without a tokenizer are counted from characters.
+	heuristic := newTokenCounter("unknown-model")
+	require.Nil(t, heuristic.tokenizer)
+	require.Equal(t, strings.Repeat("a", 2*charsPerToken), heuristic.truncate(strings.Repeat("a", 100), 2))
+}
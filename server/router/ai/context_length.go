@@ -0,0 +1,153 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// charsPerToken is the rough ratio used to estimate how many tokens a message holds.
+	charsPerToken = 4
+
+	// headerXAIModel reports the model that served a chat completion, which differs from the
+	// requested one after an overflow retry.
+	headerXAIModel = "X-AI-Model"
+)
+
+// contextLengthPattern matches OpenAI's "maximum context length is 8192 tokens. However,
+// your messages resulted in 9000 tokens" error message.
+var contextLengthPattern = regexp.MustCompile(`maximum context length is (\d+) tokens.*?resulted in (\d+) tokens`)
+
+// parseTokenOverage returns how many tokens a request exceeded the model's context by, or 0
+// when the provider error doesn't say.
+func parseTokenOverage(body []byte) int {
+	var envelope struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return 0
+	}
+	match := contextLengthPattern.FindStringSubmatch(envelope.Error.Message)
+	if match == nil {
+		return 0
+	}
+	limit, _ := strconv.Atoi(match[1])
+	requested, _ := strconv.Atoi(match[2])
+	return max(requested-limit, 0)
+}
+
+// contextTooLong reports whether err is a normalized context_too_long error and returns
+// the token overage it carries.
+func contextTooLong(err error) (int, bool) {
+	httpErr, ok := err.(*echo.HTTPError)
+	if !ok {
+		return 0, false
+	}
+	response, ok := httpErr.Message.(*ErrorResponse)
+	if !ok || response.Error == nil || response.Error.Code != errorCodeContextTooLong {
+		return 0, false
+	}
+	return response.Error.TokenOverage, true
+}
+
+// truncateForContext drops the oldest non-system messages until roughly overage tokens are
+// removed, or half of them when the overage is unknown. The latest message is always kept.
+// It returns false when nothing can be dropped.
+func truncateForContext(messages []ChatCompletionMessage, overage int) ([]ChatCompletionMessage, bool) {
+	var droppable []int
+	for i, message := range messages[:len(messages)-1] {
+		if message.Role != roleSystem && message.Role != roleDeveloper {
+			droppable = append(droppable, i)
+		}
+	}
+	if len(droppable) == 0 {
+		return messages, false
+	}
+
+	drop := map[int]bool{}
+	if overage <= 0 {
+		for _, i := range droppable[:max(len(droppable)/2, 1)] {
+			drop[i] = true
+		}
+	} else {
+		removed := 0
+		for _, i := range droppable {
+			if removed >= overage {
+				break
+			}
+			drop[i] = true
+			removed += len(messageText(messages[i]))/charsPerToken + 1
+		}
+	}
+
+	truncated := make([]ChatCompletionMessage, 0, len(messages)-len(drop))
+	for i, message := range messages {
+		if !drop[i] {
+			truncated = append(truncated, message)
+		}
+	}
+	return truncated, true
+}
+
+// truncateAndRetry shortens req after a context_too_long error when MEMOS_AI_AUTO_TRUNCATE
+// is enabled. It reports whether the request should be sent again.
+func (s *AIService) truncateAndRetry(err error, req *ChatCompletionRequest) bool {
+	if !s.autoTruncate {
+		return false
+	}
+	overage, ok := contextTooLong(err)
+	if !ok {
+		return false
+	}
+	truncated, ok := truncateForContext(req.Messages, overage)
+	if !ok {
+		return false
+	}
+	req.Messages = truncated
+	return true
+}
+
+// newOverflowModel resolves the configured overflow fallback model through aliases. A model
+// outside a non-empty allowedModels is logged and dropped, since retries with it would
+// always fail.
+func newOverflowModel(logger *slog.Logger, model string, aliases map[string]string, allowedModels []string) string {
+	if resolved, ok := aliases[model]; ok {
+		model = resolved
+	}
+	if model != "" && len(allowedModels) > 0 && !slices.Contains(allowedModels, model) {
+		logger.Warn("MEMOS_AI_OVERFLOW_FALLBACK_MODEL is not an allowed model, overflow fallback is disabled", "model", model)
+		return ""
+	}
+	return model
+}
+
+// retryContextOverflow readies req to be sent once more after a context_too_long error. It
+// switches to the overflow fallback model when one is configured and req doesn't use it
+// yet, or else drops older messages when MEMOS_AI_AUTO_TRUNCATE is enabled. It reports
+// whether the request should be sent again, which the retry budget may rule out.
+func (s *AIService) retryContextOverflow(ctx context.Context, err error, req *ChatCompletionRequest) bool {
+	if _, ok := contextTooLong(err); !ok || !s.canRetry(ctx, retryLayerOverflow) {
+		return false
+	}
+	if s.overflowModel != "" {
+		if req.Model == s.overflowModel {
+			return false
+		}
+		s.log(ctx).Info("AI conversation exceeded the context length, retrying with the overflow fallback model", "model", req.Model, "fallback_model", s.overflowModel)
+		req.Model = s.overflowModel
+		return true
+	}
+	if !s.truncateAndRetry(err, req) {
+		return false
+	}
+	s.log(ctx).Info("AI conversation exceeded the context length, retrying with older messages dropped", "messages", len(req.Messages))
+	return true
+}
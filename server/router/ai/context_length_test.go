@@ -0,0 +1,116 @@
+package ai
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+const contextLengthBody = `{"error":{"code":"context_length_exceeded","message":"This model's maximum context length is 8192 tokens. However, your messages resulted in 8200 tokens. Please reduce the length of the messages."}}`
+
+func TestParseTokenOverage(t *testing.T) {
+	require.Equal(t, 8, parseTokenOverage([]byte(contextLengthBody)))
+	require.Zero(t, parseTokenOverage([]byte(`{"error":{"code":"context_length_exceeded","message":"too long"}}`)))
+	require.Zero(t, parseTokenOverage([]byte(`not json`)))
+}
+
+func TestTruncateForContext(t *testing.T) {
+	messages := []ChatCompletionMessage{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: strings.Repeat("a", 40)},
+		{Role: "assistant", Content: strings.Repeat("b", 40)},
+		{Role: "user", Content: "latest"},
+	}
+	truncated, ok := truncateForContext(messages, 5)
+	require.True(t, ok)
+	require.Equal(t, []string{"be brief", strings.Repeat("b", 40), "latest"}, []string{truncated[0].Content, truncated[1].Content, truncated[2].Content})
+
+	truncated, ok = truncateForContext(messages, 0)
+	require.True(t, ok)
+	require.Len(t, truncated, 3, "half of the droppable messages go when the overage is unknown")
+
+	_, ok = truncateForContext([]ChatCompletionMessage{{Role: "system"}, {Role: "user"}}, 5)
+	require.False(t, ok)
+}
+
+func TestChatCompletionContextTooLong(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := new(ChatCompletionRequest)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		w.Header().Set("Content-Type", "application/json")
+		if len(req.Messages) > 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(contextLengthBody))
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	body := `{"messages":[{"role":"user","content":"old"},{"role":"assistant","content":"older reply"},{"role":"user","content":"new"}]}`
+
+	s := NewAIService(nil, "", "test-key")
+	c, _ := newTestContext(body)
+	err := s.ChatCompletion(c)
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusBadRequest, httpErr.Code)
+	detail := httpErr.Message.(*ErrorResponse).Error
+	require.Equal(t, errorCodeContextTooLong, detail.Code)
+	require.Equal(t, 8, detail.TokenOverage)
+
+	t.Setenv("MEMOS_AI_AUTO_TRUNCATE", "true")
+	s = NewAIService(nil, "", "test-key")
+	c, rec := newTestContext(body)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestChatCompletionOverflowFallbackModel(t *testing.T) {
+	var models []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := new(ChatCompletionRequest)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		models = append(models, req.Model)
+		w.Header().Set("Content-Type", "application/json")
+		if req.Model != "big-context" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(contextLengthBody))
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	t.Setenv("MEMOS_AI_AUTO_TRUNCATE", "true")
+	t.Setenv("MEMOS_AI_OVERFLOW_FALLBACK_MODEL", "big-context")
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"model":"small","messages":[{"role":"user","content":"old"},{"role":"user","content":"new"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, []string{"small", "big-context"}, models)
+	require.Equal(t, "big-context", rec.Header().Get(headerXAIModel))
+
+	// A fallback model that is still too small fails without a second retry.
+	models = nil
+	t.Setenv("MEMOS_AI_OVERFLOW_FALLBACK_MODEL", "medium")
+	s = NewAIService(nil, "", "test-key")
+	c, _ = newTestContext(`{"model":"small","messages":[{"role":"user","content":"old"},{"role":"user","content":"new"}]}`)
+	_, ok := contextTooLong(s.ChatCompletion(c))
+	require.True(t, ok)
+	require.Equal(t, []string{"small", "medium"}, models)
+}
+
+func TestNewOverflowModel(t *testing.T) {
+	aliases := map[string]string{"long": "openai/gpt-4.1"}
+	require.Equal(t, "openai/gpt-4.1", newOverflowModel(slog.Default(), "long", aliases, nil))
+	require.Equal(t, "openai/gpt-4.1", newOverflowModel(slog.Default(), "long", aliases, []string{"openai/gpt-4.1"}))
+	require.Empty(t, newOverflowModel(slog.Default(), "other", aliases, []string{"openai/gpt-4.1"}))
+	require.Empty(t, newOverflowModel(slog.Default(), "", aliases, nil))
+}
@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// headerXAIFinishReason carries the finish_reason of a non-streaming chat completion.
+// "length" means the reply was cut off by max_tokens and can be extended with /ai/continue.
+const headerXAIFinishReason = "X-AI-Finish-Reason"
+
+// continuePrompt asks the model to pick up a cut-off reply without repeating it.
+const continuePrompt = "Your previous reply was cut off. Continue it exactly where it stopped, " +
+	"without repeating any of it and without any preamble."
+
+type ContinueRequest struct {
+	// Messages is the conversation that produced the truncated reply.
+	Messages []ChatCompletionMessage `json:"messages"`
+	// Partial is the truncated assistant reply to continue.
+	Partial   string `json:"partial"`
+	Model     string `json:"model,omitempty"`
+	MaxTokens *int   `json:"max_tokens,omitempty"`
+}
+
+type ContinueResponse struct {
+	// Content is the continuation to append to the partial reply.
+	Content string `json:"content"`
+	// FinishReason is "length" when the continuation was cut off as well.
+	FinishReason string `json:"finish_reason"`
+}
+
+// Continue extends an assistant reply that was cut off by max_tokens.
+func (s *AIService) Continue(c echo.Context) error {
+	ctx := c.Request().Context()
+	apiKey, err := s.requireAPIKey(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	request := new(ContinueRequest)
+	if err := c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	}
+	if request.Partial == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "partial must not be empty")
+	}
+	messages := append(append([]ChatCompletionMessage{}, request.Messages...),
+		ChatCompletionMessage{Role: roleAssistant, Content: request.Partial},
+		ChatCompletionMessage{Role: roleUser, Content: continuePrompt},
+	)
+	req := &ChatCompletionRequest{Model: request.Model, Messages: messages, MaxTokens: request.MaxTokens}
+	if err := s.validateChatCompletionRequest(req); err != nil {
+		return err
+	}
+	if req.Model, err = s.resolveModel(req.Model); err != nil {
+		return err
+	}
+	req.Messages = s.withSystemPrompt(req.Messages)
+
+	body, _, err := s.completion(ctx, apiKey, req)
+	if err != nil {
+		return err
+	}
+	response, err := unmarshalResponse(body)
+	if err != nil {
+		return err
+	}
+	choice := response.Choices[0]
+	return c.JSON(http.StatusOK, &ContinueResponse{Content: choice.Content(), FinishReason: choice.FinishReason})
+}
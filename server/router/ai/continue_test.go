@@ -0,0 +1,47 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContinue(t *testing.T) {
+	newChatUpstream(t, " and then it ended.", func(req *ChatCompletionRequest) {
+		require.Len(t, req.Messages, 3)
+		require.Equal(t, ChatCompletionMessage{Role: roleAssistant, Content: "Once upon a time"}, req.Messages[1])
+		require.Equal(t, ChatCompletionMessage{Role: roleUser, Content: continuePrompt}, req.Messages[2])
+	})
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"messages":[{"role":"user","content":"Tell a story"}],"partial":"Once upon a time"}`)
+	require.NoError(t, s.Continue(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	response := new(ContinueResponse)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), response))
+	require.Equal(t, &ContinueResponse{Content: " and then it ended.", FinishReason: "stop"}, response)
+}
+
+func TestContinueRequiresPartial(t *testing.T) {
+	s := NewAIService(nil, "", "test-key")
+	c, _ := newTestContext(`{"messages":[{"role":"user","content":"Tell a story"}]}`)
+	require.Error(t, s.Continue(c))
+}
+
+func TestChatCompletionReportsFinishReason(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"Once upon"},"finish_reason":"length"}]}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"messages":[{"role":"user","content":"Tell a story"}],"max_tokens":2}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, "length", rec.Header().Get(headerXAIFinishReason))
+}
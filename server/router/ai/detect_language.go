@@ -0,0 +1,214 @@
+package ai
+
+import (
+	"math"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+const (
+	// undeterminedLanguage is the BCP-47 tag reported when no language can be identified.
+	undeterminedLanguage = "und"
+	// minDetectLetters is the shortest text, in letters, whose detected language is trusted.
+	// Shorter input is answered locally with at most shortTextConfidence.
+	minDetectLetters    = 20
+	shortTextConfidence = 0.3
+	// ambiguousConfidence is the local confidence below which the model is asked instead.
+	ambiguousConfidence = 0.6
+	// detectSampleRunes caps how much of the memo is sent to the model.
+	detectSampleRunes = 500
+)
+
+const detectLanguagePrompt = "You identify languages. Reply with only a JSON object whose \"lang\" field is the BCP-47 tag " +
+	"of the language the user's note is written in and whose \"confidence\" field is a number between 0 and 1, " +
+	"for example {\"lang\": \"fr\", \"confidence\": 0.9}."
+
+// stopwords are frequent short words that tell apart languages written in the Latin script.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "of", "to", "in", "that", "it", "with", "for", "this", "was", "you", "have"},
+	"es": {"el", "la", "los", "las", "y", "es", "de", "que", "en", "un", "una", "por", "con", "para", "está"},
+	"fr": {"le", "la", "les", "et", "est", "de", "des", "que", "un", "une", "pour", "dans", "avec", "pas", "sur"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "mit", "auf", "für", "ich", "zu", "den", "von"},
+	"it": {"il", "la", "le", "e", "è", "di", "che", "un", "una", "per", "con", "non", "sono", "del", "della"},
+	"pt": {"o", "a", "os", "as", "e", "é", "de", "que", "um", "uma", "para", "com", "não", "em", "do"},
+	"nl": {"de", "het", "en", "is", "van", "een", "dat", "niet", "met", "op", "voor", "zijn", "ik", "te", "die"},
+}
+
+type DetectLanguageRequest struct {
+	Content string `json:"content"`
+}
+
+type DetectLanguageResponse struct {
+	// Lang is a BCP-47 language tag, or "und" when the language could not be identified.
+	Lang       string  `json:"lang"`
+	Confidence float64 `json:"confidence"`
+}
+
+// DetectLanguage identifies the language of the given memo content. Most text is
+// classified locally; the model is only asked when the local guess is ambiguous.
+// Text shorter than minDetectLetters letters is always answered locally with low confidence.
+func (s *AIService) DetectLanguage(c echo.Context) error {
+	ctx := c.Request().Context()
+	apiKey, err := s.requireAPIKey(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	request := new(DetectLanguageRequest)
+	if err := c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	}
+	if err := s.validateContent(request.Content); err != nil {
+		return err
+	}
+
+	lang, confidence, letters := detectLanguageLocally(request.Content)
+	if letters < minDetectLetters {
+		return c.JSON(http.StatusOK, &DetectLanguageResponse{Lang: lang, Confidence: min(confidence, shortTextConfidence)})
+	}
+	if confidence >= ambiguousConfidence {
+		return c.JSON(http.StatusOK, &DetectLanguageResponse{Lang: lang, Confidence: confidence})
+	}
+
+	model, err := s.resolveModel("")
+	if err != nil {
+		return err
+	}
+	detected := new(DetectLanguageResponse)
+	if err := s.completeJSON(ctx, apiKey, &ChatCompletionRequest{
+		Model: model,
+		Messages: []ChatCompletionMessage{
+			{Role: "system", Content: detectLanguagePrompt},
+			{Role: "user", Content: truncateAtWord(request.Content, detectSampleRunes)},
+		},
+		ResponseFormat: jsonObjectFormat,
+	}, func(content string) error {
+		if err := parseJSONObject(content, detected); err != nil {
+			return err
+		}
+		if !languageTagPattern.MatchString(detected.Lang) {
+			return errors.Errorf("invalid language tag %q", detected.Lang)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	detected.Confidence = math.Max(0, math.Min(detected.Confidence, 1))
+	return c.JSON(http.StatusOK, detected)
+}
+
+// detectLanguageLocally guesses the language of text from its script and, for the Latin
+// script, from stopword frequencies. It returns the guess, a confidence between 0 and 1
+// and the number of letters the guess is based on.
+func detectLanguageLocally(text string) (string, float64, int) {
+	scripts := map[string]int{}
+	letters := 0
+	ukrainian := false
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Hangul, r):
+			scripts["ko"]++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			scripts["ja"]++
+		case unicode.Is(unicode.Han, r):
+			scripts["zh"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			scripts["ru"]++
+			ukrainian = ukrainian || strings.ContainsRune("іїєґІЇЄҐ", r)
+		case unicode.Is(unicode.Arabic, r):
+			scripts["ar"]++
+		case unicode.Is(unicode.Hebrew, r):
+			scripts["he"]++
+		case unicode.Is(unicode.Greek, r):
+			scripts["el"]++
+		case unicode.Is(unicode.Thai, r):
+			scripts["th"]++
+		case unicode.Is(unicode.Devanagari, r):
+			scripts["hi"]++
+		case unicode.Is(unicode.Latin, r):
+			scripts["latin"]++
+		}
+	}
+	if letters == 0 {
+		return undeterminedLanguage, 0, 0
+	}
+	// Japanese mixes kana with Han characters, so any kana outweighs the Han count.
+	if scripts["ja"] > 0 {
+		scripts["ja"] += scripts["zh"]
+		scripts["zh"] = 0
+	}
+
+	script, count := "", 0
+	for name, n := range scripts {
+		if n > count || (n == count && name < script) {
+			script, count = name, n
+		}
+	}
+	share := float64(count) / float64(letters)
+	switch script {
+	case "latin":
+		lang, confidence := detectLatinLanguage(text)
+		return lang, round2(confidence * share), letters
+	case "ru":
+		if ukrainian {
+			return "uk", round2(0.9 * share), letters
+		}
+		// Other Cyrillic languages share most letters with Russian.
+		return "ru", round2(0.7 * share), letters
+	case "ar":
+		// Persian and Urdu are also written in the Arabic script.
+		return "ar", round2(0.7 * share), letters
+	case "":
+		return undeterminedLanguage, 0, letters
+	default:
+		return script, round2(0.95 * share), letters
+	}
+}
+
+// detectLatinLanguage scores text against each language's stopwords. The confidence
+// grows with the lead of the best language and with the number of stopwords found.
+func detectLatinLanguage(text string) (string, float64) {
+	scores := map[string]int{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		for lang, words := range stopwords {
+			for _, stopword := range words {
+				if word == stopword {
+					scores[lang]++
+					break
+				}
+			}
+		}
+	}
+	best := ""
+	for lang, score := range scores {
+		if score > scores[best] || (score == scores[best] && lang < best) {
+			best = lang
+		}
+	}
+	if best == "" {
+		return undeterminedLanguage, 0
+	}
+	second := 0
+	for lang, score := range scores {
+		if lang != best {
+			second = max(second, score)
+		}
+	}
+	lead := float64(scores[best]-second) / float64(scores[best])
+	coverage := math.Min(float64(scores[best])/4, 1)
+	return best, lead * coverage
+}
+
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
@@ -0,0 +1,77 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectLanguageLocally(t *testing.T) {
+	tests := []struct {
+		text string
+		lang string
+	}{
+		{"The meeting is moved to Friday and the notes are in the shared folder.", "en"},
+		{"La reunión se movió al viernes y las notas están en la carpeta compartida.", "es"},
+		{"Die Besprechung ist auf Freitag verschoben und die Notizen sind in dem Ordner.", "de"},
+		{"Встреча перенесена на пятницу, заметки в общей папке.", "ru"},
+		{"Зустріч перенесено на п'ятницю, нотатки в спільній папці.", "uk"},
+		{"会議は金曜日に変更されました。メモは共有フォルダにあります。", "ja"},
+		{"会议改到星期五,笔记在共享文件夹里。", "zh"},
+		{"회의가 금요일로 변경되었습니다.", "ko"},
+	}
+	for _, test := range tests {
+		lang, confidence, _ := detectLanguageLocally(test.text)
+		require.Equal(t, test.lang, lang, test.text)
+		require.Greater(t, confidence, 0.0, test.text)
+	}
+
+	lang, confidence, letters := detectLanguageLocally("12:30 !!!")
+	require.Equal(t, undeterminedLanguage, lang)
+	require.Zero(t, confidence)
+	require.Zero(t, letters)
+}
+
+func TestDetectLanguageAnswersLocally(t *testing.T) {
+	// No upstream is configured; a request to the provider would fail the test.
+	t.Setenv("MEMOS_AI_BASE_URL", "http://127.0.0.1:1")
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"content":"The meeting is moved to Friday and the notes are in the shared folder with the agenda."}`)
+	require.NoError(t, s.DetectLanguage(c))
+	response := new(DetectLanguageResponse)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), response))
+	require.Equal(t, "en", response.Lang)
+	require.GreaterOrEqual(t, response.Confidence, ambiguousConfidence)
+
+	c, rec = newTestContext(`{"content":"ok"}`)
+	require.NoError(t, s.DetectLanguage(c))
+	response = new(DetectLanguageResponse)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), response))
+	require.LessOrEqual(t, response.Confidence, shortTextConfidence)
+}
+
+func TestDetectLanguageAsksModelWhenAmbiguous(t *testing.T) {
+	newChatUpstream(t, "```json\n{\"lang\": \"sv\", \"confidence\": 0.93}\n```", func(req *ChatCompletionRequest) {
+		require.Equal(t, detectLanguagePrompt, req.Messages[0].Content)
+	})
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"content":"Mötet flyttas till fredag, anteckningarna finns i mappen."}`)
+	require.NoError(t, s.DetectLanguage(c))
+	response := new(DetectLanguageResponse)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), response))
+	require.Equal(t, &DetectLanguageResponse{Lang: "sv", Confidence: 0.93}, response)
+}
+
+func TestDetectLanguageRejectsEmptyContent(t *testing.T) {
+	s := NewAIService(nil, "", "test-key")
+
+	c, _ := newTestContext(`{"content":"   "}`)
+	httpErr, ok := s.DetectLanguage(c).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusBadRequest, httpErr.Code)
+}
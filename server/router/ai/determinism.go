@@ -0,0 +1,20 @@
+package ai
+
+// headerXAISystemFingerprint carries the system_fingerprint of a non-streaming chat
+// completion, when the provider reports one, so clients sending a seed can tell whether
+// replies came from the same backend configuration.
+const headerXAISystemFingerprint = "X-AI-System-Fingerprint"
+
+// applyDeterminism strips seed and logit_bias for providers that don't support them.
+// Ollama and Gemini take the seed in their own options; only OpenAI-compatible providers
+// accept logit_bias.
+func applyDeterminism(provider Provider, req *ChatCompletionRequest) {
+	switch provider.Name() {
+	case providerOpenAI, providerAzure:
+	case providerOllama, providerGemini:
+		req.LogitBias = nil
+	default:
+		req.Seed = nil
+		req.LogitBias = nil
+	}
+}
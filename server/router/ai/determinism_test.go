@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeedAndLogitBiasPassthrough(t *testing.T) {
+	var received *ChatCompletionRequest
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = new(ChatCompletionRequest)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(received))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"system_fingerprint":"fp_44709d6fcb","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"seed":42,"logit_bias":{"50256":-100},"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, 42, *received.Seed)
+	require.Equal(t, map[string]int{"50256": -100}, received.LogitBias)
+	require.Equal(t, "fp_44709d6fcb", rec.Header().Get(headerXAISystemFingerprint))
+	require.Contains(t, rec.Body.String(), `"system_fingerprint":"fp_44709d6fcb"`)
+
+	// Neither is sent when unset.
+	c, rec = newTestContext(`{"messages":[{"role":"user","content":"hello"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Nil(t, received.Seed)
+	require.Nil(t, received.LogitBias)
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestApplyDeterminism(t *testing.T) {
+	seed := 42
+	for name, want := range map[string]struct{ seed, logitBias bool }{
+		providerOpenAI:    {seed: true, logitBias: true},
+		providerOllama:    {seed: true},
+		providerGemini:    {seed: true},
+		providerAnthropic: {},
+		providerMock:      {},
+	} {
+		provider, err := newProvider(ProviderConfig{Name: name, BaseURL: "https://example.com"})
+		require.NoError(t, err, name)
+		req := &ChatCompletionRequest{Seed: &seed, LogitBias: map[string]int{"50256": -100}}
+		applyDeterminism(provider, req)
+		require.Equal(t, want.seed, req.Seed != nil, name)
+		require.Equal(t, want.logitBias, req.LogitBias != nil, name)
+	}
+
+	ollama, err := newProvider(ProviderConfig{Name: providerOllama})
+	require.NoError(t, err)
+	httpReq, err := ollama.NewChatRequest(t.Context(), "", &ChatCompletionRequest{Seed: &seed, Messages: newMessages(1, "hi")})
+	require.NoError(t, err)
+	var body ollamaRequest
+	require.NoError(t, json.NewDecoder(httpReq.Body).Decode(&body))
+	require.Equal(t, seed, *body.Options.Seed)
+}
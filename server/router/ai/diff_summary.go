@@ -0,0 +1,177 @@
+package ai
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// noChangesSummary is returned without calling the model when both versions are identical.
+	noChangesSummary = "No changes"
+	// diffContextLines is how many unchanged lines are kept around each change.
+	diffContextLines = 2
+	// maxDiffCells bounds the table of the line diff. Larger changes are reported as the whole
+	// changed region removed and added again, which is correct but less compact.
+	maxDiffCells = 1 << 20
+)
+
+type DiffSummaryRequest struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+type DiffSummaryResponse struct {
+	Summary string `json:"summary"`
+}
+
+// DiffSummary describes in natural language what changed between two versions of a memo.
+// Only a line diff with a little context is sent to the model, not both versions. The
+// summary is returned as plain text when the client sends "Accept: text/plain".
+func (s *AIService) DiffSummary(c echo.Context) error {
+	ctx := c.Request().Context()
+	request := new(DiffSummaryRequest)
+	if err := c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	}
+	for _, field := range []struct{ name, content string }{{"before", request.Before}, {"after", request.After}} {
+		if len(field.content) > s.maxInputBytes {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("%s too large: %d bytes exceeds the limit of %d", field.name, len(field.content), s.maxInputBytes))
+		}
+	}
+	if request.Before == request.After {
+		return respondText(c, noChangesSummary, &DiffSummaryResponse{Summary: noChangesSummary})
+	}
+	apiKey, err := s.requireAPIKey(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	model, err := s.resolveModel("")
+	if err != nil {
+		return err
+	}
+	prompt, err := s.prompt(promptDiffSummary, diffSummaryPromptData{})
+	if err != nil {
+		return err
+	}
+	summary, err := s.complete(ctx, apiKey, &ChatCompletionRequest{
+		Model: model,
+		Messages: []ChatCompletionMessage{
+			{Role: "system", Content: withContextGuard(prompt)},
+			{Role: "user", Content: delimitContext("diff", compactDiff(request.Before, request.After))},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	summary = strings.TrimSpace(summary)
+	return respondText(c, summary, &DiffSummaryResponse{Summary: summary})
+}
+
+// diffLine is a line of a line diff: op is ' ' for an unchanged line, '-' for a removed
+// one and '+' for an added one.
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// diffLines returns a shortest line diff turning before into after.
+func diffLines(before, after []string) []diffLine {
+	prefix := 0
+	for prefix < len(before) && prefix < len(after) && before[prefix] == after[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(before)-prefix && suffix < len(after)-prefix && before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+
+	var lines []diffLine
+	for _, text := range before[:prefix] {
+		lines = append(lines, diffLine{op: ' ', text: text})
+	}
+	a, b := before[prefix:len(before)-suffix], after[prefix:len(after)-suffix]
+	if len(a)*len(b) > maxDiffCells {
+		for _, text := range a {
+			lines = append(lines, diffLine{op: '-', text: text})
+		}
+		for _, text := range b {
+			lines = append(lines, diffLine{op: '+', text: text})
+		}
+	} else {
+		// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+		common := make([][]int32, len(a)+1)
+		for i := range common {
+			common[i] = make([]int32, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					common[i][j] = common[i+1][j+1] + 1
+				} else {
+					common[i][j] = max(common[i+1][j], common[i][j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(a) || j < len(b) {
+			switch {
+			case i < len(a) && j < len(b) && a[i] == b[j]:
+				lines = append(lines, diffLine{op: ' ', text: a[i]})
+				i++
+				j++
+			case j == len(b) || (i < len(a) && common[i+1][j] >= common[i][j+1]):
+				lines = append(lines, diffLine{op: '-', text: a[i]})
+				i++
+			default:
+				lines = append(lines, diffLine{op: '+', text: b[j]})
+				j++
+			}
+		}
+	}
+	for _, text := range before[len(before)-suffix:] {
+		lines = append(lines, diffLine{op: ' ', text: text})
+	}
+	return lines
+}
+
+// compactDiff renders the line diff of before and after in the unified format, keeping
+// only diffContextLines unchanged lines around each change. Hunk headers give the line
+// numbers in before and after.
+func compactDiff(before, after string) string {
+	lines := diffLines(strings.Split(before, "\n"), strings.Split(after, "\n"))
+	// keep marks the lines within diffContextLines of a change.
+	keep := make([]bool, len(lines))
+	for i, line := range lines {
+		if line.op == ' ' {
+			continue
+		}
+		for j := max(0, i-diffContextLines); j <= min(len(lines)-1, i+diffContextLines); j++ {
+			keep[j] = true
+		}
+	}
+
+	var diff strings.Builder
+	beforeLine, afterLine := 1, 1
+	for i, line := range lines {
+		if keep[i] {
+			if i == 0 || !keep[i-1] {
+				fmt.Fprintf(&diff, "@@ -%d +%d @@\n", beforeLine, afterLine)
+			}
+			diff.WriteByte(line.op)
+			diff.WriteString(line.text)
+			diff.WriteByte('\n')
+		}
+		if line.op != '+' {
+			beforeLine++
+		}
+		if line.op != '-' {
+			afterLine++
+		}
+	}
+	return diff.String()
+}
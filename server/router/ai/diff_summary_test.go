@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactDiff(t *testing.T) {
+	before := "# Groceries\n- milk\n- eggs\n- bread\n- butter\n- jam\n- tea\n- rice"
+	after := "# Groceries\n- milk\n- oat milk\n- bread\n- butter\n- jam\n- tea\n- rice\n- apples"
+	require.Equal(t,
+		"@@ -1 +1 @@\n"+
+			" # Groceries\n"+
+			" - milk\n"+
+			"-- eggs\n"+
+			"+- oat milk\n"+
+			" - bread\n"+
+			" - butter\n"+
+			"@@ -7 +7 @@\n"+
+			" - tea\n"+
+			" - rice\n"+
+			"+- apples\n",
+		compactDiff(before, after))
+
+	require.Equal(t, []diffLine{{'-', "a"}, {' ', "b"}, {'+', "c"}}, diffLines([]string{"a", "b"}, []string{"b", "c"}))
+}
+
+func TestDiffSummary(t *testing.T) {
+	var lines []string
+	for i := range 200 {
+		lines = append(lines, fmt.Sprintf("Line %d of a long memo.", i))
+	}
+	before := strings.Join(lines, "\n")
+	lines[100] = "The meeting moved to Friday."
+	after := strings.Join(lines, "\n")
+
+	var forwarded *ChatCompletionRequest
+	newChatUpstream(t, "The meeting was moved to Friday.", func(req *ChatCompletionRequest) {
+		forwarded = req
+	})
+	s := NewAIService(nil, "", "test-key")
+
+	body, err := json.Marshal(&DiffSummaryRequest{Before: before, After: after})
+	require.NoError(t, err)
+	c, rec := newTestContext(string(body))
+	require.NoError(t, s.DiffSummary(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	response := new(DiffSummaryResponse)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), response))
+	require.Equal(t, "The meeting was moved to Friday.", response.Summary)
+
+	// Only the changed line and its context reach the model.
+	diff := forwarded.Messages[1].Content
+	require.Contains(t, diff, "-Line 100 of a long memo.\n+The meeting moved to Friday.\n")
+	require.NotContains(t, diff, "Line 10 of")
+	require.Less(t, len(diff), len(before)/10)
+}
+
+func TestDiffSummaryNoChanges(t *testing.T) {
+	newChatUpstream(t, "unused", func(*ChatCompletionRequest) {
+		t.Error("the model was called for identical texts")
+	})
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"before":"Same memo.","after":"Same memo."}`)
+	require.NoError(t, s.DiffSummary(c))
+	require.JSONEq(t, `{"summary":"No changes"}`, rec.Body.String())
+}
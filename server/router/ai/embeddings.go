@@ -0,0 +1,140 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxEmbeddingInputs caps the number of texts embedded in a single request.
+const maxEmbeddingInputs = 100
+
+type EmbeddingsRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// Embeddings computes vector embeddings for the given texts. The response follows the
+// OpenAI embeddings format ({"data": [{"index": 0, "embedding": [...]}]}) for all providers.
+func (s *AIService) Embeddings(c echo.Context) error {
+	ctx := c.Request().Context()
+	apiKey, err := s.requireAPIKey(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	request := new(EmbeddingsRequest)
+	if err := c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	}
+	if err := s.validateEmbeddingsRequest(request); err != nil {
+		return err
+	}
+	if request.Model != "" {
+		if err := s.checkModelAllowed(request.Model); err != nil {
+			return err
+		}
+	}
+
+	body, err := s.fetchEmbeddings(ctx, apiKey, request)
+	if err != nil {
+		return err
+	}
+	return c.JSONBlob(http.StatusOK, body)
+}
+
+// fetchEmbeddings requests embeddings from the provider and returns them in the OpenAI format.
+// An empty model is replaced by the configured embedding model.
+func (s *AIService) fetchEmbeddings(ctx context.Context, apiKey string, request *EmbeddingsRequest) ([]byte, error) {
+	provider, ok := s.provider.(embedder)
+	if !ok {
+		return nil, echo.NewHTTPError(http.StatusNotImplemented, fmt.Sprintf("AI provider %q does not support embeddings", s.provider.Name()))
+	}
+	if request.Model == "" {
+		request.Model = s.resolveEmbeddingModel()
+	}
+
+	resp, err := s.send(ctx, func(ctx context.Context) (*http.Request, error) {
+		return provider.NewEmbeddingsRequest(ctx, apiKey, request)
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := s.readBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, s.upstreamError(ctx, resp.StatusCode, body)
+	}
+	if body, err = provider.ParseEmbeddingsResponse(body); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadGateway, "Unexpected response from AI provider").SetInternal(err)
+	}
+	recordUsage(ctx, request.Model, parseUsage(body))
+	return body, nil
+}
+
+// embed returns one embedding vector per input, in input order.
+func (s *AIService) embed(ctx context.Context, apiKey string, inputs []string) ([][]float64, error) {
+	body, err := s.fetchEmbeddings(ctx, apiKey, &EmbeddingsRequest{Input: inputs})
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadGateway, "Unexpected response from AI provider").SetInternal(err)
+	}
+	vectors := make([][]float64, len(inputs))
+	for _, item := range response.Data {
+		if item.Index < 0 || item.Index >= len(vectors) {
+			return nil, echo.NewHTTPError(http.StatusBadGateway, "Unexpected response from AI provider")
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	for _, vector := range vectors {
+		if len(vector) == 0 {
+			return nil, echo.NewHTTPError(http.StatusBadGateway, "AI provider returned too few embeddings")
+		}
+	}
+	return vectors, nil
+}
+
+// resolveEmbeddingModel returns the configured embedding model or the provider's default.
+func (s *AIService) resolveEmbeddingModel() string {
+	if s.embeddingModel != "" {
+		return s.embeddingModel
+	}
+	if provider, ok := s.provider.(embedder); ok {
+		return provider.DefaultEmbeddingModel()
+	}
+	return ""
+}
+
+// validateEmbeddingsRequest checks that the input is non-empty and within the batch and size limits.
+func (s *AIService) validateEmbeddingsRequest(req *EmbeddingsRequest) error {
+	if len(req.Input) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "input must not be empty")
+	}
+	if len(req.Input) > maxEmbeddingInputs {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("too many inputs: %d exceeds the limit of %d", len(req.Input), maxEmbeddingInputs))
+	}
+	for i, input := range req.Input {
+		if input == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("input[%d] must not be empty", i))
+		}
+		if len(input) > s.maxInputBytes {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("input[%d] too large: %d bytes exceeds the limit of %d", i, len(input), s.maxInputBytes))
+		}
+	}
+	return nil
+}
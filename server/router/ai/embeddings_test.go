@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddings(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/embeddings", r.URL.Path)
+		req := new(EmbeddingsRequest)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		require.Equal(t, "custom-embedder", req.Model)
+		require.Equal(t, []string{"first", "second"}, req.Input)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.1]},{"object":"embedding","index":1,"embedding":[0.2]}],"usage":{"prompt_tokens":2,"total_tokens":2}}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL+"/chat/completions")
+	t.Setenv("MEMOS_AI_EMBEDDING_MODEL", "custom-embedder")
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"input":["first","second"]}`)
+	require.NoError(t, s.Embeddings(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"embedding":[0.2]`)
+}
+
+func TestEmbeddingsValidation(t *testing.T) {
+	s := NewAIService(nil, "", "test-key")
+	tooMany := `{"input":["x"` + strings.Repeat(`,"x"`, maxEmbeddingInputs) + `]}`
+	tests := []struct {
+		body string
+		code int
+	}{
+		{body: `{"input":[]}`, code: http.StatusBadRequest},
+		{body: `{"input":["ok",""]}`, code: http.StatusBadRequest},
+		{body: tooMany, code: http.StatusRequestEntityTooLarge},
+	}
+	for _, test := range tests {
+		c, _ := newTestContext(test.body)
+		err := s.Embeddings(c)
+		httpErr, ok := err.(*echo.HTTPError)
+		require.True(t, ok)
+		require.Equal(t, test.code, httpErr.Code)
+	}
+}
+
+func TestOllamaParseEmbeddingsResponse(t *testing.T) {
+	body, err := (&ollamaProvider{}).ParseEmbeddingsResponse([]byte(`{"model":"nomic-embed-text","embeddings":[[0.1,0.2]],"prompt_eval_count":3}`))
+	require.NoError(t, err)
+	usage := parseUsage(body)
+	require.NotNil(t, usage)
+	require.Equal(t, 3, usage.PromptTokens)
+	require.Contains(t, string(body), `"data":[{"embedding":[0.1,0.2],"index":0,"object":"embedding"}]`)
+}
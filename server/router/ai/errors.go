@@ -0,0 +1,307 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+// Stable error codes returned to clients regardless of the upstream provider.
+const (
+	errorCodeInvalidAPIKey  = "invalid_api_key"
+	errorCodeRateLimited    = "rate_limited"
+	errorCodeContextTooLong = "context_too_long"
+	errorCodeUpstream       = "upstream_error"
+	errorCodeContentFlagged = "content_flagged"
+	errorCodeAIUnavailable  = "ai_unavailable"
+	errorCodeInvalidJSON    = "invalid_json"
+	errorCodeModelNotFound  = "model_not_found"
+	errorCodeNotConfigured  = "not_configured"
+	errorCodeQuotaExceeded  = "quota_exceeded"
+	errorCodeInternal       = "internal_error"
+	// errorCodeInvalidRequest is reported in WebSocket frames and batch results for rejected
+	// requests that carry no stable upstream error code.
+	errorCodeInvalidRequest = "invalid_request"
+)
+
+// Error categories tell clients how to react to an error: config and internal errors need
+// an operator, auth and input errors need the request to change, and rate_limit, timeout
+// and upstream errors may succeed when retried later.
+const (
+	errorCategoryConfig    = "config"
+	errorCategoryAuth      = "auth"
+	errorCategoryRateLimit = "rate_limit"
+	errorCategoryTimeout   = "timeout"
+	errorCategoryInput     = "input"
+	errorCategoryUpstream  = "upstream"
+	errorCategoryInternal  = "internal"
+)
+
+// ErrorResponse is the normalized error body returned when the AI provider fails.
+type ErrorResponse struct {
+	Error *ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Category groups codes by how a client should react; see classifyError.
+	Category string `json:"category"`
+	// Upstream is the raw provider error body, or the model's unparseable reply for
+	// invalid_json, only included when MEMOS_AI_DEBUG is enabled.
+	Upstream string `json:"upstream,omitempty"`
+	// Categories lists the moderation categories that blocked a request.
+	Categories []string `json:"categories,omitempty"`
+	// TokenOverage is roughly how many tokens a context_too_long request went over the limit.
+	TokenOverage int `json:"token_overage,omitempty"`
+	// Available lists the models known to exist when the requested one was not found.
+	Available []string `json:"available,omitempty"`
+}
+
+// errorMessages are the client-facing messages for each stable error code.
+var errorMessages = map[string]string{
+	errorCodeInvalidAPIKey:  "The AI provider rejected the configured API key.",
+	errorCodeRateLimited:    "The AI provider is rate limiting requests, please retry later.",
+	errorCodeContextTooLong: "The conversation is too long for the selected model.",
+	errorCodeUpstream:       "The AI provider returned an error.",
+	errorCodeContentFlagged: "The message was blocked by content moderation.",
+	errorCodeAIUnavailable:  "AI features are unavailable until the server is reconfigured.",
+	errorCodeInvalidJSON:    "The AI provider did not return valid JSON.",
+	errorCodeModelNotFound:  "The requested model does not exist on the AI provider.",
+	errorCodeNotConfigured:  "The AI provider is not configured on the server.",
+	errorCodeQuotaExceeded:  "The daily AI token quota is used up.",
+	errorCodeInternal:       "The AI service failed to handle the request.",
+}
+
+// upstreamError converts a failed upstream response into a normalized error that keeps
+// the upstream status code. The raw body is logged but only returned in debug mode.
+func (s *AIService) upstreamError(ctx context.Context, status int, body []byte) *echo.HTTPError {
+	providerCode := parseProviderErrorCode(body)
+	s.log(ctx).Error("AI provider returned an error", "status", status, "provider_code", providerCode, "body", truncate(string(body), maxLoggedBodyBytes))
+
+	code := normalizeErrorCode(status, providerCode)
+	if code == errorCodeModelNotFound || status == http.StatusNotFound && mentionsModel(parseProviderErrorMessage(body)) {
+		return s.modelNotFoundError(body)
+	}
+	return s.errorResponse(status, code, body)
+}
+
+// modelNotFoundError reports a request for a model the provider does not have, always
+// with a 404, listing the models from the cached /ai/models data or the allowlist so the
+// frontend can offer valid choices.
+func (s *AIService) modelNotFoundError(body []byte) *echo.HTTPError {
+	httpErr := s.errorResponse(http.StatusNotFound, errorCodeModelNotFound, body)
+	s.modelsCache.mutex.Lock()
+	available := slices.Clone(s.modelsCache.models)
+	s.modelsCache.mutex.Unlock()
+	if len(available) == 0 {
+		available = slices.Clone(s.allowedModels)
+	}
+	httpErr.Message.(*ErrorResponse).Error.Available = available
+	return httpErr
+}
+
+// mentionsModel reports whether a provider error message is about a model, which tells a
+// missing model apart from a wrong endpoint when both answer 404.
+func mentionsModel(message string) bool {
+	message = strings.ToLower(message)
+	return strings.Contains(message, "model") || strings.Contains(message, "deployment")
+}
+
+// errorResponse builds a normalized error with the given status and stable code for a
+// failed upstream response body.
+func (s *AIService) errorResponse(status int, code string, body []byte) *echo.HTTPError {
+	detail := &ErrorDetail{
+		Code:     code,
+		Message:  errorMessages[code],
+		Category: classifyError(status, code),
+	}
+	if code == errorCodeContextTooLong {
+		detail.TokenOverage = parseTokenOverage(body)
+	}
+	if s.debug {
+		detail.Upstream = string(body)
+	}
+	return echo.NewHTTPError(status, &ErrorResponse{Error: detail})
+}
+
+// parseProviderErrorCode extracts the provider's error code from the common error shapes:
+// OpenAI ({"error": {"code": ..., "type": ...}}), Anthropic ({"error": {"type": ...}})
+// and plain string errors such as Ollama's ({"error": "..."}).
+func parseProviderErrorCode(body []byte) string {
+	var envelope struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil || len(envelope.Error) == 0 {
+		return ""
+	}
+	var detail struct {
+		Code any    `json:"code"`
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(envelope.Error, &detail); err != nil {
+		return ""
+	}
+	if code, ok := detail.Code.(string); ok && code != "" {
+		return code
+	}
+	return detail.Type
+}
+
+// parseProviderErrorMessage extracts the provider's error message from the shapes handled
+// by parseProviderErrorCode.
+func parseProviderErrorMessage(body []byte) string {
+	var envelope struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil || len(envelope.Error) == 0 {
+		return ""
+	}
+	var message string
+	if err := json.Unmarshal(envelope.Error, &message); err == nil {
+		return message
+	}
+	var detail struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(envelope.Error, &detail); err != nil {
+		return ""
+	}
+	return detail.Message
+}
+
+// normalizeErrorCode maps a provider error code and HTTP status to a stable error code.
+func normalizeErrorCode(status int, providerCode string) string {
+	switch providerCode {
+	case "invalid_api_key", "authentication_error", "unauthorized":
+		return errorCodeInvalidAPIKey
+	case "rate_limit_exceeded", "rate_limit_error", "RateLimitReached":
+		return errorCodeRateLimited
+	case "context_length_exceeded":
+		return errorCodeContextTooLong
+	case "model_not_found", "unknown_model", "DeploymentNotFound":
+		return errorCodeModelNotFound
+	}
+	switch status {
+	case http.StatusUnauthorized:
+		return errorCodeInvalidAPIKey
+	case http.StatusTooManyRequests:
+		return errorCodeRateLimited
+	default:
+		return errorCodeUpstream
+	}
+}
+
+// errorDetail converts err into the status and error detail reported where no HTTP error
+// response can carry it, such as WebSocket frames and batch results. Errors that are not
+// HTTP errors are logged and reported as a generic internal error.
+func (s *AIService) errorDetail(ctx context.Context, err error) (int, *ErrorDetail) {
+	status, detail := s.rawErrorDetail(ctx, err)
+	if detail.Category == "" {
+		detail.Category = classifyError(status, detail.Code)
+	}
+	return status, detail
+}
+
+// rawErrorDetail is errorDetail before the category is filled in.
+func (s *AIService) rawErrorDetail(ctx context.Context, err error) (int, *ErrorDetail) {
+	var httpErr *echo.HTTPError
+	if !errors.As(err, &httpErr) {
+		s.log(ctx).Error("AI request failed", "error", err)
+		return http.StatusInternalServerError, &ErrorDetail{Code: errorCodeInternal, Message: errorMessages[errorCodeInternal]}
+	}
+	switch message := httpErr.Message.(type) {
+	case *ErrorResponse:
+		if message.Error != nil {
+			return httpErr.Code, message.Error
+		}
+	case *ValidationErrorResponse:
+		problems := make([]string, 0, len(message.Errors))
+		for field, problem := range message.Errors {
+			problems = append(problems, field+" "+problem)
+		}
+		slices.Sort(problems)
+		return httpErr.Code, &ErrorDetail{Code: errorCodeInvalidRequest, Message: strings.Join(problems, "; ")}
+	}
+	return httpErr.Code, &ErrorDetail{Code: statusErrorCode(httpErr.Code), Message: fmt.Sprint(httpErr.Message)}
+}
+
+// statusErrorCode picks the error code for an error without a stable upstream code.
+func statusErrorCode(status int) string {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return errorCodeRateLimited
+	case status == http.StatusInternalServerError:
+		return errorCodeInternal
+	case status > http.StatusInternalServerError:
+		return errorCodeUpstream
+	default:
+		return errorCodeInvalidRequest
+	}
+}
+
+// classifyError maps a status and stable error code to the category reported to clients.
+// Codes that name a condition decide on their own; generic codes fall back to the status,
+// which for upstream_error is the provider's.
+func classifyError(status int, code string) string {
+	switch code {
+	case errorCodeInvalidAPIKey:
+		return errorCategoryAuth
+	case errorCodeRateLimited, errorCodeQuotaExceeded:
+		return errorCategoryRateLimit
+	case errorCodeContextTooLong, errorCodeContentFlagged, errorCodeModelNotFound:
+		return errorCategoryInput
+	case errorCodeAIUnavailable, errorCodeNotConfigured:
+		return errorCategoryConfig
+	case errorCodeInvalidJSON:
+		return errorCategoryUpstream
+	case errorCodeInternal:
+		return errorCategoryInternal
+	}
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return errorCategoryAuth
+	case status == http.StatusTooManyRequests:
+		return errorCategoryRateLimit
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		return errorCategoryTimeout
+	case status == http.StatusNotImplemented:
+		return errorCategoryConfig
+	case status < http.StatusInternalServerError:
+		return errorCategoryInput
+	default:
+		return errorCategoryUpstream
+	}
+}
+
+// errorMiddleware normalizes every error returned by an AI endpoint to an ErrorResponse
+// carrying a category, keeping its status. Field validation errors keep their own shape,
+// which carries the category too.
+func (s *AIService) errorMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		err := next(c)
+		if err == nil || c.Response().Committed {
+			return err
+		}
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) {
+			if _, ok := httpErr.Message.(*ValidationErrorResponse); ok {
+				return err
+			}
+		}
+		status, detail := s.errorDetail(c.Request().Context(), err)
+		// Echo renders an HTTP error wrapped as the internal error instead of the outer one,
+		// as c.Bind returns them, so only the underlying cause is kept.
+		internal := err
+		for errors.As(internal, &httpErr) {
+			internal = httpErr.Internal
+		}
+		return echo.NewHTTPError(status, &ErrorResponse{Error: detail}).SetInternal(internal)
+	}
+}
@@ -0,0 +1,172 @@
+package ai
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeErrorCode(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   string
+	}{
+		{status: http.StatusUnauthorized, body: `{"error":{"message":"Incorrect API key","type":"invalid_request_error","code":"invalid_api_key"}}`, want: errorCodeInvalidAPIKey},
+		{status: http.StatusUnauthorized, body: `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`, want: errorCodeInvalidAPIKey},
+		{status: http.StatusTooManyRequests, body: `{"error":{"code":"rate_limit_exceeded"}}`, want: errorCodeRateLimited},
+		{status: http.StatusBadRequest, body: `{"error":{"code":"context_length_exceeded","message":"too long"}}`, want: errorCodeContextTooLong},
+		{status: http.StatusTooManyRequests, body: `not json`, want: errorCodeRateLimited},
+		{status: http.StatusInternalServerError, body: `{"error":"model crashed"}`, want: errorCodeUpstream},
+		{status: http.StatusNotFound, body: `{"error":{"message":"The model 'gpt-5' does not exist","code":"model_not_found"}}`, want: errorCodeModelNotFound},
+		{status: http.StatusBadRequest, body: `{"error":{"code":"unknown_model","message":"Unknown model: gpt-5"}}`, want: errorCodeModelNotFound},
+	}
+	for _, test := range tests {
+		require.Equal(t, test.want, normalizeErrorCode(test.status, parseProviderErrorCode([]byte(test.body))), test.body)
+	}
+}
+
+func TestChatCompletionNormalizesUpstreamError(t *testing.T) {
+	const raw = `{"error":{"message":"internal detail","code":"invalid_api_key"}}`
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(raw))
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+
+	for _, debug := range []bool{false, true} {
+		if debug {
+			t.Setenv("MEMOS_AI_DEBUG", "true")
+		}
+		s := NewAIService(nil, "", "test-key")
+		c, _ := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+		err := s.ChatCompletion(c)
+		httpErr, ok := err.(*echo.HTTPError)
+		require.True(t, ok)
+		// The server's own key was rejected, which users cannot fix themselves.
+		require.Equal(t, http.StatusServiceUnavailable, httpErr.Code)
+
+		response, ok := httpErr.Message.(*ErrorResponse)
+		require.True(t, ok)
+		require.Equal(t, errorCodeAIUnavailable, response.Error.Code)
+		require.NotContains(t, response.Error.Message, "internal detail")
+		if debug {
+			require.Equal(t, raw, response.Error.Upstream)
+		} else {
+			require.Empty(t, response.Error.Upstream)
+		}
+	}
+}
+
+func TestUpstreamErrorDetectsMissingModel(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   bool
+	}{
+		{status: http.StatusBadRequest, body: `{"error":{"code":"unknown_model","message":"Unknown model: gpt-5"}}`, want: true},
+		{status: http.StatusNotFound, body: `{"type":"error","error":{"type":"not_found_error","message":"model: claude-9"}}`, want: true},
+		{status: http.StatusNotFound, body: `{"error":"model \"llama9\" not found, try pulling it first"}`, want: true},
+		{status: http.StatusNotFound, body: `{"error":{"code":"DeploymentNotFound","message":"The API deployment for this resource does not exist."}}`, want: true},
+		{status: http.StatusNotFound, body: `404 page not found`, want: false},
+	}
+	s := &AIService{logger: slog.Default(), allowedModels: []string{"openai/gpt-4o"}}
+	for _, test := range tests {
+		httpErr := s.upstreamError(context.Background(), test.status, []byte(test.body))
+		response := httpErr.Message.(*ErrorResponse)
+		if !test.want {
+			require.NotEqual(t, errorCodeModelNotFound, response.Error.Code, test.body)
+			continue
+		}
+		require.Equal(t, http.StatusNotFound, httpErr.Code, test.body)
+		require.Equal(t, errorCodeModelNotFound, response.Error.Code, test.body)
+		require.Equal(t, []string{"openai/gpt-4o"}, response.Error.Available, test.body)
+	}
+}
+
+func TestChatCompletionModelNotFoundListsCachedModels(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"message":"The model 'gpt-5' does not exist","code":"model_not_found"}}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+
+	s := NewAIService(nil, "", "test-key")
+	s.modelsCache.models = []string{"gpt-4o", "gpt-4o-mini"}
+	c, _ := newTestContext(`{"model":"gpt-5","messages":[{"role":"user","content":"hi"}]}`)
+	httpErr, ok := s.ChatCompletion(c).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusNotFound, httpErr.Code)
+	response := httpErr.Message.(*ErrorResponse)
+	require.Equal(t, errorCodeModelNotFound, response.Error.Code)
+	require.Equal(t, []string{"gpt-4o", "gpt-4o-mini"}, response.Error.Available)
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		status int
+		code   string
+		want   string
+	}{
+		{status: http.StatusUnauthorized, code: errorCodeInvalidAPIKey, want: errorCategoryAuth},
+		{status: http.StatusForbidden, code: errorCodeQuotaExceeded, want: errorCategoryRateLimit},
+		{status: http.StatusTooManyRequests, code: errorCodeRateLimited, want: errorCategoryRateLimit},
+		{status: http.StatusBadRequest, code: errorCodeContextTooLong, want: errorCategoryInput},
+		{status: http.StatusServiceUnavailable, code: errorCodeNotConfigured, want: errorCategoryConfig},
+		{status: http.StatusServiceUnavailable, code: errorCodeAIUnavailable, want: errorCategoryConfig},
+		{status: http.StatusBadRequest, code: errorCodeInvalidRequest, want: errorCategoryInput},
+		{status: http.StatusForbidden, code: errorCodeInvalidRequest, want: errorCategoryAuth},
+		{status: http.StatusGatewayTimeout, code: errorCodeUpstream, want: errorCategoryTimeout},
+		{status: http.StatusInternalServerError, code: errorCodeUpstream, want: errorCategoryUpstream},
+		{status: http.StatusBadGateway, code: errorCodeInvalidJSON, want: errorCategoryUpstream},
+		{status: http.StatusInternalServerError, code: errorCodeInternal, want: errorCategoryInternal},
+		{status: http.StatusNotImplemented, code: errorCodeUpstream, want: errorCategoryConfig},
+	}
+	for _, test := range tests {
+		require.Equal(t, test.want, classifyError(test.status, test.code), "%d %s", test.status, test.code)
+	}
+}
+
+func TestErrorMiddlewareAddsCategory(t *testing.T) {
+	send := func(apiKey, path, body string) *httptest.ResponseRecorder {
+		e := echo.New()
+		NewAIService(nil, "", apiKey).RegisterRoutes(e.Group("/api/v1"))
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send("", "/api/v1/ai/chat_completion", `{"messages":[{"role":"user","content":"hi"}]}`)
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.JSONEq(t, `{"error":{"code":"not_configured","message":"The AI provider is not configured on the server.","category":"config"}}`, rec.Body.String())
+
+	rec = send("test-key", "/api/v1/ai/estimate", `{`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), `"category":"input"`)
+
+	rec = send("test-key", "/api/v1/ai/chat_completion", `{"messages":[{"role":1}]}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), `"errors":`)
+	require.Contains(t, rec.Body.String(), `"category":"input"`)
+}
+
+func TestErrorDetailCategory(t *testing.T) {
+	s := NewAIService(nil, "", "")
+	status, detail := s.errorDetail(context.Background(), errors.New("boom"))
+	require.Equal(t, http.StatusInternalServerError, status)
+	require.Equal(t, &ErrorDetail{Code: errorCodeInternal, Message: errorMessages[errorCodeInternal], Category: errorCategoryInternal}, detail)
+
+	_, detail = s.errorDetail(context.Background(), echo.NewHTTPError(http.StatusGatewayTimeout, "AI provider did not respond in time"))
+	require.Equal(t, errorCategoryTimeout, detail.Category)
+}
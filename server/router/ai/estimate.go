@@ -0,0 +1,131 @@
+package ai
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/pkoukk/tiktoken-go"
+	tiktokenloader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+const (
+	estimateMethodTokenizer = "tokenizer"
+	estimateMethodHeuristic = "heuristic"
+
+	// Chat formatting overhead in OpenAI's token counting: every message is wrapped in a
+	// few tokens, and the reply is primed with a few more.
+	tokensPerMessage = 3
+	tokensPerReply   = 3
+)
+
+// extraModelPrefixEncodings covers model families newer than the tokenizer's own table.
+var extraModelPrefixEncodings = map[string]string{
+	"gpt-5": tiktoken.MODEL_O200K_BASE,
+	"o1":    tiktoken.MODEL_O200K_BASE,
+	"o3":    tiktoken.MODEL_O200K_BASE,
+	"o4":    tiktoken.MODEL_O200K_BASE,
+}
+
+// tokenizers caches one tokenizer per encoding, since building one parses its whole
+// vocabulary. The vocabularies are embedded in the binary, so nothing is downloaded.
+var tokenizers = struct {
+	once      sync.Once
+	mutex     sync.Mutex
+	encodings map[string]*tiktoken.Tiktoken
+}{encodings: map[string]*tiktoken.Tiktoken{}}
+
+type EstimateRequest struct {
+	Model    string                  `json:"model"`
+	Messages []ChatCompletionMessage `json:"messages"`
+}
+
+type EstimateResponse struct {
+	EstimatedTokens int    `json:"estimated_tokens"`
+	Model           string `json:"model"`
+	// Method is "tokenizer" when the model's tokenizer was used, or "heuristic" when the
+	// estimate is based on the number of characters.
+	Method string `json:"method"`
+	// Warning explains why the heuristic was used.
+	Warning string `json:"warning,omitempty"`
+}
+
+// Estimate reports roughly how many prompt tokens a chat completion request would use,
+// without sending it. Models without a known tokenizer get a character-based estimate.
+func (s *AIService) Estimate(c echo.Context) error {
+	request := new(EstimateRequest)
+	if err := c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	}
+	req := &ChatCompletionRequest{Model: request.Model, Messages: request.Messages}
+	if err := s.validateChatCompletionRequest(req); err != nil {
+		return err
+	}
+	model, err := s.resolveModel(req.Model)
+	if err != nil {
+		return err
+	}
+	messages := s.withSystemPrompt(req.Messages)
+
+	response := &EstimateResponse{Model: model, Method: estimateMethodTokenizer}
+	tokenizer, err := tokenizerForModel(model)
+	if err != nil {
+		s.log(c.Request().Context()).Debug("no tokenizer for model, estimating from characters", "model", model, "error", err)
+		response.Method = estimateMethodHeuristic
+		response.Warning = fmt.Sprintf("No tokenizer is known for model %q; the estimate is based on characters.", model)
+	}
+	response.EstimatedTokens = estimateTokens(tokenizer, messages)
+	return c.JSON(http.StatusOK, response)
+}
+
+// estimateTokens counts the prompt tokens of messages with tokenizer, or from the number
+// of characters when tokenizer is nil.
+func estimateTokens(tokenizer *tiktoken.Tiktoken, messages []ChatCompletionMessage) int {
+	count := tokenCounter{tokenizer: tokenizer}.count
+	total := tokensPerReply
+	for _, message := range messages {
+		total += tokensPerMessage + count(message.Role) + count(messageText(message))
+	}
+	return total
+}
+
+// tokenizerForModel returns the cached tokenizer of the model's encoding. Provider
+// prefixes such as "openai/" in "openai/gpt-4o" are ignored.
+func tokenizerForModel(model string) (*tiktoken.Tiktoken, error) {
+	encoding, err := encodingForModel(model[strings.LastIndex(model, "/")+1:])
+	if err != nil {
+		return nil, err
+	}
+	tokenizers.once.Do(func() {
+		tiktoken.SetBpeLoader(tiktokenloader.NewOfflineLoader())
+	})
+	tokenizers.mutex.Lock()
+	defer tokenizers.mutex.Unlock()
+	if tokenizer, ok := tokenizers.encodings[encoding]; ok {
+		return tokenizer, nil
+	}
+	tokenizer, err := tiktoken.GetEncoding(encoding)
+	if err != nil {
+		return nil, err
+	}
+	tokenizers.encodings[encoding] = tokenizer
+	return tokenizer, nil
+}
+
+// encodingForModel returns the name of the encoding used by an OpenAI model.
+func encodingForModel(model string) (string, error) {
+	if encoding, ok := tiktoken.MODEL_TO_ENCODING[model]; ok {
+		return encoding, nil
+	}
+	for _, prefixes := range []map[string]string{tiktoken.MODEL_PREFIX_TO_ENCODING, extraModelPrefixEncodings} {
+		for prefix, encoding := range prefixes {
+			if strings.HasPrefix(model, prefix) {
+				return encoding, nil
+			}
+		}
+	}
+	return "", errors.Errorf("no known encoding for model %q", model)
+}
@@ -0,0 +1,65 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimate(t *testing.T) {
+	s := NewAIService(nil, "", "")
+
+	c, rec := newTestContext(`{"model":"gpt-4o-mini","messages":[{"role":"user","content":"Hello, world!"}]}`)
+	require.NoError(t, s.Estimate(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	response := new(EstimateResponse)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), response))
+	// "Hello, world!" is 4 tokens and "user" 1, plus the chat formatting overhead.
+	require.Equal(t, &EstimateResponse{EstimatedTokens: 4 + 1 + tokensPerMessage + tokensPerReply, Model: "gpt-4o-mini", Method: estimateMethodTokenizer}, response)
+}
+
+func TestEstimateFallsBackToHeuristic(t *testing.T) {
+	s := NewAIService(nil, "", "")
+
+	c, rec := newTestContext(`{"model":"claude-sonnet-4","messages":[{"role":"user","content":"Hello, world!"}]}`)
+	require.NoError(t, s.Estimate(c))
+	response := new(EstimateResponse)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), response))
+	require.Equal(t, estimateMethodHeuristic, response.Method)
+	require.Contains(t, response.Warning, "claude-sonnet-4")
+	require.Equal(t, 4+1+tokensPerMessage+tokensPerReply, response.EstimatedTokens)
+}
+
+func TestEstimateRejectsEmptyMessages(t *testing.T) {
+	s := NewAIService(nil, "", "")
+
+	c, _ := newTestContext(`{"model":"gpt-4o","messages":[]}`)
+	httpErr, ok := s.Estimate(c).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusBadRequest, httpErr.Code)
+}
+
+func TestEncodingForModel(t *testing.T) {
+	for model, encoding := range map[string]string{
+		"gpt-4o":            "o200k_base",
+		"gpt-4o-2024-05-13": "o200k_base",
+		"gpt-4-turbo":       "cl100k_base",
+		"gpt-3.5-turbo":     "cl100k_base",
+		"o3-mini":           "o200k_base",
+	} {
+		got, err := encodingForModel(model)
+		require.NoError(t, err, model)
+		require.Equal(t, encoding, got, model)
+	}
+	_, err := encodingForModel("llama3")
+	require.Error(t, err)
+
+	tokenizer, err := tokenizerForModel("openai/gpt-4o")
+	require.NoError(t, err)
+	cached, err := tokenizerForModel("gpt-4o-mini")
+	require.NoError(t, err)
+	require.Same(t, tokenizer, cached)
+}
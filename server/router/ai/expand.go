@@ -0,0 +1,98 @@
+package ai
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// defaultExpandTone is the tone used when tone is not provided.
+const defaultExpandTone = "neutral"
+
+// expandTones are the tones /ai/expand accepts. The tone is interpolated into the prompt,
+// so anything else is rejected.
+var expandTones = []string{"neutral", "professional", "casual", "friendly", "formal"}
+
+// memoTagPattern matches #tags in memo content, such as "#work" or "#project/memos".
+var memoTagPattern = regexp.MustCompile(`(?:^|\s)(#[\p{L}\p{N}_/-]+)`)
+
+type ExpandRequest struct {
+	Content string `json:"content"`
+	// Tone is one of expandTones; empty selects "neutral".
+	Tone string `json:"tone"`
+}
+
+type ExpandResponse struct {
+	Expanded string `json:"expanded"`
+}
+
+// Expand turns a short memo into a fuller draft written in the requested tone.
+func (s *AIService) Expand(c echo.Context) error {
+	ctx := c.Request().Context()
+	apiKey, err := s.requireAPIKey(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	request := new(ExpandRequest)
+	if err := c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	}
+	if err := s.validateContent(request.Content); err != nil {
+		return err
+	}
+	tone := strings.ToLower(strings.TrimSpace(request.Tone))
+	if tone == "" {
+		tone = defaultExpandTone
+	}
+	if !slices.Contains(expandTones, tone) {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("tone must be one of %s", strings.Join(expandTones, ", ")))
+	}
+
+	model, err := s.resolveModel("")
+	if err != nil {
+		return err
+	}
+	prompt, err := s.prompt(promptExpand, expandPromptData{Tone: tone})
+	if err != nil {
+		return err
+	}
+	expanded, err := s.complete(ctx, apiKey, &ChatCompletionRequest{
+		Model: model,
+		Messages: []ChatCompletionMessage{
+			{Role: "system", Content: prompt},
+			{Role: "user", Content: request.Content},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &ExpandResponse{
+		Expanded: keepTags(request.Content, strings.TrimSpace(expanded)),
+	})
+}
+
+// keepTags appends any #tag from original that the model dropped from expanded, so
+// expanding a memo never changes how it is tagged.
+func keepTags(original, expanded string) string {
+	present := map[string]bool{}
+	for _, match := range memoTagPattern.FindAllStringSubmatch(expanded, -1) {
+		present[match[1]] = true
+	}
+	var missing []string
+	for _, match := range memoTagPattern.FindAllStringSubmatch(original, -1) {
+		if tag := match[1]; !present[tag] {
+			present[tag] = true
+			missing = append(missing, tag)
+		}
+	}
+	if len(missing) == 0 {
+		return expanded
+	}
+	return expanded + "\n\n" + strings.Join(missing, " ")
+}
@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// credentialHeaders, in canonical form, carry the API key of a provider. Extra headers may
+// only replace them when MEMOS_AI_EXTRA_HEADERS_OVERRIDE_AUTH is set, since doing so by
+// accident would send every request with the wrong credentials.
+var credentialHeaders = []string{"Authorization", "Api-Key", "X-Api-Key", "X-Goog-Api-Key"}
+
+// reservedHeaders, in canonical form, describe the request itself and are never taken from
+// extra headers.
+var reservedHeaders = []string{
+	"Host", "Content-Type", "Content-Length", "Transfer-Encoding", "Connection",
+	"Upgrade", "Te", "Trailer", "Keep-Alive", "Proxy-Connection", "X-Request-Id",
+}
+
+// loadExtraHeaders reads MEMOS_AI_EXTRA_HEADERS, a JSON object mapping header names to
+// values such as {"OpenAI-Organization": "org-123"}. Invalid JSON is logged and ignored.
+func loadExtraHeaders(logger *slog.Logger) map[string]string {
+	value := strings.TrimSpace(os.Getenv("MEMOS_AI_EXTRA_HEADERS"))
+	if value == "" {
+		return nil
+	}
+	var headers map[string]string
+	if err := json.Unmarshal([]byte(value), &headers); err != nil {
+		logger.Warn("invalid MEMOS_AI_EXTRA_HEADERS, extra headers are disabled", "error", err)
+		return nil
+	}
+	return headers
+}
+
+// newExtraHeaders validates the configured extra headers. Invalid names or values, reserved
+// headers and, unless overrideAuth is set, credential headers are logged and dropped.
+func newExtraHeaders(logger *slog.Logger, configured map[string]string, overrideAuth bool) http.Header {
+	headers := http.Header{}
+	for name, value := range configured {
+		name = strings.TrimSpace(name)
+		canonical := http.CanonicalHeaderKey(name)
+		switch {
+		case !httpguts.ValidHeaderFieldName(name) || !httpguts.ValidHeaderFieldValue(value):
+			logger.Warn("invalid header in MEMOS_AI_EXTRA_HEADERS, ignoring it", "header", name)
+		case slices.Contains(reservedHeaders, canonical):
+			logger.Warn("MEMOS_AI_EXTRA_HEADERS cannot set this header, ignoring it", "header", canonical)
+		case slices.Contains(credentialHeaders, canonical) && !overrideAuth:
+			logger.Warn("MEMOS_AI_EXTRA_HEADERS would replace the provider credentials, ignoring it; set MEMOS_AI_EXTRA_HEADERS_OVERRIDE_AUTH=true to allow this", "header", canonical)
+		default:
+			headers.Set(canonical, value)
+		}
+	}
+	if len(headers) == 0 {
+		return nil
+	}
+	return headers
+}
+
+// setExtraHeaders adds the configured extra headers to an upstream request, replacing any
+// the provider has set. They belong to the configured provider, so requests to a fallback
+// or a per-request endpoint go without them.
+func (s *AIService) setExtraHeaders(ctx context.Context, req *http.Request) {
+	if hasProviderOverride(ctx) {
+		return
+	}
+	for name, values := range s.extraHeaders {
+		req.Header[name] = values
+	}
+}
@@ -0,0 +1,51 @@
+package ai
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewExtraHeaders(t *testing.T) {
+	configured := map[string]string{
+		"openai-organization": "org-123",
+		"X-LiteLLM-Key":       "sk-virtual",
+		"Authorization":       "Bearer other",
+		"Content-Type":        "text/plain",
+		"Bad Header":          "x",
+		"X-Newline":           "a\r\nb",
+	}
+	headers := newExtraHeaders(slog.Default(), configured, false)
+	require.Equal(t, http.Header{
+		"Openai-Organization": {"org-123"},
+		"X-Litellm-Key":       {"sk-virtual"},
+	}, headers)
+
+	headers = newExtraHeaders(slog.Default(), configured, true)
+	require.Equal(t, "Bearer other", headers.Get("Authorization"))
+	require.Empty(t, headers.Get("Content-Type"))
+
+	require.Nil(t, newExtraHeaders(slog.Default(), map[string]string{"Host": "example.com"}, true))
+}
+
+func TestExtraHeadersSentUpstream(t *testing.T) {
+	var received http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	t.Setenv("MEMOS_AI_EXTRA_HEADERS", `{"OpenAI-Project":"proj-1","Authorization":"Bearer other"}`)
+	s := NewAIService(nil, "", "test-key")
+
+	c, _ := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, "proj-1", received.Get("OpenAI-Project"))
+	require.Equal(t, "Bearer test-key", received.Get("Authorization"))
+	require.Equal(t, "application/json", received.Get("Content-Type"))
+}
@@ -0,0 +1,81 @@
+package ai
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+const (
+	// headerXAIProvider reports whether the primary or the fallback provider served a response.
+	headerXAIProvider = "X-AI-Provider"
+
+	providerRolePrimary  = "primary"
+	providerRoleFallback = "fallback"
+)
+
+// newFallbackProvider returns the provider of the configured kind talking to the fallback
+// endpoint, or nil when none is configured or the endpoint is not allowed.
+func newFallbackProvider(logger *slog.Logger, cfg Config) Provider {
+	if cfg.FallbackBaseURL == "" {
+		return nil
+	}
+	providerConfig := cfg.Provider
+	providerConfig.BaseURL = cfg.FallbackBaseURL
+	provider, err := newProvider(providerConfig)
+	if err != nil {
+		logger.Error("invalid AI fallback provider, failover is disabled", "error", err)
+		return nil
+	}
+	if err := checkAllowedHost(cfg.AllowedHosts, providerEndpoint(provider)); err != nil {
+		logger.Error("AI fallback endpoint is not allowed, failover is disabled", "error", err)
+		return nil
+	}
+	if err := validateTarget(providerEndpoint(provider), cfg.AllowPrivate); err != nil && errors.Is(err, errBlockedTarget) {
+		logger.Error("AI fallback endpoint is not allowed, failover is disabled", "error", err)
+		return nil
+	}
+	return provider
+}
+
+// shouldFailOver reports whether a failed request made with apiKey should be sent to the
+// fallback: the provider returned a 5xx or could not be reached after retries, and the
+// request's retry budget is not used up. Requests to a per-request base URL never fail
+// over, nor do requests made with a user's own key, which is only meant for the
+// configured provider.
+func (s *AIService) shouldFailOver(ctx context.Context, apiKey string, err error) bool {
+	if s.fallback == nil || hasProviderOverride(ctx) || ctx.Err() != nil || apiKey != s.serverAPIKey() {
+		return false
+	}
+	httpErr, ok := err.(*echo.HTTPError)
+	return ok && httpErr.Code >= http.StatusInternalServerError && s.canRetry(ctx, retryLayerFailover)
+}
+
+// fallbackAPIKey returns the key for the fallback provider, reusing the server's key when
+// MEMOS_AI_FALLBACK_API_KEY is unset.
+func (s *AIService) fallbackAPIKey() string {
+	if s.fallbackKey != "" {
+		return s.fallbackKey
+	}
+	return s.serverAPIKey()
+}
+
+// completionWithFallback runs a non-streaming completion against the configured provider
+// and retries it once against the fallback provider when that fails and shouldFailOver
+// allows it. It returns which
+// provider served the response.
+func (s *AIService) completionWithFallback(ctx context.Context, apiKey string, req *ChatCompletionRequest) (body []byte, hit bool, servedBy string, err error) {
+	body, hit, err = s.completion(ctx, apiKey, req)
+	if err != nil && s.retryContextOverflow(ctx, err, req) {
+		body, hit, err = s.completion(ctx, apiKey, req)
+	}
+	if err == nil || !s.shouldFailOver(ctx, apiKey, err) {
+		return body, hit, providerRolePrimary, err
+	}
+	s.log(ctx).Warn("AI provider failed, retrying with the fallback provider", "error", err)
+	body, hit, err = s.completion(withProvider(ctx, s.fallback), s.fallbackAPIKey(), req)
+	return body, hit, providerRoleFallback, err
+}
@@ -0,0 +1,133 @@
+package ai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatCompletionFailsOverToFallback(t *testing.T) {
+	var primaryCalls atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		primaryCalls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	var fallbackAuth string
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fallbackAuth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"from fallback"},"finish_reason":"stop"}]}`))
+	}))
+	defer fallback.Close()
+
+	t.Setenv("MEMOS_AI_BASE_URL", primary.URL)
+	t.Setenv("MEMOS_AI_MAX_RETRIES", "0")
+	t.Setenv("MEMOS_AI_FALLBACK_BASE_URL", fallback.URL)
+	t.Setenv("MEMOS_AI_FALLBACK_API_KEY", "fallback-key")
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "from fallback")
+	require.Equal(t, providerRoleFallback, rec.Header().Get(headerXAIProvider))
+	require.Equal(t, "Bearer fallback-key", fallbackAuth)
+	require.Equal(t, int32(1), primaryCalls.Load())
+}
+
+func TestChatCompletionFallbackSkipsClientErrors(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer primary.Close()
+	var fallbackCalls atomic.Int32
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fallbackCalls.Add(1)
+	}))
+	defer fallback.Close()
+
+	t.Setenv("MEMOS_AI_BASE_URL", primary.URL)
+	t.Setenv("MEMOS_AI_FALLBACK_BASE_URL", fallback.URL)
+	s := NewAIService(nil, "", "test-key")
+
+	c, _ := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	httpErr, ok := s.ChatCompletion(c).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusBadRequest, httpErr.Code)
+	require.Zero(t, fallbackCalls.Load())
+}
+
+func TestChatCompletionReportsPrimaryProvider(t *testing.T) {
+	upstream := newChatUpstream(t, "hello", nil)
+	t.Setenv("MEMOS_AI_FALLBACK_BASE_URL", upstream.URL)
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, providerRolePrimary, rec.Header().Get(headerXAIProvider))
+}
+
+func TestChatCompletionStreamFailsOverToFallback(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer primary.Close()
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"streamed\"}}]}\n\ndata: [DONE]\n\n"))
+	}))
+	defer fallback.Close()
+
+	t.Setenv("MEMOS_AI_BASE_URL", primary.URL)
+	t.Setenv("MEMOS_AI_MAX_RETRIES", "0")
+	t.Setenv("MEMOS_AI_FALLBACK_BASE_URL", fallback.URL)
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Contains(t, rec.Body.String(), "streamed")
+	require.Equal(t, providerRoleFallback, rec.Header().Get(headerXAIProvider))
+}
+
+func TestFallbackNeverReceivesUserKey(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	var fallbackAuth []string
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fallbackAuth = append(fallbackAuth, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"from fallback"},"finish_reason":"stop"}]}`))
+	}))
+	defer fallback.Close()
+
+	t.Setenv("MEMOS_AI_BASE_URL", primary.URL)
+	t.Setenv("MEMOS_AI_MAX_RETRIES", "0")
+	t.Setenv("MEMOS_AI_FALLBACK_BASE_URL", fallback.URL)
+	s := NewAIService(nil, "", "server-key")
+	newRequest := func() *ChatCompletionRequest {
+		return &ChatCompletionRequest{Model: "gpt-4o-mini", Messages: newMessages(1, "hi")}
+	}
+
+	// A user's own key is not sent to the fallback host.
+	_, _, servedBy, err := s.completionWithFallback(t.Context(), "user-key", newRequest())
+	require.Error(t, err)
+	require.Equal(t, providerRolePrimary, servedBy)
+	stream := newRequest()
+	stream.Stream = true
+	_, _, _, err = s.startStream(t.Context(), "user-key", stream)
+	require.Error(t, err)
+	require.Empty(t, fallbackAuth)
+
+	// The server's key fails over, reused when no fallback key is configured.
+	_, _, servedBy, err = s.completionWithFallback(t.Context(), "server-key", newRequest())
+	require.NoError(t, err)
+	require.Equal(t, providerRoleFallback, servedBy)
+	require.Equal(t, []string{"Bearer server-key"}, fallbackAuth)
+}
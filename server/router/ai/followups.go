@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// maxFollowups caps how many follow-up questions are returned to the client.
+	maxFollowups = 3
+	// maxFollowupChars is the longest suggestion kept; longer ones are dropped rather than cut
+	// mid-question.
+	maxFollowupChars = 120
+)
+
+type FollowupsRequest struct {
+	// Message is the latest assistant message in the conversation.
+	Message string `json:"message"`
+}
+
+type FollowupsResponse struct {
+	Suggestions []string `json:"suggestions"`
+}
+
+// Followups suggests short questions the user might ask after the assistant's latest
+// message. Suggestions are a convenience, so a reply that does not parse yields an empty
+// list instead of an error.
+func (s *AIService) Followups(c echo.Context) error {
+	ctx := c.Request().Context()
+	apiKey, err := s.requireAPIKey(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	request := new(FollowupsRequest)
+	if err := c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	}
+	if err := s.validateContent(request.Message); err != nil {
+		return err
+	}
+
+	model, err := s.resolveModel("")
+	if err != nil {
+		return err
+	}
+	prompt, err := s.prompt(promptFollowups, followupsPromptData{Count: maxFollowups})
+	if err != nil {
+		return err
+	}
+	content, err := s.complete(ctx, apiKey, &ChatCompletionRequest{
+		Model: model,
+		Messages: []ChatCompletionMessage{
+			{Role: "system", Content: prompt},
+			{Role: "user", Content: request.Message},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	var suggestions []string
+	if err := parseJSONArray(content, &suggestions); err != nil {
+		s.log(ctx).Debug("failed to parse follow-up suggestions from model output", "error", err, "content", truncate(content, maxLoggedBodyBytes))
+	}
+	return c.JSON(http.StatusOK, &FollowupsResponse{
+		Suggestions: normalizeFollowups(suggestions),
+	})
+}
+
+// normalizeFollowups trims suggestions, drops blank, overlong and duplicate ones (ignoring case), and caps
+// the result at maxFollowups. It always returns a non-nil slice.
+func normalizeFollowups(suggestions []string) []string {
+	result := []string{}
+	for _, suggestion := range suggestions {
+		suggestion = strings.Join(strings.Fields(suggestion), " ")
+		duplicate := slices.ContainsFunc(result, func(kept string) bool { return strings.EqualFold(kept, suggestion) })
+		if suggestion == "" || utf8.RuneCountInString(suggestion) > maxFollowupChars || duplicate {
+			continue
+		}
+		result = append(result, suggestion)
+		if len(result) == maxFollowups {
+			break
+		}
+	}
+	return result
+}
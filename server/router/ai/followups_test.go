@@ -0,0 +1,50 @@
+package ai
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeFollowups(t *testing.T) {
+	require.Equal(t, []string{"Why?", "What next?"}, normalizeFollowups([]string{" Why? ", "", "why?", "Why?", strings.Repeat("a", maxFollowupChars+1), "What  next?"}))
+	require.Equal(t, []string{}, normalizeFollowups(nil))
+	require.Len(t, normalizeFollowups([]string{"a", "b", "c", "d"}), maxFollowups)
+}
+
+func TestFollowups(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "json array",
+			content: "```json\n[\"How do I start?\", \"What does it cost?\"]\n```",
+			want:    []string{"How do I start?", "What does it cost?"},
+		},
+		{
+			name:    "unparseable",
+			content: "You could ask how to start.",
+			want:    []string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var received *ChatCompletionRequest
+			newChatUpstream(t, test.content, func(req *ChatCompletionRequest) {
+				received = req
+			})
+			s := NewAIService(nil, "", "test-key")
+
+			c, rec := newTestContext(`{"message":"Here is a plan for the trip."}`)
+			require.NoError(t, s.Followups(c))
+			response := new(FollowupsResponse)
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), response))
+			require.Equal(t, test.want, response.Suggestions)
+			require.Equal(t, "Here is a plan for the trip.", received.Messages[1].Content)
+		})
+	}
+}
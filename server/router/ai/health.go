@@ -0,0 +1,116 @@
+package ai
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultHealthTimeout bounds the provider ping made by /ai/health.
+	defaultHealthTimeout = 5 * time.Second
+	// defaultHealthCacheTTL is how long a health result is reused so polling doesn't hit the provider.
+	defaultHealthCacheTTL = 30 * time.Second
+)
+
+// Health error categories in addition to the stable upstream error codes.
+const (
+	healthErrorNotConfigured = "not_configured"
+	healthErrorTimeout       = "timeout"
+	healthErrorUnreachable   = "unreachable"
+)
+
+// HealthResponse reports whether the AI provider answered an authenticated request.
+type HealthResponse struct {
+	Healthy   bool  `json:"healthy"`
+	LatencyMs int64 `json:"latency_ms"`
+	// Error is the failure category, such as "timeout" or "invalid_api_key", when unhealthy.
+	Error string `json:"error,omitempty"`
+}
+
+// healthCache holds the latest health result until it expires.
+type healthCache struct {
+	mutex     sync.Mutex
+	result    *HealthResponse
+	expiresAt time.Time
+}
+
+// Health pings the provider with the server's API key and reports the outcome. Unhealthy
+// results are returned with 503 so monitoring can alert on the status code alone.
+func (s *AIService) Health(c echo.Context) error {
+	result := s.checkHealth(c.Request().Context())
+	status := http.StatusOK
+	if !result.Healthy {
+		status = http.StatusServiceUnavailable
+	}
+	return c.JSON(status, result)
+}
+
+// checkHealth returns the cached health result, pinging the provider once it has expired.
+func (s *AIService) checkHealth(ctx context.Context) *HealthResponse {
+	s.healthCache.mutex.Lock()
+	defer s.healthCache.mutex.Unlock()
+
+	if s.healthCache.result != nil && time.Now().Before(s.healthCache.expiresAt) {
+		return s.healthCache.result
+	}
+	result := s.pingProvider(ctx)
+	if !result.Healthy {
+		s.log(ctx).Warn("AI provider health check failed", "error", result.Error, "latency_ms", result.LatencyMs)
+	}
+	s.healthCache.result = result
+	s.healthCache.expiresAt = time.Now().Add(s.healthCacheTTL)
+	return result
+}
+
+// pingProvider lists the provider's models, or requests a one-token completion from
+// providers without a models endpoint.
+func (s *AIService) pingProvider(ctx context.Context) *HealthResponse {
+	apiKey := s.resolveAPIKey(ctx, nil)
+	if apiKey == "" && s.provider.RequiresAPIKey() {
+		return &HealthResponse{Error: healthErrorNotConfigured}
+	}
+	if s.targetErr != nil {
+		return &HealthResponse{Error: healthErrorNotConfigured}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.healthTimeout)
+	defer cancel()
+	var req *http.Request
+	var err error
+	if lister, ok := s.provider.(modelLister); ok {
+		req, err = lister.NewModelsRequest(ctx, apiKey)
+	} else {
+		maxTokens := 1
+		req, err = s.provider.NewChatRequest(ctx, apiKey, &ChatCompletionRequest{
+			Model:     s.defaultModel,
+			Messages:  []ChatCompletionMessage{{Role: roleUser, Content: "ping"}},
+			MaxTokens: &maxTokens,
+		})
+	}
+	if err != nil || checkAllowedHost(s.allowedHosts, req.URL.String()) != nil {
+		return &HealthResponse{Error: healthErrorNotConfigured}
+	}
+
+	s.setExtraHeaders(ctx, req)
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	latency := time.Since(start).Milliseconds()
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return &HealthResponse{LatencyMs: latency, Error: healthErrorTimeout}
+		}
+		return &HealthResponse{LatencyMs: latency, Error: healthErrorUnreachable}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, s.maxResponseBytes))
+		return &HealthResponse{LatencyMs: latency, Error: normalizeErrorCode(resp.StatusCode, parseProviderErrorCode(body))}
+	}
+	return &HealthResponse{Healthy: true, LatencyMs: latency}
+}
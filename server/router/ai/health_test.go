@@ -0,0 +1,70 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth(t *testing.T) {
+	var calls atomic.Int32
+	status := atomic.Int32{}
+	status.Store(http.StatusOK)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		require.Equal(t, "/models", r.URL.Path)
+		require.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		w.WriteHeader(int(status.Load()))
+		_, _ = w.Write([]byte(`{"error":{"code":"invalid_api_key"}}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL+"/chat/completions")
+	s := NewAIService(nil, "", "test-key")
+
+	check := func() (*HealthResponse, int) {
+		c, rec := newTestContext(``)
+		require.NoError(t, s.Health(c))
+		result := new(HealthResponse)
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), result))
+		return result, rec.Code
+	}
+
+	result, code := check()
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, result.Healthy)
+	_, _ = check()
+	require.Equal(t, int32(1), calls.Load(), "results are cached")
+
+	status.Store(http.StatusUnauthorized)
+	s.healthCache.expiresAt = time.Time{}
+	result, code = check()
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.False(t, result.Healthy)
+	require.Equal(t, errorCodeInvalidAPIKey, result.Error)
+}
+
+func TestHealthTimeout(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL+"/chat/completions")
+	t.Setenv("MEMOS_AI_HEALTH_TIMEOUT", "50ms")
+	s := NewAIService(nil, "", "test-key")
+
+	result := s.checkHealth(t.Context())
+	require.False(t, result.Healthy)
+	require.Equal(t, healthErrorTimeout, result.Error)
+}
+
+func TestHealthNotConfigured(t *testing.T) {
+	t.Setenv("MEMOS_OPENAI_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	s := NewAIService(nil, "", "")
+	require.Equal(t, &HealthResponse{Error: healthErrorNotConfigured}, s.checkHealth(t.Context()))
+}
@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"context"
+	"strings"
+)
+
+// Session history strategies, chosen with MEMOS_AI_HISTORY_STRATEGY.
+const (
+	// historyStrategyDrop discards saved messages older than the history window.
+	historyStrategyDrop = "drop"
+	// historyStrategySummarize replaces them with a generated summary sent as a system note.
+	historyStrategySummarize = "summarize"
+
+	historySummaryPrompt = "You summarize conversations. Summarize the conversation between the user and the assistant below, " +
+		"keeping the facts, decisions and open questions needed to continue it. Reply with the summary only, without any preamble."
+	historySummaryNote = "Summary of the earlier conversation:\n"
+)
+
+var historyStrategies = []string{historyStrategyDrop, historyStrategySummarize}
+
+// windowHistory keeps the most recent maxHistoryMessages of a session's saved history.
+// With the summarize strategy it also returns a system note summarizing the dropped
+// messages; when summarizing fails they are dropped instead, since the request can still
+// be answered without them. The system prompt is not part of the saved history, so it is
+// always sent.
+func (s *AIService) windowHistory(ctx context.Context, apiKey string, history []ChatCompletionMessage) ([]ChatCompletionMessage, string) {
+	if s.maxHistoryMessages <= 0 || len(history) <= s.maxHistoryMessages {
+		return history, ""
+	}
+	dropped, kept := history[:len(history)-s.maxHistoryMessages], history[len(history)-s.maxHistoryMessages:]
+	if s.historyStrategy != historyStrategySummarize {
+		return kept, ""
+	}
+	summary, err := s.summarizeHistory(ctx, apiKey, dropped)
+	if err != nil {
+		s.log(ctx).Warn("failed to summarize AI session history, dropping older messages", "dropped", len(dropped), "error", err)
+		return kept, ""
+	}
+	if summary == "" {
+		return kept, ""
+	}
+	return kept, historySummaryNote + summary
+}
+
+// summarizeHistory asks the default model for a summary of messages. Identical
+// histories are served from the response cache when it is enabled.
+func (s *AIService) summarizeHistory(ctx context.Context, apiKey string, messages []ChatCompletionMessage) (string, error) {
+	var transcript strings.Builder
+	for _, message := range messages {
+		transcript.WriteString(message.Role)
+		transcript.WriteString(": ")
+		transcript.WriteString(message.Content)
+		transcript.WriteString("\n\n")
+	}
+	summary, err := s.complete(ctx, apiKey, &ChatCompletionRequest{
+		Model: s.defaultModel,
+		Messages: []ChatCompletionMessage{
+			{Role: roleSystem, Content: historySummaryPrompt},
+			{Role: "user", Content: truncateBytes(transcript.String(), s.maxInputBytes)},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(summary), nil
+}
@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func historyMessages(contents ...string) []ChatCompletionMessage {
+	messages := make([]ChatCompletionMessage, 0, len(contents))
+	for i, content := range contents {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		messages = append(messages, ChatCompletionMessage{Role: role, Content: content})
+	}
+	return messages
+}
+
+func TestWindowHistoryDrop(t *testing.T) {
+	s := NewAIService(nil, "", "test-key")
+	history := historyMessages("one", "two", "three", "four")
+
+	kept, note := s.windowHistory(context.Background(), "test-key", history)
+	require.Equal(t, history, kept)
+	require.Empty(t, note)
+
+	s.maxHistoryMessages = 2
+	kept, note = s.windowHistory(context.Background(), "test-key", history)
+	require.Equal(t, history[2:], kept)
+	require.Empty(t, note)
+}
+
+func TestWindowHistorySummarize(t *testing.T) {
+	var summaryRequest *ChatCompletionRequest
+	newChatUpstream(t, " earlier plans ", func(req *ChatCompletionRequest) {
+		summaryRequest = req
+	})
+	t.Setenv("MEMOS_AI_MAX_HISTORY_MESSAGES", "2")
+	t.Setenv("MEMOS_AI_HISTORY_STRATEGY", "summarize")
+	s := NewAIService(nil, "", "test-key")
+	history := historyMessages("one", "two", "three", "four")
+
+	kept, note := s.windowHistory(context.Background(), "test-key", history)
+	require.Equal(t, history[2:], kept)
+	require.Equal(t, historySummaryNote+"earlier plans", note)
+	require.NotNil(t, summaryRequest)
+	require.Contains(t, summaryRequest.Messages[1].Content, "user: one")
+	require.Contains(t, summaryRequest.Messages[1].Content, "assistant: two")
+	require.NotContains(t, summaryRequest.Messages[1].Content, "three")
+}
+
+func TestWindowHistorySummarizeFailureDrops(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	s := NewAIService(nil, "", "test-key")
+	s.maxHistoryMessages = 1
+	s.historyStrategy = historyStrategySummarize
+
+	history := historyMessages("one", "two")
+	kept, note := s.windowHistory(context.Background(), "test-key", history)
+	require.Equal(t, history[1:], kept)
+	require.Empty(t, note)
+}
+
+func TestWithSystemNote(t *testing.T) {
+	require.Equal(t, []ChatCompletionMessage{
+		{Role: roleSystem, Content: "prompt"},
+		{Role: roleSystem, Content: "note"},
+		{Role: "user", Content: "hi"},
+	}, withSystemNote([]ChatCompletionMessage{{Role: roleSystem, Content: "prompt"}, {Role: "user", Content: "hi"}}, "note"))
+}
+
+func TestLoadHistoryStrategy(t *testing.T) {
+	t.Setenv("MEMOS_AI_HISTORY_STRATEGY", "Summarize")
+	require.Equal(t, historyStrategySummarize, LoadConfig(slog.Default()).HistoryStrategy)
+	t.Setenv("MEMOS_AI_HISTORY_STRATEGY", "forget")
+	require.Equal(t, historyStrategyDrop, LoadConfig(slog.Default()).HistoryStrategy)
+}
@@ -0,0 +1,95 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+const (
+	headerIdempotencyKey = "Idempotency-Key"
+	// headerIdempotentReplayed marks a response replayed for a repeated Idempotency-Key.
+	headerIdempotentReplayed = "Idempotent-Replayed"
+	// defaultIdempotencyTTL is how long a response is kept for replay when
+	// MEMOS_AI_IDEMPOTENCY_TTL is unset.
+	defaultIdempotencyTTL = 10 * time.Minute
+	// maxIdempotencyKeyLength bounds the client-chosen key.
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotentRequest is a non-streaming chat completion sent with an Idempotency-Key.
+type idempotentRequest struct {
+	// key is the Idempotency-Key scoped to the user who sent it.
+	key string
+	// fingerprint hashes the request so a key reused for a different request is rejected.
+	fingerprint string
+}
+
+// idempotentEntry is what is kept for a completed idempotent request.
+type idempotentEntry struct {
+	Fingerprint string          `json:"fingerprint"`
+	Response    json.RawMessage `json:"response"`
+}
+
+// idempotencyLookup checks a chat completion's Idempotency-Key. It returns the stored
+// response when the same user already completed the same request with that key, so a
+// client retrying after a network blip is not charged twice. The header is ignored on
+// streaming requests and on requests that cannot be attributed to a user. A key reused
+// for a different request is rejected with 422.
+func (s *AIService) idempotencyLookup(c echo.Context, req *ChatCompletionRequest) (*idempotentRequest, []byte, error) {
+	key := c.Request().Header.Get(headerIdempotencyKey)
+	if key == "" || req.Stream {
+		return nil, nil, nil
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s must be at most %d characters", headerIdempotencyKey, maxIdempotencyKeyLength))
+	}
+	user, err := s.getCurrentUser(c.Request().Context(), c)
+	if err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get current user").SetInternal(err)
+	}
+	if user == nil {
+		return nil, nil, nil
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to encode request").SetInternal(err)
+	}
+	fingerprint := sha256.Sum256(data)
+	request := &idempotentRequest{
+		key:         fmt.Sprintf("%d:%s", user.ID, key),
+		fingerprint: hex.EncodeToString(fingerprint[:]),
+	}
+
+	stored, ok := s.idempotency.get(request.key)
+	if !ok {
+		return request, nil, nil
+	}
+	entry := new(idempotentEntry)
+	if err := json.Unmarshal(stored, entry); err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to read stored response").SetInternal(errors.WithStack(err))
+	}
+	if entry.Fingerprint != request.fingerprint {
+		return nil, nil, echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("%s was already used for a different request", headerIdempotencyKey))
+	}
+	return request, entry.Response, nil
+}
+
+// idempotencyStore keeps a successful response for replay. It does nothing for requests
+// without an Idempotency-Key.
+func (s *AIService) idempotencyStore(request *idempotentRequest, body []byte) {
+	if request == nil || !json.Valid(body) {
+		return
+	}
+	data, err := json.Marshal(&idempotentEntry{Fingerprint: request.fingerprint, Response: body})
+	if err != nil {
+		return
+	}
+	s.idempotency.put(request.key, data)
+}
@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usememos/memos/store"
+)
+
+func newIdempotentContext(userID int32, key, body string) (echo.Context, *httptest.ResponseRecorder) {
+	c, rec := newTestContext(body)
+	c.Request().Header.Set(headerIdempotencyKey, key)
+	if userID != 0 {
+		c.Set(currentUserContextKey, &store.User{ID: userID})
+	}
+	return c, rec
+}
+
+func TestChatCompletionReplaysIdempotentRequests(t *testing.T) {
+	var calls atomic.Int32
+	newChatUpstream(t, "hello", func(*ChatCompletionRequest) {
+		calls.Add(1)
+	})
+	s := NewAIService(nil, "", "test-key")
+	const body = `{"messages":[{"role":"user","content":"hi"}]}`
+
+	c, first := newIdempotentContext(1, "retry-1", body)
+	require.NoError(t, s.ChatCompletion(c))
+	c, replayed := newIdempotentContext(1, "retry-1", body)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, int32(1), calls.Load())
+	require.JSONEq(t, first.Body.String(), replayed.Body.String())
+	require.Equal(t, "true", replayed.Header().Get(headerIdempotentReplayed))
+
+	// Keys are scoped per user.
+	c, other := newIdempotentContext(2, "retry-1", body)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, int32(2), calls.Load())
+	require.Empty(t, other.Header().Get(headerIdempotentReplayed))
+}
+
+func TestChatCompletionRejectsReusedIdempotencyKey(t *testing.T) {
+	newChatUpstream(t, "hello", nil)
+	s := NewAIService(nil, "", "test-key")
+
+	c, _ := newIdempotentContext(1, "retry-1", `{"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	c, _ = newIdempotentContext(1, "retry-1", `{"messages":[{"role":"user","content":"something else"}]}`)
+	httpErr, ok := s.ChatCompletion(c).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusUnprocessableEntity, httpErr.Code)
+}
+
+func TestChatCompletionIgnoresIdempotencyKeyForAnonymousUsers(t *testing.T) {
+	var calls atomic.Int32
+	newChatUpstream(t, "hello", func(*ChatCompletionRequest) {
+		calls.Add(1)
+	})
+	s := NewAIService(nil, "", "test-key")
+
+	for range 2 {
+		c, rec := newIdempotentContext(0, "retry-1", `{"messages":[{"role":"user","content":"hi"}]}`)
+		require.NoError(t, s.ChatCompletion(c))
+		require.Empty(t, rec.Header().Get(headerIdempotentReplayed))
+	}
+	require.Equal(t, int32(2), calls.Load())
+}
@@ -0,0 +1,315 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// jobWorkers is how many jobs run at the same time.
+	jobWorkers = 2
+	// maxQueuedJobs caps the jobs waiting for a worker across all users.
+	maxQueuedJobs = 64
+	// maxJobsPerUser caps the unfinished jobs of one user.
+	maxJobsPerUser = 8
+	// jobTTL is how long a finished job can still be polled.
+	jobTTL = time.Hour
+
+	jobStatusQueued    = "queued"
+	jobStatusRunning   = "running"
+	jobStatusSucceeded = "succeeded"
+	jobStatusFailed    = "failed"
+	jobStatusCanceled  = "canceled"
+)
+
+// Job reports the state of a summarization job.
+type Job struct {
+	ID     string             `json:"id"`
+	Status string             `json:"status"`
+	Result *SummarizeResponse `json:"result,omitempty"`
+	Error  *ErrorDetail       `json:"error,omitempty"`
+
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// job is a queued or finished summarization. Its fields other than the request are guarded
+// by the queue's mutex.
+type job struct {
+	id      string
+	userID  int32
+	apiKey  string
+	request *SummarizeRequest
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	status     string
+	result     *SummarizeResponse
+	err        *ErrorDetail
+	createdAt  time.Time
+	finishedAt time.Time
+}
+
+func (j *job) finished() bool {
+	return !j.finishedAt.IsZero()
+}
+
+func (j *job) finish(status string) {
+	j.status = status
+	j.finishedAt = time.Now()
+	j.cancel()
+}
+
+func (j *job) snapshot() *Job {
+	response := &Job{
+		ID:        j.id,
+		Status:    j.status,
+		Result:    j.result,
+		Error:     j.err,
+		CreatedAt: j.createdAt,
+	}
+	if j.finished() {
+		finishedAt := j.finishedAt
+		response.FinishedAt = &finishedAt
+	}
+	return response
+}
+
+// jobQueue keeps the jobs of all users in memory and runs them on a bounded pool of
+// workers, started with the first job.
+type jobQueue struct {
+	mutex   sync.Mutex
+	jobs    map[string]*job
+	pending chan *job
+	closed  bool
+}
+
+// enqueue adds j to the queue, starting the workers that run jobs with run if needed.
+func (q *jobQueue) enqueue(j *job, run func(*job)) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "AI service is shutting down")
+	}
+	if q.pending == nil {
+		q.pending = make(chan *job, maxQueuedJobs)
+		for range jobWorkers {
+			go func() {
+				for j := range q.pending {
+					run(j)
+				}
+			}()
+		}
+	}
+	q.pruneLocked()
+	unfinished := 0
+	for _, other := range q.jobs {
+		if other.userID == j.userID && !other.finished() {
+			unfinished++
+		}
+	}
+	if unfinished >= maxJobsPerUser {
+		return echo.NewHTTPError(http.StatusTooManyRequests, "Too many unfinished jobs")
+	}
+	select {
+	case q.pending <- j:
+	default:
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "Job queue is full")
+	}
+	if q.jobs == nil {
+		q.jobs = map[string]*job{}
+	}
+	q.jobs[j.id] = j
+	return nil
+}
+
+// pruneLocked forgets jobs that finished more than jobTTL ago.
+func (q *jobQueue) pruneLocked() {
+	for id, j := range q.jobs {
+		if j.finished() && time.Since(j.finishedAt) > jobTTL {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+// get returns the state of the job id if userID owns it.
+func (q *jobQueue) get(id string, userID int32) (*Job, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.pruneLocked()
+	j, ok := q.jobs[id]
+	if !ok || j.userID != userID {
+		return nil, false
+	}
+	return j.snapshot(), true
+}
+
+// cancel stops the job id if userID owns it, or forgets it when it has already finished.
+// It reports whether the job was found.
+func (q *jobQueue) cancel(id string, userID int32) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	j, ok := q.jobs[id]
+	if !ok || j.userID != userID {
+		return false
+	}
+	if j.finished() {
+		delete(q.jobs, id)
+	} else {
+		j.finish(jobStatusCanceled)
+	}
+	return true
+}
+
+// begin marks j as running. It returns false when j was canceled while queued.
+func (q *jobQueue) begin(j *job) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if j.finished() {
+		return false
+	}
+	j.status = jobStatusRunning
+	return true
+}
+
+// complete records the outcome of j unless it was canceled meanwhile.
+func (q *jobQueue) complete(j *job, result *SummarizeResponse, detail *ErrorDetail) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if j.finished() {
+		return
+	}
+	if detail != nil {
+		j.err = detail
+		j.finish(jobStatusFailed)
+		return
+	}
+	j.result = result
+	j.finish(jobStatusSucceeded)
+}
+
+// close cancels the unfinished jobs and stops the workers.
+func (q *jobQueue) close() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		return
+	}
+	q.closed = true
+	for _, j := range q.jobs {
+		if !j.finished() {
+			j.finish(jobStatusCanceled)
+		}
+	}
+	if q.pending != nil {
+		close(q.pending)
+	}
+}
+
+// runJob summarizes the content of j. The tokens it uses count towards its owner's quota,
+// as those of a request would.
+func (s *AIService) runJob(j *job) {
+	if !s.jobs.begin(j) {
+		return
+	}
+	// Cancelling the job must abort its upstream call, which a shared call would outlive.
+	ctx, usage := withRequestUsage(withoutCoalescing(j.ctx), s.pricing)
+	summary, err := s.summarize(ctx, j.apiKey, j.request)
+	if tokens, cost := usage.totals(); tokens > 0 && s.quota != nil {
+		if _, addErr := s.quota.add(context.WithoutCancel(ctx), j.userID, tokens, cost); addErr != nil {
+			s.log(ctx).Error("failed to record AI token usage", "user_id", j.userID, "error", addErr)
+		}
+	}
+	if err != nil {
+		_, detail := s.errorDetail(ctx, err)
+		s.jobs.complete(j, nil, detail)
+		return
+	}
+	s.jobs.complete(j, &SummarizeResponse{Summary: summary}, nil)
+}
+
+// CreateJob queues the summarization of a large memo and returns the job to poll with
+// GET /ai/jobs/:id. Jobs belong to the signed-in user who created them.
+func (s *AIService) CreateJob(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := s.requireJobUser(ctx, c)
+	if err != nil {
+		return err
+	}
+	apiKey, err := s.requireAPIKey(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	request := new(SummarizeRequest)
+	if err := c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	}
+	if err := s.validateSummarizeRequest(request); err != nil {
+		return err
+	}
+
+	// The job outlives the request, but keeps its request ID and user for logs and quota.
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	j := &job{
+		id:        uuid.NewString(),
+		userID:    userID,
+		apiKey:    apiKey,
+		request:   request,
+		ctx:       jobCtx,
+		cancel:    cancel,
+		status:    jobStatusQueued,
+		createdAt: time.Now(),
+	}
+	if err := s.jobs.enqueue(j, s.runJob); err != nil {
+		cancel()
+		return err
+	}
+	response, _ := s.jobs.get(j.id, userID)
+	return c.JSON(http.StatusAccepted, response)
+}
+
+// GetJob reports the status of a job and, once it has succeeded, its summary. Jobs of other
+// users are reported as not found.
+func (s *AIService) GetJob(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := s.requireJobUser(ctx, c)
+	if err != nil {
+		return err
+	}
+	response, ok := s.jobs.get(c.Param("id"), userID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "Job not found")
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// CancelJob cancels an unfinished job, or deletes a finished one.
+func (s *AIService) CancelJob(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := s.requireJobUser(ctx, c)
+	if err != nil {
+		return err
+	}
+	if !s.jobs.cancel(c.Param("id"), userID) {
+		return echo.NewHTTPError(http.StatusNotFound, "Job not found")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// requireJobUser returns the ID of the signed-in user; jobs cannot be used anonymously.
+func (s *AIService) requireJobUser(ctx context.Context, c echo.Context) (int32, error) {
+	user, err := s.getCurrentUser(ctx, c)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get current user").SetInternal(err)
+	}
+	if user == nil {
+		return 0, echo.NewHTTPError(http.StatusUnauthorized, "Authentication required to use jobs")
+	}
+	return user.ID, nil
+}
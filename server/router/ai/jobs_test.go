@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usememos/memos/store"
+)
+
+// pollJob fetches job id as user until it has finished.
+func pollJob(t *testing.T, s *AIService, user *store.User, id string) *Job {
+	t.Helper()
+	var job *Job
+	require.Eventually(t, func() bool {
+		c, rec := newTestContext("")
+		c.Set(currentUserContextKey, user)
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		require.NoError(t, s.GetJob(c))
+		job = new(Job)
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), job))
+		return job.FinishedAt != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	return job
+}
+
+func TestSummarizeJob(t *testing.T) {
+	newChatUpstream(t, " A short summary. ", nil)
+	s := NewAIService(nil, "", "test-key")
+	owner := &store.User{ID: 1}
+
+	c, rec := newTestContext(`{"content":"a very long document"}`)
+	c.Set(currentUserContextKey, owner)
+	require.NoError(t, s.CreateJob(c))
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	created := new(Job)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), created))
+	require.NotEmpty(t, created.ID)
+
+	job := pollJob(t, s, owner, created.ID)
+	require.Equal(t, jobStatusSucceeded, job.Status)
+	require.Equal(t, "A short summary.", job.Result.Summary)
+
+	// Other users cannot see or cancel the job.
+	c, _ = newTestContext("")
+	c.Set(currentUserContextKey, &store.User{ID: 2})
+	c.SetParamNames("id")
+	c.SetParamValues(created.ID)
+	err := s.GetJob(c)
+	require.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+	err = s.CancelJob(c)
+	require.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+
+	// Anonymous requests cannot create jobs.
+	c, _ = newTestContext(`{"content":"a very long document"}`)
+	err = s.CreateJob(c)
+	require.Equal(t, http.StatusUnauthorized, err.(*echo.HTTPError).Code)
+}
+
+func TestCancelJob(t *testing.T) {
+	started := make(chan struct{})
+	aborted := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		// The server only notices the client going away once the body has been read.
+		_, _ = io.Copy(io.Discard, r.Body)
+		close(started)
+		<-r.Context().Done()
+		close(aborted)
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	t.Setenv("MEMOS_AI_MAX_RETRIES", "0")
+	s := NewAIService(nil, "", "test-key")
+	owner := &store.User{ID: 1}
+
+	c, rec := newTestContext(`{"content":"a very long document"}`)
+	c.Set(currentUserContextKey, owner)
+	require.NoError(t, s.CreateJob(c))
+	created := new(Job)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), created))
+	<-started
+
+	c, rec = newTestContext("")
+	c.Set(currentUserContextKey, owner)
+	c.SetParamNames("id")
+	c.SetParamValues(created.ID)
+	require.NoError(t, s.CancelJob(c))
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	job := pollJob(t, s, owner, created.ID)
+	require.Equal(t, jobStatusCanceled, job.Status)
+	require.Nil(t, job.Result)
+	select {
+	case <-aborted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the upstream request was not aborted")
+	}
+}
@@ -0,0 +1,68 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// defaultJSONRetries is how many times a malformed JSON reply is re-requested when
+// MEMOS_AI_JSON_RETRIES is unset.
+const defaultJSONRetries = 1
+
+// jsonRetryInstruction is appended to the conversation when the model's reply did not parse.
+const jsonRetryInstruction = "Return only valid JSON."
+
+// completeJSON sends req and passes the reply to parse, which decodes it. When parse fails
+// the model is shown its reply and asked again, up to jsonRetries times and within the
+// request's retry budget, before the request fails with invalid_json. The unparseable reply is included in the error in debug mode only.
+func (s *AIService) completeJSON(ctx context.Context, apiKey string, req *ChatCompletionRequest, parse func(content string) error) error {
+	messages := slices.Clone(req.Messages)
+	for attempt := 0; ; attempt++ {
+		content, err := s.complete(ctx, apiKey, req)
+		if err != nil {
+			return err
+		}
+		parseErr := parse(content)
+		if parseErr == nil {
+			return nil
+		}
+		s.log(ctx).Debug("failed to parse JSON from model output", "attempt", attempt+1, "error", parseErr, "content", truncate(content, maxLoggedBodyBytes))
+		if attempt >= s.jsonRetries || !s.canRetry(ctx, retryLayerJSON) {
+			return s.errorResponse(http.StatusBadGateway, errorCodeInvalidJSON, []byte(content))
+		}
+		retry := *req
+		retry.Messages = append(slices.Clone(messages),
+			ChatCompletionMessage{Role: roleAssistant, Content: content},
+			ChatCompletionMessage{Role: roleUser, Content: jsonRetryInstruction},
+		)
+		req = &retry
+	}
+}
+
+// parseJSONArray decodes the first JSON array found in content into v.
+// Models often wrap JSON in prose or Markdown code fences, so anything before the
+// first '[' and after the last ']' is ignored.
+func parseJSONArray(content string, v any) error {
+	start := strings.Index(content, "[")
+	end := strings.LastIndex(content, "]")
+	if start < 0 || end < start {
+		return errors.New("no JSON array found in model output")
+	}
+	return json.Unmarshal([]byte(content[start:end+1]), v)
+}
+
+// parseJSONObject decodes the first JSON object found in content into v, ignoring any
+// prose or Markdown code fences around it.
+func parseJSONObject(content string, v any) error {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return errors.New("no JSON object found in model output")
+	}
+	return json.Unmarshal([]byte(content[start:end+1]), v)
+}
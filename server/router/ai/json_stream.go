@@ -0,0 +1,211 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+// jsonArrayScanner picks the complete elements out of the first JSON array in a reply that
+// is still being streamed. It tolerates prose or code fences around the array and skips
+// elements that are not valid JSON, as parseJSONArray does for a whole reply.
+type jsonArrayScanner struct {
+	content []byte
+	// pos is the next byte of content to scan.
+	pos int
+	// start is where the current element begins; it is -1 until the array is found.
+	start    int
+	depth    int
+	inString bool
+	escaped  bool
+	// done is set once the array has been closed.
+	done     bool
+	elements []json.RawMessage
+}
+
+func newJSONArrayScanner() *jsonArrayScanner {
+	return &jsonArrayScanner{start: -1}
+}
+
+// write scans text and reports whether it completed any elements.
+func (p *jsonArrayScanner) write(text string) bool {
+	p.content = append(p.content, text...)
+	found := len(p.elements)
+	for ; p.pos < len(p.content) && !p.done; p.pos++ {
+		ch := p.content[p.pos]
+		switch {
+		case p.start < 0:
+			if ch == '[' {
+				p.start, p.depth = p.pos+1, 1
+			}
+		case p.inString:
+			switch {
+			case p.escaped:
+				p.escaped = false
+			case ch == '\\':
+				p.escaped = true
+			case ch == '"':
+				p.inString = false
+			}
+		case ch == '"':
+			p.inString = true
+		case ch == '[' || ch == '{':
+			p.depth++
+		case ch == ']' || ch == '}':
+			if p.depth--; p.depth == 0 {
+				p.addElement()
+				p.done = true
+			}
+		case ch == ',' && p.depth == 1:
+			p.addElement()
+			p.start = p.pos + 1
+		}
+	}
+	return len(p.elements) > found
+}
+
+// addElement records the element ending at pos.
+func (p *jsonArrayScanner) addElement() {
+	element := bytes.TrimSpace(p.content[p.start:p.pos])
+	if len(element) > 0 && json.Valid(element) {
+		p.elements = append(p.elements, json.RawMessage(bytes.Clone(element)))
+	}
+}
+
+// streamJSONArray answers a structured endpoint called with stream:true. It streams req and
+// sends an SSE event each time another element of the JSON array in the reply completes,
+// carrying result of all the elements so far, then "data: [DONE]". When the provider does
+// not stream or nothing could be read from the stream incrementally, the whole reply is
+// parsed and sent as a single event, with the usual JSON retries.
+func (s *AIService) streamJSONArray(c echo.Context, apiKey string, req *ChatCompletionRequest, result func(elements []json.RawMessage) any) error {
+	ctx, done, err := s.trackStream(c.Request().Context(), c)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	streamReq := *req
+	streamReq.Stream = true
+	streamReq.StreamOptions = &StreamOptions{IncludeUsage: true}
+	resp, err := s.streamUpstream(ctx, apiKey, &streamReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// The SSE response starts with the first complete element, so errors until then are
+	// still returned as ordinary error responses.
+	var writer *streamWriter
+	send := func(elements []json.RawMessage) error {
+		if writer == nil {
+			writer = s.startSSE(c, resp.Body)
+		}
+		data, err := json.Marshal(result(elements))
+		if err != nil {
+			return err
+		}
+		return writer.write(fmt.Appendf(nil, "data: %s\n\n", data))
+	}
+	defer func() {
+		if writer != nil {
+			writer.stop()
+		}
+	}()
+
+	var content string
+	streamed := false
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get(echo.HeaderContentType)); mediaType == "text/event-stream" {
+		if content, err = s.scanJSONStream(ctx, resp.Body, req.Model, send); err != nil && writer != nil {
+			// Headers are already sent, so the stream can only end.
+			s.logStreamAborted(ctx, err, int64(len(content)), nil)
+			return nil
+		}
+		streamed = writer != nil
+	} else {
+		// The provider ignored stream and answered with a complete response.
+		body, err := s.readBody(resp.Body)
+		if err != nil {
+			return err
+		}
+		if body, err = s.providerFor(ctx).ParseChatResponse(body); err != nil {
+			return echo.NewHTTPError(http.StatusBadGateway, "Unexpected response from AI provider").SetInternal(err)
+		}
+		recordUsage(ctx, req.Model, parseUsage(body))
+		response, err := unmarshalResponse(s.filterResponse(body))
+		if err != nil {
+			return err
+		}
+		content = response.Choices[0].Content()
+	}
+
+	var elements []json.RawMessage
+	if err := parseJSONArray(content, &elements); err != nil {
+		if streamed {
+			s.log(ctx).Warn("failed to parse streamed JSON, keeping the elements sent so far", "error", err)
+			if err := writer.write(streamDone); err != nil {
+				s.logStreamAborted(ctx, err, int64(len(content)), nil)
+			}
+			return nil
+		}
+		// Nothing usable arrived, so the buffered request path with its retries takes over.
+		if err := s.completeJSON(ctx, apiKey, req, func(content string) error {
+			return parseJSONArray(content, &elements)
+		}); err != nil {
+			return err
+		}
+	}
+	if err := send(elements); err != nil {
+		s.logStreamAborted(ctx, err, 0, nil)
+		return nil
+	}
+	if err := writer.write(streamDone); err != nil {
+		s.logStreamAborted(ctx, err, 0, nil)
+	}
+	return nil
+}
+
+// scanJSONStream reads a streamed reply, calling send with the elements of its JSON array
+// each time more of them complete, and returns the reply text read. It fails when the
+// stream breaks off or send does.
+func (s *AIService) scanJSONStream(ctx context.Context, body io.Reader, model string, send func(elements []json.RawMessage) error) (string, error) {
+	scanner := newJSONArrayScanner()
+	filter := newStreamFilter(s.responseFilters)
+	decoder := newStreamDecoder(s.providerFor(ctx), newStreamLineReader(body, s.maxResponseBytes))
+	var usage *Usage
+	defer func() {
+		recordUsage(ctx, model, usage)
+	}()
+	for {
+		delta, err := decoder.Next()
+		if err != nil {
+			var chunkErr *streamChunkError
+			switch {
+			case errors.As(err, &chunkErr):
+				s.log(ctx).Warn("failed to decode AI stream chunk", "error", err)
+				continue
+			case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+				scanner.write(filter.rest())
+				return string(scanner.content), nil
+			case errors.Is(err, errStreamTooLarge):
+				s.log(ctx).Warn("AI stream exceeded the response size limit, closing", "limit", s.maxResponseBytes)
+			}
+			return string(scanner.content), err
+		}
+		if delta.Usage != nil {
+			usage = delta.Usage
+		}
+		filter.apply(delta)
+		if scanner.write(delta.Content) {
+			if err := send(scanner.elements); err != nil {
+				return string(scanner.content), err
+			}
+		}
+	}
+}
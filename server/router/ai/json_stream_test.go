@@ -0,0 +1,79 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONArrayScanner(t *testing.T) {
+	scanner := newJSONArrayScanner()
+	require.False(t, scanner.write("```json\n{\"items\": [{\"text\": \"a, [b]"))
+	require.True(t, scanner.write("\"}, \"c\\\"\""))
+	require.Equal(t, []json.RawMessage{json.RawMessage(`{"text": "a, [b]"}`)}, scanner.elements)
+	require.True(t, scanner.write(", nope, 3]} [\"ignored\"]\n```"))
+	require.Equal(t, []json.RawMessage{
+		json.RawMessage(`{"text": "a, [b]"}`),
+		json.RawMessage(`"c\""`),
+		json.RawMessage(`3`),
+	}, scanner.elements)
+	require.False(t, scanner.write(`["more"]`))
+}
+
+func TestSuggestTagsStream(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := new(ChatCompletionRequest)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		require.True(t, req.Stream)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, token := range []string{`{"tags": ["#Go`, `", "Testing"`, `, "go"]}`} {
+			chunk, err := json.Marshal(map[string]any{"choices": []map[string]any{{"index": 0, "delta": map[string]any{"content": token}}}})
+			require.NoError(t, err)
+			_, _ = w.Write([]byte("data: " + string(chunk) + "\n\n"))
+		}
+		_, _ = w.Write(streamDone)
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"content":"unit tests in go","stream":true}`)
+	require.NoError(t, s.SuggestTags(c))
+	require.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	require.Equal(t, []string{
+		`{"tags":["go"]}`,
+		`{"tags":["go","testing"]}`,
+		// The final event carries the whole parsed reply.
+		`{"tags":["go","testing"]}`,
+		"[DONE]",
+	}, sseEvents(rec.Body.String()))
+}
+
+func TestActionItemsStreamFallsBackToBufferedResponse(t *testing.T) {
+	// The upstream ignores stream and answers with a complete response.
+	newChatUpstream(t, `{"items":[{"text":"Send notes"},"- [ ] Book room"]}`, nil)
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"content":"meeting notes","format":"markdown","stream":true}`)
+	require.NoError(t, s.ActionItems(c))
+	require.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	require.Equal(t, []string{
+		`{"markdown":"- [ ] Send notes\n- [ ] Book room\n"}`,
+		"[DONE]",
+	}, sseEvents(rec.Body.String()))
+}
+
+// sseEvents returns the data of each event in an SSE response body.
+func sseEvents(body string) []string {
+	var events []string
+	for _, line := range strings.Split(body, "\n") {
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			events = append(events, data)
+		}
+	}
+	return events
+}
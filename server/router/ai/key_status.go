@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/usememos/memos/store"
+)
+
+type RotateAPIKeyRequest struct {
+	APIKey string `json:"api_key"`
+}
+
+// checkServerKey tracks whether the provider accepts the server's API key from a chat
+// completion response sent with it. A rejection marks the key invalid, so /ai/status
+// disables AI features for users without their own key, and is returned as a clean
+// ai_unavailable error instead of the provider's 401. Requests keep using the key, and the
+// first one it succeeds for again, for example after the key is re-enabled at the
+// provider, clears the flag.
+func (s *AIService) checkServerKey(ctx context.Context, resp *http.Response) (*http.Response, error) {
+	if resp.StatusCode != http.StatusUnauthorized {
+		if resp.StatusCode < http.StatusBadRequest && s.keyInvalid.Swap(false) {
+			s.log(ctx).Info("the AI provider accepts the configured API key again")
+		}
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	body, err := s.readBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if !s.keyInvalid.Swap(true) {
+		s.log(ctx).Error("the AI provider rejected the configured API key, AI features are disabled until it is fixed", "body", truncate(string(body), maxLoggedBodyBytes))
+	}
+	return nil, s.errorResponse(http.StatusServiceUnavailable, errorCodeAIUnavailable, body)
+}
+
+// serverAPIKey returns the server-wide API key.
+func (s *AIService) serverAPIKey() string {
+	s.apiKeyMutex.RLock()
+	defer s.apiKeyMutex.RUnlock()
+	return s.apiKey
+}
+
+// setServerAPIKey replaces the server-wide API key. The new key has not been rejected yet,
+// so the invalid flag and the cached health result of the old key are cleared.
+func (s *AIService) setServerAPIKey(apiKey string) {
+	s.apiKeyMutex.Lock()
+	s.apiKey = apiKey
+	s.keyInvalid.Store(false)
+	s.apiKeyMutex.Unlock()
+
+	s.healthCache.mutex.Lock()
+	s.healthCache.result = nil
+	s.healthCache.expiresAt = time.Time{}
+	s.healthCache.mutex.Unlock()
+}
+
+// RotateAPIKey replaces the server-wide API key without a restart. It is limited to admins.
+// The key is kept in memory only, so MEMOS_OPENAI_API_KEY applies again after a restart.
+func (s *AIService) RotateAPIKey(c echo.Context) error {
+	ctx := c.Request().Context()
+	user, err := s.getCurrentUser(ctx, c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get current user").SetInternal(err)
+	}
+	if user == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Authentication required to change the AI API key")
+	}
+	if user.Role != store.RoleAdmin {
+		return echo.NewHTTPError(http.StatusForbidden, "Only admins can change the AI API key")
+	}
+
+	request := new(RotateAPIKeyRequest)
+	if err := c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	}
+	apiKey := strings.TrimSpace(request.APIKey)
+	if apiKey == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "api_key is required")
+	}
+	s.setServerAPIKey(apiKey)
+	s.log(ctx).Info("the AI API key was rotated", "user_id", user.ID)
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usememos/memos/store"
+)
+
+func getStatus(t *testing.T, s *AIService) *StatusResponse {
+	t.Helper()
+	c, rec := newTestContext("")
+	require.NoError(t, s.Status(c))
+	status := new(StatusResponse)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), status))
+	return status
+}
+
+func TestChatCompletionDisablesRejectedServerKey(t *testing.T) {
+	var rejected atomic.Bool
+	rejected.Store(true)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if rejected.Load() {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"message":"key revoked","code":"invalid_api_key"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	s := NewAIService(nil, "", "test-key")
+	require.Equal(t, &StatusResponse{Enabled: true}, getStatus(t, s))
+
+	c, _ := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	httpErr, ok := s.ChatCompletion(c).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusServiceUnavailable, httpErr.Code)
+	require.Equal(t, errorCodeAIUnavailable, httpErr.Message.(*ErrorResponse).Error.Code)
+	require.Equal(t, &StatusResponse{Reason: errorCodeInvalidAPIKey}, getStatus(t, s))
+
+	// The key works again once the provider accepts it.
+	rejected.Store(false)
+	c, _ = newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, &StatusResponse{Enabled: true}, getStatus(t, s))
+}
+
+func TestStatusNotConfigured(t *testing.T) {
+	t.Setenv("MEMOS_OPENAI_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	s := NewAIService(nil, "", "")
+	require.Equal(t, &StatusResponse{Reason: healthErrorNotConfigured}, getStatus(t, s))
+}
+
+func TestRotateAPIKey(t *testing.T) {
+	var auth atomic.Value
+	newChatUpstream(t, "hi", nil)
+	s := NewAIService(nil, "", "old-key")
+	s.client = doerFunc(func(req *http.Request) (*http.Response, error) {
+		auth.Store(req.Header.Get("Authorization"))
+		return cannedResponse(http.StatusOK, `{"choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`), nil
+	})
+	s.keyInvalid.Store(true)
+
+	rotate := func(user *store.User, body string) error {
+		c, _ := newTestContext(body)
+		if user != nil {
+			c.Set(currentUserContextKey, user)
+		}
+		return s.RotateAPIKey(c)
+	}
+	for _, test := range []struct {
+		user   *store.User
+		body   string
+		status int
+	}{
+		{nil, `{"api_key":"new-key"}`, http.StatusUnauthorized},
+		{&store.User{ID: 2, Role: store.RoleUser}, `{"api_key":"new-key"}`, http.StatusForbidden},
+		{&store.User{ID: 1, Role: store.RoleAdmin}, `{"api_key":"  "}`, http.StatusBadRequest},
+	} {
+		httpErr, ok := rotate(test.user, test.body).(*echo.HTTPError)
+		require.True(t, ok)
+		require.Equal(t, test.status, httpErr.Code)
+	}
+	require.Equal(t, "old-key", s.serverAPIKey())
+
+	require.NoError(t, rotate(&store.User{ID: 1, Role: store.RoleAdmin}, `{"api_key":" new-key "}`))
+	require.Equal(t, "new-key", s.serverAPIKey())
+	require.False(t, s.keyInvalid.Load())
+
+	c, _ := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, "Bearer new-key", auth.Load())
+}
@@ -0,0 +1,125 @@
+package ai
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+
+	storepb "github.com/usememos/memos/proto/gen/store"
+	"github.com/usememos/memos/store"
+)
+
+// defaultLanguage is the language replies fall back to when the user's locale is unknown.
+const defaultLanguage = "English"
+
+// localeLanguages maps the locales of the memos UI, and the base languages they are
+// matched by, to the language name put in the prompt.
+var localeLanguages = map[string]string{
+	"ar": "Arabic", "ca": "Catalan", "cs": "Czech", "de": "German", "en": "English",
+	"en-gb": "British English", "es": "Spanish", "fa": "Persian", "fr": "French", "gl": "Galician",
+	"hi": "Hindi", "hr": "Croatian", "hu": "Hungarian", "id": "Indonesian", "it": "Italian",
+	"ja": "Japanese", "ka": "Georgian", "ko": "Korean", "mr": "Marathi", "nb": "Norwegian Bokmål",
+	"nl": "Dutch", "pl": "Polish", "pt": "Portuguese", "pt-br": "Brazilian Portuguese",
+	"pt-pt": "European Portuguese", "ru": "Russian", "sl": "Slovenian", "sv": "Swedish", "th": "Thai",
+	"tr": "Turkish", "uk": "Ukrainian", "vi": "Vietnamese", "zh": "Chinese",
+	"zh-hans": "Simplified Chinese", "zh-hant": "Traditional Chinese",
+}
+
+// localeLanguage returns the language name for a locale such as "fr" or "pt-BR". A locale
+// matches its exact entry, then its base language; invalid or unknown locales fall back to
+// defaultLanguage.
+func localeLanguage(locale string) string {
+	locale = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+	if !languageTagPattern.MatchString(locale) {
+		return defaultLanguage
+	}
+	if language, ok := localeLanguages[locale]; ok {
+		return language
+	}
+	base, _, _ := strings.Cut(locale, "-")
+	if language, ok := localeLanguages[base]; ok {
+		return language
+	}
+	return defaultLanguage
+}
+
+// parseAcceptLanguage returns the preferred language tag of an Accept-Language header,
+// or "" when it names none.
+func parseAcceptLanguage(header string) string {
+	type preference struct {
+		tag     string
+		quality float64
+	}
+	var preferences []preference
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality > 0 {
+			preferences = append(preferences, preference{tag: tag, quality: quality})
+		}
+	}
+	if len(preferences) == 0 {
+		return ""
+	}
+	// The stable sort keeps the header order among equal qualities.
+	slices.SortStableFunc(preferences, func(a, b preference) int {
+		switch {
+		case a.quality > b.quality:
+			return -1
+		case a.quality < b.quality:
+			return 1
+		default:
+			return 0
+		}
+	})
+	return preferences[0].tag
+}
+
+// userLocale returns the locale of the caller: the one saved in their settings, or the
+// preferred language of the request's Accept-Language header. It returns "" when
+// neither names one.
+func (s *AIService) userLocale(ctx context.Context, caller caller) string {
+	if user := caller.user; user != nil && s.store != nil {
+		setting, err := s.store.GetUserSetting(ctx, &store.FindUserSetting{
+			UserID: &user.ID,
+			Key:    storepb.UserSetting_GENERAL,
+		})
+		if err != nil {
+			s.log(ctx).Warn("failed to get user locale", "user_id", user.ID, "error", err)
+		} else if locale := setting.GetGeneral().GetLocale(); locale != "" {
+			return locale
+		}
+	}
+	return parseAcceptLanguage(caller.acceptLanguage)
+}
+
+// withLocalePrompt adds an instruction to reply in the user's language when locale-aware
+// replies are enabled and the conversation has no system or developer message of its own.
+func (s *AIService) withLocalePrompt(ctx context.Context, caller caller, messages []ChatCompletionMessage) []ChatCompletionMessage {
+	if !s.localeAware {
+		return messages
+	}
+	for _, message := range messages {
+		if message.Role == roleSystem || message.Role == roleDeveloper {
+			return messages
+		}
+	}
+	locale := s.userLocale(ctx, caller)
+	if locale == "" {
+		return messages
+	}
+	prompt := "Respond in " + localeLanguage(locale) + "."
+	return append([]ChatCompletionMessage{{Role: roleSystem, Content: prompt}}, messages...)
+}
@@ -0,0 +1,88 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	storepb "github.com/usememos/memos/proto/gen/store"
+	"github.com/usememos/memos/store"
+	teststore "github.com/usememos/memos/store/test"
+)
+
+func TestLocaleLanguage(t *testing.T) {
+	tests := map[string]string{
+		"fr":          "French",
+		"pt-BR":       "Brazilian Portuguese",
+		"zh_Hans":     "Simplified Chinese",
+		"de-AT":       "German",
+		"xx":          defaultLanguage,
+		"not a code!": defaultLanguage,
+		"":            defaultLanguage,
+	}
+	for locale, want := range tests {
+		require.Equal(t, want, localeLanguage(locale), locale)
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	require.Equal(t, "fr-CH", parseAcceptLanguage("fr-CH, fr;q=0.9, en;q=0.8"))
+	require.Equal(t, "de", parseAcceptLanguage("en;q=0.5, de;q=0.9, *;q=0.1"))
+	require.Equal(t, "es", parseAcceptLanguage("*, es"))
+	require.Empty(t, parseAcceptLanguage(""))
+	require.Empty(t, parseAcceptLanguage("en;q=0"))
+}
+
+func TestChatCompletionLocalePrompt(t *testing.T) {
+	var forwarded *ChatCompletionRequest
+	newChatUpstream(t, "Bonjour", func(req *ChatCompletionRequest) {
+		forwarded = req
+	})
+	t.Setenv("MEMOS_AI_LOCALE_AWARE", "true")
+	s := NewAIService(nil, "", "test-key")
+
+	c, _ := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	c.Request().Header.Set("Accept-Language", "fr-FR,fr;q=0.9,en;q=0.5")
+	require.NoError(t, s.ChatCompletion(c))
+	require.Len(t, forwarded.Messages, 2)
+	require.Equal(t, roleSystem, forwarded.Messages[0].Role)
+	require.Equal(t, "Respond in French.", forwarded.Messages[0].Content)
+
+	// An explicit system message is left alone.
+	c, _ = newTestContext(`{"messages":[{"role":"system","content":"Be brief."},{"role":"user","content":"hi"}]}`)
+	c.Request().Header.Set("Accept-Language", "fr")
+	require.NoError(t, s.ChatCompletion(c))
+	require.Len(t, forwarded.Messages, 2)
+	require.Equal(t, "Be brief.", forwarded.Messages[0].Content)
+
+	// Unknown languages fall back to English.
+	c, _ = newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	c.Request().Header.Set("Accept-Language", "tlh")
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, "Respond in English.", forwarded.Messages[0].Content)
+}
+
+func TestUserLocalePrefersSettings(t *testing.T) {
+	ctx := context.Background()
+	ts := teststore.NewTestingStore(ctx, t)
+	defer ts.Close()
+	user, err := ts.CreateUser(ctx, &store.User{Username: "polyglot", Role: store.RoleUser, Email: "polyglot@test.com"})
+	require.NoError(t, err)
+	s := NewAIService(ts, "secret", "test-key")
+
+	c, _ := newTestContext(`{}`)
+	c.Request().Header.Set("Accept-Language", "fr")
+	c.Set(currentUserContextKey, user)
+	caller, err := s.resolveCaller(ctx, c)
+	require.NoError(t, err)
+	require.Equal(t, "fr", s.userLocale(ctx, caller))
+
+	_, err = ts.UpsertUserSetting(ctx, &storepb.UserSetting{
+		UserId: user.ID,
+		Key:    storepb.UserSetting_GENERAL,
+		Value:  &storepb.UserSetting_General{General: &storepb.GeneralUserSetting{Locale: "ja"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "ja", s.userLocale(ctx, caller))
+}
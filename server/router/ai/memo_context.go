@@ -0,0 +1,117 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/usememos/memos/store"
+)
+
+const (
+	// maxContextMemos caps how many memos a chat request may attach with memo_ids.
+	maxContextMemos = 20
+	// maxMemoContextBytes caps the memo content added to a chat request, so attached memos
+	// cannot push the conversation past the model's context window.
+	maxMemoContextBytes = 16 << 10
+)
+
+const (
+	memoContextHeader        = "The user attached the notes below as context for the conversation. " + contextGuardPrompt + "\n"
+	memoContextTruncatedNote = "\n[The remaining attached notes were truncated to fit the context limit.]\n"
+)
+
+// loadMemoContext builds a system message holding the content of the current user's memos
+// with the given IDs, in the order requested, and reports how many of them fit. Memos that
+// do not exist or belong to another user are reported as not found. Memos past
+// maxContextTokens, counted with model's tokenizer, or maxMemoContextBytes are dropped,
+// the last ones first.
+func (s *AIService) loadMemoContext(ctx context.Context, user *store.User, memoIDs []int32, model string) (string, ContextUsage, error) {
+	if s.store == nil {
+		return "", ContextUsage{}, echo.NewHTTPError(http.StatusNotImplemented, "No memo store is configured")
+	}
+	if user == nil {
+		return "", ContextUsage{}, echo.NewHTTPError(http.StatusUnauthorized, "Authentication required to attach memos")
+	}
+
+	ids := slices.Compact(slices.Sorted(slices.Values(memoIDs)))
+	normal := store.Normal
+	memos, err := s.store.ListMemos(ctx, &store.FindMemo{
+		IDList:    ids,
+		CreatorID: &user.ID,
+		RowStatus: &normal,
+	})
+	if err != nil {
+		return "", ContextUsage{}, echo.NewHTTPError(http.StatusInternalServerError, "Failed to list memos").SetInternal(err)
+	}
+	byID := make(map[int32]*store.Memo, len(memos))
+	for _, memo := range memos {
+		byID[memo.ID] = memo
+	}
+	for _, id := range ids {
+		if byID[id] == nil {
+			return "", ContextUsage{}, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Memo %d not found", id))
+		}
+	}
+
+	var blocks []contextBlock
+	seen := map[int32]bool{}
+	for _, id := range memoIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		blocks = append(blocks, contextBlock{source: fmt.Sprintf("memo %d", id), content: sanitizeContext(byID[id].Content)})
+	}
+	blocks, usage := fitContext(blocks, s.maxContextTokens, newTokenCounter(model), dataBlock)
+	truncated := usage.IncludedMemos < usage.RequestedMemos || usage.Truncated
+
+	var prompt strings.Builder
+	prompt.WriteString(memoContextHeader)
+	remaining := maxMemoContextBytes
+	for i, block := range blocks {
+		entry := "\n" + dataBlock(block.source, block.content)
+		if len(entry) > remaining {
+			// Truncate the content rather than the entry, so the block is still closed.
+			usage.IncludedMemos = i
+			if keep := remaining - (len(entry) - len(block.content)); keep > 0 {
+				prompt.WriteString("\n" + dataBlock(block.source, truncateBytes(block.content, keep)))
+				usage.IncludedMemos, usage.Truncated = i+1, true
+			}
+			truncated = true
+			break
+		}
+		prompt.WriteString(entry)
+		remaining -= len(entry)
+	}
+	if truncated {
+		prompt.WriteString(memoContextTruncatedNote)
+	}
+	return prompt.String(), usage, nil
+}
+
+// withSystemNote inserts a system message after the leading system and developer
+// messages, so it never displaces the configured system prompt.
+func withSystemNote(messages []ChatCompletionMessage, note string) []ChatCompletionMessage {
+	i := 0
+	for i < len(messages) && (messages[i].Role == roleSystem || messages[i].Role == roleDeveloper) {
+		i++
+	}
+	return slices.Insert(slices.Clone(messages), i, ChatCompletionMessage{Role: roleSystem, Content: note})
+}
+
+// truncateBytes cuts s to at most n bytes without splitting a UTF-8 sequence.
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
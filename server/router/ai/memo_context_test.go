@@ -0,0 +1,134 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usememos/memos/store"
+	teststore "github.com/usememos/memos/store/test"
+)
+
+func TestChatCompletionAttachesMemos(t *testing.T) {
+	ctx := context.Background()
+	ts := teststore.NewTestingStore(ctx, t)
+	defer ts.Close()
+	owner, err := ts.CreateUser(ctx, &store.User{Username: "owner", Role: store.RoleUser, Email: "owner@test.com"})
+	require.NoError(t, err)
+	other, err := ts.CreateUser(ctx, &store.User{Username: "other", Role: store.RoleUser, Email: "other@test.com"})
+	require.NoError(t, err)
+	memo, err := ts.CreateMemo(ctx, &store.Memo{UID: "memo-owner", CreatorID: owner.ID, Content: "The wifi password is hunter2.", Visibility: store.Private})
+	require.NoError(t, err)
+
+	var forwarded *ChatCompletionRequest
+	newChatUpstream(t, "It is hunter2.", func(req *ChatCompletionRequest) {
+		forwarded = req
+	})
+	t.Setenv("MEMOS_AI_SYSTEM_PROMPT", "Be brief.")
+	s := NewAIService(ts, "secret", "test-key")
+
+	body := fmt.Sprintf(`{"messages":[{"role":"user","content":"What is the wifi password?"}],"memo_ids":[%d]}`, memo.ID)
+	c, _ := newTestContext(body)
+	c.Set(currentUserContextKey, owner)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Len(t, forwarded.Messages, 3)
+	require.Equal(t, "Be brief.", forwarded.Messages[0].Content)
+	require.Equal(t, roleSystem, forwarded.Messages[1].Role)
+	require.Contains(t, forwarded.Messages[1].Content, "The wifi password is hunter2.")
+	require.Nil(t, forwarded.MemoIDs)
+
+	// Another user's memo is not found.
+	c, _ = newTestContext(body)
+	c.Set(currentUserContextKey, other)
+	httpErr, ok := s.ChatCompletion(c).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusNotFound, httpErr.Code)
+}
+
+func TestLoadMemoContextTruncates(t *testing.T) {
+	ctx := context.Background()
+	ts := teststore.NewTestingStore(ctx, t)
+	defer ts.Close()
+	user, err := ts.CreateUser(ctx, &store.User{Username: "writer", Role: store.RoleUser, Email: "writer@test.com"})
+	require.NoError(t, err)
+	var ids []int32
+	for i, content := range []string{strings.Repeat("é", maxMemoContextBytes/2-10), strings.Repeat("b", maxMemoContextBytes)} {
+		memo, err := ts.CreateMemo(ctx, &store.Memo{UID: fmt.Sprintf("memo-%d", i), CreatorID: user.ID, Content: content, Visibility: store.Private})
+		require.NoError(t, err)
+		ids = append(ids, memo.ID)
+	}
+	s := NewAIService(ts, "secret", "test-key")
+
+	memoContext, _, err := s.loadMemoContext(ctx, user, ids, "gpt-4o")
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(memoContext, memoContextTruncatedNote))
+	require.LessOrEqual(t, len(memoContext), len(memoContextHeader)+maxMemoContextBytes+len(memoContextTruncatedNote))
+}
+
+func TestLoadMemoContextGuardsAdversarialMemos(t *testing.T) {
+	ctx := context.Background()
+	ts := teststore.NewTestingStore(ctx, t)
+	defer ts.Close()
+	user, err := ts.CreateUser(ctx, &store.User{Username: "writer", Role: store.RoleUser, Email: "writer@test.com"})
+	require.NoError(t, err)
+	var ids []int32
+	for i, content := range injectionAttempts {
+		memo, err := ts.CreateMemo(ctx, &store.Memo{UID: fmt.Sprintf("memo-%d", i), CreatorID: user.ID, Content: content, Visibility: store.Private})
+		require.NoError(t, err)
+		ids = append(ids, memo.ID)
+	}
+	s := NewAIService(ts, "secret", "test-key")
+
+	memoContext, _, err := s.loadMemoContext(ctx, user, ids, "gpt-4o")
+	require.NoError(t, err)
+	memos, found := strings.CutPrefix(memoContext, memoContextHeader)
+	require.True(t, found)
+	require.Contains(t, memoContext, contextGuardPrompt)
+	// Each memo opens and closes exactly one block; none can close its block early.
+	require.Len(t, contextDelimiterPattern.FindAllString(memos, -1), 2*len(ids))
+	require.Equal(t, len(ids), strings.Count(memos, "\n</data>\n"))
+}
+
+func TestTruncateBytes(t *testing.T) {
+	require.Equal(t, "abc", truncateBytes("abc", 5))
+	require.Equal(t, "a", truncateBytes("aé", 2))
+	require.Equal(t, "aé", truncateBytes("aé", 3))
+}
+
+func TestChatCompletionCapsMemoContextTokens(t *testing.T) {
+	ctx := context.Background()
+	ts := teststore.NewTestingStore(ctx, t)
+	defer ts.Close()
+	user, err := ts.CreateUser(ctx, &store.User{Username: "writer", Role: store.RoleUser, Email: "writer@test.com"})
+	require.NoError(t, err)
+	var ids []int32
+	for i, content := range []string{"First memo.", strings.Repeat("filler ", 500), "Last memo."} {
+		memo, err := ts.CreateMemo(ctx, &store.Memo{UID: fmt.Sprintf("memo-%d", i), CreatorID: user.ID, Content: content, Visibility: store.Private})
+		require.NoError(t, err)
+		ids = append(ids, memo.ID)
+	}
+
+	var forwarded *ChatCompletionRequest
+	newChatUpstream(t, "ok", func(req *ChatCompletionRequest) {
+		forwarded = req
+	})
+	t.Setenv("MEMOS_AI_MAX_CONTEXT_TOKENS", "100")
+	s := NewAIService(ts, "secret", "test-key")
+
+	body := fmt.Sprintf(`{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}],"memo_ids":[%d,%d,%d]}`, ids[0], ids[1], ids[2])
+	c, rec := newTestContext(body)
+	c.Set(currentUserContextKey, user)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, "3", rec.Header().Get(headerXAIMemosRequested))
+	require.Equal(t, "1", rec.Header().Get(headerXAIMemosIncluded))
+	memoContext := forwarded.Messages[0].Content
+	require.Contains(t, memoContext, "First memo.")
+	require.NotContains(t, memoContext, "filler")
+	require.NotContains(t, memoContext, "Last memo.")
+	require.True(t, strings.HasSuffix(memoContext, memoContextTruncatedNote))
+}
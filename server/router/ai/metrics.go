@@ -0,0 +1,191 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Request outcomes in addition to the stable error codes carried by normalized errors.
+const (
+	outcomeSuccess       = "success"
+	outcomeTimeout       = "timeout"
+	outcomeClientError   = "client_error"
+	outcomeInternalError = "internal_error"
+)
+
+var (
+	promptTokensTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "memos",
+		Subsystem: "ai",
+		Name:      "prompt_tokens_total",
+		Help:      "Total number of prompt tokens sent to the AI provider.",
+	}, []string{"model"})
+	completionTokensTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "memos",
+		Subsystem: "ai",
+		Name:      "completion_tokens_total",
+		Help:      "Total number of completion tokens returned by the AI provider.",
+	}, []string{"model"})
+	tokensTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "memos",
+		Subsystem: "ai",
+		Name:      "tokens_total",
+		Help:      "Total number of tokens consumed by AI requests.",
+	}, []string{"model"})
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "memos",
+		Subsystem: "ai",
+		Name:      "requests_total",
+		Help:      "Total number of AI requests by endpoint, method and outcome.",
+	}, []string{"endpoint", "method", "outcome"})
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "memos",
+		Subsystem: "ai",
+		Name:      "request_duration_seconds",
+		Help:      "Total duration of AI requests by endpoint, method and outcome.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"endpoint", "method", "outcome"})
+	streamFirstByteDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "memos",
+		Subsystem: "ai",
+		Name:      "stream_first_byte_seconds",
+		Help:      "Time until the first byte of a streaming AI response was written.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"endpoint", "method"})
+)
+
+// RegisterMetrics registers the AI service metrics with reg.
+func RegisterMetrics(reg *prometheus.Registry) {
+	reg.MustRegister(promptTokensTotal, completionTokensTotal, tokensTotal, requestsTotal, requestDuration, streamFirstByteDuration, slowRequestsTotal)
+}
+
+// metricsMiddleware records the rate, duration and outcome of AI requests per endpoint and
+// method.
+// Streaming responses additionally record the time to their first byte. Requests slower
+// than slowThreshold are logged with the models and tokens they used.
+func (s *AIService) metricsMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		endpoint, method := metricsEndpoint(c.Path()), c.Request().Method
+		writer := &firstByteWriter{ResponseWriter: c.Response().Writer}
+		c.Response().Writer = writer
+		ctx, stats := withRequestStats(c.Request().Context())
+		c.SetRequest(c.Request().WithContext(ctx))
+		err := next(c)
+
+		duration := time.Since(start)
+		outcome := requestOutcome(c, err)
+		requestsTotal.WithLabelValues(endpoint, method, outcome).Inc()
+		requestDuration.WithLabelValues(endpoint, method, outcome).Observe(duration.Seconds())
+		var firstByte time.Duration
+		if !writer.firstByte.IsZero() && strings.HasPrefix(c.Response().Header().Get(echo.HeaderContentType), "text/event-stream") {
+			firstByte = writer.firstByte.Sub(start)
+			streamFirstByteDuration.WithLabelValues(endpoint, method).Observe(firstByte.Seconds())
+		}
+		s.logSlowRequest(c, endpoint, stats, duration, firstByte)
+		return err
+	}
+}
+
+// metricsEndpoint returns the route pattern relative to the /ai group, such as
+// "chat_completion" or "jobs/:id", so routes with parameters keep distinct labels.
+func metricsEndpoint(route string) string {
+	if _, endpoint, ok := strings.Cut(route, "/ai/"); ok {
+		return endpoint
+	}
+	return path.Base(route)
+}
+
+// requestOutcome classifies a finished request by the error code of a normalized upstream
+// error, or by its status code otherwise.
+func requestOutcome(c echo.Context, err error) string {
+	status := c.Response().Status
+	if err != nil {
+		httpErr, ok := err.(*echo.HTTPError)
+		if !ok {
+			return outcomeInternalError
+		}
+		if response, ok := httpErr.Message.(*ErrorResponse); ok && response.Error != nil {
+			return response.Error.Code
+		}
+		status = httpErr.Code
+	}
+	switch {
+	case status == http.StatusTooManyRequests:
+		return errorCodeRateLimited
+	case status == http.StatusGatewayTimeout:
+		return outcomeTimeout
+	case status >= http.StatusInternalServerError:
+		return errorCodeUpstream
+	case status >= http.StatusBadRequest:
+		return outcomeClientError
+	default:
+		return outcomeSuccess
+	}
+}
+
+// firstByteWriter notes when the first byte of the response body is written. SSE comments
+// such as keep-alives carry no reply, so they do not count.
+type firstByteWriter struct {
+	http.ResponseWriter
+	firstByte time.Time
+}
+
+func (w *firstByteWriter) Write(b []byte) (int, error) {
+	if w.firstByte.IsZero() && len(b) > 0 && b[0] != ':' {
+		w.firstByte = time.Now()
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer to flush streams.
+func (w *firstByteWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Usage is the token accounting reported by the provider for a completion.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// parseUsage extracts the usage object from an OpenAI-style response body or stream chunk.
+// It returns nil when the body carries no usage.
+func parseUsage(body []byte) *Usage {
+	if !bytes.Contains(body, []byte(`"usage"`)) {
+		return nil
+	}
+	var envelope struct {
+		Usage *Usage `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil
+	}
+	return envelope.Usage
+}
+
+// recordUsage adds the token counts of a completion to the usage counters, to the
+// request's running total used for quota accounting and to its stats.
+func recordUsage(ctx context.Context, model string, usage *Usage) {
+	if usage == nil {
+		return
+	}
+	if requestUsage, ok := ctx.Value(requestUsageKey{}).(*requestUsage); ok {
+		requestUsage.add(model, usage)
+	}
+	if stats, ok := ctx.Value(requestStatsKey{}).(*requestStats); ok {
+		stats.add(model, usage)
+	}
+	promptTokensTotal.WithLabelValues(model).Add(float64(usage.PromptTokens))
+	completionTokensTotal.WithLabelValues(model).Add(float64(usage.CompletionTokens))
+	tokensTotal.WithLabelValues(model).Add(float64(usage.TotalTokens))
+}
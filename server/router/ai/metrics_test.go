@@ -0,0 +1,120 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	require.NotPanics(t, func() { RegisterMetrics(reg) })
+}
+
+func TestChatCompletionRecordsUsage(t *testing.T) {
+	const model = "usage-test-model"
+	newChatUpstream(t, "hello", nil)
+	s := NewAIService(nil, "", "test-key")
+
+	before := testutil.ToFloat64(tokensTotal.WithLabelValues(model))
+	c, _ := newTestContext(`{"model":"` + model + `","messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	// The fake upstream reports no usage, so nothing is added.
+	require.Equal(t, before, testutil.ToFloat64(tokensTotal.WithLabelValues(model)))
+
+	recordUsage(context.Background(), model, &Usage{PromptTokens: 1, CompletionTokens: 2, TotalTokens: 3})
+	require.Equal(t, before+3, testutil.ToFloat64(tokensTotal.WithLabelValues(model)))
+	require.Equal(t, float64(2), testutil.ToFloat64(completionTokensTotal.WithLabelValues(model)))
+}
+
+func TestChatCompletionStreamRecordsUsage(t *testing.T) {
+	const model = "stream-usage-test-model"
+	const usageChunk = "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":2,\"completion_tokens\":3,\"total_tokens\":5}}\n\n"
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := new(ChatCompletionRequest)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		require.NotNil(t, req.StreamOptions)
+		require.True(t, req.StreamOptions.IncludeUsage)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\n")
+		fmt.Fprint(w, usageChunk)
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	s := NewAIService(nil, "", "test-key")
+
+	before := testutil.ToFloat64(tokensTotal.WithLabelValues(model))
+	c, rec := newTestContext(`{"model":"` + model + `","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	ctx, usage := withRequestUsage(c.Request().Context(), nil)
+	c.SetRequest(c.Request().WithContext(ctx))
+	require.NoError(t, s.ChatCompletion(c))
+
+	require.Contains(t, rec.Body.String(), usageChunk)
+	require.Equal(t, before+5, testutil.ToFloat64(tokensTotal.WithLabelValues(model)))
+	tokens, _ := usage.totals()
+	require.Equal(t, int64(5), tokens)
+}
+
+func TestMetricsMiddlewareRecordsOutcome(t *testing.T) {
+	tests := []struct {
+		err     error
+		outcome string
+	}{
+		{nil, outcomeSuccess},
+		{echo.NewHTTPError(http.StatusGatewayTimeout, "timeout"), outcomeTimeout},
+		{echo.NewHTTPError(http.StatusTooManyRequests, "slow down"), errorCodeRateLimited},
+		{echo.NewHTTPError(http.StatusBadRequest, "bad"), outcomeClientError},
+		{echo.NewHTTPError(http.StatusBadGateway, &ErrorResponse{Error: &ErrorDetail{Code: errorCodeInvalidAPIKey}}), errorCodeInvalidAPIKey},
+		{errors.New("boom"), outcomeInternalError},
+	}
+	for _, test := range tests {
+		c, _ := newTestContext("")
+		c.SetPath("/api/v1/ai/metrics_test")
+		before := testutil.ToFloat64(requestsTotal.WithLabelValues("metrics_test", http.MethodPost, test.outcome))
+		err := NewAIService(nil, "", "").metricsMiddleware(func(c echo.Context) error {
+			if test.err == nil {
+				return c.NoContent(http.StatusOK)
+			}
+			return test.err
+		})(c)
+		require.Equal(t, test.err, err)
+		require.Equal(t, before+1, testutil.ToFloat64(requestsTotal.WithLabelValues("metrics_test", http.MethodPost, test.outcome)), test.outcome)
+	}
+}
+
+func TestMetricsMiddlewareRecordsStreamFirstByte(t *testing.T) {
+	series := testutil.CollectAndCount(streamFirstByteDuration)
+	c, rec := newTestContext("")
+	c.SetPath("/api/v1/ai/metrics_stream_test")
+	require.NoError(t, NewAIService(nil, "", "").metricsMiddleware(func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
+		c.Response().WriteHeader(http.StatusOK)
+		_, _ = c.Response().Write([]byte("data: hi\n\n"))
+		c.Response().Flush()
+		return nil
+	})(c))
+	require.True(t, rec.Flushed)
+	// The first observation creates the endpoint's series.
+	require.Equal(t, series+1, testutil.CollectAndCount(streamFirstByteDuration))
+}
+
+func TestMetricsLabelParameterizedRoutes(t *testing.T) {
+	e := echo.New()
+	NewAIService(nil, "", "").RegisterRoutes(e.Group("/api/v1"))
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		before := testutil.ToFloat64(requestsTotal.WithLabelValues("jobs/:id", method, outcomeClientError))
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/api/v1/ai/jobs/missing", nil))
+		require.Equal(t, before+1, testutil.ToFloat64(requestsTotal.WithLabelValues("jobs/:id", method, outcomeClientError)), method)
+	}
+	require.Equal(t, "cancel/:request_id", metricsEndpoint("/api/v1/ai/cancel/:request_id"))
+}
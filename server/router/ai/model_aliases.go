@@ -0,0 +1,54 @@
+package ai
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+)
+
+// githubModelsAliases map bare OpenAI model names onto their GitHub Models IDs, which are
+// namespaced by publisher.
+var githubModelsAliases = map[string]string{"gpt-4o": fallbackModel}
+
+// loadModelAliases reads MEMOS_AI_MODEL_ALIASES, a JSON object mapping friendly names to
+// provider model IDs such as {"fast": "openai/gpt-4o-mini"}. Invalid JSON is logged and
+// ignored.
+func loadModelAliases(logger *slog.Logger) map[string]string {
+	value := strings.TrimSpace(os.Getenv("MEMOS_AI_MODEL_ALIASES"))
+	if value == "" {
+		return nil
+	}
+	var aliases map[string]string
+	if err := json.Unmarshal([]byte(value), &aliases); err != nil {
+		logger.Warn("invalid MEMOS_AI_MODEL_ALIASES, aliases are disabled", "error", err)
+		return nil
+	}
+	return aliases
+}
+
+// newModelAliases builds the alias table for provider. Configured aliases take precedence
+// over the built-in GitHub Models ones. A configured alias whose model is blank or outside a
+// non-empty allowedModels is logged and dropped, since requests for it would always fail.
+func newModelAliases(logger *slog.Logger, configured map[string]string, provider Provider, allowedModels []string) map[string]string {
+	aliases := map[string]string{}
+	if isGitHubModels(provider) {
+		for alias, model := range githubModelsAliases {
+			aliases[alias] = model
+		}
+	}
+	for alias, model := range configured {
+		alias, model = strings.TrimSpace(alias), strings.TrimSpace(model)
+		if alias == "" || model == "" {
+			logger.Warn("empty model alias in MEMOS_AI_MODEL_ALIASES, ignoring it", "alias", alias, "model", model)
+			continue
+		}
+		if len(allowedModels) > 0 && !slices.Contains(allowedModels, model) {
+			logger.Warn("model alias points to a model that is not allowed, ignoring it", "alias", alias, "model", model)
+			continue
+		}
+		aliases[alias] = model
+	}
+	return aliases
+}
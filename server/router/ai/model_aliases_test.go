@@ -0,0 +1,49 @@
+package ai
+
+import (
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatCompletionResolvesModelAlias(t *testing.T) {
+	var forwarded *ChatCompletionRequest
+	newChatUpstream(t, "hi", func(req *ChatCompletionRequest) {
+		forwarded = req
+	})
+	t.Setenv("MEMOS_AI_MODEL_ALIASES", `{"fast":"openai/gpt-4o-mini","smart":"openai/gpt-4o"}`)
+	t.Setenv("MEMOS_AI_ALLOWED_MODELS", "openai/gpt-4o-mini,openai/gpt-4o")
+	s := NewAIService(nil, "", "test-key")
+
+	c, _ := newTestContext(`{"model":"fast","messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, "openai/gpt-4o-mini", forwarded.Model)
+
+	// The allowlist is checked against the resolved ID, not the alias.
+	c, _ = newTestContext(`{"model":"gpt-4o-mini","messages":[{"role":"user","content":"hi"}]}`)
+	httpErr, ok := s.ChatCompletion(c).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusBadRequest, httpErr.Code)
+}
+
+func TestNewModelAliases(t *testing.T) {
+	github := &openaiProvider{url: defaultOpenAIURL}
+	require.Equal(t, map[string]string{"gpt-4o": fallbackModel}, newModelAliases(slog.Default(), nil, github, nil))
+	require.Equal(t, map[string]string{"gpt-4o": "openai/gpt-4o-2024-11-20"},
+		newModelAliases(slog.Default(), map[string]string{"gpt-4o": "openai/gpt-4o-2024-11-20"}, github, nil))
+
+	other := &openaiProvider{url: "https://api.openai.com/v1/chat/completions"}
+	require.Equal(t, map[string]string{"fast": "gpt-4o-mini"}, newModelAliases(slog.Default(), map[string]string{
+		"fast":  "gpt-4o-mini",
+		"smart": "gpt-4o",
+		"blank": " ",
+	}, other, []string{"gpt-4o-mini"}))
+}
+
+func TestLoadModelAliasesIgnoresInvalidJSON(t *testing.T) {
+	t.Setenv("MEMOS_AI_MODEL_ALIASES", `{"fast":`)
+	require.Nil(t, loadModelAliases(slog.Default()))
+}
@@ -0,0 +1,142 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// defaultModelsCacheTTL is how long the provider's model list is cached.
+	defaultModelsCacheTTL = 5 * time.Minute
+	// fallbackModel is the default model on GitHub Models, the default OpenAI-compatible provider.
+	fallbackModel = "openai/gpt-4o"
+)
+
+// ListModelsResponse is the normalized list of model IDs returned by /ai/models.
+type ListModelsResponse struct {
+	Models []string `json:"models"`
+}
+
+// modelsCache holds the provider's model list until it expires.
+type modelsCache struct {
+	mutex     sync.Mutex
+	models    []string
+	expiresAt time.Time
+}
+
+// ListModels returns the model IDs exposed by the configured provider.
+func (s *AIService) ListModels(c echo.Context) error {
+	ctx := c.Request().Context()
+	user, err := s.getCurrentUser(ctx, c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get current user").SetInternal(err)
+	}
+	apiKey := s.resolveAPIKey(ctx, user)
+	if apiKey == "" && s.provider.RequiresAPIKey() {
+		return s.errorResponse(http.StatusServiceUnavailable, errorCodeNotConfigured, nil)
+	}
+
+	return c.JSON(http.StatusOK, &ListModelsResponse{
+		Models: s.getModels(ctx, apiKey),
+	})
+}
+
+// getModels returns the cached model list, refreshing it from the provider once the TTL has elapsed.
+func (s *AIService) getModels(ctx context.Context, apiKey string) []string {
+	s.modelsCache.mutex.Lock()
+	defer s.modelsCache.mutex.Unlock()
+
+	if s.modelsCache.models != nil && time.Now().Before(s.modelsCache.expiresAt) {
+		return s.modelsCache.models
+	}
+
+	models, err := s.fetchModels(ctx, apiKey)
+	if err != nil || len(models) == 0 {
+		s.log(ctx).Warn("failed to list AI models, using default", "error", err)
+		models = []string{s.defaultModel}
+	}
+	s.modelsCache.models = models
+	s.modelsCache.expiresAt = time.Now().Add(s.modelsCacheTTL)
+	return models
+}
+
+// fetchModels requests the model list from the provider's /models endpoint.
+func (s *AIService) fetchModels(ctx context.Context, apiKey string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	lister, ok := s.provider.(modelLister)
+	if !ok {
+		return nil, nil
+	}
+	req, err := lister.NewModelsRequest(ctx, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAllowedHost(s.allowedHosts, req.URL.String()); err != nil {
+		return nil, err
+	}
+
+	s.setExtraHeaders(ctx, req)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, echo.NewHTTPError(resp.StatusCode, "models endpoint returned an error")
+	}
+
+	body, err := s.readBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseModels(body)
+}
+
+// siblingURL derives another endpoint of an OpenAI-compatible API, such as "models"
+// or "embeddings", from its chat completions endpoint.
+func siblingURL(chatURL, endpoint string) string {
+	base := strings.TrimSuffix(strings.TrimSuffix(chatURL, "/"), "/chat/completions")
+	return base + "/" + endpoint
+}
+
+// parseModels extracts model IDs from the OpenAI envelope ({"data": [{"id": ...}]}),
+// a bare array of model objects as returned by some OpenAI-compatible providers,
+// or Ollama's tag list ({"models": [{"name": ...}]}).
+func parseModels(body []byte) ([]string, error) {
+	type model struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	var list []model
+	if err := json.Unmarshal(body, &list); err != nil {
+		var envelope struct {
+			Data   []model `json:"data"`
+			Models []model `json:"models"`
+		}
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			return nil, err
+		}
+		list = append(envelope.Data, envelope.Models...)
+	}
+
+	models := make([]string, 0, len(list))
+	for _, m := range list {
+		id := m.ID
+		if id == "" {
+			// Gemini names models as resource paths ("models/gemini-2.0-flash").
+			id = strings.TrimPrefix(m.Name, "models/")
+		}
+		if id != "" {
+			models = append(models, id)
+		}
+	}
+	return models, nil
+}
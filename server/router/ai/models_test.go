@@ -0,0 +1,82 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseModels(t *testing.T) {
+	models, err := parseModels([]byte(`{"object":"list","data":[{"id":"gpt-4o"},{"id":"gpt-4o-mini"}]}`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"gpt-4o", "gpt-4o-mini"}, models)
+
+	models, err = parseModels([]byte(`[{"id":"openai/gpt-4o"},{"id":""}]`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"openai/gpt-4o"}, models)
+
+	models, err = parseModels([]byte(`{"models":[{"name":"llama3.2:latest"}]}`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"llama3.2:latest"}, models)
+
+	_, err = parseModels([]byte(`not json`))
+	require.Error(t, err)
+}
+
+func TestSiblingURL(t *testing.T) {
+	require.Equal(t, "https://api.openai.com/v1/models", siblingURL("https://api.openai.com/v1/chat/completions", "models"))
+	require.Equal(t, "https://api.openai.com/v1/embeddings", siblingURL("https://api.openai.com/v1/chat/completions", "embeddings"))
+	require.Equal(t, "http://localhost:8080/models", siblingURL("http://localhost:8080/", "models"))
+}
+
+func TestGetModelsCachesResult(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		require.Equal(t, "/v1/models", r.URL.Path)
+		_, _ = w.Write([]byte(`{"data":[{"id":"gpt-4o"}]}`))
+	}))
+	defer upstream.Close()
+
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL+"/v1/chat/completions")
+	s := NewAIService(nil, "", "test-key")
+	require.Equal(t, []string{"gpt-4o"}, s.getModels(context.Background(), "test-key"))
+	require.Equal(t, []string{"gpt-4o"}, s.getModels(context.Background(), "test-key"))
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestGetModelsFallsBackToDefault(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	defer upstream.Close()
+
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL+"/chat/completions")
+	s := NewAIService(nil, "", "test-key")
+	// Only GitHub Models uses the namespaced default.
+	require.Equal(t, []string{"gpt-4o"}, s.getModels(context.Background(), "test-key"))
+}
+
+func TestResolveModelDefault(t *testing.T) {
+	s := NewAIService(nil, "", "test-key")
+	model, err := s.resolveModel("")
+	require.NoError(t, err)
+	require.Equal(t, fallbackModel, model)
+	model, err = s.resolveModel("gpt-4o")
+	require.NoError(t, err)
+	require.Equal(t, fallbackModel, model, "GitHub Models namespaces bare OpenAI names")
+
+	t.Setenv("MEMOS_AI_BASE_URL", "https://api.openai.com/v1/chat/completions")
+	s = NewAIService(nil, "", "test-key")
+	model, err = s.resolveModel("gpt-4o")
+	require.NoError(t, err)
+	require.Equal(t, "gpt-4o", model)
+
+	t.Setenv("MEMOS_AI_DEFAULT_MODEL", "gpt-4.1-mini")
+	s = NewAIService(nil, "", "test-key")
+	model, err = s.resolveModel("")
+	require.NoError(t, err)
+	require.Equal(t, "gpt-4.1-mini", model)
+}
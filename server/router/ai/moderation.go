@@ -0,0 +1,121 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// moderationCacheTTL is how long a moderation verdict is reused for identical content.
+	moderationCacheTTL = 5 * time.Minute
+	// moderationCacheSize bounds the number of cached moderation verdicts.
+	moderationCacheSize = 1000
+)
+
+// moderator is implemented by providers with a content moderation endpoint.
+type moderator interface {
+	NewModerationRequest(ctx context.Context, apiKey string, input string) (*http.Request, error)
+}
+
+// moderate runs the latest user message through the provider's moderation endpoint and
+// returns 422 naming the triggered categories when it is flagged. Moderation fails closed:
+// if the check itself fails, the chat request is rejected too.
+func (s *AIService) moderate(ctx context.Context, apiKey string, messages []ChatCompletionMessage) error {
+	provider, ok := s.provider.(moderator)
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotImplemented, "AI provider does not support moderation")
+	}
+	input := latestUserMessage(messages)
+	if input == "" {
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(input))
+	key := hex.EncodeToString(sum[:])
+	verdict, ok := s.moderationCache.get(key)
+	if !ok {
+		resp, err := s.send(ctx, func(ctx context.Context) (*http.Request, error) {
+			return provider.NewModerationRequest(ctx, apiKey, input)
+		})
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		body, err := s.readBody(resp.Body)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= 400 {
+			return s.upstreamError(ctx, resp.StatusCode, body)
+		}
+		categories, err := parseModeration(body)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadGateway, "Unexpected moderation response from AI provider").SetInternal(err)
+		}
+		if verdict, err = json.Marshal(categories); err != nil {
+			return err
+		}
+		s.moderationCache.put(key, verdict)
+	}
+
+	var categories []string
+	if err := json.Unmarshal(verdict, &categories); err != nil {
+		return err
+	}
+	if len(categories) == 0 {
+		return nil
+	}
+	s.log(ctx).Info("AI chat request blocked by moderation", "categories", categories)
+	return echo.NewHTTPError(http.StatusUnprocessableEntity, &ErrorResponse{Error: &ErrorDetail{
+		Code:       errorCodeContentFlagged,
+		Message:    errorMessages[errorCodeContentFlagged],
+		Categories: categories,
+	}})
+}
+
+// parseModeration returns the sorted categories flagged in an OpenAI moderation response.
+func parseModeration(body []byte) ([]string, error) {
+	var response struct {
+		Results []struct {
+			Flagged    bool            `json:"flagged"`
+			Categories map[string]bool `json:"categories"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+	categories := []string{}
+	for _, result := range response.Results {
+		if !result.Flagged {
+			continue
+		}
+		for category, flagged := range result.Categories {
+			if flagged && !slices.Contains(categories, category) {
+				categories = append(categories, category)
+			}
+		}
+		if len(categories) == 0 {
+			categories = append(categories, "unspecified")
+		}
+	}
+	slices.Sort(categories)
+	return categories, nil
+}
+
+// latestUserMessage returns the text of the last user message.
+func latestUserMessage(messages []ChatCompletionMessage) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return strings.TrimSpace(messages[i].Content)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatCompletionModeration(t *testing.T) {
+	moderationCalls, chatCalls := 0, 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moderations" {
+			moderationCalls++
+			var req map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			flagged := req["input"] == "something hateful"
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"results": []map[string]any{{"flagged": flagged, "categories": map[string]bool{"hate": flagged, "violence": false}}},
+			})
+			return
+		}
+		chatCalls++
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL+"/chat/completions")
+	t.Setenv("MEMOS_AI_MODERATION", "true")
+	s := NewAIService(nil, "", "test-key")
+
+	for i := 0; i < 2; i++ {
+		c, _ := newTestContext(`{"messages":[{"role":"user","content":"something hateful"}]}`)
+		err := s.ChatCompletion(c)
+		httpErr, ok := err.(*echo.HTTPError)
+		require.True(t, ok)
+		require.Equal(t, http.StatusUnprocessableEntity, httpErr.Code)
+		response, ok := httpErr.Message.(*ErrorResponse)
+		require.True(t, ok)
+		require.Equal(t, errorCodeContentFlagged, response.Error.Code)
+		require.Equal(t, []string{"hate"}, response.Error.Categories)
+		require.NotContains(t, response.Error.Message, "hateful")
+	}
+	require.Equal(t, 1, moderationCalls, "verdicts are cached")
+	require.Equal(t, 0, chatCalls)
+
+	c, rec := newTestContext(`{"messages":[{"role":"user","content":"hello"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, 1, chatCalls)
+}
+
+func TestParseModeration(t *testing.T) {
+	categories, err := parseModeration([]byte(`{"results":[{"flagged":true,"categories":{"violence":true,"hate":true,"sexual":false}}]}`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"hate", "violence"}, categories)
+
+	categories, err = parseModeration([]byte(`{"results":[{"flagged":false,"categories":{"hate":false}}]}`))
+	require.NoError(t, err)
+	require.Empty(t, categories)
+}
@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// acceptsPlainText reports whether an Accept header prefers text/plain over JSON. JSON
+// stays the default, so plain text must be ranked strictly higher, for example by
+// "Accept: text/plain".
+func acceptsPlainText(header string) bool {
+	text, json := -1.0, -1.0
+	textSpecificity, jsonSpecificity := -1, -1
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if value, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		// The most specific range matching a type decides its quality.
+		if specificity, ok := mediaRangeMatch(mediaType, "text/plain"); ok && specificity > textSpecificity {
+			text, textSpecificity = quality, specificity
+		}
+		if specificity, ok := mediaRangeMatch(mediaType, echo.MIMEApplicationJSON); ok && specificity > jsonSpecificity {
+			json, jsonSpecificity = quality, specificity
+		}
+	}
+	return text > 0 && text > json
+}
+
+// mediaRangeMatch reports whether the media range of an Accept header matches mediaType,
+// and how specific it is: 2 for an exact match, 1 for "type/*" and 0 for "*/*".
+func mediaRangeMatch(mediaRange, mediaType string) (int, bool) {
+	kind, _, _ := strings.Cut(mediaType, "/")
+	switch mediaRange {
+	case mediaType:
+		return 2, true
+	case kind + "/*":
+		return 1, true
+	case "*/*":
+		return 0, true
+	}
+	return 0, false
+}
+
+// respondText sends the text derived by an endpoint: as text/plain when the client asks
+// for it with its Accept header, or else as the endpoint's JSON response. Errors are
+// returned as JSON either way.
+func respondText(c echo.Context, text string, response any) error {
+	if acceptsPlainText(c.Request().Header.Get(echo.HeaderAccept)) {
+		return c.String(http.StatusOK, text)
+	}
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,48 @@
+package ai
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptsPlainText(t *testing.T) {
+	tests := map[string]bool{
+		"":                                   false,
+		"*/*":                                false,
+		"application/json":                   false,
+		"text/plain":                         true,
+		"text/*":                             true,
+		"text/plain; charset=utf-8":          true,
+		"application/json, text/plain":       false,
+		"text/plain, application/json;q=0.5": true,
+		"text/plain;q=0.2, */*;q=0.5":        false,
+		"text/plain;q=0":                     false,
+		"text/html, */*;q=0.1":               false,
+	}
+	for header, want := range tests {
+		require.Equal(t, want, acceptsPlainText(header), header)
+	}
+}
+
+func TestSummarizePlainText(t *testing.T) {
+	newChatUpstream(t, " A short summary. ", nil)
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"content":"a long memo"}`)
+	c.Request().Header.Set(echo.HeaderAccept, "text/plain")
+	require.NoError(t, s.Summarize(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, echo.MIMETextPlainCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	require.Equal(t, "A short summary.", rec.Body.String())
+
+	// Errors keep the JSON envelope.
+	e := echo.New()
+	c, rec = newTestContext(`{"content":""}`)
+	c.Request().Header.Set(echo.HeaderAccept, "text/plain")
+	e.HTTPErrorHandler(s.Summarize(c), c)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+}
@@ -0,0 +1,54 @@
+package ai
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// ModelPrice is the price of a model per 1,000 tokens, in whatever currency the admin uses.
+type ModelPrice struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// pricingTable maps model names to their prices.
+type pricingTable map[string]ModelPrice
+
+// loadPricing reads MEMOS_AI_PRICING, a JSON object mapping model names to prices such as
+// {"gpt-4o": {"input": 0.0025, "output": 0.01}}. Invalid JSON is logged and ignored.
+func loadPricing(logger *slog.Logger) pricingTable {
+	value := strings.TrimSpace(os.Getenv("MEMOS_AI_PRICING"))
+	if value == "" {
+		return nil
+	}
+	var pricing pricingTable
+	if err := json.Unmarshal([]byte(value), &pricing); err != nil {
+		logger.Warn("invalid MEMOS_AI_PRICING, costs will not be computed", "error", err)
+		return nil
+	}
+	for model, price := range pricing {
+		if price.Input < 0 || price.Output < 0 {
+			logger.Warn("negative price in MEMOS_AI_PRICING, ignoring model", "model", model)
+			delete(pricing, model)
+		}
+	}
+	return pricing
+}
+
+// cost returns the estimated cost of usage on model. Models missing from the table cost
+// nothing. A "provider/" prefix is ignored when the full name is not listed, so a price
+// for "gpt-4o" also covers "openai/gpt-4o".
+func (p pricingTable) cost(model string, usage *Usage) float64 {
+	if usage == nil {
+		return 0
+	}
+	price, ok := p[model]
+	if !ok {
+		if _, name, found := strings.Cut(model, "/"); found {
+			price = p[name]
+		}
+	}
+	return float64(usage.PromptTokens)/1000*price.Input + float64(usage.CompletionTokens)/1000*price.Output
+}
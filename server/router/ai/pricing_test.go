@@ -0,0 +1,26 @@
+package ai
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPricingCost(t *testing.T) {
+	pricing := pricingTable{"gpt-4o": {Input: 0.0025, Output: 0.01}}
+	usage := &Usage{PromptTokens: 2000, CompletionTokens: 500, TotalTokens: 2500}
+	require.InDelta(t, 0.01, pricing.cost("gpt-4o", usage), 1e-9)
+	require.InDelta(t, 0.01, pricing.cost("openai/gpt-4o", usage), 1e-9)
+	require.Zero(t, pricing.cost("llama3", usage))
+	require.Zero(t, pricing.cost("gpt-4o", nil))
+	require.Zero(t, pricingTable(nil).cost("gpt-4o", usage))
+}
+
+func TestLoadPricing(t *testing.T) {
+	t.Setenv("MEMOS_AI_PRICING", `{"gpt-4o": {"input": 0.0025, "output": 0.01}, "bad": {"input": -1}}`)
+	require.Equal(t, pricingTable{"gpt-4o": {Input: 0.0025, Output: 0.01}}, loadPricing(slog.Default()))
+
+	t.Setenv("MEMOS_AI_PRICING", `not json`)
+	require.Nil(t, loadPricing(slog.Default()))
+}
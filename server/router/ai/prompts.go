@@ -0,0 +1,167 @@
+package ai
+
+import (
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+// Prompt template names. An admin overrides one by placing <name>.tmpl in MEMOS_AI_PROMPTS_DIR.
+const (
+	promptSummarize       = "summarize"
+	promptTitle           = "title"
+	promptTranslate       = "translate"
+	promptSuggestTags     = "suggest_tags"
+	promptExpand          = "expand"
+	promptFollowups       = "followups"
+	promptActionItems     = "action_items"
+	promptDiffSummary     = "diff_summary"
+	promptCleanTranscript = "clean_transcript"
+)
+
+// summarizePromptData is the data available to the summarize template.
+type summarizePromptData struct {
+	MaxWords int
+}
+
+// titlePromptData is the data available to the title template.
+type titlePromptData struct {
+	MaxChars int
+}
+
+// translatePromptData is the data available to the translate template.
+type translatePromptData struct {
+	// TargetLang is a validated BCP-47 language tag.
+	TargetLang string
+}
+
+// suggestTagsPromptData is the data available to the suggest_tags template; it has no fields.
+type suggestTagsPromptData struct{}
+
+// followupsPromptData is the data available to the followups template.
+type followupsPromptData struct {
+	Count int
+}
+
+// actionItemsPromptData is the data available to the action_items template.
+type actionItemsPromptData struct {
+	MaxItems int
+}
+
+// diffSummaryPromptData is the data available to the diff_summary template; it has no fields.
+type diffSummaryPromptData struct{}
+
+// cleanTranscriptPromptData is the data available to the clean_transcript template; it
+// has no fields.
+type cleanTranscriptPromptData struct{}
+
+// expandPromptData is the data available to the expand template.
+type expandPromptData struct {
+	// Tone is one of expandTones.
+	Tone string
+}
+
+const (
+	summarizePrompt = "You summarize notes. Write a concise summary of the user's note in at most {{.MaxWords}} words. " +
+		"Reply with the summary only, without any preamble."
+	titlePrompt = "You write titles for notes. Write a short, descriptive title for the user's note in fewer than {{.MaxChars}} characters. " +
+		"Reply with the title only, without quotes or trailing punctuation."
+	translatePrompt = `You are a translator. Translate the user's note into the language identified by the BCP-47 tag {{printf "%q" .TargetLang}}. ` +
+		"Preserve Markdown formatting, code blocks, links and tags. Reply with the translation only, without any preamble."
+	suggestTagsPrompt = "You suggest tags for notes. Reply with only a JSON object whose \"tags\" field lists 3 to 5 " +
+		"short, lowercase tags that describe the user's note, for example {\"tags\": [\"work\", \"ideas\"]}. " +
+		"Do not include the '#' character."
+	expandPrompt = "You are a writing assistant. Expand the user's short note into a fuller draft in a {{.Tone}} tone, " +
+		"keeping its meaning and Markdown formatting. Keep every #tag from the note exactly as written; do not remove, rename or add tags. " +
+		"Reply with the draft only, without any preamble."
+	followupsPrompt = "You suggest follow-up questions. Given the assistant's latest message in a conversation, write {{.Count}} short " +
+		"questions the user might ask next. Reply with only a JSON array of strings, for example [\"What are the next steps?\"]."
+	actionItemsPrompt = "You extract action items from notes. Reply with only a JSON object whose \"items\" field lists at most {{.MaxItems}} " +
+		"tasks, to-dos and follow-ups from the user's note, each an object with a \"text\" field describing the task and a \"done\" field " +
+		"that is true only when the note marks the task as completed, for example {\"items\": [{\"text\": \"Send the slides to Anna\", \"done\": false}]}. " +
+		"Reply with {\"items\": []} when the note has no action items."
+	diffSummaryPrompt = "You describe how a note was edited. The user's message is a line diff between two versions of the note: " +
+		"lines starting with \"-\" were removed, lines starting with \"+\" were added, and the other lines are unchanged context. " +
+		"In a few sentences, summarize the meaningful changes, ignoring whitespace and formatting-only edits. " +
+		"Reply with the summary only, without any preamble."
+	// cleanTranscriptPrompt restricts the model to tidying a dictated note so the memo keeps
+	// the speaker's words.
+	cleanTranscriptPrompt = "You clean up voice transcripts. Remove filler words such as \"um\", \"uh\", \"you know\" and \"like\" " +
+		"when used as fillers, as well as false starts and accidental repetitions, and restore punctuation, capitalization and paragraph breaks. " +
+		"Keep the speaker's wording otherwise exactly as it is: do not summarize, reword, translate, answer questions or add content. " +
+		"The transcript may be an excerpt of a longer one that starts or ends mid-thought. " +
+		"Reply with the cleaned text only, without any explanation, preamble or surrounding quotes."
+)
+
+// builtinPrompts are the default templates and sample data used to check that a template
+// only refers to fields its data provides.
+var builtinPrompts = map[string]struct {
+	text   string
+	sample any
+}{
+	promptSummarize:       {summarizePrompt, summarizePromptData{MaxWords: defaultSummaryWords}},
+	promptTitle:           {titlePrompt, titlePromptData{MaxChars: defaultTitleMaxChars}},
+	promptTranslate:       {translatePrompt, translatePromptData{TargetLang: "en"}},
+	promptSuggestTags:     {suggestTagsPrompt, suggestTagsPromptData{}},
+	promptExpand:          {expandPrompt, expandPromptData{Tone: defaultExpandTone}},
+	promptFollowups:       {followupsPrompt, followupsPromptData{Count: maxFollowups}},
+	promptActionItems:     {actionItemsPrompt, actionItemsPromptData{MaxItems: maxActionItems}},
+	promptDiffSummary:     {diffSummaryPrompt, diffSummaryPromptData{}},
+	promptCleanTranscript: {cleanTranscriptPrompt, cleanTranscriptPromptData{}},
+}
+
+// promptTemplates holds the parsed prompt template for each endpoint.
+type promptTemplates struct {
+	templates map[string]*template.Template
+}
+
+// loadPromptTemplates parses the built-in prompts, replacing each with <name>.tmpl from dir
+// when that file exists. Every template is executed once with sample data so mistakes such
+// as unknown fields are reported at startup rather than on the first request.
+func loadPromptTemplates(dir string) (*promptTemplates, error) {
+	prompts := &promptTemplates{templates: map[string]*template.Template{}}
+	for name, builtin := range builtinPrompts {
+		text := builtin.text
+		if dir != "" {
+			data, err := os.ReadFile(filepath.Join(dir, name+".tmpl"))
+			if err == nil {
+				text = string(data)
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return nil, errors.Wrapf(err, "failed to read prompt template %s", name)
+			}
+		}
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid prompt template %s", name)
+		}
+		if err := tmpl.Execute(&strings.Builder{}, builtin.sample); err != nil {
+			return nil, errors.Wrapf(err, "invalid prompt template %s", name)
+		}
+		prompts.templates[name] = tmpl
+	}
+	return prompts, nil
+}
+
+// render executes the named prompt template with data.
+func (p *promptTemplates) render(name string, data any) (string, error) {
+	var prompt strings.Builder
+	if err := p.templates[name].Execute(&prompt, data); err != nil {
+		return "", errors.Wrapf(err, "failed to render prompt template %s", name)
+	}
+	return strings.TrimSpace(prompt.String()), nil
+}
+
+// prompt renders the named prompt template for a request.
+func (s *AIService) prompt(name string, data any) (string, error) {
+	prompt, err := s.prompts.render(name, data)
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusInternalServerError, "Failed to build prompt").SetInternal(err)
+	}
+	return prompt, nil
+}
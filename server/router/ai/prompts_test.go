@@ -0,0 +1,61 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPromptTemplatesBuiltin(t *testing.T) {
+	prompts, err := loadPromptTemplates("")
+	require.NoError(t, err)
+
+	prompt, err := prompts.render(promptTranslate, translatePromptData{TargetLang: "pt-BR"})
+	require.NoError(t, err)
+	require.Contains(t, prompt, `BCP-47 tag "pt-BR"`)
+	prompt, err = prompts.render(promptSuggestTags, suggestTagsPromptData{})
+	require.NoError(t, err)
+	require.Equal(t, suggestTagsPrompt, prompt)
+}
+
+func TestLoadPromptTemplatesOverride(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "summarize.tmpl"), []byte("Summarize in {{.MaxWords}} words, like a pirate.\n"), 0o600))
+
+	prompts, err := loadPromptTemplates(dir)
+	require.NoError(t, err)
+	prompt, err := prompts.render(promptSummarize, summarizePromptData{MaxWords: 20})
+	require.NoError(t, err)
+	require.Equal(t, "Summarize in 20 words, like a pirate.", prompt)
+	// Templates without a file keep their built-in text.
+	prompt, err = prompts.render(promptTitle, titlePromptData{MaxChars: 40})
+	require.NoError(t, err)
+	require.Contains(t, prompt, "fewer than 40 characters")
+}
+
+func TestLoadPromptTemplatesRejectsInvalid(t *testing.T) {
+	for name, text := range map[string]string{
+		"parse error":   "Summarize in {{.MaxWords words.",
+		"unknown field": "Summarize in {{.Words}} words.",
+	} {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "summarize.tmpl"), []byte(text), 0o600))
+		_, err := loadPromptTemplates(dir)
+		require.Error(t, err, name)
+	}
+}
+
+func TestSummarizeUsesPromptTemplate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "summarize.tmpl"), []byte("Custom summary prompt, {{.MaxWords}} words."), 0o600))
+	t.Setenv("MEMOS_AI_PROMPTS_DIR", dir)
+	newChatUpstream(t, "short", func(req *ChatCompletionRequest) {
+		require.Equal(t, withContextGuard("Custom summary prompt, 10 words."), req.Messages[0].Content)
+	})
+	s := NewAIService(nil, "", "test-key")
+
+	c, _ := newTestContext(`{"content":"A long note.","max_words":10}`)
+	require.NoError(t, s.Summarize(c))
+}
@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// proofreadPrompt restricts the model to spelling and grammar fixes so the memo keeps its wording.
+const proofreadPrompt = "You are a proofreader. Correct only spelling, grammar and punctuation mistakes in the user's note. " +
+	"Do not reword, rephrase, summarize or add content, and keep all Markdown formatting exactly as it is. " +
+	"Reply with the corrected text only, without any explanation, preamble or surrounding quotes. " +
+	"If there is nothing to correct, reply with the note unchanged."
+
+type ProofreadRequest struct {
+	Content string `json:"content"`
+}
+
+type ProofreadResponse struct {
+	Corrected string `json:"corrected"`
+}
+
+// Proofread fixes spelling and grammar in the given memo content without rewording it. The
+// correction is returned as plain text when the client sends "Accept: text/plain".
+func (s *AIService) Proofread(c echo.Context) error {
+	ctx := c.Request().Context()
+	apiKey, err := s.requireAPIKey(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	request := new(ProofreadRequest)
+	if err := c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	}
+	if err := s.validateContent(request.Content); err != nil {
+		return err
+	}
+
+	model, err := s.resolveModel("")
+	if err != nil {
+		return err
+	}
+	corrected, err := s.complete(ctx, apiKey, &ChatCompletionRequest{
+		Model: model,
+		Messages: []ChatCompletionMessage{
+			{Role: "system", Content: proofreadPrompt},
+			{Role: "user", Content: request.Content},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	corrected = acceptCorrection(request.Content, corrected)
+	return respondText(c, corrected, &ProofreadResponse{
+		Corrected: corrected,
+	})
+}
+
+// acceptCorrection returns the model's correction, or the original content when the
+// output is empty or more than twice as long, which indicates added commentary.
+func acceptCorrection(original, corrected string) string {
+	trimmed := strings.TrimSpace(corrected)
+	if trimmed == "" || len(trimmed) > 2*len(original) {
+		return original
+	}
+	// Keep the original's surrounding whitespace, which models tend to drop.
+	leading := original[:len(original)-len(strings.TrimLeft(original, " \t\r\n"))]
+	trailing := original[len(strings.TrimRight(original, " \t\r\n")):]
+	return leading + trimmed + trailing
+}
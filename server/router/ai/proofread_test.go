@@ -0,0 +1,32 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProofread(t *testing.T) {
+	newChatUpstream(t, "I **have** a cat.\n", func(req *ChatCompletionRequest) {
+		require.Equal(t, proofreadPrompt, req.Messages[0].Content)
+		require.Equal(t, "I **has** a cat.", req.Messages[1].Content)
+	})
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"content":"I **has** a cat."}`)
+	require.NoError(t, s.Proofread(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	response := new(ProofreadResponse)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), response))
+	require.Equal(t, "I **have** a cat.", response.Corrected)
+}
+
+func TestAcceptCorrection(t *testing.T) {
+	require.Equal(t, "Hello world.\n", acceptCorrection("Helo world.\n", "Hello world."))
+	require.Equal(t, "Helo", acceptCorrection("Helo", "   "))
+	require.Equal(t, "Helo", acceptCorrection("Helo", "Hello! "+strings.Repeat("I fixed it for you. ", 3)))
+}
@@ -0,0 +1,195 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	providerOpenAI    = "openai"
+	providerAnthropic = "anthropic"
+	providerOllama    = "ollama"
+	providerAzure     = "azure"
+	providerGemini    = "gemini"
+
+	// defaultOpenAIURL is the chat completions endpoint used when MEMOS_AI_BASE_URL is unset.
+	defaultOpenAIURL = "https://models.github.ai/inference/chat/completions"
+	// defaultOpenAIEmbeddingModel is the embedding model used when none is configured.
+	defaultOpenAIEmbeddingModel = "openai/text-embedding-3-small"
+)
+
+// gitHubModelsHosts are the hosts serving GitHub Models.
+var gitHubModelsHosts = []string{"models.github.ai", "models.inference.ai.azure.com"}
+
+// Provider adapts the OpenAI-style chat completion API exposed to the frontend
+// to a specific upstream AI provider.
+type Provider interface {
+	// Name identifies the provider in logs and configuration.
+	Name() string
+	// DefaultModel is the model used when a request does not specify one.
+	DefaultModel() string
+	// RequiresAPIKey reports whether requests must carry an API key.
+	RequiresAPIKey() bool
+	// NewChatRequest builds the upstream HTTP request for a chat completion.
+	NewChatRequest(ctx context.Context, apiKey string, req *ChatCompletionRequest) (*http.Request, error)
+	// ParseChatResponse converts a successful upstream response body into the
+	// OpenAI "choices" envelope the frontend expects.
+	ParseChatResponse(body []byte) ([]byte, error)
+}
+
+// modelLister is implemented by providers that can list their available models.
+type modelLister interface {
+	NewModelsRequest(ctx context.Context, apiKey string) (*http.Request, error)
+}
+
+// embedder is implemented by providers that can compute text embeddings.
+// Responses are converted to the OpenAI embeddings format.
+type embedder interface {
+	DefaultEmbeddingModel() string
+	NewEmbeddingsRequest(ctx context.Context, apiKey string, req *EmbeddingsRequest) (*http.Request, error)
+	ParseEmbeddingsResponse(body []byte) ([]byte, error)
+}
+
+// newProvider returns the provider selected by cfg.Name talking to cfg.BaseURL.
+// An empty name selects the OpenAI-compatible provider and an empty BaseURL the
+// provider's default endpoint.
+func newProvider(cfg ProviderConfig) (Provider, error) {
+	name, baseURL := cfg.Name, cfg.BaseURL
+	switch strings.ToLower(name) {
+	case "", providerOpenAI:
+		if baseURL == "" {
+			baseURL = defaultOpenAIURL
+		}
+		return &openaiProvider{url: baseURL}, nil
+	case providerAnthropic:
+		if baseURL == "" {
+			baseURL = defaultAnthropicURL
+		}
+		return &anthropicProvider{baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+	case providerOllama:
+		if baseURL == "" {
+			baseURL = defaultOllamaURL
+		}
+		return &ollamaProvider{baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+	case providerGemini:
+		if baseURL == "" {
+			baseURL = defaultGeminiURL
+		}
+		return &geminiProvider{baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+	case providerAzure:
+		return newAzureProvider(baseURL, cfg.AzureDeployment, cfg.AzureAPIVersion)
+	case providerMock:
+		return &mockProvider{}, nil
+	default:
+		return nil, errors.Errorf("unknown AI provider: %s", name)
+	}
+}
+
+// openaiProvider talks to any OpenAI-compatible chat completions endpoint, including GitHub Models.
+type openaiProvider struct {
+	// url is the full chat completions endpoint.
+	url string
+}
+
+func (*openaiProvider) Name() string {
+	return providerOpenAI
+}
+
+// DefaultModel returns GitHub Models' namespaced gpt-4o on GitHub Models and plain gpt-4o on
+// other OpenAI-compatible endpoints.
+func (p *openaiProvider) DefaultModel() string {
+	if p.isGitHubModels() {
+		return fallbackModel
+	}
+	return "gpt-4o"
+}
+
+// isGitHubModels reports whether provider talks to GitHub Models.
+func isGitHubModels(provider Provider) bool {
+	p, ok := provider.(*openaiProvider)
+	return ok && p.isGitHubModels()
+}
+
+// isGitHubModels reports whether the endpoint is GitHub Models, which namespaces model names
+// by vendor.
+func (p *openaiProvider) isGitHubModels() bool {
+	target, err := url.Parse(p.url)
+	if err != nil {
+		return false
+	}
+	return slices.Contains(gitHubModelsHosts, strings.ToLower(target.Hostname()))
+}
+
+func (*openaiProvider) RequiresAPIKey() bool {
+	return true
+}
+
+func (p *openaiProvider) NewChatRequest(ctx context.Context, apiKey string, req *ChatCompletionRequest) (*http.Request, error) {
+	jsonBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	return httpReq, nil
+}
+
+func (*openaiProvider) ParseChatResponse(body []byte) ([]byte, error) {
+	return body, nil
+}
+
+func (p *openaiProvider) NewModelsRequest(ctx context.Context, apiKey string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, siblingURL(p.url, "models"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	return req, nil
+}
+
+func (*openaiProvider) DefaultEmbeddingModel() string {
+	return defaultOpenAIEmbeddingModel
+}
+
+func (p *openaiProvider) NewEmbeddingsRequest(ctx context.Context, apiKey string, req *EmbeddingsRequest) (*http.Request, error) {
+	jsonBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, siblingURL(p.url, "embeddings"), bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	return httpReq, nil
+}
+
+func (*openaiProvider) ParseEmbeddingsResponse(body []byte) ([]byte, error) {
+	return body, nil
+}
+
+func (p *openaiProvider) NewModerationRequest(ctx context.Context, apiKey string, input string) (*http.Request, error) {
+	jsonBody, err := json.Marshal(map[string]string{"input": input})
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, siblingURL(p.url, "moderations"), bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	return httpReq, nil
+}
@@ -0,0 +1,254 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultAnthropicURL is the Anthropic API base used when MEMOS_AI_BASE_URL is unset.
+	defaultAnthropicURL = "https://api.anthropic.com"
+	// anthropicVersion is the API version sent in the anthropic-version header.
+	anthropicVersion = "2023-06-01"
+	// defaultAnthropicModel is the model used when a request does not specify one.
+	defaultAnthropicModel = "claude-sonnet-4-5"
+	// defaultAnthropicMaxTokens is sent when the caller omits max_tokens, which Anthropic requires.
+	defaultAnthropicMaxTokens = 4096
+)
+
+// anthropicProvider talks to Anthropic's Messages API.
+type anthropicProvider struct {
+	baseURL string
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model         string             `json:"model"`
+	System        string             `json:"system,omitempty"`
+	Messages      []anthropicMessage `json:"messages"`
+	MaxTokens     int                `json:"max_tokens"`
+	Temperature   *float64           `json:"temperature,omitempty"`
+	TopP          *float64           `json:"top_p,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+	Metadata      *anthropicMetadata `json:"metadata,omitempty"`
+}
+
+type anthropicMetadata struct {
+	UserID string `json:"user_id"`
+}
+
+type anthropicResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+func (*anthropicProvider) Name() string {
+	return providerAnthropic
+}
+
+func (*anthropicProvider) DefaultModel() string {
+	return defaultAnthropicModel
+}
+
+func (*anthropicProvider) RequiresAPIKey() bool {
+	return true
+}
+
+func (p *anthropicProvider) NewChatRequest(ctx context.Context, apiKey string, req *ChatCompletionRequest) (*http.Request, error) {
+	jsonBody, err := json.Marshal(toAnthropicRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v1/messages", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	p.setHeaders(httpReq, apiKey)
+	httpReq.Header.Set("Content-Type", "application/json")
+	return httpReq, nil
+}
+
+func (*anthropicProvider) ParseChatResponse(body []byte) ([]byte, error) {
+	response := new(anthropicResponse)
+	if err := json.Unmarshal(body, response); err != nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	for _, block := range response.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	return json.Marshal(map[string]any{
+		"id":      response.ID,
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   response.Model,
+		"choices": []map[string]any{
+			{
+				"index":         0,
+				"message":       map[string]any{"role": "assistant", "content": text.String()},
+				"finish_reason": anthropicFinishReason(response.StopReason),
+			},
+		},
+		"usage": map[string]any{
+			"prompt_tokens":     response.Usage.InputTokens,
+			"completion_tokens": response.Usage.OutputTokens,
+			"total_tokens":      response.Usage.InputTokens + response.Usage.OutputTokens,
+		},
+	})
+}
+
+// NewStreamDecoder decodes Anthropic's typed stream events. Text arrives in
+// content_block_delta events, the stop reason and output tokens in message_delta, and
+// message_stop ends the stream.
+func (*anthropicProvider) NewStreamDecoder(lines *streamLineReader) streamDecoder {
+	return &anthropicStreamDecoder{lines: lines}
+}
+
+type anthropicStreamDecoder struct {
+	lines       *streamLineReader
+	id          string
+	model       string
+	inputTokens int
+}
+
+type anthropicStreamEvent struct {
+	Type    string             `json:"type"`
+	Message *anthropicResponse `json:"message"`
+	Delta   struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (d *anthropicStreamDecoder) Next() (*streamDelta, error) {
+	for {
+		data, err := d.lines.nextData()
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		event := new(anthropicStreamEvent)
+		if err := json.Unmarshal(data, event); err != nil {
+			return nil, &streamChunkError{err: err}
+		}
+
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				d.id, d.model, d.inputTokens = event.Message.ID, event.Message.Model, event.Message.Usage.InputTokens
+			}
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+				return &streamDelta{ID: d.id, Model: d.model, Content: event.Delta.Text}, nil
+			}
+		case "message_delta":
+			return &streamDelta{
+				ID:           d.id,
+				Model:        d.model,
+				FinishReason: anthropicFinishReason(event.Delta.StopReason),
+				Usage: &Usage{
+					PromptTokens:     d.inputTokens,
+					CompletionTokens: event.Usage.OutputTokens,
+					TotalTokens:      d.inputTokens + event.Usage.OutputTokens,
+				},
+			}, nil
+		case "message_stop":
+			return nil, io.EOF
+		case "error":
+			if event.Error != nil {
+				return nil, errors.Errorf("anthropic stream error %s: %s", event.Error.Type, event.Error.Message)
+			}
+			return nil, errors.New("anthropic stream error")
+		}
+	}
+}
+
+func (p *anthropicProvider) NewModelsRequest(ctx context.Context, apiKey string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/v1/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	p.setHeaders(req, apiKey)
+	return req, nil
+}
+
+func (*anthropicProvider) setHeaders(req *http.Request, apiKey string) {
+	req.Header.Set("x-api-key", apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+}
+
+// toAnthropicRequest converts an OpenAI-style request. Anthropic takes the system
+// prompt as a top-level field, so system messages are lifted out of the message list.
+func toAnthropicRequest(req *ChatCompletionRequest) *anthropicRequest {
+	result := &anthropicRequest{
+		Model:         req.Model,
+		MaxTokens:     defaultAnthropicMaxTokens,
+		Temperature:   req.Temperature,
+		TopP:          req.TopP,
+		StopSequences: req.Stop,
+		Stream:        req.Stream,
+	}
+	if req.MaxTokens != nil {
+		result.MaxTokens = *req.MaxTokens
+	}
+	if req.User != "" {
+		result.Metadata = &anthropicMetadata{UserID: req.User}
+	}
+
+	var system []string
+	for _, message := range req.Messages {
+		if message.Role == "system" {
+			system = append(system, message.Content)
+			continue
+		}
+		result.Messages = append(result.Messages, anthropicMessage{Role: message.Role, Content: message.Content})
+	}
+	result.System = strings.Join(system, "\n\n")
+	return result
+}
+
+// anthropicFinishReason maps Anthropic stop reasons to OpenAI finish reasons.
+func anthropicFinishReason(stopReason string) string {
+	switch stopReason {
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	default:
+		return "stop"
+	}
+}
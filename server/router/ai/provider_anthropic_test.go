@@ -0,0 +1,86 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnthropicChatCompletion(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/messages", r.URL.Path)
+		require.Equal(t, "test-key", r.Header.Get("x-api-key"))
+		require.Equal(t, anthropicVersion, r.Header.Get("anthropic-version"))
+		require.Empty(t, r.Header.Get("Authorization"))
+
+		req := new(anthropicRequest)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		require.Equal(t, defaultAnthropicModel, req.Model)
+		require.Equal(t, "Be brief.", req.System)
+		require.Equal(t, []anthropicMessage{{Role: "user", Content: "hi"}}, req.Messages)
+		require.Equal(t, defaultAnthropicMaxTokens, req.MaxTokens)
+
+		_, _ = w.Write([]byte(`{"id":"msg_1","model":"claude-sonnet-4-5","content":[{"type":"text","text":"Hello"},{"type":"text","text":"!"}],"stop_reason":"max_tokens","usage":{"input_tokens":3,"output_tokens":2}}`))
+	}))
+	defer upstream.Close()
+
+	t.Setenv("MEMOS_AI_PROVIDER", "anthropic")
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"messages":[{"role":"system","content":"Be brief."},{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var response struct {
+		Choices []struct {
+			Message      ChatCompletionMessage `json:"message"`
+			FinishReason string                `json:"finish_reason"`
+		} `json:"choices"`
+		Usage struct {
+			TotalTokens int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.Len(t, response.Choices, 1)
+	require.Equal(t, "Hello!", response.Choices[0].Message.Content)
+	require.Equal(t, "assistant", response.Choices[0].Message.Role)
+	require.Equal(t, "length", response.Choices[0].FinishReason)
+	require.Equal(t, 5, response.Usage.TotalTokens)
+}
+
+func TestAnthropicStreamTranslation(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := new(anthropicRequest)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		require.True(t, req.Stream)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"model\":\"claude-sonnet-4-5\",\"usage\":{\"input_tokens\":3}}}\n\n"))
+		_, _ = w.Write([]byte("event: ping\ndata: {\"type\":\"ping\"}\n\n"))
+		_, _ = w.Write([]byte("event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hel\"}}\n\n"))
+		_, _ = w.Write([]byte("event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"lo\"}}\n\n"))
+		_, _ = w.Write([]byte("event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"max_tokens\"},\"usage\":{\"output_tokens\":2}}\n\n"))
+		_, _ = w.Write([]byte("event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"))
+	}))
+	defer upstream.Close()
+
+	t.Setenv("MEMOS_AI_PROVIDER", "anthropic")
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+
+	events := strings.Split(strings.TrimSpace(rec.Body.String()), "\n\n")
+	require.Len(t, events, 4)
+	require.Contains(t, events[0], `"content":"Hel"`)
+	require.Contains(t, events[0], `"id":"msg_1"`)
+	require.Contains(t, events[1], `"content":"lo"`)
+	require.Contains(t, events[2], `"finish_reason":"length"`)
+	require.Contains(t, events[2], `"total_tokens":5`)
+	require.Equal(t, "data: [DONE]", events[3])
+}
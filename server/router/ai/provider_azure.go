@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// defaultAzureAPIVersion is the Azure OpenAI API version used when MEMOS_AZURE_API_VERSION is unset.
+const defaultAzureAPIVersion = "2024-10-21"
+
+// azureProvider talks to an Azure OpenAI deployment. Request and response bodies are
+// OpenAI-compatible; only the URL layout and authentication header differ.
+type azureProvider struct {
+	// url is the deployment's chat completions endpoint including the api-version query.
+	url        string
+	deployment string
+}
+
+func newAzureProvider(endpoint, deployment, apiVersion string) (*azureProvider, error) {
+	if endpoint == "" || deployment == "" {
+		return nil, errors.New("azure provider requires MEMOS_AZURE_ENDPOINT and MEMOS_AZURE_DEPLOYMENT")
+	}
+	if apiVersion == "" {
+		apiVersion = defaultAzureAPIVersion
+	}
+	return &azureProvider{
+		url:        strings.TrimSuffix(endpoint, "/") + "/openai/deployments/" + url.PathEscape(deployment) + "/chat/completions?api-version=" + url.QueryEscape(apiVersion),
+		deployment: deployment,
+	}, nil
+}
+
+func (*azureProvider) Name() string {
+	return providerAzure
+}
+
+// DefaultModel returns the deployment name; Azure routes by deployment and ignores the model field.
+func (p *azureProvider) DefaultModel() string {
+	return p.deployment
+}
+
+func (*azureProvider) RequiresAPIKey() bool {
+	return true
+}
+
+func (p *azureProvider) NewChatRequest(ctx context.Context, apiKey string, req *ChatCompletionRequest) (*http.Request, error) {
+	jsonBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("api-key", apiKey)
+	return httpReq, nil
+}
+
+func (*azureProvider) ParseChatResponse(body []byte) ([]byte, error) {
+	return body, nil
+}
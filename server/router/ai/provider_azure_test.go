@@ -0,0 +1,41 @@
+package ai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAzureChatCompletion(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/openai/deployments/my-gpt/chat/completions", r.URL.Path)
+		require.Equal(t, "2024-06-01", r.URL.Query().Get("api-version"))
+		require.Equal(t, "test-key", r.Header.Get("api-key"))
+		require.Empty(t, r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}]}`))
+	}))
+	defer upstream.Close()
+
+	t.Setenv("MEMOS_AI_PROVIDER", "azure")
+	t.Setenv("MEMOS_AZURE_ENDPOINT", upstream.URL+"/")
+	t.Setenv("MEMOS_AZURE_DEPLOYMENT", "my-gpt")
+	t.Setenv("MEMOS_AZURE_API_VERSION", "2024-06-01")
+	s := NewAIService(nil, "", "test-key")
+	require.Equal(t, providerAzure, s.provider.Name())
+
+	c, rec := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"content":"Hello"`)
+}
+
+func TestNewAzureProviderRequiresDeployment(t *testing.T) {
+	_, err := newAzureProvider("https://example.openai.azure.com", "", "")
+	require.Error(t, err)
+
+	p, err := newAzureProvider("https://example.openai.azure.com", "gpt", "")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.openai.azure.com/openai/deployments/gpt/chat/completions?api-version="+defaultAzureAPIVersion, p.url)
+}
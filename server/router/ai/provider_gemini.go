@@ -0,0 +1,233 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultGeminiURL is the Generative Language API base used when MEMOS_AI_BASE_URL is unset.
+	defaultGeminiURL = "https://generativelanguage.googleapis.com"
+	// defaultGeminiModel is the model used when a request does not specify one.
+	defaultGeminiModel = "gemini-2.0-flash"
+)
+
+// geminiProvider talks to Google's Generative Language API.
+type geminiProvider struct {
+	baseURL string
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"topP,omitempty"`
+	MaxOutputTokens  *int     `json:"maxOutputTokens,omitempty"`
+	StopSequences    []string `json:"stopSequences,omitempty"`
+	PresencePenalty  *float64 `json:"presencePenalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequencyPenalty,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+}
+
+type geminiRequest struct {
+	Contents          []geminiContent         `json:"contents"`
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+	ModelVersion string `json:"modelVersion"`
+}
+
+func (*geminiProvider) Name() string {
+	return providerGemini
+}
+
+func (*geminiProvider) DefaultModel() string {
+	return defaultGeminiModel
+}
+
+func (*geminiProvider) RequiresAPIKey() bool {
+	return true
+}
+
+// NewChatRequest posts to :generateContent, or to :streamGenerateContent with SSE framing
+// when the request streams.
+func (p *geminiProvider) NewChatRequest(ctx context.Context, apiKey string, req *ChatCompletionRequest) (*http.Request, error) {
+	jsonBody, err := json.Marshal(toGeminiRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{"key": {apiKey}}
+	method := "generateContent"
+	if req.Stream {
+		method = "streamGenerateContent"
+		query.Set("alt", "sse")
+	}
+	model := strings.TrimPrefix(req.Model, "models/")
+	endpoint := fmt.Sprintf("%s/v1beta/models/%s:%s?%s", p.baseURL, url.PathEscape(model), method, query.Encode())
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	return httpReq, nil
+}
+
+func (*geminiProvider) ParseChatResponse(body []byte) ([]byte, error) {
+	response := new(geminiResponse)
+	if err := json.Unmarshal(body, response); err != nil {
+		return nil, err
+	}
+	content, finishReason := response.text()
+	result := map[string]any{
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   response.ModelVersion,
+		"choices": []map[string]any{
+			{
+				"index":         0,
+				"message":       map[string]any{"role": "assistant", "content": content},
+				"finish_reason": geminiFinishReason(finishReason),
+			},
+		},
+	}
+	if usage := response.usage(); usage != nil {
+		result["usage"] = usage
+	}
+	return json.Marshal(result)
+}
+
+// NewStreamDecoder decodes the SSE of a :streamGenerateContent response. Gemini has no
+// end-of-stream sentinel, so the stream ends with the chunk carrying a finish reason.
+func (*geminiProvider) NewStreamDecoder(lines *streamLineReader) streamDecoder {
+	return &geminiStreamDecoder{lines: lines}
+}
+
+type geminiStreamDecoder struct {
+	lines    *streamLineReader
+	finished bool
+}
+
+func (d *geminiStreamDecoder) Next() (*streamDelta, error) {
+	if d.finished {
+		return nil, io.EOF
+	}
+	data, err := d.lines.nextData()
+	if errors.Is(err, io.EOF) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	response := new(geminiResponse)
+	if err := json.Unmarshal(data, response); err != nil {
+		return nil, &streamChunkError{err: err}
+	}
+
+	content, finishReason := response.text()
+	delta := &streamDelta{Model: response.ModelVersion, Content: content}
+	if finishReason != "" {
+		d.finished = true
+		delta.FinishReason = geminiFinishReason(finishReason)
+		delta.Usage = response.usage()
+	}
+	return delta, nil
+}
+
+func (p *geminiProvider) NewModelsRequest(ctx context.Context, apiKey string) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/v1beta/models?"+url.Values{"key": {apiKey}}.Encode(), nil)
+}
+
+// text returns the first candidate's text and finish reason.
+func (r *geminiResponse) text() (string, string) {
+	if len(r.Candidates) == 0 {
+		return "", ""
+	}
+	var text strings.Builder
+	for _, part := range r.Candidates[0].Content.Parts {
+		text.WriteString(part.Text)
+	}
+	return text.String(), r.Candidates[0].FinishReason
+}
+
+func (r *geminiResponse) usage() *Usage {
+	if r.UsageMetadata == nil {
+		return nil
+	}
+	return &Usage{
+		PromptTokens:     r.UsageMetadata.PromptTokenCount,
+		CompletionTokens: r.UsageMetadata.CandidatesTokenCount,
+		TotalTokens:      r.UsageMetadata.TotalTokenCount,
+	}
+}
+
+// toGeminiRequest converts an OpenAI-style request. Gemini names the assistant role "model"
+// and takes system messages as a separate system instruction.
+func toGeminiRequest(req *ChatCompletionRequest) *geminiRequest {
+	result := &geminiRequest{}
+	var system []geminiPart
+	for _, message := range req.Messages {
+		switch message.Role {
+		case "system":
+			system = append(system, geminiPart{Text: message.Content})
+		case "assistant":
+			result.Contents = append(result.Contents, geminiContent{Role: "model", Parts: []geminiPart{{Text: message.Content}}})
+		default:
+			result.Contents = append(result.Contents, geminiContent{Role: "user", Parts: []geminiPart{{Text: message.Content}}})
+		}
+	}
+	if len(system) > 0 {
+		result.SystemInstruction = &geminiContent{Parts: system}
+	}
+	if req.Temperature != nil || req.TopP != nil || req.MaxTokens != nil || len(req.Stop) > 0 ||
+		req.PresencePenalty != nil || req.FrequencyPenalty != nil || req.Seed != nil {
+		result.GenerationConfig = &geminiGenerationConfig{
+			Temperature:      req.Temperature,
+			TopP:             req.TopP,
+			MaxOutputTokens:  req.MaxTokens,
+			StopSequences:    req.Stop,
+			PresencePenalty:  req.PresencePenalty,
+			FrequencyPenalty: req.FrequencyPenalty,
+			Seed:             req.Seed,
+		}
+	}
+	return result
+}
+
+// geminiFinishReason maps Gemini finish reasons to OpenAI finish reasons.
+func geminiFinishReason(finishReason string) string {
+	switch finishReason {
+	case "MAX_TOKENS":
+		return "length"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII":
+		return "content_filter"
+	default:
+		return "stop"
+	}
+}
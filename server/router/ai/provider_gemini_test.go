@@ -0,0 +1,59 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeminiChatCompletion(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1beta/models/gemini-2.0-flash:generateContent", r.URL.Path)
+		require.Equal(t, "test-key", r.URL.Query().Get("key"))
+
+		req := new(geminiRequest)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		require.Equal(t, "Be brief.", req.SystemInstruction.Parts[0].Text)
+		require.Len(t, req.Contents, 2)
+		require.Equal(t, "model", req.Contents[1].Role)
+
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"Hi "},{"text":"there"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":2,"totalTokenCount":7},"modelVersion":"gemini-2.0-flash"}`))
+	}))
+	defer upstream.Close()
+
+	t.Setenv("MEMOS_AI_PROVIDER", "gemini")
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"messages":[{"role":"system","content":"Be brief."},{"role":"user","content":"hi"},{"role":"assistant","content":"hello"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"content":"Hi there"`)
+	require.Contains(t, rec.Body.String(), `"total_tokens":7`)
+}
+
+func TestGeminiStreamTranslation(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1beta/models/gemini-2.0-flash:streamGenerateContent", r.URL.Path)
+		require.Equal(t, "sse", r.URL.Query().Get("alt"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"Hel\"}]}}]}\r\n\r\n"))
+		_, _ = w.Write([]byte("data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"lo\"}]},\"finishReason\":\"MAX_TOKENS\"}],\"usageMetadata\":{\"promptTokenCount\":1,\"candidatesTokenCount\":2,\"totalTokenCount\":3}}\r\n\r\n"))
+	}))
+	defer upstream.Close()
+
+	t.Setenv("MEMOS_AI_PROVIDER", "gemini")
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	body := rec.Body.String()
+	require.Contains(t, body, `"content":"Hel"`)
+	require.Contains(t, body, `"finish_reason":"length"`)
+	require.True(t, strings.HasSuffix(body, "data: [DONE]\n\n"))
+}
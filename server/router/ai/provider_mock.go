@@ -0,0 +1,186 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	providerMock = "mock"
+
+	// mockURL is the endpoint of the mock provider. Its scheme is one no real transport
+	// dials, so a request reaching the network by mistake fails instead of leaking.
+	mockURL = "mock://memos/chat/completions"
+	// mockModel is the model the mock provider reports.
+	mockModel = "mock"
+	// mockLorem is the reply when the conversation has no user text to echo.
+	mockLorem = "Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore et dolore magna aliqua."
+)
+
+// mockProvider answers chat completions in-process with canned replies, so the AI features
+// can be developed and tested end to end without an API key or network access
+// (MEMOS_AI_PROVIDER=mock). Its requests are served by mockTransport.
+type mockProvider struct{}
+
+func (*mockProvider) Name() string {
+	return providerMock
+}
+
+func (*mockProvider) DefaultModel() string {
+	return mockModel
+}
+
+func (*mockProvider) RequiresAPIKey() bool {
+	return false
+}
+
+func (*mockProvider) NewChatRequest(ctx context.Context, _ string, req *ChatCompletionRequest) (*http.Request, error) {
+	jsonBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, mockURL, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	return httpReq, nil
+}
+
+func (*mockProvider) ParseChatResponse(body []byte) ([]byte, error) {
+	return body, nil
+}
+
+// mockTransport is the HTTP client of the mock provider. It replies to chat completion
+// requests in the OpenAI format, streaming or not, without any network call.
+type mockTransport struct{}
+
+func (mockTransport) Do(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	if req.URL.String() != mockURL {
+		return nil, errors.Errorf("mock provider cannot serve %s", req.URL)
+	}
+	chatReq := new(ChatCompletionRequest)
+	if err := json.NewDecoder(req.Body).Decode(chatReq); err != nil {
+		return mockResponse(http.StatusBadRequest, "application/json", []byte(`{"error":{"message":"invalid request body"}}`)), nil
+	}
+	words, finishReason := mockReply(chatReq)
+	model := chatReq.Model
+	if model == "" {
+		model = mockModel
+	}
+	usage := &Usage{PromptTokens: mockPromptTokens(chatReq.Messages), CompletionTokens: len(words)}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	if chatReq.Stream {
+		return mockResponse(http.StatusOK, "text/event-stream", mockStream(model, words, finishReason, usage, chatReq.StreamOptions)), nil
+	}
+	body, err := json.Marshal(map[string]any{
+		"id":      "mock-completion",
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   model,
+		"choices": []map[string]any{
+			{
+				"index":         0,
+				"message":       map[string]any{"role": roleAssistant, "content": strings.Join(words, " ")},
+				"finish_reason": finishReason,
+			},
+		},
+		"usage": usage,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mockResponse(http.StatusOK, "application/json", body), nil
+}
+
+// mockReply returns the words of the canned reply to req and its finish reason. The reply
+// echoes the last user message, or is mockLorem when there is none. Requests for JSON get
+// an empty object. Every word counts as one token, so max_tokens truncates the reply.
+func mockReply(req *ChatCompletionRequest) ([]string, string) {
+	if len(req.ResponseFormat) > 0 && !isJSONNull(req.ResponseFormat) {
+		return []string{"{}"}, "stop"
+	}
+	text := mockLorem
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == roleUser && strings.TrimSpace(req.Messages[i].Content) != "" {
+			text = req.Messages[i].Content
+			break
+		}
+	}
+	words := strings.Fields(text)
+	limit := req.MaxTokens
+	if req.MaxCompletionTokens != nil {
+		limit = req.MaxCompletionTokens
+	}
+	if limit != nil && *limit >= 0 && *limit < len(words) {
+		return words[:*limit], "length"
+	}
+	return words, "stop"
+}
+
+// mockPromptTokens counts the words of messages as their prompt tokens.
+func mockPromptTokens(messages []ChatCompletionMessage) int {
+	tokens := 0
+	for _, message := range messages {
+		tokens += len(strings.Fields(message.Content))
+	}
+	return tokens
+}
+
+// mockStream renders words as OpenAI-style SSE, one chunk per word, followed by the usage
+// chunk when requested and [DONE].
+func mockStream(model string, words []string, finishReason string, usage *Usage, options *StreamOptions) []byte {
+	var buf bytes.Buffer
+	for i, word := range words {
+		if i > 0 {
+			word = " " + word
+		}
+		fmt.Fprintf(&buf, "data: %s\n\n", mockChunk(model, map[string]any{"content": word}, nil))
+	}
+	fmt.Fprintf(&buf, "data: %s\n\n", mockChunk(model, map[string]any{}, finishReason))
+	if options != nil && options.IncludeUsage {
+		data, _ := json.Marshal(map[string]any{
+			"id":      "mock-completion",
+			"object":  "chat.completion.chunk",
+			"created": time.Now().Unix(),
+			"model":   model,
+			"choices": []any{},
+			"usage":   usage,
+		})
+		fmt.Fprintf(&buf, "data: %s\n\n", data)
+	}
+	buf.Write(streamDone)
+	return buf.Bytes()
+}
+
+func mockChunk(model string, delta map[string]any, finishReason any) []byte {
+	data, _ := json.Marshal(map[string]any{
+		"id":      "mock-completion",
+		"object":  "chat.completion.chunk",
+		"created": time.Now().Unix(),
+		"model":   model,
+		"choices": []map[string]any{
+			{"index": 0, "delta": delta, "finish_reason": finishReason},
+		},
+	})
+	return data
+}
+
+func mockResponse(status int, contentType string, body []byte) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{contentType}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}
+}
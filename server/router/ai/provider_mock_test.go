@@ -0,0 +1,81 @@
+package ai
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newMockTestService(t *testing.T) *AIService {
+	t.Helper()
+	t.Setenv("MEMOS_AI_PROVIDER", "mock")
+	t.Setenv("MEMOS_AI_ALLOWED_HOSTS", "models.github.ai")
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("MEMOS_OPENAI_API_KEY", "")
+	return NewAIService(nil, "", "")
+}
+
+func TestMockProviderEchoesLastUserMessage(t *testing.T) {
+	s := newMockTestService(t)
+	require.Nil(t, s.targetErr)
+
+	c, rec := newTestContext(`{"messages":[{"role":"user","content":"first"},{"role":"assistant","content":"ok"},{"role":"user","content":"hello mock world"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"content":"hello mock world"`)
+	require.Contains(t, rec.Body.String(), `"model":"mock"`)
+	require.Contains(t, rec.Body.String(), `"completion_tokens":3`)
+}
+
+func TestMockProviderHonorsMaxTokens(t *testing.T) {
+	s := newMockTestService(t)
+
+	c, rec := newTestContext(`{"max_tokens":2,"messages":[{"role":"user","content":"one two three four"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"content":"one two"`)
+	require.Contains(t, rec.Body.String(), `"finish_reason":"length"`)
+}
+
+func TestMockProviderStreams(t *testing.T) {
+	s := newMockTestService(t)
+
+	c, rec := newTestContext(`{"stream":true,"messages":[{"role":"user","content":"hello world"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+
+	body := rec.Body.String()
+	require.Contains(t, body, `"content":"hello"`)
+	require.Contains(t, body, `"content":" world"`)
+	require.Contains(t, body, `"finish_reason":"stop"`)
+	require.True(t, strings.HasSuffix(strings.TrimSpace(body), "data: [DONE]"))
+}
+
+func TestMockReply(t *testing.T) {
+	words, finishReason := mockReply(&ChatCompletionRequest{})
+	require.Equal(t, strings.Fields(mockLorem), words)
+	require.Equal(t, "stop", finishReason)
+
+	words, _ = mockReply(&ChatCompletionRequest{
+		Messages:       []ChatCompletionMessage{{Role: roleUser, Content: "tag this"}},
+		ResponseFormat: []byte(`{"type":"json_object"}`),
+	})
+	require.Equal(t, []string{"{}"}, words)
+
+	maxTokens := 1
+	words, finishReason = mockReply(&ChatCompletionRequest{
+		Messages:            []ChatCompletionMessage{{Role: roleUser, Content: "a b"}},
+		MaxCompletionTokens: &maxTokens,
+	})
+	require.Equal(t, []string{"a"}, words)
+	require.Equal(t, "length", finishReason)
+}
+
+func TestMockTransportRejectsOtherURLs(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "https://api.openai.com/v1/chat/completions", strings.NewReader("{}"))
+	require.NoError(t, err)
+	_, err = mockTransport{}.Do(req)
+	require.Error(t, err)
+}
@@ -0,0 +1,220 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultOllamaURL is the local Ollama server used when MEMOS_AI_BASE_URL is unset.
+	defaultOllamaURL = "http://localhost:11434"
+	// defaultOllamaModel is the model used when a request does not specify one.
+	defaultOllamaModel = "llama3.2"
+	// defaultOllamaEmbeddingModel is the embedding model used when none is configured.
+	defaultOllamaEmbeddingModel = "nomic-embed-text"
+)
+
+// ollamaProvider talks to a local Ollama server using its native /api/chat API.
+type ollamaProvider struct {
+	baseURL string
+}
+
+type ollamaOptions struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	NumPredict       *int     `json:"num_predict,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+}
+
+type ollamaRequest struct {
+	Model    string                  `json:"model"`
+	Messages []ChatCompletionMessage `json:"messages"`
+	// Stream is always sent because Ollama streams unless told otherwise.
+	Stream  bool           `json:"stream"`
+	Options *ollamaOptions `json:"options,omitempty"`
+	// Format is "json" or a JSON schema, translated from response_format.
+	Format json.RawMessage `json:"format,omitempty"`
+}
+
+// ollamaResponse is both the complete response and a single line of a streamed response.
+type ollamaResponse struct {
+	Model           string                `json:"model"`
+	Message         ChatCompletionMessage `json:"message"`
+	Done            bool                  `json:"done"`
+	DoneReason      string                `json:"done_reason"`
+	PromptEvalCount int                   `json:"prompt_eval_count"`
+	EvalCount       int                   `json:"eval_count"`
+}
+
+func (*ollamaProvider) Name() string {
+	return providerOllama
+}
+
+func (*ollamaProvider) DefaultModel() string {
+	return defaultOllamaModel
+}
+
+func (*ollamaProvider) RequiresAPIKey() bool {
+	return false
+}
+
+func (p *ollamaProvider) NewChatRequest(ctx context.Context, _ string, req *ChatCompletionRequest) (*http.Request, error) {
+	ollamaReq := &ollamaRequest{
+		Model:    req.Model,
+		Messages: req.Messages,
+		Stream:   req.Stream,
+		Format:   ollamaFormat(req.ResponseFormat),
+	}
+	if req.Temperature != nil || req.TopP != nil || req.MaxTokens != nil || len(req.Stop) > 0 ||
+		req.PresencePenalty != nil || req.FrequencyPenalty != nil || req.Seed != nil {
+		ollamaReq.Options = &ollamaOptions{
+			Temperature:      req.Temperature,
+			TopP:             req.TopP,
+			NumPredict:       req.MaxTokens,
+			Stop:             req.Stop,
+			PresencePenalty:  req.PresencePenalty,
+			FrequencyPenalty: req.FrequencyPenalty,
+			Seed:             req.Seed,
+		}
+	}
+	jsonBody, err := json.Marshal(ollamaReq)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/api/chat", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	return httpReq, nil
+}
+
+func (*ollamaProvider) ParseChatResponse(body []byte) ([]byte, error) {
+	response := new(ollamaResponse)
+	if err := json.Unmarshal(body, response); err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]any{
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   response.Model,
+		"choices": []map[string]any{
+			{
+				"index":         0,
+				"message":       map[string]any{"role": "assistant", "content": response.Message.Content},
+				"finish_reason": ollamaFinishReason(response.DoneReason),
+			},
+		},
+		"usage": map[string]any{
+			"prompt_tokens":     response.PromptEvalCount,
+			"completion_tokens": response.EvalCount,
+			"total_tokens":      response.PromptEvalCount + response.EvalCount,
+		},
+	})
+}
+
+// NewStreamDecoder decodes Ollama's newline-delimited JSON stream, which ends with the
+// object whose done field is set.
+func (*ollamaProvider) NewStreamDecoder(lines *streamLineReader) streamDecoder {
+	return &ollamaStreamDecoder{lines: lines}
+}
+
+type ollamaStreamDecoder struct {
+	lines    *streamLineReader
+	finished bool
+}
+
+func (d *ollamaStreamDecoder) Next() (*streamDelta, error) {
+	if d.finished {
+		return nil, io.EOF
+	}
+	line, err := d.lines.next()
+	for err == nil && len(line) == 0 {
+		line, err = d.lines.next()
+	}
+	if errors.Is(err, io.EOF) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	response := new(ollamaResponse)
+	if err := json.Unmarshal(line, response); err != nil {
+		return nil, &streamChunkError{err: err}
+	}
+
+	delta := &streamDelta{Model: response.Model, Content: response.Message.Content}
+	if response.Done {
+		d.finished = true
+		delta.FinishReason = ollamaFinishReason(response.DoneReason)
+		delta.Usage = &Usage{
+			PromptTokens:     response.PromptEvalCount,
+			CompletionTokens: response.EvalCount,
+			TotalTokens:      response.PromptEvalCount + response.EvalCount,
+		}
+	}
+	return delta, nil
+}
+
+func (p *ollamaProvider) NewModelsRequest(ctx context.Context, _ string) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/api/tags", nil)
+}
+
+func (*ollamaProvider) DefaultEmbeddingModel() string {
+	return defaultOllamaEmbeddingModel
+}
+
+func (p *ollamaProvider) NewEmbeddingsRequest(ctx context.Context, _ string, req *EmbeddingsRequest) (*http.Request, error) {
+	jsonBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/api/embed", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	return httpReq, nil
+}
+
+// ParseEmbeddingsResponse converts Ollama's {"embeddings": [[...]]} into the OpenAI format.
+func (*ollamaProvider) ParseEmbeddingsResponse(body []byte) ([]byte, error) {
+	var response struct {
+		Model           string      `json:"model"`
+		Embeddings      [][]float64 `json:"embeddings"`
+		PromptEvalCount int         `json:"prompt_eval_count"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+	data := make([]map[string]any, len(response.Embeddings))
+	for i, embedding := range response.Embeddings {
+		data[i] = map[string]any{"object": "embedding", "index": i, "embedding": embedding}
+	}
+	return json.Marshal(map[string]any{
+		"object": "list",
+		"model":  response.Model,
+		"data":   data,
+		"usage": map[string]any{
+			"prompt_tokens": response.PromptEvalCount,
+			"total_tokens":  response.PromptEvalCount,
+		},
+	})
+}
+
+// ollamaFinishReason maps Ollama done reasons to OpenAI finish reasons.
+func ollamaFinishReason(doneReason string) string {
+	if doneReason == "length" {
+		return "length"
+	}
+	return "stop"
+}
@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOllamaChatCompletionWithoutKey(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/chat", r.URL.Path)
+		require.Empty(t, r.Header.Get("Authorization"))
+
+		var req map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, defaultOllamaModel, req["model"])
+		require.Equal(t, false, req["stream"])
+
+		_, _ = w.Write([]byte(`{"model":"llama3.2","message":{"role":"assistant","content":"Hi there"},"done":true,"done_reason":"stop","prompt_eval_count":4,"eval_count":2}`))
+	}))
+	defer upstream.Close()
+
+	t.Setenv("MEMOS_AI_PROVIDER", "ollama")
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("MEMOS_OPENAI_API_KEY", "")
+	s := NewAIService(nil, "", "")
+
+	c, rec := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"content":"Hi there"`)
+	require.Contains(t, rec.Body.String(), `"total_tokens":6`)
+}
+
+func TestOllamaStreamTranslation(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"model":"llama3.2","message":{"role":"assistant","content":"Hel"},"done":false}` + "\n"))
+		_, _ = w.Write([]byte(`{"model":"llama3.2","message":{"role":"assistant","content":"lo"},"done":false}` + "\n"))
+		_, _ = w.Write([]byte(`{"model":"llama3.2","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop"}` + "\n"))
+	}))
+	defer upstream.Close()
+
+	t.Setenv("MEMOS_AI_PROVIDER", "ollama")
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	s := NewAIService(nil, "", "")
+
+	c, rec := newTestContext(`{"stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+
+	events := strings.Split(strings.TrimSpace(rec.Body.String()), "\n\n")
+	require.Len(t, events, 4)
+	require.Contains(t, events[0], `"content":"Hel"`)
+	require.Contains(t, events[1], `"content":"lo"`)
+	require.Contains(t, events[2], `"finish_reason":"stop"`)
+	require.Equal(t, "data: [DONE]", events[3])
+}
@@ -0,0 +1,215 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	storepb "github.com/usememos/memos/proto/gen/store"
+	"github.com/usememos/memos/store"
+)
+
+// headerQuotaRemaining reports the tokens left in the user's daily quota.
+const headerQuotaRemaining = "X-AI-Quota-Remaining"
+
+// requestUsageKey is the context key of the per-request usage accumulator.
+type requestUsageKey struct{}
+
+// requestUsage collects the tokens recorded while handling a request and their estimated cost.
+type requestUsage struct {
+	pricing pricingTable
+
+	mutex  sync.Mutex
+	tokens int64
+	cost   float64
+}
+
+// withRequestUsage returns a context that collects the usage recorded while handling a
+// request, priced with pricing.
+func withRequestUsage(ctx context.Context, pricing pricingTable) (context.Context, *requestUsage) {
+	usage := &requestUsage{pricing: pricing}
+	return context.WithValue(ctx, requestUsageKey{}, usage), usage
+}
+
+func (u *requestUsage) add(model string, usage *Usage) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.tokens += int64(usage.TotalTokens)
+	u.cost += u.pricing.cost(model, usage)
+}
+
+// totals returns the tokens and cost recorded so far.
+func (u *requestUsage) totals() (int64, float64) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.tokens, u.cost
+}
+
+// quotaTracker records per-user token usage and cost for the current day and month, and
+// enforces a daily token limit when limit is positive. Totals are persisted in the user's
+// AI_USAGE setting and reset at midnight UTC and at the start of each UTC month.
+type quotaTracker struct {
+	store *store.Store
+	limit int64
+
+	// mutex serializes read-modify-write updates of the stored totals.
+	mutex sync.Mutex
+}
+
+func newQuotaTracker(store *store.Store, limit int64) *quotaTracker {
+	return &quotaTracker{
+		store: store,
+		limit: limit,
+	}
+}
+
+// used returns the tokens the user has consumed today.
+func (q *quotaTracker) used(ctx context.Context, userID int32) (int64, error) {
+	usage, err := q.usage(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return usage.GetTokens(), nil
+}
+
+// usage returns the user's totals for the current day and month.
+func (q *quotaTracker) usage(ctx context.Context, userID int32) (*storepb.AIUsageUserSetting, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.load(ctx, userID)
+}
+
+// add records tokens and their cost against the user's totals and returns the new total
+// for the day.
+func (q *quotaTracker) add(ctx context.Context, userID int32, tokens int64, cost float64) (int64, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	usage, err := q.load(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	usage.Tokens += tokens
+	usage.Cost += cost
+	usage.MonthTokens += tokens
+	usage.MonthCost += cost
+	if _, err := q.store.UpsertUserSetting(ctx, &storepb.UserSetting{
+		UserId: userID,
+		Key:    storepb.UserSetting_AI_USAGE,
+		Value:  &storepb.UserSetting_AiUsage{AiUsage: usage},
+	}); err != nil {
+		return 0, err
+	}
+	return usage.Tokens, nil
+}
+
+func (q *quotaTracker) load(ctx context.Context, userID int32) (*storepb.AIUsageUserSetting, error) {
+	userSetting, err := q.store.GetUserSetting(ctx, &store.FindUserSetting{
+		UserID: &userID,
+		Key:    storepb.UserSetting_AI_USAGE,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return currentUsage(userSetting.GetAiUsage(), time.Now()), nil
+}
+
+// currentUsage returns a copy of stored with the totals of past days and months cleared.
+func currentUsage(stored *storepb.AIUsageUserSetting, now time.Time) *storepb.AIUsageUserSetting {
+	usage := &storepb.AIUsageUserSetting{
+		Date:  quotaDate(now),
+		Month: quotaMonth(now),
+	}
+	if stored.GetDate() == usage.Date {
+		usage.Tokens = stored.GetTokens()
+		usage.Cost = stored.GetCost()
+	}
+	if stored.GetMonth() == usage.Month {
+		usage.MonthTokens = stored.GetMonthTokens()
+		usage.MonthCost = stored.GetMonthCost()
+	}
+	return usage
+}
+
+// quotaDate returns the UTC day a quota total belongs to.
+func quotaDate(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// quotaMonth returns the UTC month a monthly total belongs to.
+func quotaMonth(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// quotaMiddleware rejects requests from users who have used up their daily token quota and
+// adds the tokens consumed by each request, and their cost, to the user's totals once it
+// completes. Unauthenticated requests cannot be attributed to a user and are not metered.
+func (s *AIService) quotaMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if s.quota == nil {
+			return next(c)
+		}
+		ctx := c.Request().Context()
+		user, err := s.getCurrentUser(ctx, c)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get current user").SetInternal(err)
+		}
+		if user == nil {
+			return next(c)
+		}
+
+		ctx, usage := withRequestUsage(context.WithValue(ctx, quotaUserKey{}, user.ID), s.pricing)
+		c.SetRequest(c.Request().WithContext(ctx))
+		if s.quota.limit > 0 {
+			used, err := s.quota.used(ctx, user.ID)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get AI usage").SetInternal(err)
+			}
+			if used >= s.quota.limit {
+				c.Response().Header().Set(headerQuotaRemaining, "0")
+				return s.errorResponse(http.StatusForbidden, errorCodeQuotaExceeded, nil)
+			}
+			// Non-streaming responses know their usage before the headers are written.
+			c.Response().Before(func() {
+				tokens, _ := usage.totals()
+				remaining := max(s.quota.limit-used-tokens, 0)
+				c.Response().Header().Set(headerQuotaRemaining, strconv.FormatInt(remaining, 10))
+			})
+		}
+
+		err = next(c)
+		if tokens, cost := usage.totals(); tokens > 0 {
+			// The request context may already be cancelled; the totals must still be saved.
+			if _, addErr := s.quota.add(context.WithoutCancel(ctx), user.ID, tokens, cost); addErr != nil {
+				s.log(ctx).Error("failed to record AI token usage", "user_id", user.ID, "error", addErr)
+			}
+		}
+		return err
+	}
+}
+
+// quotaExceeded reports whether the metered user of ctx has used up their daily quota,
+// counting the tokens the current request has recorded so far. Requests that make several
+// upstream calls, such as batches, check it before each one.
+func (s *AIService) quotaExceeded(ctx context.Context) (bool, error) {
+	if s.quota == nil || s.quota.limit <= 0 {
+		return false, nil
+	}
+	userID, ok := ctx.Value(quotaUserKey{}).(int32)
+	if !ok {
+		return false, nil
+	}
+	used, err := s.quota.used(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if usage, ok := ctx.Value(requestUsageKey{}).(*requestUsage); ok {
+		tokens, _ := usage.totals()
+		used += tokens
+	}
+	return used >= s.quota.limit, nil
+}
@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	storepb "github.com/usememos/memos/proto/gen/store"
+	"github.com/usememos/memos/store"
+	teststore "github.com/usememos/memos/store/test"
+)
+
+func TestQuotaMiddleware(t *testing.T) {
+	ctx := context.Background()
+	ts := teststore.NewTestingStore(ctx, t)
+	defer ts.Close()
+	user, err := ts.CreateUser(ctx, &store.User{Username: "quota", Role: store.RoleUser, Email: "quota@test.com"})
+	require.NoError(t, err)
+
+	t.Setenv("MEMOS_AI_DAILY_TOKEN_LIMIT", "10")
+	s := NewAIService(ts, "secret", "test-key")
+	require.NotNil(t, s.quota)
+
+	handler := s.quotaMiddleware(func(c echo.Context) error {
+		recordUsage(c.Request().Context(), "test-model", &Usage{TotalTokens: 6})
+		return c.JSON(http.StatusOK, map[string]string{})
+	})
+	send := func() (*http.Response, error) {
+		c, rec := newTestContext(`{}`)
+		c.Set(currentUserContextKey, user)
+		err := handler(c)
+		return rec.Result(), err
+	}
+
+	resp, err := send()
+	require.NoError(t, err)
+	require.Equal(t, "4", resp.Header.Get(headerQuotaRemaining))
+	_, err = send()
+	require.NoError(t, err, "the running total is below the limit before the request")
+
+	resp, err = send()
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusForbidden, httpErr.Code)
+	require.Equal(t, "0", resp.Header.Get(headerQuotaRemaining))
+
+	used, err := s.quota.used(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(12), used)
+}
+
+func TestQuotaResetsDaily(t *testing.T) {
+	ctx := context.Background()
+	ts := teststore.NewTestingStore(ctx, t)
+	defer ts.Close()
+	user, err := ts.CreateUser(ctx, &store.User{Username: "quota", Role: store.RoleUser, Email: "quota@test.com"})
+	require.NoError(t, err)
+	_, err = ts.UpsertUserSetting(ctx, &storepb.UserSetting{
+		UserId: user.ID,
+		Key:    storepb.UserSetting_AI_USAGE,
+		Value:  &storepb.UserSetting_AiUsage{AiUsage: &storepb.AIUsageUserSetting{Date: "2000-01-01", Tokens: 500}},
+	})
+	require.NoError(t, err)
+
+	quota := newQuotaTracker(ts, 100)
+	used, err := quota.used(ctx, user.ID)
+	require.NoError(t, err)
+	require.Zero(t, used)
+
+	total, err := quota.add(ctx, user.ID, 7, 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), total)
+}
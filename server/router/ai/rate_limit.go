@@ -0,0 +1,120 @@
+package ai
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// defaultRateLimit is the number of AI requests per minute allowed per user.
+	defaultRateLimit = 20
+	// rateLimiterIdleTTL is how long an unused limiter is kept before it is cleaned up.
+	rateLimiterIdleTTL = 10 * time.Minute
+)
+
+type rateLimiterEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimiter hands out a token bucket per client key, allowing perMinute requests
+// per minute with bursts of the same size. Idle buckets are swept periodically.
+type rateLimiter struct {
+	perMinute int
+
+	mutex       sync.Mutex
+	limiters    map[string]*rateLimiterEntry
+	lastCleanup time.Time
+}
+
+func newRateLimiter(perMinute int) *rateLimiter {
+	return &rateLimiter{
+		perMinute:   perMinute,
+		limiters:    make(map[string]*rateLimiterEntry),
+		lastCleanup: time.Now(),
+	}
+}
+
+// reserve takes a token for key. It returns zero when the request is allowed,
+// or how long the client must wait before retrying.
+func (l *rateLimiter) reserve(key string) time.Duration {
+	return l.reserveN(key, 1)
+}
+
+// reserveN takes n tokens for key at once, for a request that counts as n requests. n
+// must not exceed perMinute, the size of a burst.
+func (l *rateLimiter) reserveN(key string, n int) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastCleanup) > rateLimiterIdleTTL {
+		for k, entry := range l.limiters {
+			if now.Sub(entry.lastSeen) > rateLimiterIdleTTL {
+				delete(l.limiters, k)
+			}
+		}
+		l.lastCleanup = now
+	}
+
+	entry, ok := l.limiters[key]
+	if !ok {
+		entry = &rateLimiterEntry{
+			limiter: rate.NewLimiter(rate.Limit(float64(l.perMinute)/60), l.perMinute),
+		}
+		l.limiters[key] = entry
+	}
+	entry.lastSeen = now
+
+	reservation := entry.limiter.ReserveN(now, n)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return delay
+	}
+	return 0
+}
+
+// rateLimitMiddleware limits AI requests per authenticated user, or per client IP
+// for unauthenticated requests. It is a no-op when MEMOS_AI_RATE_LIMIT is 0.
+func (s *AIService) rateLimitMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if s.rateLimiter == nil {
+			return next(c)
+		}
+
+		key, err := s.rateLimitKey(c)
+		if err != nil {
+			return err
+		}
+		if delay := s.rateLimiter.reserve(key); delay > 0 {
+			return rateLimitExceeded(c, delay)
+		}
+		return next(c)
+	}
+}
+
+// rateLimitKey returns the rate limiter key of a request: its user, or its client IP for
+// unauthenticated requests.
+func (s *AIService) rateLimitKey(c echo.Context) (string, error) {
+	user, err := s.getCurrentUser(c.Request().Context(), c)
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusInternalServerError, "Failed to get current user").SetInternal(err)
+	}
+	if user != nil {
+		return fmt.Sprintf("user:%d", user.ID), nil
+	}
+	return "ip:" + c.RealIP(), nil
+}
+
+// rateLimitExceeded rejects a request over the rate limit, telling the client when to retry.
+func rateLimitExceeded(c echo.Context, delay time.Duration) error {
+	c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+	return echo.NewHTTPError(http.StatusTooManyRequests, "AI rate limit exceeded, please retry later")
+}
@@ -0,0 +1,80 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// upstreamRateLimitHeaders maps the rate-limit headers providers send to the
+// X-AI-RateLimit-* headers forwarded to the client. OpenAI, Azure and GitHub Models use
+// the x-ratelimit-* names; Anthropic uses its own.
+var upstreamRateLimitHeaders = map[string]string{
+	"x-ratelimit-limit-requests":     "X-AI-RateLimit-Limit-Requests",
+	"x-ratelimit-limit-tokens":       "X-AI-RateLimit-Limit-Tokens",
+	"x-ratelimit-remaining-requests": "X-AI-RateLimit-Remaining-Requests",
+	"x-ratelimit-remaining-tokens":   "X-AI-RateLimit-Remaining-Tokens",
+	"x-ratelimit-reset-requests":     "X-AI-RateLimit-Reset-Requests",
+	"x-ratelimit-reset-tokens":       "X-AI-RateLimit-Reset-Tokens",
+
+	"anthropic-ratelimit-requests-limit":     "X-AI-RateLimit-Limit-Requests",
+	"anthropic-ratelimit-tokens-limit":       "X-AI-RateLimit-Limit-Tokens",
+	"anthropic-ratelimit-requests-remaining": "X-AI-RateLimit-Remaining-Requests",
+	"anthropic-ratelimit-tokens-remaining":   "X-AI-RateLimit-Remaining-Tokens",
+	"anthropic-ratelimit-requests-reset":     "X-AI-RateLimit-Reset-Requests",
+	"anthropic-ratelimit-tokens-reset":       "X-AI-RateLimit-Reset-Tokens",
+}
+
+// upstreamRateLimitsKey is the context key of a request's upstreamRateLimits.
+type upstreamRateLimitsKey struct{}
+
+// upstreamRateLimits holds the rate-limit headers of the latest upstream response of a
+// request. Upstream calls may run concurrently, so it is guarded by a mutex.
+type upstreamRateLimits struct {
+	mutex  sync.Mutex
+	header http.Header
+}
+
+// rateLimitHeadersMiddleware forwards the provider's rate-limit headers to the client, so
+// the frontend can slow down before it is throttled. They are added just before the
+// response headers are written, after the upstream call has returned them.
+func rateLimitHeadersMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		limits := &upstreamRateLimits{header: http.Header{}}
+		c.SetRequest(c.Request().WithContext(context.WithValue(c.Request().Context(), upstreamRateLimitsKey{}, limits)))
+		c.Response().Before(func() {
+			limits.mutex.Lock()
+			defer limits.mutex.Unlock()
+			for name, values := range limits.header {
+				c.Response().Header()[name] = values
+			}
+		})
+		return next(c)
+	}
+}
+
+// recordUpstreamRateLimits keeps the rate-limit headers of an upstream response for the
+// client and logs them.
+func (s *AIService) recordUpstreamRateLimits(ctx context.Context, header http.Header) {
+	found := http.Header{}
+	var attrs []any
+	for upstream, name := range upstreamRateLimitHeaders {
+		if value := header.Get(upstream); value != "" {
+			found.Set(name, value)
+			attrs = append(attrs, upstream, value)
+		}
+	}
+	if len(found) == 0 {
+		return
+	}
+	s.log(ctx).Debug("AI provider rate limits", attrs...)
+	limits, ok := ctx.Value(upstreamRateLimitsKey{}).(*upstreamRateLimits)
+	if !ok {
+		return
+	}
+	limits.mutex.Lock()
+	defer limits.mutex.Unlock()
+	limits.header = found
+}
@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpstreamRateLimitHeadersForwarded(t *testing.T) {
+	status := http.StatusOK
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("x-ratelimit-remaining-requests", "59")
+		w.Header().Set("x-ratelimit-reset-tokens", "6m0s")
+		w.Header().Set("x-request-id", "req_123")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	t.Setenv("MEMOS_AI_MAX_RETRIES", "0")
+	s := NewAIService(nil, "", "test-key")
+	e := echo.New()
+	s.RegisterRoutes(e.Group("/api/v1"))
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/ai/chat_completion", strings.NewReader(`{"messages":[{"role":"user","content":"hi"}]}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send()
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "59", rec.Header().Get("X-AI-RateLimit-Remaining-Requests"))
+	require.Equal(t, "6m0s", rec.Header().Get("X-AI-RateLimit-Reset-Tokens"))
+	require.Empty(t, rec.Header().Get("X-AI-RateLimit-Limit-Requests"))
+
+	// The headers are also forwarded when the provider throttles the request.
+	status = http.StatusTooManyRequests
+	rec = send()
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "59", rec.Header().Get("X-AI-RateLimit-Remaining-Requests"))
+}
+
+func TestAnthropicRateLimitHeadersMapped(t *testing.T) {
+	c, rec := newTestContext("")
+	s := NewAIService(nil, "", "test-key")
+	require.NoError(t, rateLimitHeadersMiddleware(func(c echo.Context) error {
+		s.recordUpstreamRateLimits(c.Request().Context(), http.Header{
+			"Anthropic-Ratelimit-Tokens-Remaining": {"1000"},
+			"Anthropic-Ratelimit-Requests-Reset":   {"2026-01-01T00:00:00Z"},
+		})
+		return c.NoContent(http.StatusNoContent)
+	})(c))
+	require.Equal(t, "1000", rec.Header().Get("X-AI-RateLimit-Remaining-Tokens"))
+	require.Equal(t, "2026-01-01T00:00:00Z", rec.Header().Get("X-AI-RateLimit-Reset-Requests"))
+}
@@ -0,0 +1,44 @@
+package ai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	limiter := newRateLimiter(2)
+	require.Zero(t, limiter.reserve("user:1"))
+	require.Zero(t, limiter.reserve("user:1"))
+	require.Positive(t, limiter.reserve("user:1"))
+	// Other clients have their own bucket.
+	require.Zero(t, limiter.reserve("user:2"))
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	t.Setenv("MEMOS_AI_RATE_LIMIT", "1")
+	s := NewAIService(nil, "", "")
+
+	e := echo.New()
+	s.RegisterRoutes(e.Group("/api/v1"))
+
+	request := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.NotEqual(t, http.StatusTooManyRequests, request("/api/v1/ai/models").Code)
+	rec := request("/api/v1/ai/models")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.NotEmpty(t, rec.Header().Get("Retry-After"))
+
+	// The status probe is exempt from rate limiting.
+	require.Equal(t, http.StatusOK, request("/api/v1/ai/status").Code)
+	require.Equal(t, http.StatusOK, request("/api/v1/ai/status").Code)
+}
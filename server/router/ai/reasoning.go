@@ -0,0 +1,68 @@
+package ai
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// defaultReasoningModels lists the model name prefixes of reasoning models, which take
+// reasoning_effort and reject sampling parameters. Override with MEMOS_AI_REASONING_MODELS.
+var defaultReasoningModels = []string{"o1", "o3", "o4"}
+
+// reasoningEfforts are the accepted reasoning_effort values.
+var reasoningEfforts = []string{"minimal", "low", "medium", "high"}
+
+// isReasoningModel reports whether model matches one of the reasoning model prefixes.
+func (s *AIService) isReasoningModel(model string) bool {
+	return matchesModelPrefix(s.reasoningModels, model)
+}
+
+// applyReasoningModel adapts a request for a reasoning model: sampling parameters the model
+// rejects are removed, or the request fails with 400 when stripping them is disabled, and
+// OpenAI-compatible providers get max_tokens as max_completion_tokens.
+func (s *AIService) applyReasoningModel(provider Provider, req *ChatCompletionRequest) error {
+	if !s.isReasoningModel(req.Model) {
+		return nil
+	}
+	if unsupported := unsupportedReasoningParams(req); len(unsupported) > 0 {
+		if s.rejectReasoningParams {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("model %q is a reasoning model and does not support %s", req.Model, strings.Join(unsupported, ", ")))
+		}
+		req.Temperature, req.TopP, req.PresencePenalty, req.FrequencyPenalty = nil, nil, nil, nil
+	}
+	if req.MaxTokens != nil && (provider.Name() == providerOpenAI || provider.Name() == providerAzure) {
+		req.MaxCompletionTokens, req.MaxTokens = req.MaxTokens, nil
+	}
+	return nil
+}
+
+// unsupportedReasoningParams returns the names of the sampling parameters set on req.
+func unsupportedReasoningParams(req *ChatCompletionRequest) []string {
+	var names []string
+	if req.Temperature != nil {
+		names = append(names, "temperature")
+	}
+	if req.TopP != nil {
+		names = append(names, "top_p")
+	}
+	if req.PresencePenalty != nil {
+		names = append(names, "presence_penalty")
+	}
+	if req.FrequencyPenalty != nil {
+		names = append(names, "frequency_penalty")
+	}
+	return names
+}
+
+// matchesModelPrefix reports whether model starts with one of prefixes. A vendor prefix
+// such as "openai/" is ignored.
+func matchesModelPrefix(prefixes []string, model string) bool {
+	name := model[strings.LastIndex(model, "/")+1:]
+	return slices.ContainsFunc(prefixes, func(prefix string) bool {
+		return strings.HasPrefix(name, prefix)
+	})
+}
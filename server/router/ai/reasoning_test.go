@@ -0,0 +1,59 @@
+package ai
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatCompletionReasoningModel(t *testing.T) {
+	var received *ChatCompletionRequest
+	newChatUpstream(t, "thought", func(req *ChatCompletionRequest) {
+		received = req
+	})
+	s := NewAIService(nil, "", "test-key")
+
+	c, _ := newTestContext(`{"model":"o3-mini","reasoning_effort":"high","temperature":0.2,"top_p":0.9,"max_tokens":100,"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, "high", *received.ReasoningEffort)
+	require.Nil(t, received.Temperature)
+	require.Nil(t, received.TopP)
+	require.Nil(t, received.MaxTokens)
+	require.Equal(t, 100, *received.MaxCompletionTokens)
+
+	c, _ = newTestContext(`{"model":"gpt-4o-mini","temperature":0.2,"max_tokens":100,"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, 0.2, *received.Temperature)
+	require.Equal(t, 100, *received.MaxTokens)
+	require.Nil(t, received.MaxCompletionTokens)
+}
+
+func TestChatCompletionRejectsReasoningParams(t *testing.T) {
+	newChatUpstream(t, "thought", nil)
+	t.Setenv("MEMOS_AI_STRIP_REASONING_PARAMS", "false")
+	t.Setenv("MEMOS_AI_REASONING_MODELS", "deepseek-r")
+	s := NewAIService(nil, "", "test-key")
+
+	c, _ := newTestContext(`{"model":"deepseek-r1","temperature":0.2,"frequency_penalty":1,"messages":[{"role":"user","content":"hi"}]}`)
+	httpErr, ok := s.ChatCompletion(c).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusBadRequest, httpErr.Code)
+	require.Equal(t, `model "deepseek-r1" is a reasoning model and does not support temperature, frequency_penalty`, httpErr.Message)
+
+	c, _ = newTestContext(`{"model":"o3","temperature":0.2,"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c), "only the configured prefixes are reasoning models")
+}
+
+func TestValidateReasoningEffort(t *testing.T) {
+	s := NewAIService(nil, "", "test-key")
+	effort := "extreme"
+	err := s.validateChatCompletionRequest(&ChatCompletionRequest{
+		Messages:        []ChatCompletionMessage{{Role: "user", Content: "hi"}},
+		ReasoningEffort: &effort,
+	})
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusBadRequest, httpErr.Code)
+}
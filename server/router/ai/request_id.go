@@ -0,0 +1,66 @@
+package ai
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// maxRequestIDLength caps the length of a client-supplied request ID, which is logged,
+// forwarded upstream and used as a key of the stream registries.
+const maxRequestIDLength = 128
+
+// requestIDKey is the context key under which the request ID is stored.
+type requestIDKey struct{}
+
+// requestIDMiddleware assigns every AI request an ID for correlating client, server and
+// upstream logs. The ID is taken from the client's X-Request-ID header or from echo's
+// RequestID middleware when configured, and is generated otherwise. It is echoed back
+// on the response and forwarded to the provider. A client ID that is too long or holds
+// characters other than letters, digits and "-_.:" is replaced by a generated one.
+func requestIDMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id := c.Request().Header.Get(echo.HeaderXRequestID)
+		if !validRequestID(id) {
+			id = ""
+		}
+		if id == "" {
+			id = c.Response().Header().Get(echo.HeaderXRequestID)
+		}
+		if id == "" {
+			id = uuid.NewString()
+		}
+		c.Response().Header().Set(echo.HeaderXRequestID, id)
+		c.SetRequest(c.Request().WithContext(context.WithValue(c.Request().Context(), requestIDKey{}, id)))
+		return next(c)
+	}
+}
+
+func validRequestID(id string) bool {
+	if len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || strings.ContainsRune("-_.:", r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// requestIDFromContext returns the request ID assigned by requestIDMiddleware, if any.
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// log returns the service logger annotated with the request ID carried by ctx.
+func (s *AIService) log(ctx context.Context) *slog.Logger {
+	if id := requestIDFromContext(ctx); id != "" {
+		return s.logger.With("request_id", id)
+	}
+	return s.logger
+}
@@ -0,0 +1,53 @@
+package ai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIDPropagation(t *testing.T) {
+	var upstreamID string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamID = r.Header.Get(echo.HeaderXRequestID)
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	s := NewAIService(nil, "", "test-key")
+	e := echo.New()
+	s.RegisterRoutes(e.Group("/api/v1"))
+
+	send := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/ai/chat_completion", strings.NewReader(`{"messages":[{"role":"user","content":"hi"}]}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if id != "" {
+			req.Header.Set(echo.HeaderXRequestID, id)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send("client-id")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "client-id", rec.Header().Get(echo.HeaderXRequestID))
+	require.Equal(t, "client-id", upstreamID)
+
+	rec = send("")
+	generated := rec.Header().Get(echo.HeaderXRequestID)
+	require.Len(t, generated, 36)
+	require.Equal(t, generated, upstreamID)
+
+	// IDs that are too long or hold unexpected characters are replaced.
+	for _, id := range []string{strings.Repeat("a", maxRequestIDLength+1), "id with spaces", "id\x00"} {
+		rec = send(id)
+		require.Len(t, rec.Header().Get(echo.HeaderXRequestID), 36, id)
+		require.Equal(t, rec.Header().Get(echo.HeaderXRequestID), upstreamID)
+	}
+	require.Equal(t, strings.Repeat("a", maxRequestIDLength), send(strings.Repeat("a", maxRequestIDLength)).Header().Get(echo.HeaderXRequestID))
+}
@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+// ChatCompletionResponse is the part of an OpenAI-style chat completion response the
+// derived endpoints read. The plain chat endpoint forwards the upstream body as is.
+type ChatCompletionResponse struct {
+	ID      string   `json:"id,omitempty"`
+	Model   string   `json:"model,omitempty"`
+	Choices []Choice `json:"choices"`
+	Usage   *Usage   `json:"usage,omitempty"`
+	// SystemFingerprint identifies the backend configuration that served a seeded request.
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
+}
+
+// Choice is one completion alternative.
+type Choice struct {
+	Index int `json:"index"`
+	// Message accepts content either as a string or as an array of content parts, as
+	// GitHub Models and Azure may send it; content cut by a content filter is null.
+	Message ChatCompletionMessage `json:"message"`
+	// Text carries the content instead of Message in the legacy completions envelope some
+	// OpenAI-compatible servers still answer with.
+	Text         string `json:"text,omitempty"`
+	FinishReason string `json:"finish_reason"`
+}
+
+// Content returns the choice's reply text.
+func (c *Choice) Content() string {
+	if c.Message.Content == "" {
+		return c.Text
+	}
+	return c.Message.Content
+}
+
+// unmarshalResponse parses an upstream chat completion body. Bodies that carry no choices,
+// including error envelopes sent with a 200 status, are reported as a 502.
+func unmarshalResponse(body []byte) (*ChatCompletionResponse, error) {
+	var envelope struct {
+		ChatCompletionResponse
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadGateway, "Unexpected response from AI provider").SetInternal(err)
+	}
+	if len(envelope.Choices) == 0 {
+		err := errors.New("response has no choices")
+		if envelope.Error != nil && envelope.Error.Message != "" {
+			err = errors.Errorf("response has no choices: %s", envelope.Error.Message)
+		}
+		return nil, echo.NewHTTPError(http.StatusBadGateway, "Unexpected response from AI provider").SetInternal(err)
+	}
+	return &envelope.ChatCompletionResponse, nil
+}
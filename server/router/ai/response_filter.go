@@ -0,0 +1,210 @@
+package ai
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// redactedText replaces the output matched by a redaction pattern.
+	redactedText = "[REDACTED]"
+	// streamFilterTail is how many bytes of a streamed reply are always held back between
+	// deltas, so a match split across chunks is seen whole.
+	streamFilterTail = 64
+	// streamFilterMaxHold caps how much of a streamed reply a match still in progress can
+	// hold back. Longer matches are cut, so filtering streams is best-effort.
+	streamFilterMaxHold = 4096
+)
+
+// responseFilter rewrites model output before it reaches the client, for example to redact
+// leaked internal URLs.
+type responseFilter interface {
+	// Filter returns text with the filter applied.
+	Filter(text string) string
+	// Hold returns the offset, at most end, from which a streamed text must be held back
+	// because a match starting there may continue in the next delta.
+	Hold(text string, end int) int
+}
+
+// responseFilters is a chain of filters applied in order.
+type responseFilters []responseFilter
+
+func (f responseFilters) Filter(text string) string {
+	for _, filter := range f {
+		text = filter.Filter(text)
+	}
+	return text
+}
+
+func (f responseFilters) Hold(text string, end int) int {
+	for _, filter := range f {
+		end = filter.Hold(text, end)
+	}
+	return end
+}
+
+// redactFilter replaces every match of its patterns with redactedText.
+type redactFilter struct {
+	patterns []*regexp.Regexp
+}
+
+func (f *redactFilter) Filter(text string) string {
+	for _, pattern := range f.patterns {
+		text = pattern.ReplaceAllLiteralString(text, redactedText)
+	}
+	return text
+}
+
+// Hold moves end back to the start of any match that crosses it.
+func (f *redactFilter) Hold(text string, end int) int {
+	for _, pattern := range f.patterns {
+		for _, match := range pattern.FindAllStringIndex(text, -1) {
+			if match[0] < end && match[1] > end {
+				end = match[0]
+			}
+		}
+	}
+	return end
+}
+
+// loadRedactPatterns reads MEMOS_AI_REDACT_PATTERNS, a JSON array of regular expressions
+// such as ["https://intranet\\.example\\.com\\S*"]. Invalid JSON is logged and ignored.
+func loadRedactPatterns(logger *slog.Logger) []string {
+	value := strings.TrimSpace(os.Getenv("MEMOS_AI_REDACT_PATTERNS"))
+	if value == "" {
+		return nil
+	}
+	var patterns []string
+	if err := json.Unmarshal([]byte(value), &patterns); err != nil {
+		logger.Warn("invalid MEMOS_AI_REDACT_PATTERNS, redaction is disabled", "error", err)
+		return nil
+	}
+	return patterns
+}
+
+// newResponseFilters builds the filter chain for the configured redaction patterns.
+// Patterns that do not compile are logged and dropped.
+func newResponseFilters(logger *slog.Logger, redactPatterns []string) responseFilters {
+	var compiled []*regexp.Regexp
+	for _, pattern := range redactPatterns {
+		if strings.TrimSpace(pattern) == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			logger.Warn("invalid pattern in MEMOS_AI_REDACT_PATTERNS, ignoring it", "pattern", pattern, "error", err)
+			continue
+		}
+		compiled = append(compiled, re)
+	}
+	if len(compiled) == 0 {
+		return nil
+	}
+	return responseFilters{&redactFilter{patterns: compiled}}
+}
+
+// filterResponse applies the response filters to the content of every choice of an
+// OpenAI-style completion body. Other fields are kept as they are; a body that cannot be
+// rewritten is returned unchanged.
+func (s *AIService) filterResponse(body []byte) []byte {
+	if len(s.responseFilters) == 0 {
+		return body
+	}
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return body
+	}
+	var choices []map[string]json.RawMessage
+	if err := json.Unmarshal(envelope["choices"], &choices); err != nil {
+		return body
+	}
+	for _, choice := range choices {
+		var message map[string]json.RawMessage
+		if err := json.Unmarshal(choice["message"], &message); err != nil || message == nil {
+			continue
+		}
+		var content string
+		if err := json.Unmarshal(message["content"], &content); err != nil {
+			continue
+		}
+		message["content"], _ = json.Marshal(s.responseFilters.Filter(content))
+		choice["message"], _ = json.Marshal(message)
+	}
+	var err error
+	if envelope["choices"], err = json.Marshal(choices); err != nil {
+		return body
+	}
+	filtered, err := json.Marshal(envelope)
+	if err != nil {
+		return body
+	}
+	return filtered
+}
+
+// streamFilter applies response filters to a streamed reply. It holds back the end of the
+// unfiltered text until more arrives: the last streamFilterTail bytes and any match that
+// reaches them, so a pattern whose match spans deltas is still redacted.
+type streamFilter struct {
+	filter  responseFilter
+	pending string
+}
+
+// newStreamFilter returns a streamFilter for filters, or nil when there are none.
+func newStreamFilter(filters responseFilters) *streamFilter {
+	if len(filters) == 0 {
+		return nil
+	}
+	return &streamFilter{filter: filters}
+}
+
+// write adds a delta and returns the filtered text that is safe to send.
+func (f *streamFilter) write(delta string) string {
+	text := f.pending + delta
+	cut := f.filter.Hold(text, max(len(text)-streamFilterTail, 0))
+	cut = max(cut, len(text)-streamFilterMaxHold)
+	// Never split a multi-byte character.
+	for cut > 0 && cut < len(text) && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	f.pending = text[cut:]
+	if cut == 0 {
+		return ""
+	}
+	return f.filter.Filter(text[:cut])
+}
+
+// flush returns the held back text once the reply is complete.
+func (f *streamFilter) flush() string {
+	text := f.filter.Filter(f.pending)
+	f.pending = ""
+	return text
+}
+
+// apply filters the content of delta in place, releasing the held back text with the
+// delta that finishes the reply. The raw upstream chunk is dropped when the content
+// changes, since it carries the unfiltered text. A nil streamFilter leaves delta as is.
+func (f *streamFilter) apply(delta *streamDelta) {
+	if f == nil {
+		return
+	}
+	content := f.write(delta.Content)
+	if delta.FinishReason != "" {
+		content += f.flush()
+	}
+	if content != delta.Content {
+		delta.Content = content
+		delta.raw = nil
+	}
+}
+
+// rest returns the text still held back when the stream ends without a finish reason.
+func (f *streamFilter) rest() string {
+	if f == nil {
+		return ""
+	}
+	return f.flush()
+}
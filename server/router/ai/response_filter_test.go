@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewResponseFilters(t *testing.T) {
+	require.Nil(t, newResponseFilters(slog.Default(), nil))
+	require.Nil(t, newResponseFilters(slog.Default(), []string{"(unclosed", " "}))
+
+	filters := newResponseFilters(slog.Default(), []string{"(unclosed", `https://intranet\.example\.com\S*`, `(?i)secret`})
+	require.Equal(t, "See [REDACTED] for the [REDACTED] plan.", filters.Filter("See https://intranet.example.com/wiki/x for the SECRET plan."))
+}
+
+func TestLoadRedactPatterns(t *testing.T) {
+	t.Setenv("MEMOS_AI_REDACT_PATTERNS", `["foo", "ba+r"]`)
+	require.Equal(t, []string{"foo", "ba+r"}, loadRedactPatterns(slog.Default()))
+
+	t.Setenv("MEMOS_AI_REDACT_PATTERNS", `foo`)
+	require.Nil(t, loadRedactPatterns(slog.Default()))
+}
+
+func TestFilterResponse(t *testing.T) {
+	s := &AIService{responseFilters: newResponseFilters(slog.Default(), []string{"secret"})}
+	body := s.filterResponse([]byte(`{"id":"x","choices":[{"index":0,"message":{"role":"assistant","content":"a secret"},"finish_reason":"stop"}],"usage":{"total_tokens":3}}`))
+
+	response, err := unmarshalResponse(body)
+	require.NoError(t, err)
+	require.Equal(t, "a [REDACTED]", response.Choices[0].Content())
+	require.Equal(t, "stop", response.Choices[0].FinishReason)
+	require.Equal(t, 3, response.Usage.TotalTokens)
+
+	// Bodies that cannot be rewritten are returned unchanged.
+	require.Equal(t, []byte(`not json`), s.filterResponse([]byte(`not json`)))
+}
+
+func TestStreamFilterRedactsAcrossChunks(t *testing.T) {
+	filter := newStreamFilter(newResponseFilters(slog.Default(), []string{`https://intranet\.example\.com\S*`}))
+	text := "Details are on https://intranet.example.com/wiki/launch-plan/2026/q4/final-review-notes-for-the-whole-team and nowhere else, so please read them carefully before the meeting."
+
+	var out strings.Builder
+	for i := 0; i < len(text); i += 5 {
+		delta := &streamDelta{Content: text[i:min(i+5, len(text))]}
+		filter.apply(delta)
+		out.WriteString(delta.Content)
+	}
+	out.WriteString(filter.rest())
+	require.Equal(t, "Details are on [REDACTED] and nowhere else, so please read them carefully before the meeting.", out.String())
+}
+
+func TestStreamFilterReleasesTailOnFinish(t *testing.T) {
+	filter := newStreamFilter(newResponseFilters(slog.Default(), []string{"secret"}))
+
+	delta := &streamDelta{Content: "short secret", raw: []byte(`{}`)}
+	filter.apply(delta)
+	require.True(t, delta.empty())
+
+	delta = &streamDelta{FinishReason: "stop"}
+	filter.apply(delta)
+	require.Equal(t, "short [REDACTED]", delta.Content)
+	require.Empty(t, filter.rest())
+}
+
+func TestStreamResponseAppliesFilters(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(`data: {"choices":[{"index":0,"delta":{"content":"the sec"}}]}` + "\n\n"))
+		_, _ = w.Write([]byte(`data: {"choices":[{"index":0,"delta":{"content":"ret word"},"finish_reason":"stop"}]}` + "\n\n"))
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer upstream.Close()
+
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	t.Setenv("MEMOS_OPENAI_API_KEY", "test-key")
+	t.Setenv("MEMOS_AI_REDACT_PATTERNS", `["secret"]`)
+	s := NewAIService(nil, "", "")
+
+	c, rec := newTestContext(`{"stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.NotContains(t, rec.Body.String(), "ret word")
+	require.Contains(t, rec.Body.String(), `"content":"the [REDACTED] word"`)
+	require.True(t, strings.HasSuffix(strings.TrimSpace(rec.Body.String()), "data: [DONE]"))
+}
@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	responseFormatText       = "text"
+	responseFormatJSONObject = "json_object"
+	responseFormatJSONSchema = "json_schema"
+)
+
+// jsonObjectFormat asks OpenAI-compatible providers for a single JSON object.
+var jsonObjectFormat = json.RawMessage(`{"type":"json_object"}`)
+
+// responseFormat is the part of an OpenAI response_format object the proxy inspects.
+type responseFormat struct {
+	Type       string `json:"type"`
+	JSONSchema *struct {
+		Schema json.RawMessage `json:"schema"`
+	} `json:"json_schema,omitempty"`
+}
+
+// parseResponseFormat decodes and checks a response_format value.
+func parseResponseFormat(raw json.RawMessage) (*responseFormat, error) {
+	format := new(responseFormat)
+	if err := json.Unmarshal(raw, format); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "response_format must be an object").SetInternal(err)
+	}
+	switch format.Type {
+	case responseFormatText, responseFormatJSONObject:
+	case responseFormatJSONSchema:
+		if format.JSONSchema == nil || len(format.JSONSchema.Schema) == 0 {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "response_format.json_schema.schema is required")
+		}
+	default:
+		return nil, echo.NewHTTPError(http.StatusBadRequest, `response_format.type must be one of text, json_object, json_schema`)
+	}
+	return format, nil
+}
+
+// supportsResponseFormat reports whether the provider enforces response_format itself.
+// Ollama translates it to its own format field.
+func supportsResponseFormat(provider Provider) bool {
+	switch provider.Name() {
+	case providerOpenAI, providerAzure, providerOllama:
+		return true
+	default:
+		return false
+	}
+}
+
+// applyResponseFormat strips response_format for providers that don't support it and asks
+// for JSON in the prompt instead.
+func applyResponseFormat(provider Provider, req *ChatCompletionRequest) {
+	if len(req.ResponseFormat) == 0 || supportsResponseFormat(provider) {
+		return
+	}
+	format, err := parseResponseFormat(req.ResponseFormat)
+	req.ResponseFormat = nil
+	if err != nil || format.Type == responseFormatText {
+		return
+	}
+	instruction := "Respond with a single valid JSON object and nothing else."
+	if format.Type == responseFormatJSONSchema {
+		instruction = "Respond with a single valid JSON object matching this JSON schema and nothing else: " + string(format.JSONSchema.Schema)
+	}
+	req.Messages = appendInstruction(req.Messages, instruction)
+}
+
+// appendInstruction adds instruction to the leading system message, prepending one when
+// there is none. It returns a new slice.
+func appendInstruction(messages []ChatCompletionMessage, instruction string) []ChatCompletionMessage {
+	if len(messages) > 0 && (messages[0].Role == roleSystem || messages[0].Role == roleDeveloper) && len(messages[0].Parts) == 0 {
+		result := slices.Clone(messages)
+		result[0].Content += "\n\n" + instruction
+		return result
+	}
+	return append([]ChatCompletionMessage{{Role: roleSystem, Content: instruction}}, messages...)
+}
+
+// ollamaFormat converts a response_format value to Ollama's format field: "json" for JSON
+// mode or the schema itself for structured output. It returns nil for plain text.
+func ollamaFormat(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return nil
+	}
+	format, err := parseResponseFormat(raw)
+	if err != nil {
+		return nil
+	}
+	switch format.Type {
+	case responseFormatJSONObject:
+		return json.RawMessage(`"json"`)
+	case responseFormatJSONSchema:
+		return format.JSONSchema.Schema
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseFormatPassthrough(t *testing.T) {
+	var received json.RawMessage
+	newChatUpstream(t, `{"ok":true}`, func(req *ChatCompletionRequest) {
+		received = req.ResponseFormat
+	})
+	s := NewAIService(nil, "", "test-key")
+
+	c, _ := newTestContext(`{"response_format":{"type":"json_object"},"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.JSONEq(t, `{"type":"json_object"}`, string(received))
+}
+
+func TestApplyResponseFormatDegrades(t *testing.T) {
+	anthropic, err := newProvider(ProviderConfig{Name: providerAnthropic})
+	require.NoError(t, err)
+
+	req := &ChatCompletionRequest{
+		ResponseFormat: jsonObjectFormat,
+		Messages:       []ChatCompletionMessage{{Role: "system", Content: "Suggest tags."}, {Role: "user", Content: "hi"}},
+	}
+	applyResponseFormat(anthropic, req)
+	require.Nil(t, req.ResponseFormat)
+	require.Len(t, req.Messages, 2)
+	require.Contains(t, req.Messages[0].Content, "Suggest tags.")
+	require.Contains(t, req.Messages[0].Content, "valid JSON object")
+
+	req = &ChatCompletionRequest{
+		ResponseFormat: json.RawMessage(`{"type":"json_schema","json_schema":{"name":"tags","schema":{"type":"object"}}}`),
+		Messages:       []ChatCompletionMessage{{Role: "user", Content: "hi"}},
+	}
+	applyResponseFormat(anthropic, req)
+	require.Len(t, req.Messages, 2)
+	require.Equal(t, "system", req.Messages[0].Role)
+	require.Contains(t, req.Messages[0].Content, `{"type":"object"}`)
+
+	openai, err := newProvider(ProviderConfig{Name: providerOpenAI})
+	require.NoError(t, err)
+	req = &ChatCompletionRequest{ResponseFormat: jsonObjectFormat, Messages: []ChatCompletionMessage{{Role: "user", Content: "hi"}}}
+	applyResponseFormat(openai, req)
+	require.Equal(t, jsonObjectFormat, req.ResponseFormat)
+	require.Len(t, req.Messages, 1)
+}
+
+func TestOllamaFormat(t *testing.T) {
+	require.Equal(t, json.RawMessage(`"json"`), ollamaFormat(jsonObjectFormat))
+	require.JSONEq(t, `{"type":"object"}`, string(ollamaFormat(json.RawMessage(`{"type":"json_schema","json_schema":{"schema":{"type":"object"}}}`))))
+	require.Nil(t, ollamaFormat(json.RawMessage(`{"type":"text"}`)))
+	require.Nil(t, ollamaFormat(nil))
+}
+
+func TestValidateResponseFormat(t *testing.T) {
+	s := NewAIService(nil, "", "test-key")
+	for _, format := range []string{`"json"`, `{"type":"yaml"}`, `{"type":"json_schema"}`} {
+		err := s.validateChatCompletionRequest(&ChatCompletionRequest{
+			Messages:       []ChatCompletionMessage{{Role: "user", Content: "hi"}},
+			ResponseFormat: json.RawMessage(format),
+		})
+		httpErr, ok := err.(*echo.HTTPError)
+		require.True(t, ok, format)
+		require.Equal(t, http.StatusBadRequest, httpErr.Code, format)
+	}
+}
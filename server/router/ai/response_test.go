@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		content string
+	}{
+		{
+			name:    "openai",
+			body:    `{"id":"chatcmpl-1","model":"gpt-4o-mini","choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`,
+			content: "hello",
+		},
+		{
+			name:    "github models content parts",
+			body:    `{"prompt_filter_results":[{"prompt_index":0}],"choices":[{"index":0,"content_filter_results":{},"message":{"role":"assistant","content":[{"type":"text","text":"hello"}]},"finish_reason":"stop"}]}`,
+			content: "hello",
+		},
+		{
+			name:    "legacy text",
+			body:    `{"choices":[{"index":0,"text":"hello","finish_reason":"stop"}]}`,
+			content: "hello",
+		},
+		{
+			name: "filtered",
+			body: `{"choices":[{"index":0,"message":{"role":"assistant","content":null},"finish_reason":"content_filter"}]}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := unmarshalResponse([]byte(test.body))
+			require.NoError(t, err)
+			require.Equal(t, test.content, response.Choices[0].Content())
+		})
+	}
+
+	response, err := unmarshalResponse([]byte(`{"choices":[{"index":0,"message":{"content":"hi"},"finish_reason":"length"}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`))
+	require.NoError(t, err)
+	require.Equal(t, "length", response.Choices[0].FinishReason)
+	require.Equal(t, &Usage{PromptTokens: 3, CompletionTokens: 1, TotalTokens: 4}, response.Usage)
+}
+
+func TestUnmarshalResponseRejectsUnexpectedEnvelopes(t *testing.T) {
+	for _, body := range []string{
+		`not json`,
+		`{"choices":[]}`,
+		`{"error":{"message":"model is overloaded"}}`,
+	} {
+		_, err := unmarshalResponse([]byte(body))
+		httpErr, ok := err.(*echo.HTTPError)
+		require.True(t, ok, body)
+		require.Equal(t, http.StatusBadGateway, httpErr.Code, body)
+	}
+}
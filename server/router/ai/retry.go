@@ -0,0 +1,195 @@
+package ai
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultMaxRetries is the number of retries used when MEMOS_AI_MAX_RETRIES is unset.
+	defaultMaxRetries = 3
+	// defaultRetryBaseDelay is the backoff before the first retry; it doubles on each attempt.
+	defaultRetryBaseDelay = 500 * time.Millisecond
+	// maxRetryDelay caps both the computed backoff and any Retry-After hint from the provider.
+	maxRetryDelay = 30 * time.Second
+	// defaultRetryBudget is how many upstream attempts a client request may make across all
+	// retry layers when MEMOS_AI_RETRY_BUDGET is unset: the first attempt and its transient
+	// retries, a JSON re-request and a failover.
+	defaultRetryBudget = defaultMaxRetries + 1 + defaultJSONRetries + 1
+)
+
+// Retry layers, as logged when the retry budget stops one.
+const (
+	retryLayerTransient = "transient"
+	retryLayerJSON      = "invalid_json"
+	retryLayerOverflow  = "context_overflow"
+	retryLayerFailover  = "failover"
+)
+
+type retryBudgetKey struct{}
+
+// retryBudget counts the upstream attempts left to a client request. Every layer that
+// sends a request again, from transient error retries to JSON re-requests, context
+// overflow retries and failover, draws on the same budget, so retries cannot compound.
+type retryBudget struct {
+	remaining atomic.Int64
+}
+
+// withRetryBudget gives the request served under ctx a fresh budget of attempts.
+func withRetryBudget(ctx context.Context, attempts int) context.Context {
+	budget := &retryBudget{}
+	budget.remaining.Store(int64(attempts))
+	return context.WithValue(ctx, retryBudgetKey{}, budget)
+}
+
+// reservedRetries is how many attempts of a request's budget the layers other than
+// transient retries may need: the JSON re-requests, a context overflow retry and a
+// failover, as far as each is enabled.
+func (s *AIService) reservedRetries() int64 {
+	reserved := int64(s.jsonRetries)
+	if s.overflowModel != "" || s.autoTruncate {
+		reserved++
+	}
+	if s.fallback != nil {
+		reserved++
+	}
+	return reserved
+}
+
+// retryBudgetMiddleware gives every request its own budget of MEMOS_AI_RETRY_BUDGET
+// upstream attempts.
+func (s *AIService) retryBudgetMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.SetRequest(c.Request().WithContext(withRetryBudget(c.Request().Context(), s.retryBudget)))
+		return next(c)
+	}
+}
+
+// spendAttempt takes an upstream attempt from ctx's budget. The first attempt of a request
+// is always made, so the budget only limits retries.
+func spendAttempt(ctx context.Context) {
+	if budget, ok := ctx.Value(retryBudgetKey{}).(*retryBudget); ok {
+		budget.remaining.Add(-1)
+	}
+}
+
+// canRetry reports whether ctx's budget has an attempt left for a retry by layer, logging
+// when it is used up. Transient retries leave reservedRetries attempts to the other layers,
+// so retrying a failing provider cannot rule out failing over from it. Requests without a
+// budget, such as those of tests and health checks, are not limited.
+func (s *AIService) canRetry(ctx context.Context, layer string) bool {
+	budget, ok := ctx.Value(retryBudgetKey{}).(*retryBudget)
+	if !ok {
+		return true
+	}
+	var reserved int64
+	if layer == retryLayerTransient {
+		reserved = s.reservedRetries()
+	}
+	if budget.remaining.Load() > reserved {
+		return true
+	}
+	s.log(ctx).Warn("AI retry budget exhausted, not retrying", "layer", layer, "budget", s.retryBudget)
+	return false
+}
+
+// isRetryableStatus reports whether an upstream status code indicates a transient failure.
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// doWithRetry sends the request built by newRequest, retrying transient failures with
+// exponential backoff and jitter. newRequest is called once per attempt so the request
+// body is fresh each time. When all retries are exhausted the last upstream response
+// (or error) is returned, as it is once the request's retry budget is used up. Cancelling
+// ctx aborts any pending backoff.
+func (s *AIService) doWithRetry(ctx context.Context, client httpDoer, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		spendAttempt(ctx)
+		resp, err := client.Do(req)
+		// A connection refused for pointing at a private address fails the same way again.
+		if attempt >= s.maxRetries || ctx.Err() != nil || errors.Is(err, errBlockedTarget) {
+			return resp, err
+		}
+		if err == nil && !isRetryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+		if !s.canRetry(ctx, retryLayerTransient) {
+			return resp, err
+		}
+
+		delay := s.backoff(attempt)
+		if err == nil {
+			if hint, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok && resp.StatusCode == http.StatusTooManyRequests {
+				delay = hint
+			}
+			s.log(ctx).Warn("AI provider returned a transient error, retrying", "status", resp.StatusCode, "attempt", attempt+1, "delay", delay)
+			// Drain the body so the underlying connection can be reused.
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		} else {
+			s.log(ctx).Warn("failed to contact AI provider, retrying", "error", err, "attempt", attempt+1, "delay", delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the exponential delay for the given attempt with up to 50% jitter.
+func (s *AIService) backoff(attempt int) time.Duration {
+	delay := s.retryBaseDelay << attempt
+	if delay <= 0 || delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int64N(int64(half)+1))
+}
+
+// parseRetryAfter parses a Retry-After header given either as seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		delay = time.Until(at)
+	} else {
+		return 0, false
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay, true
+}
@@ -0,0 +1,191 @@
+package ai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatCompletionRetriesTransientErrors(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[]}`))
+	}))
+	defer upstream.Close()
+
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	s := NewAIService(nil, "", "test-key")
+	s.retryBaseDelay = time.Millisecond
+
+	c, rec := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, int32(3), calls.Load())
+}
+
+func TestChatCompletionGivesUpAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte(`{"error":"down"}`))
+	}))
+	defer upstream.Close()
+
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	t.Setenv("MEMOS_AI_MAX_RETRIES", "2")
+	s := NewAIService(nil, "", "test-key")
+	s.retryBaseDelay = time.Millisecond
+
+	c, _ := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	err := s.ChatCompletion(c)
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusBadGateway, httpErr.Code)
+	require.Equal(t, int32(3), calls.Load())
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	delay, ok := parseRetryAfter("2")
+	require.True(t, ok)
+	require.Equal(t, 2*time.Second, delay)
+
+	delay, ok = parseRetryAfter("3600")
+	require.True(t, ok)
+	require.Equal(t, maxRetryDelay, delay)
+
+	_, ok = parseRetryAfter("soon")
+	require.False(t, ok)
+
+	_, ok = parseRetryAfter(time.Now().Add(5 * time.Second).UTC().Format(http.TimeFormat))
+	require.True(t, ok)
+}
+
+func TestRetryBudgetCapsFailover(t *testing.T) {
+	var primaryCalls, fallbackCalls atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		primaryCalls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fallbackCalls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer fallback.Close()
+
+	t.Setenv("MEMOS_AI_BASE_URL", primary.URL)
+	t.Setenv("MEMOS_AI_FALLBACK_BASE_URL", fallback.URL)
+	t.Setenv("MEMOS_AI_MAX_RETRIES", "3")
+	t.Setenv("MEMOS_AI_RETRY_BUDGET", "6")
+	s := NewAIService(nil, "", "test-key")
+	s.retryBaseDelay = time.Millisecond
+
+	c, _ := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	err := s.retryBudgetMiddleware(s.ChatCompletion)(c)
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusServiceUnavailable, httpErr.Code)
+	// The primary's transient retries leave the failover its attempt; without the budget the
+	// fallback would be tried four times as well.
+	require.Equal(t, int32(4), primaryCalls.Load())
+	require.Equal(t, int32(1), fallbackCalls.Load())
+}
+
+func TestDefaultRetryBudgetKeepsFailover(t *testing.T) {
+	var primaryCalls atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		primaryCalls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"from fallback"},"finish_reason":"stop"}]}`))
+	}))
+	defer fallback.Close()
+
+	t.Setenv("MEMOS_AI_BASE_URL", primary.URL)
+	t.Setenv("MEMOS_AI_FALLBACK_BASE_URL", fallback.URL)
+	s := NewAIService(nil, "", "test-key")
+	s.retryBaseDelay = time.Millisecond
+
+	c, rec := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.retryBudgetMiddleware(s.ChatCompletion)(c))
+	require.Equal(t, providerRoleFallback, rec.Header().Get(headerXAIProvider))
+	require.Equal(t, int32(defaultMaxRetries+1), primaryCalls.Load())
+}
+
+func TestRetryBudgetSharedWithOverflowRetry(t *testing.T) {
+	var primaryCalls, fallbackCalls atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if primaryCalls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(contextLengthBody))
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fallbackCalls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer fallback.Close()
+
+	t.Setenv("MEMOS_AI_BASE_URL", primary.URL)
+	t.Setenv("MEMOS_AI_FALLBACK_BASE_URL", fallback.URL)
+	t.Setenv("MEMOS_AI_OVERFLOW_FALLBACK_MODEL", "gpt-4o-long")
+	t.Setenv("MEMOS_AI_MAX_RETRIES", "3")
+	s := NewAIService(nil, "", "test-key")
+	s.retryBaseDelay = time.Millisecond
+
+	// The overflow retry draws on the default budget and its transient retries stop short of
+	// the attempt reserved for failover; without the budget the request would make nine
+	// attempts.
+	c, _ := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	httpErr, ok := s.retryBudgetMiddleware(s.ChatCompletion)(c).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusServiceUnavailable, httpErr.Code)
+	require.Equal(t, int32(3), primaryCalls.Load())
+	require.Equal(t, int32(1), fallbackCalls.Load())
+}
+
+func TestRetryBudgetSharedWithJSONRetries(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"not json"},"finish_reason":"stop"}]}`))
+	}))
+	defer upstream.Close()
+
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	t.Setenv("MEMOS_AI_JSON_RETRIES", "5")
+	t.Setenv("MEMOS_AI_RETRY_BUDGET", "4")
+	s := NewAIService(nil, "", "test-key")
+	s.retryBaseDelay = time.Millisecond
+
+	// Three JSON re-requests use up the budget; the remaining JSON retries are never sent.
+	// Every request has a budget of its own.
+	for i := range 2 {
+		calls.Store(0)
+		c, _ := newTestContext(`{"content":"Meeting notes about the roadmap."}`)
+		httpErr, ok := s.retryBudgetMiddleware(s.SuggestTags)(c).(*echo.HTTPError)
+		require.True(t, ok, "request %d", i)
+		response, ok := httpErr.Message.(*ErrorResponse)
+		require.True(t, ok)
+		require.Equal(t, errorCodeInvalidJSON, response.Error.Code)
+		require.Equal(t, int32(4), calls.Load())
+	}
+}
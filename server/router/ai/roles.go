@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	roleSystem    = "system"
+	roleDeveloper = "developer"
+	roleUser      = "user"
+	roleAssistant = "assistant"
+	roleTool      = "tool"
+)
+
+// defaultDeveloperRoleModels lists the model name prefixes that expect instructions in the
+// developer role rather than system. Override with MEMOS_AI_DEVELOPER_ROLE_MODELS.
+var defaultDeveloperRoleModels = []string{"o1", "o3", "o4"}
+
+// allowedRoles returns the message roles the provider understands. Anthropic and Gemini
+// take instructions separately from the conversation and have no tool role here.
+func allowedRoles(provider Provider) []string {
+	switch provider.Name() {
+	case providerOpenAI, providerAzure, providerOllama:
+		return []string{roleSystem, roleDeveloper, roleUser, roleAssistant, roleTool}
+	default:
+		return []string{roleSystem, roleDeveloper, roleUser, roleAssistant}
+	}
+}
+
+// validateRoles rejects messages whose role the configured provider does not accept.
+func (s *AIService) validateRoles(messages []ChatCompletionMessage) error {
+	roles := allowedRoles(s.provider)
+	for i, message := range messages {
+		if !slices.Contains(roles, message.Role) {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("messages[%d]: invalid role %q, must be one of %s", i, message.Role, strings.Join(roles, ", ")))
+		}
+	}
+	return nil
+}
+
+// normalizeRoles maps instruction roles to the one the provider and model expect: developer
+// becomes system for providers without a developer role, and system becomes developer for
+// models that require it. It returns a new slice when any role changes.
+func (s *AIService) normalizeRoles(model string, messages []ChatCompletionMessage) []ChatCompletionMessage {
+	from, to := roleDeveloper, roleSystem
+	if s.provider.Name() == providerOpenAI || s.provider.Name() == providerAzure {
+		if !s.usesDeveloperRole(model) {
+			return messages
+		}
+		from, to = roleSystem, roleDeveloper
+	}
+	if !slices.ContainsFunc(messages, func(message ChatCompletionMessage) bool { return message.Role == from }) {
+		return messages
+	}
+	normalized := slices.Clone(messages)
+	for i := range normalized {
+		if normalized[i].Role == from {
+			normalized[i].Role = to
+		}
+	}
+	return normalized
+}
+
+// usesDeveloperRole reports whether model matches one of the developer role model prefixes.
+func (s *AIService) usesDeveloperRole(model string) bool {
+	return matchesModelPrefix(s.developerRoleModels, model)
+}
@@ -0,0 +1,50 @@
+package ai
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRoles(t *testing.T) {
+	s := NewAIService(nil, "", "test-key")
+	require.NoError(t, s.validateRoles([]ChatCompletionMessage{
+		{Role: "developer", Content: "be brief"},
+		{Role: "user", Content: "hi"},
+		{Role: "tool", Content: "{}", ToolCallID: "call_1"},
+	}))
+
+	err := s.validateRoles([]ChatCompletionMessage{{Role: "user"}, {Role: "moderator"}})
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusBadRequest, httpErr.Code)
+	require.Contains(t, httpErr.Message, `messages[1]: invalid role "moderator"`)
+	require.Contains(t, httpErr.Message, "system, developer, user, assistant, tool")
+
+	t.Setenv("MEMOS_AI_PROVIDER", providerAnthropic)
+	s = NewAIService(nil, "", "test-key")
+	require.Error(t, s.validateRoles([]ChatCompletionMessage{{Role: "tool"}}))
+}
+
+func TestNormalizeRoles(t *testing.T) {
+	s := NewAIService(nil, "", "test-key")
+	messages := []ChatCompletionMessage{{Role: "system", Content: "be brief"}, {Role: "user", Content: "hi"}}
+
+	require.Equal(t, messages, s.normalizeRoles("openai/gpt-4o", messages))
+	normalized := s.normalizeRoles("openai/o3-mini", messages)
+	require.Equal(t, "developer", normalized[0].Role)
+	require.Equal(t, "system", messages[0].Role, "the input slice is not modified")
+
+	t.Setenv("MEMOS_AI_PROVIDER", providerAnthropic)
+	s = NewAIService(nil, "", "test-key")
+	normalized = s.normalizeRoles("claude-sonnet-4-5", []ChatCompletionMessage{{Role: "developer", Content: "be brief"}})
+	require.Equal(t, "system", normalized[0].Role)
+
+	t.Setenv("MEMOS_AI_PROVIDER", "")
+	t.Setenv("MEMOS_AI_DEVELOPER_ROLE_MODELS", "gpt-5")
+	s = NewAIService(nil, "", "test-key")
+	require.Equal(t, "developer", s.normalizeRoles("gpt-5-mini", messages)[0].Role)
+	require.Equal(t, "system", s.normalizeRoles("o3", messages)[0].Role)
+}
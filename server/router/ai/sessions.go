@@ -0,0 +1,176 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/usememos/memos/store"
+)
+
+// maxSessionTitleChars bounds the title of a saved conversation.
+const maxSessionTitleChars = 256
+
+// CreateSessionRequest starts a saved conversation.
+type CreateSessionRequest struct {
+	Title string `json:"title"`
+}
+
+// Session is a saved conversation owned by the current user.
+type Session struct {
+	ID         int32  `json:"id"`
+	Title      string `json:"title"`
+	CreateTime int64  `json:"create_time"`
+}
+
+type ListSessionsResponse struct {
+	Sessions []*Session `json:"sessions"`
+}
+
+// CreateSession creates an empty conversation. Chat requests carrying its id append to it.
+func (s *AIService) CreateSession(c echo.Context) error {
+	ctx := c.Request().Context()
+	user, err := s.requireSessionUser(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	request := new(CreateSessionRequest)
+	if err := c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	}
+	title := strings.TrimSpace(request.Title)
+	if utf8.RuneCountInString(title) > maxSessionTitleChars {
+		return echo.NewHTTPError(http.StatusBadRequest, "title is too long")
+	}
+
+	session, err := s.store.CreateAISession(ctx, &store.AISession{
+		CreatorID: user.ID,
+		Title:     title,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create session").SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, convertSession(session))
+}
+
+// ListSessions returns the current user's conversations, newest first.
+func (s *AIService) ListSessions(c echo.Context) error {
+	ctx := c.Request().Context()
+	user, err := s.requireSessionUser(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := s.store.ListAISessions(ctx, &store.FindAISession{CreatorID: &user.ID})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list sessions").SetInternal(err)
+	}
+	response := &ListSessionsResponse{Sessions: make([]*Session, 0, len(sessions))}
+	for _, session := range sessions {
+		response.Sessions = append(response.Sessions, convertSession(session))
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// requireSessionUser returns the authenticated user; sessions are never shared or anonymous.
+func (s *AIService) requireSessionUser(ctx context.Context, c echo.Context) (*store.User, error) {
+	if s.store == nil {
+		return nil, echo.NewHTTPError(http.StatusServiceUnavailable, "Sessions are not available")
+	}
+	user, err := s.getCurrentUser(ctx, c)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get current user").SetInternal(err)
+	}
+	return s.sessionUser(user)
+}
+
+// sessionUser checks that user, the resolved current user, may use sessions.
+func (s *AIService) sessionUser(user *store.User) (*store.User, error) {
+	if s.store == nil {
+		return nil, echo.NewHTTPError(http.StatusServiceUnavailable, "Sessions are not available")
+	}
+	if user == nil {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "Authentication required to use sessions")
+	}
+	return user, nil
+}
+
+// loadSession returns the current user's session and its stored messages. Sessions of
+// other users are reported as not found.
+func (s *AIService) loadSession(ctx context.Context, user *store.User, sessionID int32) (*store.AISession, []ChatCompletionMessage, error) {
+	user, err := s.sessionUser(user)
+	if err != nil {
+		return nil, nil, err
+	}
+	session, err := s.store.GetAISession(ctx, &store.FindAISession{ID: &sessionID, CreatorID: &user.ID})
+	if err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get session").SetInternal(err)
+	}
+	if session == nil {
+		return nil, nil, echo.NewHTTPError(http.StatusNotFound, "Session not found")
+	}
+
+	messages, err := s.store.ListAIMessages(ctx, &store.FindAIMessage{SessionID: &session.ID})
+	if err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to load session messages").SetInternal(err)
+	}
+	history := make([]ChatCompletionMessage, 0, len(messages))
+	for _, message := range messages {
+		history = append(history, ChatCompletionMessage{Role: message.Role, Content: message.Content})
+	}
+	return session, history, nil
+}
+
+// saveSessionMessages appends the request's messages and the assistant reply to a session.
+// Only the text of multimodal messages is kept.
+func (s *AIService) saveSessionMessages(ctx context.Context, sessionID int32, messages []ChatCompletionMessage, reply string) error {
+	messages = append(messages, ChatCompletionMessage{Role: "assistant", Content: reply})
+	for _, message := range messages {
+		// Instructions are supplied per request, not replayed from history.
+		if message.Role == roleSystem || message.Role == roleDeveloper {
+			continue
+		}
+		if _, err := s.store.CreateAIMessage(ctx, &store.AIMessage{
+			SessionID: sessionID,
+			Role:      message.Role,
+			Content:   messageText(message),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveSession persists a completed exchange. The reply has already been produced, so a
+// failure is logged rather than returned.
+func (s *AIService) saveSession(ctx context.Context, session *store.AISession, messages []ChatCompletionMessage, reply string) {
+	if err := s.saveSessionMessages(context.WithoutCancel(ctx), session.ID, messages, reply); err != nil {
+		s.log(ctx).Error("failed to save AI session messages", "session_id", session.ID, "error", err)
+	}
+}
+
+// messageText returns a message's plain-text content, joining the text parts of multimodal messages.
+func messageText(message ChatCompletionMessage) string {
+	if len(message.Parts) == 0 {
+		return message.Content
+	}
+	var texts []string
+	for _, part := range message.Parts {
+		if part.Type == contentPartText {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func convertSession(session *store.AISession) *Session {
+	return &Session{
+		ID:         session.ID,
+		Title:      session.Title,
+		CreateTime: session.CreatedTs,
+	}
+}
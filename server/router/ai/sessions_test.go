@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usememos/memos/store"
+	teststore "github.com/usememos/memos/store/test"
+)
+
+func TestChatCompletionWithSession(t *testing.T) {
+	ctx := context.Background()
+	ts := teststore.NewTestingStore(ctx, t)
+	defer ts.Close()
+	user, err := ts.CreateUser(ctx, &store.User{Username: "session", Role: store.RoleUser, Email: "session@test.com"})
+	require.NoError(t, err)
+	other, err := ts.CreateUser(ctx, &store.User{Username: "other", Role: store.RoleUser, Email: "other@test.com"})
+	require.NoError(t, err)
+
+	var received []ChatCompletionMessage
+	newChatUpstream(t, "reply", func(req *ChatCompletionRequest) {
+		received = req.Messages
+	})
+	s := NewAIService(ts, "secret", "test-key")
+
+	c, rec := newTestContext(`{"title":"Planning"}`)
+	c.Set(currentUserContextKey, user)
+	require.NoError(t, s.CreateSession(c))
+	session := new(Session)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), session))
+	require.Equal(t, "Planning", session.Title)
+
+	chat := func(u *store.User, content string) error {
+		c, _ := newTestContext(`{"session_id":` + strconv.Itoa(int(session.ID)) + `,"messages":[{"role":"user","content":"` + content + `"}]}`)
+		c.Set(currentUserContextKey, u)
+		return s.ChatCompletion(c)
+	}
+	require.NoError(t, chat(user, "first"))
+	require.Len(t, received, 1)
+	require.NoError(t, chat(user, "second"))
+	require.Equal(t, []string{"first", "reply", "second"}, []string{received[0].Content, received[1].Content, received[2].Content})
+	require.Equal(t, "assistant", received[1].Role)
+
+	messages, err := ts.ListAIMessages(ctx, &store.FindAIMessage{SessionID: &session.ID})
+	require.NoError(t, err)
+	require.Len(t, messages, 4)
+
+	err = chat(other, "intrude")
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusNotFound, httpErr.Code)
+
+	c, rec = newTestContext(``)
+	c.Set(currentUserContextKey, other)
+	require.NoError(t, s.ListSessions(c))
+	require.JSONEq(t, `{"sessions":[]}`, rec.Body.String())
+}
+
+func TestSessionsRequireAuthentication(t *testing.T) {
+	ctx := context.Background()
+	ts := teststore.NewTestingStore(ctx, t)
+	defer ts.Close()
+	s := NewAIService(ts, "secret", "test-key")
+
+	c, _ := newTestContext(`{}`)
+	err := s.CreateSession(c)
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusUnauthorized, httpErr.Code)
+}
@@ -0,0 +1,65 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+// requestTracker counts in-flight AI requests so shutdown can wait for them. Once closed
+// it admits no new requests.
+type requestTracker struct {
+	mutex  sync.Mutex
+	closed bool
+	active sync.WaitGroup
+}
+
+// begin admits a request, returning false once the tracker is closed. Every admitted
+// request must call the tracker's active.Done when it finishes.
+func (t *requestTracker) begin() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.closed {
+		return false
+	}
+	t.active.Add(1)
+	return true
+}
+
+// drainMiddleware rejects requests with 503 once Shutdown has been called and tracks the
+// others until they complete, streams included.
+func (s *AIService) drainMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !s.requests.begin() {
+			return echo.NewHTTPError(http.StatusServiceUnavailable, "AI service is shutting down")
+		}
+		defer s.requests.active.Done()
+		return next(c)
+	}
+}
+
+// Shutdown stops accepting AI requests and waits for in-flight ones, such as streams and
+// WebSocket chats, to finish. It returns the context's error if they are still running
+// when ctx is done.
+func (s *AIService) Shutdown(ctx context.Context) error {
+	s.requests.mutex.Lock()
+	s.requests.closed = true
+	s.requests.mutex.Unlock()
+	// Jobs are kept in memory only, so they cannot outlive the service.
+	s.jobs.close()
+
+	drained := make(chan struct{})
+	go func() {
+		s.requests.active.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "AI requests still in flight")
+	}
+}
@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	s := NewAIService(nil, "", "test-key")
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := s.drainMiddleware(func(echo.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+
+	inFlight := make(chan error)
+	go func() {
+		c, _ := newTestContext("")
+		inFlight <- handler(c)
+	}()
+	<-started
+
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- s.Shutdown(context.Background())
+	}()
+	// Wait until Shutdown has closed the tracker, then check new requests are rejected.
+	require.Eventually(t, func() bool {
+		s.requests.mutex.Lock()
+		defer s.requests.mutex.Unlock()
+		return s.requests.closed
+	}, time.Second, time.Millisecond)
+	c, _ := newTestContext("")
+	httpErr, ok := s.drainMiddleware(func(echo.Context) error { return nil })(c).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusServiceUnavailable, httpErr.Code)
+
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown returned while a request was in flight")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	require.NoError(t, <-inFlight)
+	require.NoError(t, <-shutdown)
+}
+
+func TestShutdownGivesUpWhenContextExpires(t *testing.T) {
+	s := NewAIService(nil, "", "test-key")
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	go func() {
+		c, _ := newTestContext("")
+		_ = s.drainMiddleware(func(echo.Context) error {
+			close(started)
+			<-release
+			return nil
+		})(c)
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, s.Shutdown(ctx), context.DeadlineExceeded)
+}
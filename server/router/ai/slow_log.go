@@ -0,0 +1,91 @@
+package ai
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultSlowThreshold is how long an AI request may take before it is logged as slow.
+const defaultSlowThreshold = 10 * time.Second
+
+// Kinds of slow requests: streams that were slow to start and requests that were slow to
+// finish.
+const (
+	slowFirstByte = "first_byte"
+	slowTotal     = "total"
+)
+
+var slowRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "memos",
+	Subsystem: "ai",
+	Name:      "slow_requests_total",
+	Help:      "Total number of AI requests slower than MEMOS_AI_SLOW_THRESHOLD, by endpoint and kind.",
+}, []string{"endpoint", "kind"})
+
+type requestStatsKey struct{}
+
+// requestStats collects the models and tokens of the completions made while handling a
+// request, for the slow request log.
+type requestStats struct {
+	mutex            sync.Mutex
+	models           []string
+	promptTokens     int
+	completionTokens int
+}
+
+func withRequestStats(ctx context.Context) (context.Context, *requestStats) {
+	stats := &requestStats{}
+	return context.WithValue(ctx, requestStatsKey{}, stats), stats
+}
+
+func (r *requestStats) add(model string, usage *Usage) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !slices.Contains(r.models, model) {
+		r.models = append(r.models, model)
+	}
+	r.promptTokens += usage.PromptTokens
+	r.completionTokens += usage.CompletionTokens
+}
+
+// logSlowRequest warns about a request that took at least slowThreshold, telling streams
+// that were slow to send their first byte apart from those that were only slow to finish.
+// firstByte is zero for non-streaming requests.
+func (s *AIService) logSlowRequest(c echo.Context, endpoint string, stats *requestStats, duration, firstByte time.Duration) {
+	if duration < s.slowThreshold {
+		return
+	}
+	stats.mutex.Lock()
+	models := slices.Clone(stats.models)
+	attrs := []any{
+		"endpoint", endpoint,
+		"prompt_tokens", stats.promptTokens,
+		"completion_tokens", stats.completionTokens,
+		"duration", duration,
+		"threshold", s.slowThreshold,
+	}
+	stats.mutex.Unlock()
+	// Streams that report no usage still name the model that served them.
+	if model := c.Response().Header().Get(headerXAIModel); len(models) == 0 && model != "" {
+		models = []string{model}
+	}
+	attrs = append(attrs, "models", models)
+
+	ctx := c.Request().Context()
+	switch {
+	case firstByte >= s.slowThreshold:
+		slowRequestsTotal.WithLabelValues(endpoint, slowFirstByte).Inc()
+		s.log(ctx).Warn("slow AI stream: first byte exceeded the threshold", append(attrs, "first_byte", firstByte)...)
+	case firstByte > 0:
+		slowRequestsTotal.WithLabelValues(endpoint, slowTotal).Inc()
+		s.log(ctx).Warn("slow AI stream: completion exceeded the threshold", append(attrs, "first_byte", firstByte)...)
+	default:
+		slowRequestsTotal.WithLabelValues(endpoint, slowTotal).Inc()
+		s.log(ctx).Warn("slow AI request", attrs...)
+	}
+}
@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsMiddlewareLogsSlowRequests(t *testing.T) {
+	var logs bytes.Buffer
+	t.Setenv("MEMOS_AI_SLOW_THRESHOLD", "20ms")
+	s := NewAIServiceWithLogger(nil, "", "test-key", slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	c, _ := newTestContext("")
+	c.SetPath("/api/v1/ai/slow_test")
+	require.NoError(t, s.metricsMiddleware(func(c echo.Context) error {
+		recordUsage(c.Request().Context(), "slow-model", &Usage{PromptTokens: 7, CompletionTokens: 3, TotalTokens: 10})
+		time.Sleep(30 * time.Millisecond)
+		return c.NoContent(http.StatusOK)
+	})(c))
+	require.Contains(t, logs.String(), `"msg":"slow AI request"`)
+	require.Contains(t, logs.String(), `"models":["slow-model"]`)
+	require.Contains(t, logs.String(), `"prompt_tokens":7`)
+	require.Contains(t, logs.String(), `"completion_tokens":3`)
+
+	// A stream that starts quickly but runs long is slow to complete, not to start.
+	logs.Reset()
+	c, _ = newTestContext("")
+	c.SetPath("/api/v1/ai/slow_test")
+	require.NoError(t, s.metricsMiddleware(func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
+		c.Response().WriteHeader(http.StatusOK)
+		_, _ = c.Response().Write([]byte("data: hi\n\n"))
+		time.Sleep(30 * time.Millisecond)
+		return nil
+	})(c))
+	require.Contains(t, logs.String(), `"msg":"slow AI stream: completion exceeded the threshold"`)
+	require.Contains(t, logs.String(), `"first_byte"`)
+
+	// Fast requests are not logged.
+	logs.Reset()
+	c, _ = newTestContext("")
+	require.NoError(t, s.metricsMiddleware(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})(c))
+	require.Empty(t, logs.String())
+}
@@ -0,0 +1,201 @@
+package ai
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// resolveTimeout bounds the DNS lookup performed when validating a provider endpoint.
+const resolveTimeout = 5 * time.Second
+
+// errBlockedTarget marks endpoints rejected because they point at a private address.
+var errBlockedTarget = errors.New("AI provider endpoint resolves to a private address")
+
+// errHostNotAllowed marks endpoints whose host is missing from a non-empty MEMOS_AI_ALLOWED_HOSTS.
+var errHostNotAllowed = errors.New("AI provider host is not in MEMOS_AI_ALLOWED_HOSTS")
+
+// lookupIPAddr resolves host names for validateTarget. Tests replace it to simulate DNS answers.
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which net.IP does not classify as private.
+var sharedAddressSpace = &net.IPNet{IP: net.IP{100, 64, 0, 0}, Mask: net.CIDRMask(10, 32)}
+
+// validateTarget rejects provider endpoints on loopback, private, link-local or unspecified
+// addresses, such as 169.254.169.254 or services on localhost. Host names are resolved and
+// every returned address is checked, so a name that resolves to both a public and a private
+// address is rejected. MEMOS_AI_ALLOW_PRIVATE=true disables the check, which self-hosted
+// providers such as Ollama need.
+func validateTarget(rawURL string, allowPrivate bool) error {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.Wrap(err, "invalid AI provider endpoint")
+	}
+	if target.Scheme != "https" && target.Scheme != "http" {
+		return errors.Errorf("AI provider endpoint must be an http(s) URL: %s", rawURL)
+	}
+	host := strings.ToLower(strings.TrimSuffix(target.Hostname(), "."))
+	if host == "" {
+		return errors.Errorf("AI provider endpoint has no host: %s", rawURL)
+	}
+	if allowPrivate {
+		return nil
+	}
+
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || host == "metadata" || strings.HasSuffix(host, ".internal") {
+		return errors.Wrapf(errBlockedTarget, "host %q", host)
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if isPrivateIP(ip) {
+			return errors.Wrapf(errBlockedTarget, "host %q", host)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	addrs, err := lookupIPAddr(ctx, host)
+	if err != nil {
+		return errors.Wrapf(err, "failed to resolve AI provider host %q", host)
+	}
+	if len(addrs) == 0 {
+		return errors.Errorf("AI provider host %q has no addresses", host)
+	}
+	for _, addr := range addrs {
+		if isPrivateIP(addr.IP) {
+			return errors.Wrapf(errBlockedTarget, "host %q resolves to %s", host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// checkAllowedHost rejects rawURL unless its host, with or without the port, is one of
+// allowedHosts. It applies on top of validateTarget, so only endpoints the operator listed
+// are ever contacted; an empty allowlist allows any host.
+func checkAllowedHost(allowedHosts []string, rawURL string) error {
+	if len(allowedHosts) == 0 {
+		return nil
+	}
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.Wrap(err, "invalid AI provider endpoint")
+	}
+	if !hostAllowed(allowedHosts, target) {
+		return errors.Wrapf(errHostNotAllowed, "host %q", target.Hostname())
+	}
+	return nil
+}
+
+// hostAllowed reports whether target's host, with or without the port, is one of allowedHosts.
+func hostAllowed(allowedHosts []string, target *url.URL) bool {
+	host := strings.TrimSuffix(target.Hostname(), ".")
+	return host != "" && slices.ContainsFunc(allowedHosts, func(allowed string) bool {
+		return strings.EqualFold(allowed, host) || strings.EqualFold(allowed, target.Host)
+	})
+}
+
+// guardedDialContext returns a DialContext that refuses connections to private addresses,
+// so a host that passed validateTarget cannot be rebound to one afterwards: the address is
+// checked in the dialer's Control hook, right before the connection is made. Host names are
+// resolved with lookupIPAddr and each address is tried in turn. Connections to proxies in
+// proxies, given as host:port, are not checked; the proxy resolves the provider itself.
+func guardedDialContext(dialer *net.Dialer, proxies []string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	guarded := *dialer
+	guarded.Control = func(_, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(host); ip == nil || isPrivateIP(ip) {
+			return errors.Wrapf(errBlockedTarget, "address %s", address)
+		}
+		return nil
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if slices.Contains(proxies, addr) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if net.ParseIP(host) != nil {
+			return guarded.DialContext(ctx, network, addr)
+		}
+		addrs, err := lookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		dialErr := errors.Errorf("host %q has no addresses", host)
+		for _, ip := range addrs {
+			conn, err := guarded.DialContext(ctx, network, net.JoinHostPort(ip.IP.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			dialErr = err
+		}
+		return nil, dialErr
+	}
+}
+
+// proxyAddrs returns the host:port addresses of the proxies outbound requests may go
+// through: proxy when it is set, otherwise the standard proxy variables.
+func proxyAddrs(proxy string) []string {
+	values := []string{proxy}
+	if proxy == "" {
+		values = []string{os.Getenv("HTTPS_PROXY"), os.Getenv("https_proxy"), os.Getenv("HTTP_PROXY"), os.Getenv("http_proxy")}
+	}
+	var addrs []string
+	for _, value := range values {
+		proxyURL, err := url.Parse(value)
+		if value == "" || err != nil || proxyURL.Hostname() == "" {
+			continue
+		}
+		port := proxyURL.Port()
+		if port == "" {
+			port = map[string]string{"https": "443", "socks5": "1080", "socks5h": "1080"}[proxyURL.Scheme]
+		}
+		if port == "" {
+			port = "80"
+		}
+		addrs = append(addrs, net.JoinHostPort(proxyURL.Hostname(), port))
+	}
+	return addrs
+}
+
+// isPrivateIP reports whether ip is not routable on the public internet. IPv4-mapped IPv6
+// addresses are checked as their IPv4 form.
+func isPrivateIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
+
+// providerEndpoint returns the URL provider sends requests to.
+func providerEndpoint(provider Provider) string {
+	switch p := provider.(type) {
+	case *openaiProvider:
+		return p.url
+	case *azureProvider:
+		return p.url
+	case *anthropicProvider:
+		return p.baseURL
+	case *ollamaProvider:
+		return p.baseURL
+	case *geminiProvider:
+		return p.baseURL
+	case *mockProvider:
+		return mockURL
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,221 @@
+package ai
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	// Most tests talk to httptest servers on 127.0.0.1; the SSRF tests re-enable the guard.
+	os.Setenv("MEMOS_AI_ALLOW_PRIVATE", "true")
+	os.Exit(m.Run())
+}
+
+// fakeResolver makes validateTarget resolve host names from answers for the duration of the test.
+func fakeResolver(t *testing.T, answers map[string][]string) {
+	original := lookupIPAddr
+	t.Cleanup(func() { lookupIPAddr = original })
+	lookupIPAddr = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		ips, ok := answers[host]
+		if !ok {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		var addrs []net.IPAddr
+		for _, ip := range ips {
+			addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+		}
+		return addrs, nil
+	}
+}
+
+func TestValidateTarget(t *testing.T) {
+	fakeResolver(t, map[string][]string{
+		"api.example.com":      {"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946"},
+		"rebind.example.com":   {"93.184.216.34", "169.254.169.254"},
+		"loopback.example.com": {"127.0.0.1"},
+		"ula.example.com":      {"2001:db8::1", "fd00::1"},
+	})
+
+	for _, rawURL := range []string{
+		"https://api.example.com/v1/chat/completions",
+		"https://93.184.216.34/v1",
+		"https://[2606:2800:220:1:248:1893:25c8:1946]/v1",
+	} {
+		require.NoError(t, validateTarget(rawURL, false), rawURL)
+	}
+
+	for _, rawURL := range []string{
+		// IPv4 literals.
+		"http://127.0.0.1:11434",
+		"http://10.0.0.5/v1",
+		"http://172.16.0.1/v1",
+		"http://192.168.1.1/v1",
+		"http://169.254.169.254/latest/meta-data",
+		"http://100.64.0.1/v1",
+		"http://0.0.0.0:8080",
+		// IPv6 literals.
+		"http://[::1]:11434",
+		"http://[fe80::1]/v1",
+		"http://[fd12:3456::1]/v1",
+		"http://[::ffff:127.0.0.1]/v1",
+		"http://[::]/v1",
+		// Names that resolve, in part or fully, to private addresses.
+		"https://rebind.example.com/v1",
+		"https://loopback.example.com/v1",
+		"https://ula.example.com/v1",
+		"http://localhost:11434",
+		"http://ollama.localhost",
+		"http://metadata.google.internal/computeMetadata/v1",
+	} {
+		err := validateTarget(rawURL, false)
+		require.ErrorIs(t, err, errBlockedTarget, rawURL)
+	}
+
+	err := validateTarget("https://unknown.example.com/v1", false)
+	require.Error(t, err)
+	require.False(t, errors.Is(err, errBlockedTarget))
+	require.Error(t, validateTarget("ftp://api.example.com/", false))
+}
+
+func TestValidateTargetAllowPrivate(t *testing.T) {
+	require.NoError(t, validateTarget("http://localhost:11434", true))
+	require.NoError(t, validateTarget("http://[::1]:11434", true))
+	require.NoError(t, validateTarget("http://169.254.169.254/", true))
+}
+
+func TestNewAIServiceRefusesPrivateEndpoint(t *testing.T) {
+	t.Setenv("MEMOS_AI_ALLOW_PRIVATE", "false")
+	t.Setenv("MEMOS_AI_BASE_URL", "http://169.254.169.254/v1/chat/completions")
+	s := NewAIService(nil, "", "test-key")
+	require.ErrorIs(t, s.targetErr, errBlockedTarget)
+
+	c, _ := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	err := s.ChatCompletion(c)
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusServiceUnavailable, httpErr.Code)
+}
+
+func TestOverrideProviderRejectsRebindingHost(t *testing.T) {
+	t.Setenv("MEMOS_AI_ALLOW_PRIVATE", "false")
+	t.Setenv("MEMOS_AI_ALLOWED_HOSTS", "models.github.ai,rebind.example.com")
+	fakeResolver(t, map[string][]string{"rebind.example.com": {"93.184.216.34", "10.0.0.1"}})
+	s := NewAIService(nil, "", "test-key")
+	_, err := s.overrideProvider("https://rebind.example.com/v1/chat/completions")
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusBadRequest, httpErr.Code)
+}
+
+func TestCheckAllowedHost(t *testing.T) {
+	require.NoError(t, checkAllowedHost(nil, "https://anything.example.com/v1"))
+	allowed := []string{"api.openai.com", "localhost:11434"}
+	require.NoError(t, checkAllowedHost(allowed, "https://API.openai.com/v1/chat/completions"))
+	require.NoError(t, checkAllowedHost(allowed, "http://localhost:11434/api/chat"))
+	require.ErrorIs(t, checkAllowedHost(allowed, "http://localhost:8080/api/chat"), errHostNotAllowed)
+	require.ErrorIs(t, checkAllowedHost(allowed, "https://api.openai.com.evil.example/v1"), errHostNotAllowed)
+}
+
+func TestNewAIServiceFromConfigRejectsUnlistedEndpoint(t *testing.T) {
+	_, err := NewAIServiceFromConfig(nil, "", Config{
+		Provider:     ProviderConfig{BaseURL: "https://api.openai.com/v1/chat/completions"},
+		AllowedHosts: []string{"ai.example.com"},
+		AllowPrivate: true,
+	}, nil)
+	require.ErrorIs(t, err, errHostNotAllowed)
+	require.Contains(t, err.Error(), `"api.openai.com"`)
+
+	_, err = NewAIServiceFromConfig(nil, "", Config{
+		Provider:     ProviderConfig{BaseURL: "https://api.openai.com/v1/chat/completions"},
+		AllowedHosts: []string{"api.openai.com"},
+		AllowPrivate: true,
+	}, nil)
+	require.NoError(t, err)
+}
+
+func TestNewAIServiceRefusesUnlistedEndpoint(t *testing.T) {
+	t.Setenv("MEMOS_AI_ALLOWED_HOSTS", "ai.example.com")
+	s := NewAIService(nil, "", "test-key")
+	require.ErrorIs(t, s.targetErr, errHostNotAllowed)
+
+	c, _ := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	httpErr, ok := s.ChatCompletion(c).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusServiceUnavailable, httpErr.Code)
+}
+
+func TestSendChecksAllowedHostsPerRequest(t *testing.T) {
+	upstreamCalled := false
+	upstream := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		upstreamCalled = true
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	s := NewAIService(nil, "", "test-key")
+	s.allowedHosts = []string{"ai.example.com"}
+
+	c, _ := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	httpErr, ok := s.ChatCompletion(c).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusServiceUnavailable, httpErr.Code)
+	require.False(t, upstreamCalled)
+}
+
+func TestFallbackOutsideAllowedHostsIsDisabled(t *testing.T) {
+	require.Nil(t, newFallbackProvider(slog.Default(), Config{
+		FallbackBaseURL: "https://backup.example.com/v1/chat/completions",
+		AllowedHosts:    []string{"api.openai.com"},
+	}))
+}
+
+func TestDialRejectsRebindingHost(t *testing.T) {
+	var requests atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		requests.Add(1)
+	}))
+	defer upstream.Close()
+	_, port, err := net.SplitHostPort(upstream.Listener.Addr().String())
+	require.NoError(t, err)
+
+	// The first lookup, at startup, answers with a public address; later ones with the
+	// loopback address the upstream listens on.
+	var lookups atomic.Int32
+	original := lookupIPAddr
+	t.Cleanup(func() { lookupIPAddr = original })
+	lookupIPAddr = func(context.Context, string) ([]net.IPAddr, error) {
+		if lookups.Add(1) == 1 {
+			return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}}, nil
+		}
+		return []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}, nil
+	}
+	t.Setenv("MEMOS_AI_ALLOW_PRIVATE", "false")
+	t.Setenv("MEMOS_AI_BASE_URL", "http://rebind.example.com:"+port+"/v1/chat/completions")
+	s := NewAIService(nil, "", "test-key")
+	require.NoError(t, s.targetErr)
+
+	c, _ := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	err = s.ChatCompletion(c)
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusServiceUnavailable, httpErr.Code)
+	require.ErrorIs(t, httpErr.Internal, errBlockedTarget)
+	require.Equal(t, int32(0), requests.Load())
+	require.Equal(t, int32(2), lookups.Load())
+}
+
+func TestProxyAddrs(t *testing.T) {
+	require.Equal(t, []string{"10.0.0.5:3128"}, proxyAddrs("http://10.0.0.5:3128"))
+	require.Equal(t, []string{"proxy.corp:1080"}, proxyAddrs("socks5://proxy.corp"))
+	t.Setenv("HTTPS_PROXY", "https://proxy.corp")
+	require.Equal(t, []string{"proxy.corp:443"}, proxyAddrs(""))
+}
@@ -0,0 +1,183 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// errStreamTooLarge is returned by a streamLineReader once the stream exceeds its size limit.
+var errStreamTooLarge = errors.New("stream exceeded the response size limit")
+
+// streamDelta is one piece of a streamed reply, independent of the provider's wire format.
+type streamDelta struct {
+	ID           string
+	Model        string
+	Content      string
+	FinishReason string
+	Usage        *Usage
+	// raw is the upstream chunk when it is already an OpenAI chunk. It is forwarded as is so
+	// fields without a normalized form, such as tool call deltas, reach the client.
+	raw json.RawMessage
+}
+
+// empty reports whether delta has nothing left to send once filtering held back its content.
+func (d *streamDelta) empty() bool {
+	return d.raw == nil && d.Content == "" && d.FinishReason == "" && d.Usage == nil
+}
+
+// streamDecoder reads a provider's streaming response as a sequence of deltas.
+// Next returns io.EOF once the provider has signalled the end of the stream and
+// io.ErrUnexpectedEOF when the body ends without that signal. A *streamChunkError
+// reports a chunk that could not be decoded; the stream can be read past it.
+type streamDecoder interface {
+	Next() (*streamDelta, error)
+}
+
+// streamDecoderProvider is implemented by providers whose streaming format differs from
+// OpenAI's SSE.
+type streamDecoderProvider interface {
+	NewStreamDecoder(lines *streamLineReader) streamDecoder
+}
+
+// newStreamDecoder returns the decoder for provider's streaming format.
+func newStreamDecoder(provider Provider, lines *streamLineReader) streamDecoder {
+	if p, ok := provider.(streamDecoderProvider); ok {
+		return p.NewStreamDecoder(lines)
+	}
+	return &openAIStreamDecoder{lines: lines}
+}
+
+// streamChunkError reports an upstream chunk that could not be decoded.
+type streamChunkError struct {
+	err error
+}
+
+func (e *streamChunkError) Error() string {
+	return "malformed stream chunk: " + e.err.Error()
+}
+
+func (e *streamChunkError) Unwrap() error {
+	return e.err
+}
+
+// streamLineReader reads an upstream stream line by line, counting the bytes read so the
+// stream can be cut off at maxResponseBytes.
+type streamLineReader struct {
+	reader *bufio.Reader
+	limit  int64
+	read   int64
+}
+
+func newStreamLineReader(body io.Reader, limit int64) *streamLineReader {
+	return &streamLineReader{reader: bufio.NewReader(body), limit: limit}
+}
+
+// next returns the next line without surrounding whitespace. The last line is returned
+// even when the body does not end with a newline.
+func (l *streamLineReader) next() ([]byte, error) {
+	line, err := l.reader.ReadBytes('\n')
+	if l.read += int64(len(line)); l.read > l.limit {
+		return nil, errStreamTooLarge
+	}
+	if err != nil && (len(line) == 0 || !errors.Is(err, io.EOF)) {
+		return nil, err
+	}
+	return bytes.TrimSpace(line), nil
+}
+
+// nextData returns the payload of the next SSE "data:" field, skipping event names,
+// comments and blank lines.
+func (l *streamLineReader) nextData() ([]byte, error) {
+	for {
+		line, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		if data, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+			return bytes.TrimSpace(data), nil
+		}
+	}
+}
+
+// openAIStreamDecoder reads OpenAI-style SSE, which ends with a "data: [DONE]" line.
+type openAIStreamDecoder struct {
+	lines *streamLineReader
+}
+
+func (d *openAIStreamDecoder) Next() (*streamDelta, error) {
+	data, err := d.lines.nextData()
+	if errors.Is(err, io.EOF) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(data, []byte("[DONE]")) {
+		return nil, io.EOF
+	}
+	var chunk struct {
+		ID      string `json:"id"`
+		Model   string `json:"model"`
+		Choices []struct {
+			Delta struct {
+				Content string `json:"content"`
+			} `json:"delta"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage *Usage `json:"usage"`
+	}
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return nil, &streamChunkError{err: err}
+	}
+	delta := &streamDelta{ID: chunk.ID, Model: chunk.Model, Usage: chunk.Usage, raw: data}
+	for _, choice := range chunk.Choices {
+		delta.Content += choice.Delta.Content
+		if choice.FinishReason != "" {
+			delta.FinishReason = choice.FinishReason
+		}
+	}
+	return delta, nil
+}
+
+// encodeStreamDelta writes delta as an OpenAI-style SSE chunk.
+func encodeStreamDelta(delta *streamDelta) ([]byte, error) {
+	if delta.raw != nil {
+		return fmt.Appendf(nil, "data: %s\n\n", delta.raw), nil
+	}
+	content := map[string]any{}
+	if delta.Content != "" {
+		content["content"] = delta.Content
+	}
+	var finishReason any
+	if delta.FinishReason != "" {
+		finishReason = delta.FinishReason
+	}
+	chunk := map[string]any{
+		"object":  "chat.completion.chunk",
+		"created": time.Now().Unix(),
+		"model":   delta.Model,
+		"choices": []map[string]any{
+			{"index": 0, "delta": content, "finish_reason": finishReason},
+		},
+	}
+	if delta.ID != "" {
+		chunk["id"] = delta.ID
+	}
+	if delta.Usage != nil {
+		chunk["usage"] = delta.Usage
+	}
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Appendf(nil, "data: %s\n\n", data), nil
+}
+
+// streamDone is the sentinel that ends an OpenAI-style stream.
+var streamDone = []byte("data: [DONE]\n\n")
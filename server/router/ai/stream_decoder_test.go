@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIStreamDecoder(t *testing.T) {
+	body := ": keep-alive\n\n" +
+		"data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n" +
+		"data: not json\n\n" +
+		"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":4,\"total_tokens\":7}}\n\n" +
+		"data: [DONE]\n\n"
+	decoder := newStreamDecoder(&openaiProvider{}, newStreamLineReader(strings.NewReader(body), defaultMaxResponseBytes))
+
+	delta, err := decoder.Next()
+	require.NoError(t, err)
+	require.Equal(t, "Hel", delta.Content)
+	require.Nil(t, delta.Usage)
+
+	_, err = decoder.Next()
+	var chunkErr *streamChunkError
+	require.ErrorAs(t, err, &chunkErr)
+
+	delta, err = decoder.Next()
+	require.NoError(t, err)
+	require.Empty(t, delta.Content)
+	require.Equal(t, &Usage{PromptTokens: 3, CompletionTokens: 4, TotalTokens: 7}, delta.Usage)
+
+	_, err = decoder.Next()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestOpenAIStreamDecoderUnexpectedEOF(t *testing.T) {
+	decoder := newStreamDecoder(&openaiProvider{}, newStreamLineReader(strings.NewReader("data: {\"choices\":[]}"), defaultMaxResponseBytes))
+	_, err := decoder.Next()
+	require.NoError(t, err)
+	_, err = decoder.Next()
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestStreamLineReaderLimit(t *testing.T) {
+	lines := newStreamLineReader(strings.NewReader("12345\n67890\n"), 8)
+	line, err := lines.next()
+	require.NoError(t, err)
+	require.Equal(t, "12345", string(line))
+	_, err = lines.next()
+	require.ErrorIs(t, err, errStreamTooLarge)
+}
+
+func TestEncodeStreamDelta(t *testing.T) {
+	raw := `{"choices":[{"delta":{"tool_calls":[{"index":0}]}}]}`
+	chunk, err := encodeStreamDelta(&streamDelta{raw: []byte(raw)})
+	require.NoError(t, err)
+	require.Equal(t, "data: "+raw+"\n\n", string(chunk))
+
+	chunk, err = encodeStreamDelta(&streamDelta{Model: "m", Content: "hi", FinishReason: "stop", Usage: &Usage{TotalTokens: 2}})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(chunk), "data: "))
+	require.Contains(t, string(chunk), `"object":"chat.completion.chunk"`)
+	require.Contains(t, string(chunk), `"delta":{"content":"hi"}`)
+	require.Contains(t, string(chunk), `"finish_reason":"stop"`)
+	require.Contains(t, string(chunk), `"total_tokens":2`)
+}
@@ -0,0 +1,250 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// headerLastEventID is sent by SSE clients reconnecting to a stream, with the ID of the last
+// event they received.
+const headerLastEventID = "Last-Event-ID"
+
+// streamReplay buffers the events of a stream so a client whose connection dropped can
+// reconnect with Last-Event-ID, receive the events it missed and follow the rest of the
+// stream. Event n, counted from 1, has the ID "<request ID>:<n>". The completion goes on
+// while no client is attached, but is aborted once none has been for ttl.
+type streamReplay struct {
+	requestID string
+	// userID owns the stream.
+	userID int32
+	ttl    time.Duration
+	abort  func()
+
+	mutex      sync.Mutex
+	events     [][]byte
+	done       bool
+	finishedAt time.Time
+	// changed is closed and replaced whenever an event is added or the stream ends.
+	changed chan struct{}
+	clients int
+	grace   *time.Timer
+}
+
+// append records data, an encoded SSE event without an ID, and returns it with its ID.
+func (r *streamReplay) append(data []byte) []byte {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	event := append([]byte(fmt.Sprintf("id: %s:%d\n", r.requestID, len(r.events)+1)), data...)
+	r.events = append(r.events, event)
+	close(r.changed)
+	r.changed = make(chan struct{})
+	return event
+}
+
+// finish marks the stream as complete; clients attached to it stop after its last event.
+func (r *streamReplay) finish() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.done = true
+	r.finishedAt = time.Now()
+	if r.grace != nil {
+		r.grace.Stop()
+	}
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// since returns the events after the first n, whether the stream is complete and a channel
+// closed once that changes.
+func (r *streamReplay) since(n int) ([][]byte, bool, <-chan struct{}) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.events[min(n, len(r.events)):], r.done, r.changed
+}
+
+// attach records a client following the stream.
+func (r *streamReplay) attach() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.clients++
+	if r.grace != nil {
+		r.grace.Stop()
+		r.grace = nil
+	}
+}
+
+// detach records that a client went away. Once no client is left, a client has ttl to
+// reconnect before the completion is aborted.
+func (r *streamReplay) detach() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.clients--
+	if r.clients > 0 || r.done {
+		return
+	}
+	r.grace = time.AfterFunc(r.ttl, func() {
+		r.mutex.Lock()
+		abandoned := r.clients == 0 && !r.done
+		r.mutex.Unlock()
+		if abandoned {
+			r.abort()
+		}
+	})
+}
+
+func (r *streamReplay) expired(now time.Time) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.done && now.Sub(r.finishedAt) > r.ttl
+}
+
+// streamReplays keeps the replay buffers of recent streams by request ID.
+type streamReplays struct {
+	mutex   sync.Mutex
+	replays map[string]*streamReplay
+}
+
+// add records replay, forgetting the streams that finished more than their ttl ago.
+func (r *streamReplays) add(replay *streamReplay) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.replays == nil {
+		r.replays = map[string]*streamReplay{}
+	}
+	now := time.Now()
+	for id, other := range r.replays {
+		if other.expired(now) {
+			delete(r.replays, id)
+		}
+	}
+	r.replays[replay.requestID] = replay
+}
+
+// lookup returns the stream the event lastEventID belongs to, if userID owns it, and how
+// many of its events the client has received.
+func (r *streamReplays) lookup(lastEventID string, userID int32) (*streamReplay, int, bool) {
+	i := strings.LastIndex(lastEventID, ":")
+	if i < 0 {
+		return nil, 0, false
+	}
+	received, err := strconv.Atoi(lastEventID[i+1:])
+	if err != nil || received < 0 {
+		return nil, 0, false
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	replay, ok := r.replays[lastEventID[:i]]
+	if !ok || replay.userID != userID || replay.expired(time.Now()) {
+		return nil, 0, false
+	}
+	return replay, received, true
+}
+
+// replayable reports whether the stream served under ctx is buffered for reconnecting
+// clients, which requires MEMOS_AI_STREAM_REPLAY_TTL and a request ID.
+func (s *AIService) replayable(ctx context.Context) bool {
+	return s.streamReplayTTL > 0 && requestIDFromContext(ctx) != ""
+}
+
+// newStreamReplay starts buffering the stream served to c, whose upstream body abort closes.
+// It returns nil when the stream is not replayable. Anonymous streams are not buffered:
+// every anonymous client could resume them by sending the same request ID.
+func (s *AIService) newStreamReplay(c echo.Context, abort func()) *streamReplay {
+	ctx := c.Request().Context()
+	if !s.replayable(ctx) {
+		return nil
+	}
+	user, err := s.getCurrentUser(ctx, c)
+	if err != nil {
+		s.log(ctx).Warn("failed to get current user, not buffering the stream for replay", "error", err)
+		return nil
+	}
+	if user == nil {
+		return nil
+	}
+	replay := &streamReplay{
+		requestID: requestIDFromContext(ctx),
+		userID:    user.ID,
+		ttl:       s.streamReplayTTL,
+		abort:     abort,
+		changed:   make(chan struct{}),
+		clients:   1,
+	}
+	s.replays.add(replay)
+	return replay
+}
+
+// resumeStream serves a client reconnecting with the Last-Event-ID lastEventID: the events
+// it missed are replayed, then the stream is followed until it ends. It reports false
+// without writing anything when no buffered stream of the caller matches, so the request
+// is served as a new completion.
+func (s *AIService) resumeStream(c echo.Context, lastEventID string) (bool, error) {
+	ctx := c.Request().Context()
+	user, err := s.getCurrentUser(ctx, c)
+	if err != nil {
+		return true, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get current user").SetInternal(err)
+	}
+	if user == nil {
+		return false, nil
+	}
+	replay, received, ok := s.replays.lookup(lastEventID, user.ID)
+	if !ok {
+		return false, nil
+	}
+	replay.attach()
+	defer replay.detach()
+
+	// A blocked write fails once its deadline expires; the completion itself goes on.
+	writer := s.startSSE(c, io.NopCloser(nil))
+	defer writer.stop()
+	for {
+		events, done, changed := replay.since(received)
+		for _, event := range events {
+			if err := writer.write(event); err != nil {
+				return true, nil
+			}
+		}
+		received += len(events)
+		if done {
+			return true, nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return true, nil
+		}
+	}
+}
+
+// replaySender returns the function streamResponse sends events with. Without a replay
+// it writes them to writer. With one, it adds their IDs and buffers them, writing them to
+// writer for as long as its client is connected; the stream then goes on for clients that
+// reconnect. The returned function stops watching the client's connection.
+func replaySender(ctx context.Context, writer *streamWriter, replay *streamReplay) (func([]byte) error, func() bool) {
+	if replay == nil {
+		return writer.write, func() bool { return true }
+	}
+	var gone atomic.Bool
+	leave := func() {
+		if !gone.Swap(true) {
+			replay.detach()
+		}
+	}
+	stop := context.AfterFunc(ctx, leave)
+	return func(data []byte) error {
+		event := replay.append(data)
+		if !gone.Load() && writer.write(event) != nil {
+			leave()
+		}
+		return nil
+	}, stop
+}
@@ -0,0 +1,152 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usememos/memos/store"
+)
+
+func TestStreamResumesFromLastEventID(t *testing.T) {
+	var requests atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 1; i <= 5; i++ {
+			_, _ = fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":\"chunk%d \"}}]}\n\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	t.Setenv("MEMOS_AI_STREAM_REPLAY_TTL", "5s")
+	s := NewAIService(nil, "", "test-key")
+
+	body := `{"stream":true,"messages":[{"role":"user","content":"hi"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ai/chat_completion", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req = req.WithContext(context.WithValue(req.Context(), requestIDKey{}, "stream-1"))
+	w := &brokenPipeWriter{ResponseRecorder: httptest.NewRecorder()}
+	c := echo.New().NewContext(req, w)
+	user := &store.User{ID: 1}
+	c.Set(currentUserContextKey, user)
+	finished := make(chan error, 1)
+	go func() {
+		finished <- s.ChatCompletion(c)
+	}()
+
+	// The first client receives one event before its connection drops.
+	require.Eventually(t, func() bool {
+		_, _, ok := s.replays.lookup("stream-1:1", user.ID)
+		return ok
+	}, 2*time.Second, 5*time.Millisecond)
+
+	// Reconnecting replays the missed events, then follows the stream without a new
+	// completion.
+	c, rec := newTestContext(body)
+	c.Request().Header.Set(headerLastEventID, "stream-1:1")
+	c.Set(currentUserContextKey, user)
+	require.NoError(t, s.ChatCompletion(c))
+	select {
+	case err := <-finished:
+		require.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream did not finish")
+	}
+	require.Equal(t, "id: stream-1:1\n"+`data: {"choices":[{"delta":{"content":"chunk1 "}}]}`+"\n\n", w.Body.String())
+	require.Equal(t, int32(1), requests.Load())
+	require.Contains(t, rec.Body.String(), "id: stream-1:2\n")
+	require.NotContains(t, rec.Body.String(), "chunk1")
+	events := sseEvents(rec.Body.String())
+	require.Len(t, events, 5)
+	require.Contains(t, events[0], "chunk2")
+	require.Equal(t, "[DONE]", events[4])
+
+	// Unknown event IDs start a new completion.
+	c, rec = newTestContext(body)
+	c.Request().Header.Set(headerLastEventID, "stream-2:1")
+	c.Set(currentUserContextKey, user)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, int32(2), requests.Load())
+	require.Contains(t, rec.Body.String(), "chunk1")
+}
+
+func TestStreamReplayAbortsAbandonedStream(t *testing.T) {
+	upstreamDone := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(upstreamDone)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for {
+			if _, err := w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"chunk\"}}]}\n\n")); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	t.Setenv("MEMOS_AI_STREAM_REPLAY_TTL", "50ms")
+	s := NewAIService(nil, "", "test-key")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ai/chat_completion", strings.NewReader(`{"stream":true,"messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req = req.WithContext(context.WithValue(req.Context(), requestIDKey{}, "stream-1"))
+	c := echo.New().NewContext(req, &brokenPipeWriter{ResponseRecorder: httptest.NewRecorder()})
+	c.Set(currentUserContextKey, &store.User{ID: 1})
+	require.NoError(t, s.ChatCompletion(c))
+
+	select {
+	case <-upstreamDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("upstream request was not cancelled after no client reconnected")
+	}
+}
+
+func TestStreamReplaysLookupChecksOwner(t *testing.T) {
+	var replays streamReplays
+	replay := &streamReplay{requestID: "a:b", userID: 1, ttl: time.Minute, changed: make(chan struct{})}
+	replays.add(replay)
+
+	found, received, ok := replays.lookup("a:b:3", 1)
+	require.True(t, ok)
+	require.Same(t, replay, found)
+	require.Equal(t, 3, received)
+	_, _, ok = replays.lookup("a:b:3", 2)
+	require.False(t, ok)
+	_, _, ok = replays.lookup("a:b", 1)
+	require.False(t, ok)
+}
+
+func TestStreamReplaySkipsAnonymousStreams(t *testing.T) {
+	t.Setenv("MEMOS_AI_STREAM_REPLAY_TTL", "5s")
+	s := NewAIService(nil, "", "test-key")
+
+	// Anonymous clients cannot tell each other apart, so their streams are not resumable.
+	c, _ := newTestContext("")
+	c.SetRequest(c.Request().WithContext(context.WithValue(c.Request().Context(), requestIDKey{}, "stream-1")))
+	require.Nil(t, s.newStreamReplay(c, func() {}))
+	require.Empty(t, s.replays.replays)
+
+	s.replays.add(&streamReplay{requestID: "stream-1", userID: 1, ttl: time.Minute, changed: make(chan struct{})})
+	c, rec := newTestContext("")
+	resumed, err := s.resumeStream(c, "stream-1:0")
+	require.NoError(t, err)
+	require.False(t, resumed)
+	require.Empty(t, rec.Body.String())
+}
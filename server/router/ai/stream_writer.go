@@ -0,0 +1,211 @@
+package ai
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// defaultSSEKeepAlive is how long a stream may go without data before a keep-alive
+	// comment is sent when MEMOS_AI_SSE_KEEPALIVE is unset.
+	defaultSSEKeepAlive = 15 * time.Second
+	// defaultStreamFlushInterval bounds how long buffered data waits when only
+	// MEMOS_AI_STREAM_FLUSH_BYTES is set.
+	defaultStreamFlushInterval = 50 * time.Millisecond
+	// defaultStreamWriteTimeout is how long a write to the client may block when
+	// MEMOS_AI_STREAM_WRITE_TIMEOUT is unset.
+	defaultStreamWriteTimeout = 30 * time.Second
+)
+
+// sseKeepAliveComment is an SSE comment line; clients ignore it, but it keeps proxies and
+// load balancers from timing out a stream while the model is thinking.
+var sseKeepAliveComment = []byte(": keep-alive\n\n")
+
+// streamWriter serializes writes to an event stream. By default every write is flushed
+// immediately; with a flush interval, writes are buffered and flushed once flushBytes have
+// accumulated or the oldest buffered byte has waited flushInterval, whichever comes first.
+// Whenever nothing has been written for keepAlive a keep-alive comment is sent.
+//
+// Writes go straight to the response, so a client that reads slowly blocks the stream
+// through TCP backpressure instead of making it buffer: at most flushBytes and one chunk
+// are held in memory. watch bounds how long such a write may block.
+type streamWriter struct {
+	w             *echo.Response
+	keepAlive     time.Duration
+	flushBytes    int
+	flushInterval time.Duration
+
+	mutex      sync.Mutex
+	last       time.Time
+	buffer     bytes.Buffer
+	bufferedAt time.Time
+	// err is the first write error; once set the client is gone and nothing more is written.
+	err error
+	// writingSince is the UnixNano time the current write to the client started, or zero.
+	// It is read without the mutex, which a blocked write holds.
+	writingSince atomic.Int64
+
+	// wake tells the background goroutine that the buffer has become non-empty.
+	wake    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	// flushed is closed once stop has written the last data, ending the watchdog.
+	flushed  chan struct{}
+	watchdog sync.WaitGroup
+}
+
+// newStreamWriter starts a streamWriter on w; stop must be called when the stream ends.
+// A zero flushInterval with a positive flushBytes buffers for defaultStreamFlushInterval.
+func newStreamWriter(w *echo.Response, keepAlive time.Duration, flushBytes int, flushInterval time.Duration) *streamWriter {
+	if flushBytes > 0 && flushInterval <= 0 {
+		flushInterval = defaultStreamFlushInterval
+	}
+	sw := &streamWriter{
+		w:             w,
+		keepAlive:     keepAlive,
+		flushBytes:    flushBytes,
+		flushInterval: flushInterval,
+		last:          time.Now(),
+		wake:          make(chan struct{}, 1),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+		flushed:       make(chan struct{}),
+	}
+	go sw.run()
+	return sw
+}
+
+// write sends b to the client, or buffers it when buffering is enabled. It returns an
+// error once a write to the client has failed.
+func (sw *streamWriter) write(b []byte) error {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+	if sw.err != nil {
+		return sw.err
+	}
+	sw.last = time.Now()
+	if sw.flushInterval <= 0 {
+		sw.buffer.Write(b)
+		return sw.flushLocked()
+	}
+	if sw.buffer.Len() == 0 {
+		sw.bufferedAt = sw.last
+		select {
+		case sw.wake <- struct{}{}:
+		default:
+		}
+	}
+	sw.buffer.Write(b)
+	if sw.flushBytes > 0 && sw.buffer.Len() >= sw.flushBytes {
+		return sw.flushLocked()
+	}
+	return nil
+}
+
+// stop ends the background goroutine, waits for it and flushes anything still buffered,
+// so the response is no longer written to once it returns.
+func (sw *streamWriter) stop() {
+	close(sw.done)
+	<-sw.stopped
+	sw.mutex.Lock()
+	_ = sw.flushLocked()
+	sw.mutex.Unlock()
+	close(sw.flushed)
+	sw.watchdog.Wait()
+}
+
+// watch starts a watchdog that calls abort and fails the pending write once a write to the
+// client has been blocked for timeout, so a client that stopped reading cannot hold the
+// upstream request open. The write is failed by expiring the connection's write deadline,
+// which not every ResponseWriter supports; abort should therefore cancel the upstream
+// request itself.
+func (sw *streamWriter) watch(timeout time.Duration, abort func()) {
+	if timeout <= 0 {
+		return
+	}
+	sw.watchdog.Add(1)
+	go func() {
+		defer sw.watchdog.Done()
+		ticker := time.NewTicker(max(timeout/4, time.Millisecond))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-sw.flushed:
+				return
+			case now := <-ticker.C:
+				since := sw.writingSince.Load()
+				if since == 0 || now.Sub(time.Unix(0, since)) < timeout {
+					continue
+				}
+				abort()
+				_ = http.NewResponseController(sw.w).SetWriteDeadline(now)
+				return
+			}
+		}
+	}()
+}
+
+func (sw *streamWriter) flushLocked() error {
+	if sw.err != nil || sw.buffer.Len() == 0 {
+		return sw.err
+	}
+	sw.writingSince.Store(time.Now().UnixNano())
+	defer sw.writingSince.Store(0)
+	_, err := sw.w.Write(sw.buffer.Bytes())
+	sw.buffer.Reset()
+	if err != nil {
+		sw.err = err
+		return err
+	}
+	sw.w.Flush()
+	return nil
+}
+
+// run flushes buffered data that has waited long enough and sends keep-alives.
+func (sw *streamWriter) run() {
+	defer close(sw.stopped)
+	timer := time.NewTimer(sw.keepAlive)
+	defer timer.Stop()
+	for {
+		select {
+		case <-sw.done:
+			return
+		case <-sw.wake:
+		case <-timer.C:
+		}
+		next, err := sw.tick(time.Now())
+		if err != nil {
+			// The client went away; the stream notices on its next write.
+			return
+		}
+		timer.Reset(next)
+	}
+}
+
+// tick does whatever is due at now and returns how long until something next may be.
+func (sw *streamWriter) tick(now time.Time) (time.Duration, error) {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+	if sw.buffer.Len() > 0 && now.Sub(sw.bufferedAt) >= sw.flushInterval {
+		if err := sw.flushLocked(); err != nil {
+			return 0, err
+		}
+	}
+	if sw.buffer.Len() == 0 && now.Sub(sw.last) >= sw.keepAlive {
+		sw.buffer.Write(sseKeepAliveComment)
+		if err := sw.flushLocked(); err != nil {
+			return 0, err
+		}
+		sw.last = now
+	}
+	next := sw.keepAlive - now.Sub(sw.last)
+	if sw.buffer.Len() > 0 {
+		next = min(next, sw.flushInterval-now.Sub(sw.bufferedAt))
+	}
+	return next, nil
+}
@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatCompletionStreamSendsKeepAlive(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"late\"}}]}\n\ndata: [DONE]\n\n"))
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	t.Setenv("MEMOS_AI_SSE_KEEPALIVE", "20ms")
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	body := rec.Body.String()
+	require.True(t, strings.HasPrefix(body, string(sseKeepAliveComment)), body)
+	require.Contains(t, body, "late")
+	require.True(t, strings.HasSuffix(body, "data: [DONE]\n\n"), body)
+}
+
+func TestStreamWriterStopsKeepAlive(t *testing.T) {
+	c, rec := newTestContext(``)
+	writer := newStreamWriter(c.Response(), 10*time.Millisecond, 0, 0)
+	require.NoError(t, writer.write([]byte("data: x\n\n")))
+	writer.stop()
+	written := rec.Body.String()
+
+	time.Sleep(30 * time.Millisecond)
+	require.Equal(t, written, rec.Body.String())
+}
+
+func TestStreamWriterBuffersUntilFlushBytes(t *testing.T) {
+	c, rec := newTestContext(``)
+	writer := newStreamWriter(c.Response(), time.Minute, 10, time.Minute)
+	written := func() string {
+		writer.mutex.Lock()
+		defer writer.mutex.Unlock()
+		return rec.Body.String()
+	}
+	require.NoError(t, writer.write([]byte("abcd")))
+	require.Empty(t, written())
+	require.NoError(t, writer.write([]byte("efghij")))
+	require.Equal(t, "abcdefghij", written())
+	require.NoError(t, writer.write([]byte("k")))
+	writer.stop()
+	require.Equal(t, "abcdefghijk", rec.Body.String())
+}
+
+func TestStreamWriterFlushesAfterInterval(t *testing.T) {
+	c, rec := newTestContext(``)
+	writer := newStreamWriter(c.Response(), time.Minute, 1<<20, 20*time.Millisecond)
+	defer writer.stop()
+	written := func() string {
+		writer.mutex.Lock()
+		defer writer.mutex.Unlock()
+		return rec.Body.String()
+	}
+	require.NoError(t, writer.write([]byte("data: x\n\n")))
+	require.NoError(t, writer.write([]byte("data: y\n\n")))
+	require.Empty(t, written())
+	require.Eventually(t, func() bool {
+		return written() == "data: x\n\ndata: y\n\n"
+	}, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// defaultSummaryWords is the summary length used when max_words is not provided.
+	defaultSummaryWords = 50
+	// maxSummaryWords caps the requested summary length.
+	maxSummaryWords = 500
+)
+
+type SummarizeRequest struct {
+	Content  string `json:"content"`
+	MaxWords int    `json:"max_words"`
+}
+
+type SummarizeResponse struct {
+	Summary string `json:"summary"`
+}
+
+// Summarize returns a concise summary of the given memo content, as plain text when the
+// client sends "Accept: text/plain".
+func (s *AIService) Summarize(c echo.Context) error {
+	ctx := c.Request().Context()
+	apiKey, err := s.requireAPIKey(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	request := new(SummarizeRequest)
+	if err := c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	}
+	if err := s.validateSummarizeRequest(request); err != nil {
+		return err
+	}
+	summary, err := s.summarize(ctx, apiKey, request)
+	if err != nil {
+		return err
+	}
+
+	return respondText(c, summary, &SummarizeResponse{
+		Summary: summary,
+	})
+}
+
+// validateSummarizeRequest checks the content of request and defaults its summary length.
+func (s *AIService) validateSummarizeRequest(request *SummarizeRequest) error {
+	if err := s.validateContent(request.Content); err != nil {
+		return err
+	}
+	if request.MaxWords == 0 {
+		request.MaxWords = defaultSummaryWords
+	}
+	if request.MaxWords < 0 || request.MaxWords > maxSummaryWords {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("max_words must be between 1 and %d", maxSummaryWords))
+	}
+	return nil
+}
+
+// summarize asks the model for a summary of a validated request.
+func (s *AIService) summarize(ctx context.Context, apiKey string, request *SummarizeRequest) (string, error) {
+	model, err := s.resolveModel("")
+	if err != nil {
+		return "", err
+	}
+	prompt, err := s.prompt(promptSummarize, summarizePromptData{MaxWords: request.MaxWords})
+	if err != nil {
+		return "", err
+	}
+	summary, err := s.complete(ctx, apiKey, &ChatCompletionRequest{
+		Model: model,
+		Messages: []ChatCompletionMessage{
+			{Role: "system", Content: withContextGuard(prompt)},
+			{Role: "user", Content: delimitContext("note", request.Content)},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(summary), nil
+}
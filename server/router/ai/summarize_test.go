@@ -0,0 +1,34 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarize(t *testing.T) {
+	newChatUpstream(t, "  A short summary.\n", func(req *ChatCompletionRequest) {
+		require.Len(t, req.Messages, 2)
+		require.Equal(t, "system", req.Messages[0].Role)
+		require.Contains(t, req.Messages[0].Content, "at most 20 words")
+		require.Contains(t, req.Messages[0].Content, contextGuardPrompt)
+		require.Equal(t, "<data source=\"note\">\nA long memo about many things.\n</data>\n", req.Messages[1].Content)
+	})
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"content":"A long memo about many things.","max_words":20}`)
+	require.NoError(t, s.Summarize(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	response := new(SummarizeResponse)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), response))
+	require.Equal(t, "A short summary.", response.Summary)
+}
+
+func TestSummarizeRejectsEmptyContent(t *testing.T) {
+	s := NewAIService(nil, "", "test-key")
+	c, _ := newTestContext(`{"content":"   "}`)
+	require.Error(t, s.Summarize(c))
+}
@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxSuggestedTags caps how many tags are returned to the client.
+const maxSuggestedTags = 5
+
+type SuggestTagsRequest struct {
+	Content string `json:"content"`
+	// Stream sends the tags as server-sent events while the model generates them, each
+	// event carrying a SuggestTagsResponse with all the tags so far.
+	Stream bool `json:"stream"`
+}
+
+type SuggestTagsResponse struct {
+	Tags []string `json:"tags"`
+}
+
+// SuggestTags asks the model for a handful of tags describing the memo content.
+func (s *AIService) SuggestTags(c echo.Context) error {
+	ctx := c.Request().Context()
+	apiKey, err := s.requireAPIKey(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	request := new(SuggestTagsRequest)
+	if err := c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	}
+	if err := s.validateContent(request.Content); err != nil {
+		return err
+	}
+
+	model, err := s.resolveModel("")
+	if err != nil {
+		return err
+	}
+	prompt, err := s.prompt(promptSuggestTags, suggestTagsPromptData{})
+	if err != nil {
+		return err
+	}
+	req := &ChatCompletionRequest{
+		Model: model,
+		Messages: []ChatCompletionMessage{
+			{Role: "system", Content: prompt},
+			{Role: "user", Content: request.Content},
+		},
+		ResponseFormat: jsonObjectFormat,
+	}
+	if request.Stream {
+		return s.streamJSONArray(c, apiKey, req, func(elements []json.RawMessage) any {
+			return &SuggestTagsResponse{Tags: normalizeTags(decodeTags(elements))}
+		})
+	}
+	// The tags array is found inside the {"tags": [...]} object, and bare arrays from models
+	// that ignore the format still parse.
+	var tags []string
+	if err := s.completeJSON(ctx, apiKey, req, func(content string) error {
+		return parseJSONArray(content, &tags)
+	}); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, &SuggestTagsResponse{
+		Tags: normalizeTags(tags),
+	})
+}
+
+// decodeTags returns the string elements of a streamed tags array, skipping any others.
+func decodeTags(elements []json.RawMessage) []string {
+	tags := make([]string, 0, len(elements))
+	for _, element := range elements {
+		var tag string
+		if json.Unmarshal(element, &tag) == nil {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// normalizeTags lowercases tags, strips leading '#', drops blanks and duplicates,
+// and caps the result at maxSuggestedTags. It always returns a non-nil slice.
+func normalizeTags(tags []string) []string {
+	result := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(tag), "#")))
+		tag = strings.Join(strings.Fields(tag), "-")
+		if tag == "" || slices.Contains(result, tag) {
+			continue
+		}
+		result = append(result, tag)
+		if len(result) == maxSuggestedTags {
+			break
+		}
+	}
+	return result
+}
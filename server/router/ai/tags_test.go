@@ -0,0 +1,81 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJSONArray(t *testing.T) {
+	var tags []string
+	require.NoError(t, parseJSONArray("Sure! Here are some tags:\n```json\n[\"work\", \"ideas\"]\n```", &tags))
+	require.Equal(t, []string{"work", "ideas"}, tags)
+
+	require.Error(t, parseJSONArray("work, ideas", &tags))
+	require.Error(t, parseJSONArray("] oops [", &tags))
+}
+
+func TestNormalizeTags(t *testing.T) {
+	require.Equal(t, []string{"work", "side-project", "ideas"}, normalizeTags([]string{"#Work", "side project", " ", "work", "ideas"}))
+	require.Equal(t, []string{}, normalizeTags(nil))
+	require.Len(t, normalizeTags([]string{"a", "b", "c", "d", "e", "f"}), maxSuggestedTags)
+}
+
+func TestSuggestTagsRetriesMalformedJSON(t *testing.T) {
+	var requests []*ChatCompletionRequest
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := new(ChatCompletionRequest)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		requests = append(requests, req)
+		content := "I think this note is about work."
+		if len(requests) > 1 {
+			content = `{"tags": ["work", "planning"]}`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"index": 0, "message": map[string]any{"role": "assistant", "content": content}}},
+		}))
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"content":"Quarterly planning meeting notes"}`)
+	require.NoError(t, s.SuggestTags(c))
+	response := new(SuggestTagsResponse)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), response))
+	require.Equal(t, []string{"work", "planning"}, response.Tags)
+
+	require.Len(t, requests, 2)
+	retry := requests[1].Messages
+	require.Len(t, retry, 4)
+	require.Equal(t, ChatCompletionMessage{Role: roleAssistant, Content: "I think this note is about work."}, retry[2])
+	require.Equal(t, ChatCompletionMessage{Role: roleUser, Content: jsonRetryInstruction}, retry[3])
+}
+
+func TestSuggestTagsFailsOnPersistentMalformedJSON(t *testing.T) {
+	for _, debug := range []bool{false, true} {
+		newChatUpstream(t, "I think this note is about work.", nil)
+		t.Setenv("MEMOS_AI_JSON_RETRIES", "2")
+		t.Setenv("MEMOS_AI_DEBUG", strconv.FormatBool(debug))
+		s := NewAIService(nil, "", "test-key")
+
+		c, _ := newTestContext(`{"content":"Quarterly planning meeting notes"}`)
+		httpErr, ok := s.SuggestTags(c).(*echo.HTTPError)
+		require.True(t, ok)
+		require.Equal(t, http.StatusBadGateway, httpErr.Code)
+		response, ok := httpErr.Message.(*ErrorResponse)
+		require.True(t, ok)
+		require.Equal(t, errorCodeInvalidJSON, response.Error.Code)
+		if debug {
+			require.Equal(t, "I think this note is about work.", response.Error.Upstream)
+		} else {
+			require.Empty(t, response.Error.Upstream)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+)
+
+// defaultTitleMaxChars is the title length limit used when MEMOS_AI_TITLE_MAX_CHARS is unset.
+const defaultTitleMaxChars = 60
+
+type GenerateTitleRequest struct {
+	Content string `json:"content"`
+}
+
+type GenerateTitleResponse struct {
+	Title string `json:"title"`
+}
+
+// GenerateTitle suggests a short title for the given memo content.
+// Content that already fits within the limit is returned as is without calling the provider.
+func (s *AIService) GenerateTitle(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	request := new(GenerateTitleRequest)
+	if err := c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	}
+	if err := s.validateContent(request.Content); err != nil {
+		return err
+	}
+	content := strings.Join(strings.Fields(request.Content), " ")
+	if utf8.RuneCountInString(content) <= s.titleMaxChars {
+		return c.JSON(http.StatusOK, &GenerateTitleResponse{
+			Title: truncateAtWord(content, s.titleMaxChars),
+		})
+	}
+
+	apiKey, err := s.requireAPIKey(ctx, c)
+	if err != nil {
+		return err
+	}
+	model, err := s.resolveModel("")
+	if err != nil {
+		return err
+	}
+	prompt, err := s.prompt(promptTitle, titlePromptData{MaxChars: s.titleMaxChars})
+	if err != nil {
+		return err
+	}
+	title, err := s.complete(ctx, apiKey, &ChatCompletionRequest{
+		Model: model,
+		Messages: []ChatCompletionMessage{
+			{Role: "system", Content: prompt},
+			{Role: "user", Content: request.Content},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &GenerateTitleResponse{
+		Title: truncateAtWord(cleanTitle(title), s.titleMaxChars),
+	})
+}
+
+// cleanTitle trims whitespace and the quotes models tend to wrap titles in.
+func cleanTitle(title string) string {
+	title = strings.TrimSpace(title)
+	for len(title) >= 2 {
+		first, _ := utf8.DecodeRuneInString(title)
+		last, _ := utf8.DecodeLastRuneInString(title)
+		if !strings.ContainsRune(`"'“”‘’`+"`", first) || !strings.ContainsRune(`"'“”‘’`+"`", last) {
+			break
+		}
+		title = strings.TrimSpace(title[utf8.RuneLen(first) : len(title)-utf8.RuneLen(last)])
+	}
+	return title
+}
+
+// truncateAtWord shortens s to at most n runes, cutting at the last word boundary when possible.
+func truncateAtWord(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	cut := string(runes[:n])
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimSpace(cut)
+}
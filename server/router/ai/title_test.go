@@ -0,0 +1,51 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateTitle(t *testing.T) {
+	newChatUpstream(t, " \"Weekly Planning Notes\"\n", func(req *ChatCompletionRequest) {
+		require.Contains(t, req.Messages[0].Content, "fewer than 60 characters")
+	})
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"content":"` + strings.Repeat("plan the week ahead ", 10) + `"}`)
+	require.NoError(t, s.GenerateTitle(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	response := new(GenerateTitleResponse)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), response))
+	require.Equal(t, "Weekly Planning Notes", response.Title)
+}
+
+func TestGenerateTitleShortContentSkipsProvider(t *testing.T) {
+	newChatUpstream(t, "unused", func(*ChatCompletionRequest) {
+		t.Fatal("provider must not be called for short content")
+	})
+	s := NewAIService(nil, "", "")
+
+	c, rec := newTestContext(`{"content":"  Buy milk\nand eggs  "}`)
+	require.NoError(t, s.GenerateTitle(c))
+
+	response := new(GenerateTitleResponse)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), response))
+	require.Equal(t, "Buy milk and eggs", response.Title)
+}
+
+func TestCleanTitle(t *testing.T) {
+	require.Equal(t, "Title", cleanTitle(`  "Title"  `))
+	require.Equal(t, "Title", cleanTitle(`“'Title'”`))
+	require.Equal(t, `It's "fine"`, cleanTitle(`It's "fine"`))
+}
+
+func TestTruncateAtWord(t *testing.T) {
+	require.Equal(t, "short", truncateAtWord("short", 10))
+	require.Equal(t, "hello", truncateAtWord("hello world", 8))
+	require.Equal(t, "abcdefgh", truncateAtWord("abcdefghij", 8))
+}
@@ -0,0 +1,74 @@
+package ai
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// languageTagPattern matches BCP-47 style tags such as "es", "pt-BR" or "zh-Hans-CN".
+// The tag is interpolated into the prompt, so anything else is rejected.
+var languageTagPattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8}){0,3}$`)
+
+type TranslateRequest struct {
+	Content string `json:"content"`
+	// TargetLang is a BCP-47 language tag. It is required; there is no server default.
+	TargetLang string `json:"target_lang"`
+}
+
+type TranslateResponse struct {
+	Translated string `json:"translated"`
+}
+
+// Translate translates the given memo content into the target language, returned as plain
+// text when the client sends "Accept: text/plain". A missing or malformed target_lang is
+// rejected with 400.
+func (s *AIService) Translate(c echo.Context) error {
+	ctx := c.Request().Context()
+	apiKey, err := s.requireAPIKey(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	request := new(TranslateRequest)
+	if err := c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body").SetInternal(err)
+	}
+	if err := s.validateContent(request.Content); err != nil {
+		return err
+	}
+	targetLang := strings.TrimSpace(request.TargetLang)
+	if targetLang == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "target_lang is required")
+	}
+	if !languageTagPattern.MatchString(targetLang) {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("target_lang %q is not a valid BCP-47 language tag", targetLang))
+	}
+
+	model, err := s.resolveModel("")
+	if err != nil {
+		return err
+	}
+	prompt, err := s.prompt(promptTranslate, translatePromptData{TargetLang: targetLang})
+	if err != nil {
+		return err
+	}
+	translated, err := s.complete(ctx, apiKey, &ChatCompletionRequest{
+		Model: model,
+		Messages: []ChatCompletionMessage{
+			{Role: "system", Content: prompt},
+			{Role: "user", Content: request.Content},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	translated = strings.TrimSpace(translated)
+	return respondText(c, translated, &TranslateResponse{
+		Translated: translated,
+	})
+}
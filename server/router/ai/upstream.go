@@ -0,0 +1,204 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+// cancelOnClose releases the request context, and the concurrency slot held by the
+// request, once the response body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// callUpstream sends req to the provider's chat completions endpoint using the service's
+// timeout and retry policy. Errors are returned as *echo.HTTPError. The caller must close
+// the response body, which also releases the request timeout. Message roles,
+// response_format, seed and logit_bias are first adapted to the provider and model.
+func (s *AIService) callUpstream(ctx context.Context, apiKey string, req *ChatCompletionRequest) (*http.Response, error) {
+	provider := s.providerFor(ctx)
+	if err := s.applyReasoningModel(provider, req); err != nil {
+		return nil, err
+	}
+	req.Messages = s.normalizeRoles(req.Model, req.Messages)
+	req.User = upstreamUserFromContext(ctx)
+	applyResponseFormat(provider, req)
+	applyDeterminism(provider, req)
+	s.log(ctx).Debug("sending AI chat completion request", "provider", provider.Name(), "model", req.Model, "stream", req.Stream, "has_key", apiKey != "")
+	resp, err := s.send(ctx, func(ctx context.Context) (*http.Request, error) {
+		return provider.NewChatRequest(ctx, apiKey, req)
+	})
+	if err != nil || apiKey == "" || apiKey != s.serverAPIKey() || hasProviderOverride(ctx) {
+		return resp, err
+	}
+	return s.checkServerKey(ctx, resp)
+}
+
+// send performs an upstream request built by newRequest with the service's timeout and
+// retry policy. Errors are returned as *echo.HTTPError. The caller must close the
+// response body, which also releases the request timeout.
+func (s *AIService) send(ctx context.Context, newRequest func(ctx context.Context) (*http.Request, error)) (*http.Response, error) {
+	if s.targetErr != nil && !hasProviderOverride(ctx) {
+		return nil, s.errorResponse(http.StatusServiceUnavailable, errorCodeNotConfigured, nil).SetInternal(s.targetErr)
+	}
+	// The circuit breaker tracks the configured provider only.
+	breaker := s.breaker
+	if hasProviderOverride(ctx) {
+		breaker = nil
+	}
+	if breaker != nil && !breaker.allow() {
+		return nil, echo.NewHTTPError(http.StatusServiceUnavailable, "AI provider is temporarily unavailable")
+	}
+
+	if s.concurrency != nil {
+		if err := s.concurrency.acquire(ctx); err != nil {
+			return nil, err
+		}
+	}
+	// The request context is cancelled when the client disconnects, which also aborts the upstream call.
+	ctx, cancelTimeout := context.WithTimeout(ctx, s.timeout)
+	// cancel releases everything held by the request; it runs exactly once, when the
+	// response body is closed or, on failure or panic, before send returns.
+	cancel := sync.OnceFunc(func() {
+		cancelTimeout()
+		if s.concurrency != nil {
+			s.concurrency.release()
+		}
+	})
+	handedOff := false
+	defer func() {
+		if !handedOff {
+			cancel()
+		}
+	}()
+
+	resp, err := s.doWithRetry(ctx, s.client, func() (*http.Request, error) {
+		req, err := newRequest(ctx)
+		if err != nil {
+			return nil, err
+		}
+		// Checked on every request, since overrides and the fallback pick their own endpoints.
+		if err := checkAllowedHost(s.allowedHosts, req.URL.String()); err != nil {
+			return nil, err
+		}
+		s.setExtraHeaders(ctx, req)
+		if id := requestIDFromContext(ctx); id != "" {
+			req.Header.Set(echo.HeaderXRequestID, id)
+		}
+		return req, nil
+	})
+	if breaker != nil {
+		// Requests abandoned by the client say nothing about the provider's health.
+		if errors.Is(err, context.Canceled) {
+			breaker.record(true)
+		} else {
+			breaker.record(err == nil && resp.StatusCode < http.StatusInternalServerError)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, errHostNotAllowed) || errors.Is(err, errBlockedTarget) {
+			return nil, s.errorResponse(http.StatusServiceUnavailable, errorCodeNotConfigured, nil).SetInternal(err)
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, echo.NewHTTPError(http.StatusGatewayTimeout, "AI provider did not respond in time").SetInternal(err)
+		}
+		return nil, echo.NewHTTPError(http.StatusBadGateway, "Failed to contact AI provider").SetInternal(err)
+	}
+	s.recordUpstreamRateLimits(ctx, resp.Header)
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	handedOff = true
+	return resp, nil
+}
+
+// readBody reads a non-streaming upstream body, refusing to buffer more than maxResponseBytes.
+func (s *AIService) readBody(body io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(body, s.maxResponseBytes+1))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to read upstream response").SetInternal(err)
+	}
+	if int64(len(data)) > s.maxResponseBytes {
+		return nil, echo.NewHTTPError(http.StatusBadGateway, fmt.Sprintf("AI provider response too large: exceeds the limit of %d bytes", s.maxResponseBytes))
+	}
+	return data, nil
+}
+
+// completion runs a non-streaming chat completion and returns the response in the OpenAI
+// format, serving it from the response cache when possible. hit reports a cache hit.
+// Requests sent to a per-request base URL are not cached.
+// Identical concurrent requests to the configured provider share one upstream call.
+func (s *AIService) completion(ctx context.Context, apiKey string, req *ChatCompletionRequest) (body []byte, hit bool, err error) {
+	req.Stream = false
+	if hasProviderOverride(ctx) {
+		body, err = s.fetchCompletion(ctx, apiKey, req)
+		return body, false, err
+	}
+	key, err := cacheKey(s.provider.Name(), apiKey, req)
+	if err != nil {
+		return nil, false, echo.NewHTTPError(http.StatusInternalServerError, "Failed to build cache key").SetInternal(err)
+	}
+	if s.responseCache != nil {
+		if body, ok := s.responseCache.get(key); ok {
+			return body, true, nil
+		}
+	}
+
+	if body, err = s.coalesce(ctx, key, func(ctx context.Context) ([]byte, error) {
+		return s.fetchCompletion(ctx, apiKey, req)
+	}); err != nil {
+		return nil, false, err
+	}
+	if s.responseCache != nil {
+		s.responseCache.put(key, body)
+	}
+	return body, false, nil
+}
+
+// fetchCompletion sends a non-streaming completion upstream and returns the response in the
+// OpenAI format.
+func (s *AIService) fetchCompletion(ctx context.Context, apiKey string, req *ChatCompletionRequest) ([]byte, error) {
+	resp, err := s.callUpstream(ctx, apiKey, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := s.readBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, s.upstreamError(ctx, resp.StatusCode, body)
+	}
+	if body, err = s.providerFor(ctx).ParseChatResponse(body); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadGateway, "Unexpected response from AI provider").SetInternal(err)
+	}
+	recordUsage(ctx, req.Model, parseUsage(body))
+	return s.filterResponse(body), nil
+}
+
+// complete runs a non-streaming completion and returns the text content of the first choice.
+// It is the building block for endpoints that derive a single answer from the model.
+func (s *AIService) complete(ctx context.Context, apiKey string, req *ChatCompletionRequest) (string, error) {
+	body, _, err := s.completion(ctx, apiKey, req)
+	if err != nil {
+		return "", err
+	}
+	response, err := unmarshalResponse(body)
+	if err != nil {
+		return "", err
+	}
+	return response.Choices[0].Content(), nil
+}
@@ -0,0 +1,150 @@
+package ai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatCompletionResponseTooLarge(t *testing.T) {
+	newChatUpstream(t, strings.Repeat("x", 256), nil)
+	t.Setenv("MEMOS_AI_MAX_RESPONSE_BYTES", "128")
+	s := NewAIService(nil, "", "test-key")
+
+	c, _ := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	err := s.ChatCompletion(c)
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusBadGateway, httpErr.Code)
+	require.Contains(t, httpErr.Message, "too large")
+}
+
+func TestStreamResponseStopsAtLimit(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 10; i++ {
+			_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"chunk\"}}]}\n\n"))
+		}
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	t.Setenv("MEMOS_AI_MAX_RESPONSE_BYTES", "100")
+	s := NewAIService(nil, "", "test-key")
+
+	c, rec := newTestContext(`{"stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	require.NoError(t, s.ChatCompletion(c))
+	require.LessOrEqual(t, rec.Body.Len(), 100)
+	require.Contains(t, rec.Body.String(), "chunk")
+}
+
+// brokenPipeWriter fails every write after the first, like a connection the client closed.
+type brokenPipeWriter struct {
+	*httptest.ResponseRecorder
+	writes int
+}
+
+func (w *brokenPipeWriter) Write(b []byte) (int, error) {
+	if w.writes++; w.writes > 1 {
+		return 0, syscall.EPIPE
+	}
+	return w.ResponseRecorder.Write(b)
+}
+
+func TestStreamAbortsUpstreamOnClientDisconnect(t *testing.T) {
+	upstreamDone := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(upstreamDone)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for {
+			if _, err := w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"chunk\"}}]}\n\n")); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	s := NewAIService(nil, "", "test-key")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ai/chat_completion", strings.NewReader(`{"stream":true,"messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	w := &brokenPipeWriter{ResponseRecorder: httptest.NewRecorder()}
+	c := echo.New().NewContext(req, w)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, 1, strings.Count(w.Body.String(), "chunk"))
+
+	select {
+	case <-upstreamDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("upstream request was not cancelled after the client disconnected")
+	}
+}
+
+// stalledWriter blocks every write after the first until its write deadline is set, like a
+// client that stopped reading.
+type stalledWriter struct {
+	*httptest.ResponseRecorder
+	writes   int
+	deadline chan struct{}
+}
+
+func (w *stalledWriter) Write(b []byte) (int, error) {
+	if w.writes++; w.writes > 1 {
+		<-w.deadline
+		return 0, os.ErrDeadlineExceeded
+	}
+	return w.ResponseRecorder.Write(b)
+}
+
+func (w *stalledWriter) SetWriteDeadline(time.Time) error {
+	close(w.deadline)
+	return nil
+}
+
+func TestStreamAbortsUpstreamWhenClientStopsReading(t *testing.T) {
+	upstreamDone := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(upstreamDone)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for {
+			if _, err := w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"chunk\"}}]}\n\n")); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	t.Setenv("MEMOS_AI_STREAM_WRITE_TIMEOUT", "50ms")
+	s := NewAIService(nil, "", "test-key")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ai/chat_completion", strings.NewReader(`{"stream":true,"messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	w := &stalledWriter{ResponseRecorder: httptest.NewRecorder(), deadline: make(chan struct{})}
+	c := echo.New().NewContext(req, w)
+	require.NoError(t, s.ChatCompletion(c))
+	require.Equal(t, 1, strings.Count(w.Body.String(), "chunk"))
+
+	select {
+	case <-upstreamDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("upstream request was not cancelled after the client stopped reading")
+	}
+}
@@ -0,0 +1,49 @@
+package ai
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// upstreamUserKey is the context key under which the hashed user identifier is stored.
+type upstreamUserKey struct{}
+
+// upstreamUserMiddleware attaches an identifier of the authenticated user to the request
+// context, sent upstream in the "user" field so the provider can attribute abuse to a
+// user without learning who they are.
+func (s *AIService) upstreamUserMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if s.omitUser {
+			return next(c)
+		}
+		ctx := c.Request().Context()
+		user, err := s.getCurrentUser(ctx, c)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get current user").SetInternal(err)
+		}
+		if user != nil {
+			c.SetRequest(c.Request().WithContext(context.WithValue(ctx, upstreamUserKey{}, s.hashUser(user.ID))))
+		}
+		return next(c)
+	}
+}
+
+// hashUser returns a stable identifier for a user. It is keyed with the server secret so
+// it cannot be reversed by hashing candidate IDs.
+func (s *AIService) hashUser(userID int32) string {
+	mac := hmac.New(sha256.New, s.userHashKey)
+	fmt.Fprintf(mac, "memos-ai-user:%d", userID)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// upstreamUserFromContext returns the identifier set by upstreamUserMiddleware, if any.
+func upstreamUserFromContext(ctx context.Context) string {
+	user, _ := ctx.Value(upstreamUserKey{}).(string)
+	return user
+}
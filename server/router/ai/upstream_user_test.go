@@ -0,0 +1,51 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/usememos/memos/store"
+)
+
+func TestChatCompletionSendsHashedUser(t *testing.T) {
+	var forwarded *ChatCompletionRequest
+	newChatUpstream(t, "hello", func(req *ChatCompletionRequest) {
+		forwarded = req
+	})
+	s := NewAIService(nil, "secret", "test-key")
+
+	send := func(userID int32, body string) string {
+		c, _ := newTestContext(body)
+		if userID != 0 {
+			c.Set(currentUserContextKey, &store.User{ID: userID, Email: "user@example.com"})
+		}
+		require.NoError(t, s.upstreamUserMiddleware(s.ChatCompletion)(c))
+		return forwarded.User
+	}
+
+	first := send(1, `{"messages":[{"role":"user","content":"one"}]}`)
+	require.Len(t, first, 64)
+	require.Equal(t, first, send(1, `{"messages":[{"role":"user","content":"two"}]}`))
+	require.NotEqual(t, first, send(2, `{"messages":[{"role":"user","content":"three"}]}`))
+	// A client-supplied value is never forwarded.
+	require.Empty(t, send(0, `{"messages":[{"role":"user","content":"four"}],"user":"user@example.com"}`))
+	require.Equal(t, first, send(1, `{"messages":[{"role":"user","content":"five"}],"user":"user@example.com"}`))
+
+	// Another server secret yields unrelated identifiers.
+	require.NotEqual(t, first, NewAIService(nil, "other-secret", "test-key").hashUser(1))
+}
+
+func TestChatCompletionOmitsUserWhenDisabled(t *testing.T) {
+	var forwarded *ChatCompletionRequest
+	newChatUpstream(t, "hello", func(req *ChatCompletionRequest) {
+		forwarded = req
+	})
+	t.Setenv("MEMOS_AI_SEND_USER", "false")
+	s := NewAIService(nil, "secret", "test-key")
+
+	c, _ := newTestContext(`{"messages":[{"role":"user","content":"hi"}]}`)
+	c.Set(currentUserContextKey, &store.User{ID: 1})
+	require.NoError(t, s.upstreamUserMiddleware(s.ChatCompletion)(c))
+	require.Empty(t, forwarded.User)
+}
@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	storepb "github.com/usememos/memos/proto/gen/store"
+	"github.com/usememos/memos/store"
+)
+
+// UsagePeriod is a user's token usage and its estimated cost over a day or month.
+type UsagePeriod struct {
+	// Period is the UTC day (YYYY-MM-DD) or month (YYYY-MM) the totals cover.
+	Period string  `json:"period"`
+	Tokens int64   `json:"tokens"`
+	Cost   float64 `json:"cost"`
+}
+
+// UsageReport is a user's AI usage for the current day and month.
+type UsageReport struct {
+	UserID int32       `json:"user_id"`
+	Day    UsagePeriod `json:"day"`
+	Month  UsagePeriod `json:"month"`
+}
+
+type ListUsageResponse struct {
+	Users []*UsageReport `json:"users"`
+}
+
+// GetUsage returns the current user's AI usage for today and this month.
+func (s *AIService) GetUsage(c echo.Context) error {
+	ctx := c.Request().Context()
+	user, err := s.requireUsageUser(c)
+	if err != nil {
+		return err
+	}
+	usage, err := s.quota.usage(ctx, user.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get AI usage").SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, newUsageReport(user.ID, usage))
+}
+
+// ListUsage returns every user's AI usage for today and this month. It is limited to admins.
+func (s *AIService) ListUsage(c echo.Context) error {
+	ctx := c.Request().Context()
+	user, err := s.requireUsageUser(c)
+	if err != nil {
+		return err
+	}
+	if user.Role != store.RoleAdmin {
+		return echo.NewHTTPError(http.StatusForbidden, "Only admins can view the AI usage of all users")
+	}
+	settings, err := s.store.ListUserSettings(ctx, &store.FindUserSetting{Key: storepb.UserSetting_AI_USAGE})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list AI usage").SetInternal(err)
+	}
+	now := time.Now()
+	response := &ListUsageResponse{Users: []*UsageReport{}}
+	for _, setting := range settings {
+		response.Users = append(response.Users, newUsageReport(setting.UserId, currentUsage(setting.GetAiUsage(), now)))
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// requireUsageUser returns the signed-in user; usage is only recorded for signed-in users
+// of a server with a store.
+func (s *AIService) requireUsageUser(c echo.Context) (*store.User, error) {
+	if s.quota == nil {
+		return nil, echo.NewHTTPError(http.StatusServiceUnavailable, "AI usage is not available")
+	}
+	user, err := s.getCurrentUser(c.Request().Context(), c)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get current user").SetInternal(err)
+	}
+	if user == nil {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "Authentication required to view AI usage")
+	}
+	return user, nil
+}
+
+func newUsageReport(userID int32, usage *storepb.AIUsageUserSetting) *UsageReport {
+	return &UsageReport{
+		UserID: userID,
+		Day:    UsagePeriod{Period: usage.GetDate(), Tokens: usage.GetTokens(), Cost: usage.GetCost()},
+		Month:  UsagePeriod{Period: usage.GetMonth(), Tokens: usage.GetMonthTokens(), Cost: usage.GetMonthCost()},
+	}
+}
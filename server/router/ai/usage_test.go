@@ -0,0 +1,77 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	storepb "github.com/usememos/memos/proto/gen/store"
+	"github.com/usememos/memos/store"
+	teststore "github.com/usememos/memos/store/test"
+)
+
+func TestUsageReports(t *testing.T) {
+	ctx := context.Background()
+	ts := teststore.NewTestingStore(ctx, t)
+	defer ts.Close()
+	admin, err := ts.CreateUser(ctx, &store.User{Username: "admin", Role: store.RoleAdmin, Email: "admin@test.com"})
+	require.NoError(t, err)
+	user, err := ts.CreateUser(ctx, &store.User{Username: "usage", Role: store.RoleUser, Email: "usage@test.com"})
+	require.NoError(t, err)
+
+	t.Setenv("MEMOS_AI_PRICING", `{"test-model": {"input": 1, "output": 2}}`)
+	s := NewAIService(ts, "secret", "test-key")
+	handler := s.quotaMiddleware(func(c echo.Context) error {
+		recordUsage(c.Request().Context(), "test-model", &Usage{PromptTokens: 1000, CompletionTokens: 500, TotalTokens: 1500})
+		return c.NoContent(http.StatusOK)
+	})
+	c, _ := newTestContext(`{}`)
+	c.Set(currentUserContextKey, user)
+	require.NoError(t, handler(c))
+
+	c, rec := newTestContext(``)
+	c.Set(currentUserContextKey, user)
+	require.NoError(t, s.GetUsage(c))
+	report := new(UsageReport)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), report))
+	now := time.Now()
+	require.Equal(t, &UsageReport{
+		UserID: user.ID,
+		Day:    UsagePeriod{Period: quotaDate(now), Tokens: 1500, Cost: 2},
+		Month:  UsagePeriod{Period: quotaMonth(now), Tokens: 1500, Cost: 2},
+	}, report)
+
+	c, _ = newTestContext(``)
+	c.Set(currentUserContextKey, user)
+	httpErr, ok := s.ListUsage(c).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusForbidden, httpErr.Code)
+
+	c, rec = newTestContext(``)
+	c.Set(currentUserContextKey, admin)
+	require.NoError(t, s.ListUsage(c))
+	response := new(ListUsageResponse)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), response))
+	require.Len(t, response.Users, 1)
+	require.Equal(t, report, response.Users[0])
+
+	c, _ = newTestContext(``)
+	httpErr, ok = s.GetUsage(c).(*echo.HTTPError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusUnauthorized, httpErr.Code)
+}
+
+func TestCurrentUsageResetsPastPeriods(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	stored := &storepb.AIUsageUserSetting{Date: "2026-03-01", Tokens: 10, Cost: 1, Month: "2026-03", MonthTokens: 30, MonthCost: 3}
+	require.Equal(t, &storepb.AIUsageUserSetting{Date: "2026-03-02", Month: "2026-03", MonthTokens: 30, MonthCost: 3}, currentUsage(stored, now))
+
+	stored.Month = "2026-02"
+	require.Equal(t, &storepb.AIUsageUserSetting{Date: "2026-03-02", Month: "2026-03"}, currentUsage(stored, now))
+	require.Equal(t, &storepb.AIUsageUserSetting{Date: "2026-03-02", Month: "2026-03"}, currentUsage(nil, now))
+}
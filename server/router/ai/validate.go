@@ -0,0 +1,100 @@
+package ai
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// defaultMaxMessages is the maximum number of messages accepted in a single request.
+	defaultMaxMessages = 100
+	// defaultMaxInputBytes is the maximum total size of message content in a single request.
+	defaultMaxInputBytes = 32 << 10
+	// maxChoices caps n, since every choice is billed.
+	maxChoices = 4
+)
+
+// validateChatCompletionRequest checks the request against the configured input limits.
+func (s *AIService) validateChatCompletionRequest(req *ChatCompletionRequest) error {
+	if len(req.Messages) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "messages must not be empty")
+	}
+	if len(req.Messages) > s.maxMessages {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("too many messages: %d exceeds the limit of %d", len(req.Messages), s.maxMessages))
+	}
+
+	total := 0
+	for _, message := range req.Messages {
+		total += len(message.Content)
+	}
+	if total > s.maxInputBytes {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("message content too large: %d bytes exceeds the limit of %d", total, s.maxInputBytes))
+	}
+
+	if len(req.MemoIDs) > maxContextMemos {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("too many memo_ids: %d exceeds the limit of %d", len(req.MemoIDs), maxContextMemos))
+	}
+
+	if err := s.validateRoles(req.Messages); err != nil {
+		return err
+	}
+	if err := s.validateContentParts(req.Messages); err != nil {
+		return err
+	}
+
+	if len(req.ResponseFormat) > 0 {
+		if _, err := parseResponseFormat(req.ResponseFormat); err != nil {
+			return err
+		}
+	}
+
+	if req.Temperature != nil && (*req.Temperature < 0 || *req.Temperature > 2) {
+		return echo.NewHTTPError(http.StatusBadRequest, "temperature must be between 0 and 2")
+	}
+	if req.MaxTokens != nil && *req.MaxTokens <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "max_tokens must be positive")
+	}
+	if req.MaxCompletionTokens != nil && *req.MaxCompletionTokens <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "max_completion_tokens must be positive")
+	}
+	if req.TopP != nil && (*req.TopP < 0 || *req.TopP > 1) {
+		return echo.NewHTTPError(http.StatusBadRequest, "top_p must be between 0 and 1")
+	}
+	if req.PresencePenalty != nil && (*req.PresencePenalty < -2 || *req.PresencePenalty > 2) {
+		return echo.NewHTTPError(http.StatusBadRequest, "presence_penalty must be between -2 and 2")
+	}
+	if req.FrequencyPenalty != nil && (*req.FrequencyPenalty < -2 || *req.FrequencyPenalty > 2) {
+		return echo.NewHTTPError(http.StatusBadRequest, "frequency_penalty must be between -2 and 2")
+	}
+	if req.N != nil && (*req.N < 1 || *req.N > maxChoices) {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("n must be between 1 and %d", maxChoices))
+	}
+	if req.ReasoningEffort != nil && !slices.Contains(reasoningEfforts, *req.ReasoningEffort) {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("reasoning_effort must be one of %s", strings.Join(reasoningEfforts, ", ")))
+	}
+	for token, bias := range req.LogitBias {
+		if _, err := strconv.Atoi(token); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("logit_bias keys must be token IDs, got %q", token))
+		}
+		if bias < -100 || bias > 100 {
+			return echo.NewHTTPError(http.StatusBadRequest, "logit_bias values must be between -100 and 100")
+		}
+	}
+	return nil
+}
+
+// validateContent checks the memo content passed to a derived endpoint such as summarize.
+func (s *AIService) validateContent(content string) error {
+	if strings.TrimSpace(content) == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "content must not be empty")
+	}
+	if len(content) > s.maxInputBytes {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("content too large: %d bytes exceeds the limit of %d", len(content), s.maxInputBytes))
+	}
+	return nil
+}
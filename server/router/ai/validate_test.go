@@ -0,0 +1,88 @@
+package ai
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func newMessages(n int, content string) []ChatCompletionMessage {
+	messages := make([]ChatCompletionMessage, n)
+	for i := range messages {
+		messages[i] = ChatCompletionMessage{Role: "user", Content: content}
+	}
+	return messages
+}
+
+func TestValidateChatCompletionRequest(t *testing.T) {
+	t.Setenv("MEMOS_AI_MAX_MESSAGES", "3")
+	t.Setenv("MEMOS_AI_MAX_INPUT_BYTES", "10")
+	s := NewAIService(nil, "", "")
+
+	tests := []struct {
+		name     string
+		messages []ChatCompletionMessage
+		wantCode int
+	}{
+		{name: "empty", messages: nil, wantCode: http.StatusBadRequest},
+		{name: "at message limit", messages: newMessages(3, "a"), wantCode: 0},
+		{name: "over message limit", messages: newMessages(4, "a"), wantCode: http.StatusRequestEntityTooLarge},
+		{name: "at byte limit", messages: newMessages(2, strings.Repeat("a", 5)), wantCode: 0},
+		{name: "over byte limit", messages: newMessages(2, strings.Repeat("a", 6)), wantCode: http.StatusRequestEntityTooLarge},
+	}
+	for _, test := range tests {
+		err := s.validateChatCompletionRequest(&ChatCompletionRequest{Messages: test.messages})
+		if test.wantCode == 0 {
+			require.NoError(t, err, test.name)
+			continue
+		}
+		httpErr, ok := err.(*echo.HTTPError)
+		require.True(t, ok, test.name)
+		require.Equal(t, test.wantCode, httpErr.Code, test.name)
+	}
+}
+
+func TestValidateGenerationParameters(t *testing.T) {
+	s := NewAIService(nil, "", "")
+	float := func(v float64) *float64 { return &v }
+	integer := func(v int) *int { return &v }
+
+	tests := []struct {
+		name    string
+		req     ChatCompletionRequest
+		wantErr bool
+	}{
+		{name: "unset", req: ChatCompletionRequest{}},
+		{name: "temperature min", req: ChatCompletionRequest{Temperature: float(0)}},
+		{name: "temperature max", req: ChatCompletionRequest{Temperature: float(2)}},
+		{name: "temperature too high", req: ChatCompletionRequest{Temperature: float(2.1)}, wantErr: true},
+		{name: "temperature negative", req: ChatCompletionRequest{Temperature: float(-0.1)}, wantErr: true},
+		{name: "max_tokens positive", req: ChatCompletionRequest{MaxTokens: integer(1)}},
+		{name: "max_tokens zero", req: ChatCompletionRequest{MaxTokens: integer(0)}, wantErr: true},
+		{name: "top_p too high", req: ChatCompletionRequest{TopP: float(1.5)}, wantErr: true},
+		{name: "presence_penalty min", req: ChatCompletionRequest{PresencePenalty: float(-2)}},
+		{name: "presence_penalty too high", req: ChatCompletionRequest{PresencePenalty: float(2.5)}, wantErr: true},
+		{name: "frequency_penalty max", req: ChatCompletionRequest{FrequencyPenalty: float(2)}},
+		{name: "frequency_penalty too low", req: ChatCompletionRequest{FrequencyPenalty: float(-2.1)}, wantErr: true},
+		{name: "n one", req: ChatCompletionRequest{N: integer(1)}},
+		{name: "n max", req: ChatCompletionRequest{N: integer(maxChoices)}},
+		{name: "n zero", req: ChatCompletionRequest{N: integer(0)}, wantErr: true},
+		{name: "n over max", req: ChatCompletionRequest{N: integer(maxChoices + 1)}, wantErr: true},
+		{name: "seed", req: ChatCompletionRequest{Seed: integer(42)}},
+		{name: "logit_bias bounds", req: ChatCompletionRequest{LogitBias: map[string]int{"50256": -100, "1734": 100}}},
+		{name: "logit_bias too high", req: ChatCompletionRequest{LogitBias: map[string]int{"50256": 101}}, wantErr: true},
+		{name: "logit_bias not a token ID", req: ChatCompletionRequest{LogitBias: map[string]int{"hello": 1}}, wantErr: true},
+	}
+	for _, test := range tests {
+		test.req.Messages = newMessages(1, "hi")
+		err := s.validateChatCompletionRequest(&test.req)
+		if test.wantErr {
+			require.Error(t, err, test.name)
+		} else {
+			require.NoError(t, err, test.name)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package ai
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// webSocketImageAllowance is how many inline images at the MEMOS_AI_MAX_IMAGE_BYTES limit
+	// the request message of a WebSocket client may carry.
+	webSocketImageAllowance = 4
+	// webSocketJSONOverhead allows for the JSON structure and parameters around the message
+	// content of a WebSocket request.
+	webSocketJSONOverhead = 64 << 10
+
+	webSocketFrameToken = "token"
+	webSocketFrameDone  = "done"
+	webSocketFrameError = "error"
+)
+
+// WebSocketFrame is a message sent to /ai/ws clients: a "token" frame per chunk of the
+// reply, then a "done" frame with the usage reported by the provider, or an "error" frame.
+type WebSocketFrame struct {
+	Type    string `json:"type"`
+	Content string `json:"content,omitempty"`
+	Usage   *Usage `json:"usage,omitempty"`
+	// Status is the HTTP status the request would have failed with over SSE.
+	Status int          `json:"status,omitempty"`
+	Error  *ErrorDetail `json:"error,omitempty"`
+}
+
+// ChatWebSocket streams a chat completion over a WebSocket as an alternative to SSE. The
+// client sends a ChatCompletionRequest as its first text message and receives
+// WebSocketFrames. Closing the socket cancels the upstream request.
+func (s *AIService) ChatWebSocket(c echo.Context) error {
+	ctx := c.Request().Context()
+	apiKey, err := s.requireAPIKey(ctx, c)
+	if err != nil {
+		return err
+	}
+	server := websocket.Server{
+		Handshake: checkSameOrigin,
+		Handler: func(ws *websocket.Conn) {
+			s.serveChatWebSocket(ctx, c, ws, apiKey)
+		},
+	}
+	server.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
+
+// checkSameOrigin rejects browser handshakes from other origins, since the socket is
+// authenticated with the user's cookies. Clients that send no Origin are not browsers.
+func checkSameOrigin(config *websocket.Config, req *http.Request) error {
+	if req.Header.Get("Origin") == "" {
+		return nil
+	}
+	origin, err := websocket.Origin(config, req)
+	if err != nil {
+		return err
+	}
+	if origin == nil || origin.Host != req.Host {
+		return errors.Errorf("cross-origin WebSocket request from %s", req.Header.Get("Origin"))
+	}
+	config.Origin = origin
+	return nil
+}
+
+// maxWebSocketMessageBytes bounds the request message a WebSocket client may send, which
+// is read before any validation: the message content allowed by MEMOS_AI_MAX_INPUT_BYTES
+// and a few base64-encoded images, plus JSON overhead. The request itself is then checked
+// against the same input limits as on HTTP.
+func (s *AIService) maxWebSocketMessageBytes() int {
+	return s.maxInputBytes + webSocketImageAllowance*base64.StdEncoding.EncodedLen(s.maxImageBytes) + webSocketJSONOverhead
+}
+
+func (s *AIService) serveChatWebSocket(ctx context.Context, c echo.Context, ws *websocket.Conn, apiKey string) {
+	ws.MaxPayloadBytes = s.maxWebSocketMessageBytes()
+	var message []byte
+	if err := websocket.Message.Receive(ws, &message); err != nil {
+		if errors.Is(err, websocket.ErrFrameTooLarge) {
+			s.sendWebSocketError(ctx, ws, echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("request message exceeds the limit of %d bytes", s.maxWebSocketMessageBytes())))
+			return
+		}
+		s.sendWebSocketError(ctx, ws, echo.NewHTTPError(http.StatusBadRequest, "Invalid request message").SetInternal(err))
+		return
+	}
+	req := new(ChatCompletionRequest)
+	if err := decodeChatCompletionRequest(message, req); err != nil {
+		s.sendWebSocketError(ctx, ws, err)
+		return
+	}
+	req.Stream = true
+
+	// The connection is hijacked, so the request context is not cancelled when the client
+	// goes away; a closed socket is noticed by reading from it instead.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		defer cancel()
+		var ignored string
+		for websocket.Message.Receive(ws, &ignored) == nil {
+		}
+	}()
+
+	caller, err := s.resolveCaller(ctx, c)
+	if err != nil {
+		s.sendWebSocketError(ctx, ws, err)
+		return
+	}
+	ctx, session, newMessages, err := s.prepareChatCompletion(ctx, caller, apiKey, req)
+	if err != nil {
+		s.sendWebSocketError(ctx, ws, err)
+		return
+	}
+	start := time.Now()
+	var usage *Usage
+	defer func() {
+		s.audit(c, req, usage, start, err)
+	}()
+
+	req.StreamOptions = &StreamOptions{IncludeUsage: true}
+	resp, provider, _, err := s.startStream(ctx, apiKey, req)
+	if err != nil {
+		s.sendWebSocketError(ctx, ws, err)
+		return
+	}
+	defer resp.Body.Close()
+	var reply string
+	usage, reply, err = s.streamWebSocket(ctx, ws, resp.Body, provider)
+	recordUsage(ctx, req.Model, usage)
+	if err != nil {
+		s.log(ctx).Debug("AI WebSocket stream aborted by client disconnect", "error", err)
+		return
+	}
+	if session != nil {
+		s.saveSession(ctx, session, newMessages, reply)
+	}
+	if err := websocket.JSON.Send(ws, &WebSocketFrame{Type: webSocketFrameDone, Usage: usage}); err != nil {
+		s.log(ctx).Debug("failed to send WebSocket done frame", "error", err)
+	}
+}
+
+// streamWebSocket forwards the reply carried by an upstream stream as token frames.
+// It returns the usage reported in the stream, the reply text and an error when the
+// client went away before the stream ended.
+func (s *AIService) streamWebSocket(ctx context.Context, ws *websocket.Conn, body io.Reader, provider Provider) (*Usage, string, error) {
+	var usage *Usage
+	var reply []byte
+	decoder := newStreamDecoder(provider, newStreamLineReader(body, s.maxResponseBytes))
+	filter := newStreamFilter(s.responseFilters)
+	for {
+		delta, err := decoder.Next()
+		if err != nil {
+			var chunkErr *streamChunkError
+			switch {
+			case errors.As(err, &chunkErr):
+				s.log(ctx).Warn("failed to decode AI stream chunk", "error", err)
+				continue
+			case errors.Is(err, errStreamTooLarge):
+				s.log(ctx).Warn("AI stream exceeded the response size limit, closing", "limit", s.maxResponseBytes)
+			case ctx.Err() != nil:
+				return usage, string(reply), ctx.Err()
+			}
+			if rest := filter.rest(); rest != "" {
+				reply = append(reply, rest...)
+				if sendErr := websocket.JSON.Send(ws, &WebSocketFrame{Type: webSocketFrameToken, Content: rest}); sendErr != nil {
+					return usage, string(reply), sendErr
+				}
+			}
+			return usage, string(reply), nil
+		}
+		if delta.Usage != nil {
+			usage = delta.Usage
+		}
+		filter.apply(delta)
+		if delta.Content != "" {
+			reply = append(reply, delta.Content...)
+			// A client that stops reading fails the send instead of holding the upstream open.
+			_ = ws.SetWriteDeadline(time.Now().Add(s.streamWriteTimeout))
+			if sendErr := websocket.JSON.Send(ws, &WebSocketFrame{Type: webSocketFrameToken, Content: delta.Content}); sendErr != nil {
+				return usage, string(reply), sendErr
+			}
+			_ = ws.SetWriteDeadline(time.Time{})
+		}
+	}
+}
+
+// sendWebSocketError reports err to the client in an error frame.
+func (s *AIService) sendWebSocketError(ctx context.Context, ws *websocket.Conn, err error) {
+	status, detail := s.errorDetail(ctx, err)
+	frame := &WebSocketFrame{Type: webSocketFrameError, Status: status, Error: detail}
+	if sendErr := websocket.JSON.Send(ws, frame); sendErr != nil {
+		s.log(ctx).Debug("failed to send WebSocket error frame", "error", sendErr)
+	}
+}
@@ -0,0 +1,123 @@
+package ai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+// newWebSocketServer serves the /ai/ws handler and returns its WebSocket URL and origin.
+func newWebSocketServer(t *testing.T, s *AIService) (string, string) {
+	t.Helper()
+	e := echo.New()
+	e.GET("/ws", s.ChatWebSocket)
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/ws", server.URL
+}
+
+func TestChatWebSocketStreamsTokens(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hel\"}}]}\n\n" +
+			"data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"lo\"}}]}\n\n" +
+			"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":2,\"total_tokens\":5}}\n\n" +
+			"data: [DONE]\n\n"))
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	url, origin := newWebSocketServer(t, NewAIService(nil, "", "test-key"))
+
+	ws, err := websocket.Dial(url, "", origin)
+	require.NoError(t, err)
+	defer ws.Close()
+	require.NoError(t, websocket.JSON.Send(ws, &ChatCompletionRequest{Messages: []ChatCompletionMessage{{Role: "user", Content: "hi"}}}))
+
+	var reply string
+	for {
+		frame := new(WebSocketFrame)
+		require.NoError(t, websocket.JSON.Receive(ws, frame))
+		if frame.Type == webSocketFrameDone {
+			require.NotNil(t, frame.Usage)
+			require.Equal(t, 5, frame.Usage.TotalTokens)
+			break
+		}
+		require.Equal(t, webSocketFrameToken, frame.Type)
+		reply += frame.Content
+	}
+	require.Equal(t, "Hello", reply)
+}
+
+func TestChatWebSocketReportsErrors(t *testing.T) {
+	url, origin := newWebSocketServer(t, NewAIService(nil, "", "test-key"))
+
+	ws, err := websocket.Dial(url, "", origin)
+	require.NoError(t, err)
+	defer ws.Close()
+	require.NoError(t, websocket.JSON.Send(ws, &ChatCompletionRequest{}))
+
+	frame := new(WebSocketFrame)
+	require.NoError(t, websocket.JSON.Receive(ws, frame))
+	require.Equal(t, webSocketFrameError, frame.Type)
+	require.Equal(t, http.StatusBadRequest, frame.Status)
+	require.Equal(t, errorCodeInvalidRequest, frame.Error.Code)
+}
+
+func TestChatWebSocketRejectsCrossOrigin(t *testing.T) {
+	url, _ := newWebSocketServer(t, NewAIService(nil, "", "test-key"))
+
+	_, err := websocket.Dial(url, "", "https://evil.example.com")
+	require.Error(t, err)
+}
+
+func TestChatWebSocketCloseCancelsUpstream(t *testing.T) {
+	cancelled := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"partial\"}}]}\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		close(cancelled)
+	}))
+	defer upstream.Close()
+	t.Setenv("MEMOS_AI_BASE_URL", upstream.URL)
+	url, origin := newWebSocketServer(t, NewAIService(nil, "", "test-key"))
+
+	ws, err := websocket.Dial(url, "", origin)
+	require.NoError(t, err)
+	require.NoError(t, websocket.JSON.Send(ws, &ChatCompletionRequest{Messages: []ChatCompletionMessage{{Role: "user", Content: "hi"}}}))
+	frame := new(WebSocketFrame)
+	require.NoError(t, websocket.JSON.Receive(ws, frame))
+	require.Equal(t, "partial", frame.Content)
+	require.NoError(t, ws.Close())
+
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream request was not cancelled after the socket closed")
+	}
+}
+
+func TestChatWebSocketLimitsMessageSize(t *testing.T) {
+	t.Setenv("MEMOS_AI_MAX_INPUT_BYTES", "1024")
+	t.Setenv("MEMOS_AI_MAX_IMAGE_BYTES", "1024")
+	s := NewAIService(nil, "", "test-key")
+	require.Equal(t, 1024+webSocketImageAllowance*1368+webSocketJSONOverhead, s.maxWebSocketMessageBytes())
+	url, origin := newWebSocketServer(t, s)
+
+	ws, err := websocket.Dial(url, "", origin)
+	require.NoError(t, err)
+	defer ws.Close()
+	require.NoError(t, websocket.Message.Send(ws, strings.Repeat("x", s.maxWebSocketMessageBytes()+1)))
+
+	frame := new(WebSocketFrame)
+	require.NoError(t, websocket.JSON.Receive(ws, frame))
+	require.Equal(t, webSocketFrameError, frame.Type)
+	require.Equal(t, http.StatusRequestEntityTooLarge, frame.Status)
+}
@@ -13,6 +13,8 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/usememos/memos/internal/profile"
 	storepb "github.com/usememos/memos/proto/gen/store"
@@ -31,6 +33,8 @@ type Server struct {
 	Store   *store.Store
 
 	echoServer        *echo.Echo
+	metricsServer     *http.Server
+	aiService         *ai.AIService
 	runnerCancelFuncs []context.CancelFunc
 }
 
@@ -71,8 +75,22 @@ func NewServer(ctx context.Context, profile *profile.Profile, store *store.Store
 	apiV1Service := apiv1.NewAPIV1Service(s.Secret, profile, store)
 
 	// Register AI Service
-	aiService := ai.NewAIService("")
+	aiService, err := ai.NewAIServiceFromConfig(store, s.Secret, ai.LoadConfig(slog.Default()), slog.Default())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create AI service")
+	}
 	aiService.RegisterRoutes(echoServer.Group("/api/v1"))
+	s.aiService = aiService
+
+	// Serve Prometheus metrics on their own listener, only when one is configured: they break
+	// down traffic and token usage per endpoint and model, which is not for the public.
+	if profile.MetricsAddr != "" {
+		metricsRegistry := prometheus.NewRegistry()
+		ai.RegisterMetrics(metricsRegistry)
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
+		s.metricsServer = &http.Server{Addr: profile.MetricsAddr, Handler: metricsMux, ReadHeaderTimeout: 10 * time.Second}
+	}
 
 	// Register HTTP file server routes BEFORE gRPC-Gateway to ensure proper range request handling for Safari.
 	// This uses native HTTP serving (http.ServeContent) instead of gRPC for video/audio files.
@@ -110,6 +128,17 @@ func (s *Server) Start(ctx context.Context) error {
 			slog.Error("failed to start echo server", "error", err)
 		}
 	}()
+	if s.metricsServer != nil {
+		metricsListener, err := net.Listen("tcp", s.metricsServer.Addr)
+		if err != nil {
+			return errors.Wrap(err, "failed to listen for metrics")
+		}
+		go func() {
+			if err := s.metricsServer.Serve(metricsListener); err != nil && err != http.ErrServerClosed {
+				slog.Error("failed to start metrics server", "error", err)
+			}
+		}()
+	}
 	s.StartBackgroundRunners(ctx)
 
 	return nil
@@ -128,10 +157,21 @@ func (s *Server) Shutdown(ctx context.Context) {
 		}
 	}
 
+	// Let in-flight AI streams finish before connections are closed; hijacked WebSocket
+	// connections are not tracked by the echo server.
+	if err := s.aiService.Shutdown(ctx); err != nil {
+		slog.Error("failed to drain AI requests", slog.String("error", err.Error()))
+	}
+
 	// Shutdown echo server.
 	if err := s.echoServer.Shutdown(ctx); err != nil {
 		slog.Error("failed to shutdown server", slog.String("error", err.Error()))
 	}
+	if s.metricsServer != nil {
+		if err := s.metricsServer.Shutdown(ctx); err != nil {
+			slog.Error("failed to shutdown metrics server", slog.String("error", err.Error()))
+		}
+	}
 
 	// Close database connection.
 	if err := s.Store.Close(); err != nil {
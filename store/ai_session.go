@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+)
+
+// AISession is a saved AI chat conversation.
+type AISession struct {
+	ID        int32
+	CreatorID int32
+	CreatedTs int64
+	Title     string
+}
+
+type FindAISession struct {
+	ID        *int32
+	CreatorID *int32
+}
+
+// AIMessage is one message of an AISession, in the order it was created.
+type AIMessage struct {
+	ID        int32
+	SessionID int32
+	CreatedTs int64
+	Role      string
+	Content   string
+}
+
+type FindAIMessage struct {
+	SessionID *int32
+}
+
+func (s *Store) CreateAISession(ctx context.Context, create *AISession) (*AISession, error) {
+	return s.driver.CreateAISession(ctx, create)
+}
+
+func (s *Store) ListAISessions(ctx context.Context, find *FindAISession) ([]*AISession, error) {
+	return s.driver.ListAISessions(ctx, find)
+}
+
+func (s *Store) GetAISession(ctx context.Context, find *FindAISession) (*AISession, error) {
+	list, err := s.ListAISessions(ctx, find)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return list[0], nil
+}
+
+func (s *Store) CreateAIMessage(ctx context.Context, create *AIMessage) (*AIMessage, error) {
+	return s.driver.CreateAIMessage(ctx, create)
+}
+
+func (s *Store) ListAIMessages(ctx context.Context, find *FindAIMessage) ([]*AIMessage, error) {
+	return s.driver.ListAIMessages(ctx, find)
+}
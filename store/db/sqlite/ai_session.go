@@ -0,0 +1,133 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+
+	"github.com/usememos/memos/store"
+)
+
+func (d *DB) CreateAISession(ctx context.Context, create *store.AISession) (*store.AISession, error) {
+	fields := []string{"`creator_id`", "`title`"}
+	placeholder := []string{"?", "?"}
+	args := []any{create.CreatorID, create.Title}
+	stmt := "INSERT INTO `ai_session` (" + strings.Join(fields, ", ") + ") VALUES (" + strings.Join(placeholder, ", ") + ") RETURNING `id`, `created_ts`"
+	if err := d.db.QueryRowContext(ctx, stmt, args...).Scan(
+		&create.ID,
+		&create.CreatedTs,
+	); err != nil {
+		return nil, err
+	}
+
+	session := create
+	return session, nil
+}
+
+func (d *DB) ListAISessions(ctx context.Context, find *store.FindAISession) ([]*store.AISession, error) {
+	where, args := []string{"1 = 1"}, []any{}
+
+	if find.ID != nil {
+		where, args = append(where, "`id` = ?"), append(args, *find.ID)
+	}
+	if find.CreatorID != nil {
+		where, args = append(where, "`creator_id` = ?"), append(args, *find.CreatorID)
+	}
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT
+			id,
+			creator_id,
+			created_ts,
+			title
+		FROM ai_session
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id DESC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*store.AISession{}
+	for rows.Next() {
+		session := &store.AISession{}
+		if err := rows.Scan(
+			&session.ID,
+			&session.CreatorID,
+			&session.CreatedTs,
+			&session.Title,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (d *DB) CreateAIMessage(ctx context.Context, create *store.AIMessage) (*store.AIMessage, error) {
+	fields := []string{"`session_id`", "`role`", "`content`"}
+	placeholder := []string{"?", "?", "?"}
+	args := []any{create.SessionID, create.Role, create.Content}
+	stmt := "INSERT INTO `ai_message` (" + strings.Join(fields, ", ") + ") VALUES (" + strings.Join(placeholder, ", ") + ") RETURNING `id`, `created_ts`"
+	if err := d.db.QueryRowContext(ctx, stmt, args...).Scan(
+		&create.ID,
+		&create.CreatedTs,
+	); err != nil {
+		return nil, err
+	}
+
+	message := create
+	return message, nil
+}
+
+func (d *DB) ListAIMessages(ctx context.Context, find *store.FindAIMessage) ([]*store.AIMessage, error) {
+	where, args := []string{"1 = 1"}, []any{}
+
+	if find.SessionID != nil {
+		where, args = append(where, "`session_id` = ?"), append(args, *find.SessionID)
+	}
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT
+			id,
+			session_id,
+			created_ts,
+			role,
+			content
+		FROM ai_message
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id ASC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*store.AIMessage{}
+	for rows.Next() {
+		message := &store.AIMessage{}
+		if err := rows.Scan(
+			&message.ID,
+			&message.SessionID,
+			&message.CreatedTs,
+			&message.Role,
+			&message.Content,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, message)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
@@ -67,4 +67,10 @@ type Driver interface {
 	ListReactions(ctx context.Context, find *FindReaction) ([]*Reaction, error)
 	GetReaction(ctx context.Context, find *FindReaction) (*Reaction, error)
 	DeleteReaction(ctx context.Context, delete *DeleteReaction) error
+
+	// AISession model related methods.
+	CreateAISession(ctx context.Context, create *AISession) (*AISession, error)
+	ListAISessions(ctx context.Context, find *FindAISession) ([]*AISession, error)
+	CreateAIMessage(ctx context.Context, create *AIMessage) (*AIMessage, error)
+	ListAIMessages(ctx context.Context, find *FindAIMessage) ([]*AIMessage, error)
 }
@@ -0,0 +1,14 @@
+CREATE TABLE `ai_session` (
+  `id` INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `creator_id` INT NOT NULL,
+  `created_ts` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `title` VARCHAR(256) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE `ai_message` (
+  `id` INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `session_id` INT NOT NULL,
+  `created_ts` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `role` VARCHAR(256) NOT NULL,
+  `content` TEXT NOT NULL
+);
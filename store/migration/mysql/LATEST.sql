@@ -105,3 +105,20 @@ CREATE TABLE `reaction` (
   `reaction_type` VARCHAR(256) NOT NULL,
   UNIQUE(`creator_id`,`content_id`,`reaction_type`)  
 );
+
+-- ai_session
+CREATE TABLE `ai_session` (
+  `id` INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `creator_id` INT NOT NULL,
+  `created_ts` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `title` VARCHAR(256) NOT NULL DEFAULT ''
+);
+
+-- ai_message
+CREATE TABLE `ai_message` (
+  `id` INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `session_id` INT NOT NULL,
+  `created_ts` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `role` VARCHAR(256) NOT NULL,
+  `content` TEXT NOT NULL
+);
@@ -0,0 +1,14 @@
+CREATE TABLE ai_session (
+  id SERIAL PRIMARY KEY,
+  creator_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+  title TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE ai_message (
+  id SERIAL PRIMARY KEY,
+  session_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+  role TEXT NOT NULL,
+  content TEXT NOT NULL DEFAULT ''
+);
@@ -105,3 +105,20 @@ CREATE TABLE reaction (
   reaction_type TEXT NOT NULL,
   UNIQUE(creator_id, content_id, reaction_type)
 );
+
+-- ai_session
+CREATE TABLE ai_session (
+  id SERIAL PRIMARY KEY,
+  creator_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+  title TEXT NOT NULL DEFAULT ''
+);
+
+-- ai_message
+CREATE TABLE ai_message (
+  id SERIAL PRIMARY KEY,
+  session_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT EXTRACT(EPOCH FROM NOW()),
+  role TEXT NOT NULL,
+  content TEXT NOT NULL DEFAULT ''
+);
@@ -0,0 +1,14 @@
+CREATE TABLE ai_session (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  creator_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  title TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE ai_message (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  session_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  role TEXT NOT NULL,
+  content TEXT NOT NULL DEFAULT ''
+);
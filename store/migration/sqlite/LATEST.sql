@@ -106,3 +106,20 @@ CREATE TABLE reaction (
   reaction_type TEXT NOT NULL,
   UNIQUE(creator_id, content_id, reaction_type)
 );
+
+-- ai_session
+CREATE TABLE ai_session (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  creator_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  title TEXT NOT NULL DEFAULT ''
+);
+
+-- ai_message
+CREATE TABLE ai_message (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  session_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  role TEXT NOT NULL,
+  content TEXT NOT NULL DEFAULT ''
+);
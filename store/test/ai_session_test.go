@@ -0,0 +1,52 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/usememos/memos/store"
+)
+
+func TestAISessionStore(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	ts := NewTestingStore(ctx, t)
+
+	user, err := createTestingHostUser(ctx, ts)
+	require.NoError(t, err)
+
+	session, err := ts.CreateAISession(ctx, &store.AISession{
+		CreatorID: user.ID,
+		Title:     "Weekly planning",
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, session.ID)
+	require.NotEmpty(t, session.CreatedTs)
+
+	sessions, err := ts.ListAISessions(ctx, &store.FindAISession{CreatorID: &user.ID})
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, session, sessions[0])
+
+	otherUserID := user.ID + 1
+	sessions, err = ts.ListAISessions(ctx, &store.FindAISession{CreatorID: &otherUserID})
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+
+	for _, message := range []*store.AIMessage{
+		{SessionID: session.ID, Role: "user", Content: "What should I focus on?"},
+		{SessionID: session.ID, Role: "assistant", Content: "Finish the migration."},
+	} {
+		_, err := ts.CreateAIMessage(ctx, message)
+		require.NoError(t, err)
+	}
+	messages, err := ts.ListAIMessages(ctx, &store.FindAIMessage{SessionID: &session.ID})
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	require.Equal(t, "user", messages[0].Role)
+	require.Equal(t, "Finish the migration.", messages[1].Content)
+
+	ts.Close()
+}
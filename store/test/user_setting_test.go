@@ -873,3 +873,28 @@ func TestUserSettingJSONFieldsEdgeCases(t *testing.T) {
 
 	ts.Close()
 }
+
+func TestUserSettingAI(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	ts := NewTestingStore(ctx, t)
+	user, err := createTestingHostUser(ctx, ts)
+	require.NoError(t, err)
+
+	_, err = ts.UpsertUserSetting(ctx, &storepb.UserSetting{
+		UserId: user.ID,
+		Key:    storepb.UserSetting_AI,
+		Value:  &storepb.UserSetting_Ai{Ai: &storepb.AIUserSetting{ApiKey: "sk-user-key"}},
+	})
+	require.NoError(t, err)
+
+	setting, err := ts.GetUserSetting(ctx, &store.FindUserSetting{
+		UserID: &user.ID,
+		Key:    storepb.UserSetting_AI,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, setting)
+	require.Equal(t, "sk-user-key", setting.GetAi().GetApiKey())
+
+	ts.Close()
+}